@@ -0,0 +1,147 @@
+// signerd 独立的签名进程
+// 热钱包私钥只加载到本进程，业务进程通过 signer.mode: remote 调用 POST /v1/sign 签名，
+// 每笔签名先经过本进程自己的转出策略引擎（黑白名单、单笔和每日上限、频率、审批），业务进程被攻破时也无法绕过。
+// 审批单和转出记录保存在与业务进程相同的数据库中：运营在业务进程中审批，业务进程按广播结果确认或归还额度，
+// 建议为本进程使用只能读写 payout_approval 和 payout_spend 表的数据库账号
+//
+//	CLAIMASK_MASTER_KEY=k1:... SIGNERD_TOKEN=... SIGNERD_DSN='user:pass@tcp(db:3306)/claimask?parseTime=true' \
+//	  signerd --keys keys.json --max-tx 500000000000 --daily-per-wallet 2000000000000
+//
+// keys.json 为地址到 claimask keys seal 输出密文的映射：{"D...": "enc:v1:..."}
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"claimask/comm/utils"
+	"claimask/pkg/keystore"
+	"claimask/pkg/policy"
+	"claimask/pkg/signer"
+
+	"github.com/spf13/pflag"
+	"go.uber.org/zap"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func main() {
+	flags := pflag.NewFlagSet("signerd", pflag.ContinueOnError)
+	listen := flags.String("listen", "127.0.0.1:7070", "监听地址")
+	keysFile := flags.String("keys", "keys.json", "私钥密文文件，地址到密文的映射")
+	masterKeyEnv := flags.String("master-key-env", "CLAIMASK_MASTER_KEY", "主密钥所在的环境变量")
+	masterKeyFile := flags.String("master-key-file", "", "主密钥文件，环境变量为空时读取")
+	activeKey := flags.String("active-key", "", "当前主密钥ID，只有一个主密钥时可不填")
+	tokenEnv := flags.String("token-env", "SIGNERD_TOKEN", "访问令牌所在的环境变量")
+	dsnEnv := flags.String("dsn-env", "SIGNERD_DSN", "审批单和转出记录所在数据库的DSN所在的环境变量")
+	var rules policy.Rules
+	flags.Int64Var(&rules.MaxTx, "max-tx", 0, "单笔交易转出上限（ELON），0为不限")
+	flags.Int64Var(&rules.DailyPerWallet, "daily-per-wallet", 0, "每个转出钱包每日转出上限（ELON），0为不限")
+	flags.Int64Var(&rules.DailyPerAddress, "daily-per-address", 0, "每个收款地址每日收款上限（ELON），0为不限")
+	flags.IntVar(&rules.VelocityCount, "velocity-count", 0, "每个转出钱包在 velocity-window 内最多签名的交易数，0为不限")
+	flags.DurationVar(&rules.VelocityWindow, "velocity-window", time.Hour, "频率统计窗口")
+	flags.StringSliceVar(&rules.Allow, "allow", nil, "收款地址白名单，非空时只允许转给名单中的地址")
+	flags.StringSliceVar(&rules.Deny, "deny", nil, "收款地址黑名单")
+	flags.Int64Var(&rules.ApprovalThreshold, "approval-threshold", 0, "单个收款地址的金额超过该值时需审批（ELON），0为不需要")
+	logLevel := flags.String("log-level", "info", "日志级别")
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	utils.InitLogger(*logLevel)
+	if err := run(*listen, *keysFile, *masterKeyEnv, *masterKeyFile, *activeKey, os.Getenv(*tokenEnv), os.Getenv(*dsnEnv), rules); err != nil {
+		zap.L().Fatal("签名进程异常退出", zap.Error(err))
+	}
+	zap.L().Info("签名进程已关闭")
+}
+
+// run 加载私钥并启动签名接口，收到 SIGINT/SIGTERM 后优雅关闭
+func run(listen, keysFile, masterKeyEnv, masterKeyFile, activeKey, token, dsn string, rules policy.Rules) error {
+	if token == "" {
+		return errors.New("access token is required")
+	}
+	if dsn == "" {
+		return errors.New("database DSN is required to persist spend usage and read approvals")
+	}
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return fmt.Errorf("open database failed: %w", err)
+	}
+	keyring, err := keystore.LoadKeyring(masterKeyEnv, masterKeyFile, activeKey)
+	if err != nil {
+		return err
+	}
+	sealed, err := loadKeys(keysFile)
+	if err != nil {
+		return err
+	}
+	// 启动时逐个解密校验，避免签名时才发现密文或主密钥错误
+	for address, value := range sealed {
+		key, err := keyring.Open(value)
+		if err != nil {
+			return fmt.Errorf("open key of %s failed: %w", address, err)
+		}
+		keystore.Zero(key)
+	}
+
+	keys := keystore.New(keyring, keystore.NewMemoryBackend(sealed))
+	engine := policy.NewEngine(rules, policy.NewGormStore(db))
+	handler := signer.NewHandler(signer.NewLocal(keys, engine), token)
+	server := &http.Server{Addr: listen, Handler: handler, ReadHeaderTimeout: 5 * time.Second}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		zap.L().Info("签名进程启动", zap.String("listen", listen), zap.Int("keys", len(sealed)),
+			zap.Int64("maxTx", rules.MaxTx), zap.Int64("dailyPerWallet", rules.DailyPerWallet),
+			zap.Int64("dailyPerAddress", rules.DailyPerAddress), zap.Int("velocityCount", rules.VelocityCount),
+			zap.Int("allow", len(rules.Allow)), zap.Int("deny", len(rules.Deny)), zap.Int64("approvalThreshold", rules.ApprovalThreshold))
+		serveErr <- server.ListenAndServe()
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	select {
+	case err := <-serveErr:
+		return err
+	case sig := <-quit:
+		zap.L().Info("收到退出信号", zap.String("signal", sig.String()))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return server.Shutdown(ctx)
+}
+
+// loadKeys 读取地址到私钥密文的映射，拒绝明文私钥
+func loadKeys(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read keys file failed: %w", err)
+	}
+	var sealed map[string]string
+	if err := json.Unmarshal(data, &sealed); err != nil {
+		return nil, fmt.Errorf("parse keys file failed: %w", err)
+	}
+	if len(sealed) == 0 {
+		return nil, errors.New("keys file has no keys")
+	}
+	for address, value := range sealed {
+		if !keystore.IsSealed(value) {
+			return nil, fmt.Errorf("key of %s must be sealed with `claimask keys seal`", address)
+		}
+	}
+	return sealed, nil
+}
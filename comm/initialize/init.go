@@ -6,48 +6,107 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
 	"reflect"
 	"runtime"
+	"sync"
+	"syscall"
 	"time"
 
+	"claimask/comm/utils"
+	"claimask/conf"
+	"claimask/pkg/dogechain"
+	"claimask/pkg/keystore"
+	"claimask/pkg/mq"
+
 	"github.com/gin-gonic/gin"
-	"github.com/jinzhu/gorm"
-	_ "github.com/jinzhu/gorm/dialects/mysql"
-	"github.com/spf13/viper"
+	"github.com/go-redis/redis"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
+// defaultShutdownTimeout 未配置 server.shutdownTimeout 时的优雅关闭超时
+const defaultShutdownTimeout = 15 * time.Second
+
+// Module 服务模块的生命周期
+// Init 在HTTP服务启动前调用，用于创建服务和注册路由；Start 启动后台任务；
+// Stop 在HTTP服务排空后按注册的逆序调用，ctx 到期后应尽快返回
+type Module interface {
+	Name() string
+	Init(s *Server) error
+	Start() error
+	Stop(ctx context.Context) error
+}
+
 // Server server
 type Server struct {
 	httpServer *http.Server
 	Engine     *gin.Engine
+	cfg        *conf.Config
+
+	mysqlDB     *gorm.DB
+	redisClient *redis.Client
+	rpcClient   *dogechain.RPCClient
+	publisher   mq.Publisher
+	subscriber  mq.Subscriber
 
-	mysqlDB *gorm.DB
+	keyringOnce sync.Once
+	keyring     *keystore.Keyring
+	keyringErr  error
+
+	modules  []Module
+	started  []Module
+	reloads  []func(*conf.Config)
+	serveErr chan error
 }
 
 var server *Server
 
-// NewServer 创建服务器
-func NewServer(configPath string) *Server {
-	// 加载配置文件
-	viper.SetConfigFile(configPath)
-	if err := viper.ReadInConfig(); err != nil {
-		log.Fatalf("读取配置文件失败: %v", err)
-	}
+// NewServer 按配置创建服务器，初始化日志、数据库、Redis、RPC和消息总线
+func NewServer(cfg *conf.Config) *Server {
+	// 初始化日志
+	utils.InitLogger(cfg.Log.Level)
+	zap.L().Info("配置加载完成", zap.Any("config", cfg))
 
-	db, err := InitDB()
+	db, err := InitDB(cfg.MySQL, cfg.System.Env)
 	if err != nil {
 		log.Fatalf("初始化数据库失败: %v", err)
 	}
 
+	redisClient := InitRedis(cfg.Redis.Addr, cfg.Redis.Password.Reveal(), cfg.Redis.DB)
+
+	rpcClient := InitDogecoinRPC(cfg.RPC.IP, cfg.RPC.Port, cfg.RPC.User, cfg.RPC.Password.Reveal())
+
+	publisher, subscriber, err := mq.Open(mq.Config{
+		Driver:     cfg.MQ.Driver,
+		Brokers:    cfg.MQ.Brokers,
+		Redis:      redisClient,
+		MaxLen:     cfg.MQ.MaxLen,
+		RetryDelay: cfg.MQ.RetryDelay,
+	})
+	if err != nil {
+		log.Fatalf("初始化消息总线失败: %v", err)
+	}
+
 	e := gin.Default()
 	server = &Server{
 		httpServer: &http.Server{
-			Addr:    fmt.Sprintf(":%s", viper.GetString("server.port")),
+			Addr:    fmt.Sprintf(":%d", cfg.Server.Port),
 			Handler: e,
 		},
-		Engine:  e,
-		mysqlDB: db,
+		Engine:      e,
+		cfg:         cfg,
+		mysqlDB:     db,
+		redisClient: redisClient,
+		rpcClient:   rpcClient,
+		publisher:   publisher,
+		subscriber:  subscriber,
+		serveErr:    make(chan error, 1),
 	}
+	server.OnReload(func(c *conf.Config) {
+		utils.SetLogLevel(c.Log.Level)
+	})
 
 	return server
 }
@@ -60,54 +119,186 @@ func (s *Server) AddServer(serverFunc func(e *gin.Engine)) *Server {
 	return s
 }
 
+// Register 注册模块，模块按注册顺序初始化和启动，按逆序停止
+func (s *Server) Register(modules ...Module) *Server {
+	for _, m := range modules {
+		log.Printf("[模块注册] 模块名称: %s\n", m.Name())
+	}
+	s.modules = append(s.modules, modules...)
+	return s
+}
+
+// Config 启动时加载的配置，热更新的配置项通过 OnReload 获取
+func (s *Server) Config() *conf.Config {
+	return s.cfg
+}
+
+// OnReload 注册配置热更新回调，配置文件中可热更新的配置项变化后按注册顺序调用
+func (s *Server) OnReload(fn func(*conf.Config)) {
+	s.reloads = append(s.reloads, fn)
+}
+
+// DB 共享的数据库连接池
+func (s *Server) DB() *gorm.DB {
+	return s.mysqlDB
+}
+
+// Redis 共享的Redis连接
+func (s *Server) Redis() *redis.Client {
+	return s.redisClient
+}
+
+// RPC Dogecoin节点RPC客户端
+func (s *Server) RPC() *dogechain.RPCClient {
+	return s.rpcClient
+}
+
+// Publisher 消息总线发布端
+func (s *Server) Publisher() mq.Publisher {
+	return s.publisher
+}
+
+// Subscriber 消息总线订阅端
+func (s *Server) Subscriber() mq.Subscriber {
+	return s.subscriber
+}
+
+// Keyring 私钥加密的主密钥，首次调用时按 keystore 配置读取
+// 未配置主密钥时返回 keystore.ErrNoMasterKey
+func (s *Server) Keyring() (*keystore.Keyring, error) {
+	s.keyringOnce.Do(func() {
+		s.keyring, s.keyringErr = LoadKeyring(s.cfg.Keystore)
+	})
+	return s.keyring, s.keyringErr
+}
+
+// Run 初始化并启动所有模块和HTTP服务，收到 SIGINT/SIGTERM 或HTTP服务异常退出后优雅关闭
+func (s *Server) Run() error {
+	for _, m := range s.modules {
+		if err := m.Init(s); err != nil {
+			s.closeResources()
+			return fmt.Errorf("init module %s failed: %w", m.Name(), err)
+		}
+	}
+	for _, m := range s.modules {
+		if err := m.Start(); err != nil {
+			startErr := fmt.Errorf("start module %s failed: %w", m.Name(), err)
+			return errors.Join(startErr, s.Shutdown())
+		}
+		s.started = append(s.started, m)
+	}
+	s.AsyncStart()
+	if s.cfg != nil {
+		s.cfg.Watch(zap.L(), s.reload)
+	}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	var serveErr error
+	select {
+	case sig := <-quit:
+		zap.L().Info("收到退出信号，开始优雅关闭", zap.String("signal", sig.String()))
+	case serveErr = <-s.serveErr:
+		zap.L().Error("HTTP服务异常退出，开始关闭", zap.Error(serveErr))
+	}
+	return errors.Join(serveErr, s.Shutdown())
+}
+
+// reload 依次调用配置热更新回调
+func (s *Server) reload(cfg *conf.Config) {
+	for _, fn := range s.reloads {
+		fn(cfg)
+	}
+}
+
 // AsyncStart async start
 func (s *Server) AsyncStart() {
 	log.Printf("[服务启动] 服务地址: %s\n", s.httpServer.Addr)
 	go func() {
 		if err := s.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Printf("[服务启动] 服务异常: %v\n", err)
+			if s.serveErr != nil {
+				s.serveErr <- err
+			}
 		}
 	}()
 }
 
-// Stop stop
-func (s *Server) Stop() {
-	c, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	log.Println("[服务关闭] 关闭服务")
-	if err := s.httpServer.Shutdown(c); err != nil {
-		log.Fatalf("[服务关闭] 关闭服务异常: %v\n", err)
+// Shutdown 在 server.shutdownTimeout 内依次排空HTTP请求、按逆序停止已启动的模块、
+// 关闭消息总线（发布端刷出缓冲的消息）、Redis和数据库连接，返回过程中的所有错误
+func (s *Server) Shutdown() error {
+	var timeout time.Duration
+	if s.cfg != nil {
+		timeout = s.cfg.Server.ShutdownTimeout
 	}
-}
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
-// GetMysqlInstance get mysql instance
-func GetMysqlInstance() *gorm.DB {
-	return server.mysqlDB
-}
+	var errs []error
+	log.Println("[服务关闭] 关闭HTTP服务")
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("shutdown http server: %w", err))
+	}
 
-// InitDB 初始化数据库连接并迁移表结构
-func InitDB() (*gorm.DB, error) {
-	dbUser := viper.GetString("mysql.user")
-	dbPassword := viper.GetString("mysql.password")
-	dbHost := viper.GetString("mysql.host")
-	dbPort := viper.GetInt("mysql.port")
-	dbName := viper.GetString("mysql.database")
+	for i := len(s.started) - 1; i >= 0; i-- {
+		m := s.started[i]
+		log.Printf("[服务关闭] 停止模块: %s\n", m.Name())
+		if err := m.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stop module %s: %w", m.Name(), err))
+		}
+	}
+	s.started = nil
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		dbUser, dbPassword, dbHost, dbPort, dbName)
+	errs = append(errs, s.closeResources())
+	return errors.Join(errs...)
+}
 
-	db, err := gorm.Open("mysql", dsn)
-	if err != nil {
-		return nil, err
+// closeResources 关闭消息总线、Redis和数据库连接
+func (s *Server) closeResources() error {
+	var errs []error
+	if s.publisher != nil {
+		errs = append(errs, s.publisher.Close())
+	}
+	if s.subscriber != nil {
+		errs = append(errs, s.subscriber.Close())
+	}
+	if s.redisClient != nil {
+		errs = append(errs, s.redisClient.Close())
 	}
+	if s.mysqlDB != nil {
+		errs = append(errs, CloseDB(s.mysqlDB))
+	}
+	return errors.Join(errs...)
+}
 
-	// 设置连接池
-	db.DB().SetMaxIdleConns(10)
-	db.DB().SetMaxOpenConns(100)
-	db.DB().SetConnMaxLifetime(time.Hour)
+// Stop stop
+func (s *Server) Stop() {
+	if err := s.Shutdown(); err != nil {
+		log.Printf("[服务关闭] 关闭服务异常: %v\n", err)
+	}
+}
 
-	// 开启日志
-	db.LogMode(true)
+// StopFunc 在ctx到期前等待stop返回，用于将阻塞的停止函数适配到 Module.Stop
+func StopFunc(ctx context.Context, stop func()) error {
+	done := make(chan struct{})
+	go func() {
+		stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
-	return db, nil
+// GetMysqlInstance get mysql instance
+func GetMysqlInstance() *gorm.DB {
+	return server.mysqlDB
 }
@@ -0,0 +1,59 @@
+package initialize
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// fakeModule 记录停止顺序的测试模块
+type fakeModule struct {
+	name    string
+	stopped *[]string
+	stopErr error
+}
+
+func (m *fakeModule) Name() string         { return m.name }
+func (m *fakeModule) Init(s *Server) error { return nil }
+func (m *fakeModule) Start() error         { return nil }
+func (m *fakeModule) Stop(ctx context.Context) error {
+	*m.stopped = append(*m.stopped, m.name)
+	return m.stopErr
+}
+
+// 测试关闭时按启动的逆序停止模块，并汇总停止错误
+func TestShutdownStopsModulesInReverseOrder(t *testing.T) {
+	var stopped []string
+	stopErr := errors.New("boom")
+	s := &Server{httpServer: &http.Server{}}
+	s.started = []Module{
+		&fakeModule{name: "a", stopped: &stopped},
+		&fakeModule{name: "b", stopped: &stopped, stopErr: stopErr},
+		&fakeModule{name: "c", stopped: &stopped},
+	}
+
+	err := s.Shutdown()
+	if !errors.Is(err, stopErr) {
+		t.Errorf("Expected stop error to be reported, got %v", err)
+	}
+	if len(stopped) != 3 || stopped[0] != "c" || stopped[1] != "b" || stopped[2] != "a" {
+		t.Errorf("Unexpected stop order %v", stopped)
+	}
+}
+
+// 测试StopFunc在ctx到期后不再等待阻塞的停止函数
+func TestStopFuncTimeout(t *testing.T) {
+	if err := StopFunc(context.Background(), func() {}); err != nil {
+		t.Errorf("Expected nil, got %v", err)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := StopFunc(ctx, func() { <-release }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected DeadlineExceeded, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package initialize
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"claimask/conf"
+	"claimask/pkg/keystore"
+)
+
+// KeysUsage keys 子命令用法
+const KeysUsage = `usage: claimask keys <command>
+  gen <id>              生成一个主密钥，输出 id:base64，追加到主密钥环境变量或文件
+  seal                  从标准输入读取私钥，用当前主密钥加密后输出，填入配置文件
+  rewrap                从标准输入读取密文，用当前主密钥重新加密数据密钥后输出，用于轮换配置文件中的私钥
+数据库中钱包组的私钥通过 POST /api/v1/admin/wallets/rotate-keys 轮换`
+
+// LoadKeyring 按配置读取主密钥
+func LoadKeyring(cfg conf.KeystoreConfig) (*keystore.Keyring, error) {
+	return keystore.LoadKeyring(cfg.MasterKeyEnv, cfg.MasterKeyFile, cfg.ActiveKey)
+}
+
+// Keys 执行 keys 子命令，只读取主密钥，不连接数据库
+func Keys(cfg *conf.Config, args []string, in io.Reader, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(KeysUsage)
+	}
+
+	switch {
+	case args[0] == "gen" && len(args) == 2:
+		entry, err := keystore.GenerateMasterKey(args[1])
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, entry)
+		return err
+	case (args[0] == "seal" || args[0] == "rewrap") && len(args) == 1:
+	default:
+		return errors.New(KeysUsage)
+	}
+
+	keyring, err := LoadKeyring(cfg.Keystore)
+	if err != nil {
+		return err
+	}
+	input, err := readLine(in)
+	if err != nil {
+		return err
+	}
+	defer keystore.Zero(input)
+
+	var sealed string
+	if args[0] == "seal" {
+		sealed, err = keyring.Seal(input)
+	} else {
+		sealed, _, err = keyring.Rewrap(string(input))
+	}
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, sealed)
+	return err
+}
+
+// readLine 读取第一行并去掉首尾空白
+func readLine(in io.Reader) ([]byte, error) {
+	line, err := bufio.NewReader(in).ReadBytes('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	// 不经过字符串，避免私钥留下无法清零的副本
+	trimmed := append([]byte(nil), bytes.TrimSpace(line)...)
+	keystore.Zero(line)
+	if len(trimmed) == 0 {
+		return nil, errors.New("keys: empty input")
+	}
+	return trimmed, nil
+}
@@ -0,0 +1,54 @@
+package initialize
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"claimask/conf"
+)
+
+// 测试 keys 子命令生成主密钥、加密私钥和轮换主密钥
+func TestKeys(t *testing.T) {
+	cfg := &conf.Config{Keystore: conf.KeystoreConfig{MasterKeyEnv: "TEST_CLAIMASK_MASTER_KEY"}}
+	run := func(input string, args ...string) string {
+		t.Helper()
+		var out bytes.Buffer
+		if err := Keys(cfg, args, strings.NewReader(input), &out); err != nil {
+			t.Fatalf("keys %v failed: %v", args, err)
+		}
+		return strings.TrimSpace(out.String())
+	}
+
+	oldKey, newKey := run("", "gen", "old"), run("", "gen", "new")
+	t.Setenv("TEST_CLAIMASK_MASTER_KEY", oldKey)
+	sealed := run("QWifPrivateKey\n", "seal")
+	if !strings.HasPrefix(sealed, "enc:v1:old:") {
+		t.Fatalf("Unexpected sealed value %q", sealed)
+	}
+
+	t.Setenv("TEST_CLAIMASK_MASTER_KEY", oldKey+","+newKey)
+	cfg.Keystore.ActiveKey = "new"
+	rewrapped := run(sealed, "rewrap")
+	if !strings.HasPrefix(rewrapped, "enc:v1:new:") {
+		t.Fatalf("Unexpected rewrapped value %q", rewrapped)
+	}
+
+	keyring, err := LoadKeyring(cfg.Keystore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := keyring.Open(rewrapped)
+	if err != nil || string(plain) != "QWifPrivateKey" {
+		t.Errorf("Open got %q, %v", plain, err)
+	}
+
+	for _, args := range [][]string{nil, {"gen"}, {"seal", "x"}, {"show"}} {
+		if err := Keys(cfg, args, strings.NewReader("x"), &bytes.Buffer{}); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+	if err := Keys(cfg, []string{"seal"}, strings.NewReader("\n"), &bytes.Buffer{}); err == nil {
+		t.Error("Expected empty input to be rejected")
+	}
+}
@@ -0,0 +1,145 @@
+package initialize
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"claimask/conf"
+	"claimask/pkg/migrate"
+	"claimask/sql/migrations"
+)
+
+// MigrateUsage migrate 子命令用法
+const MigrateUsage = `usage: claimask migrate <command>
+  up [version]          执行未执行的迁移，指定版本号时执行到该版本为止
+  down [steps]          回滚最近执行的迁移，默认1个
+  status                查看各版本的执行状态
+  force <version> [up|down]  修复 dirty 版本后重置状态：up 记为已执行（默认），down 删除执行记录`
+
+// migrateCommand 解析后的 migrate 子命令
+type migrateCommand struct {
+	name    string
+	version uint64
+	steps   int
+	applied bool
+}
+
+// parseMigrateArgs 解析 migrate 子命令参数
+func parseMigrateArgs(args []string) (migrateCommand, error) {
+	if len(args) == 0 {
+		return migrateCommand{}, errors.New(MigrateUsage)
+	}
+	cmd := migrateCommand{name: args[0], steps: 1, applied: true}
+	rest := args[1:]
+
+	var err error
+	switch cmd.name {
+	case "up":
+		if len(rest) > 1 {
+			return cmd, errors.New(MigrateUsage)
+		}
+		if len(rest) == 1 {
+			cmd.version, err = strconv.ParseUint(rest[0], 10, 64)
+		}
+	case "down":
+		if len(rest) > 1 {
+			return cmd, errors.New(MigrateUsage)
+		}
+		if len(rest) == 1 {
+			cmd.steps, err = strconv.Atoi(rest[0])
+			if err == nil && cmd.steps <= 0 {
+				err = fmt.Errorf("steps must be positive, got %d", cmd.steps)
+			}
+		}
+	case "status":
+		if len(rest) != 0 {
+			return cmd, errors.New(MigrateUsage)
+		}
+	case "force":
+		if len(rest) == 0 || len(rest) > 2 {
+			return cmd, errors.New(MigrateUsage)
+		}
+		cmd.version, err = strconv.ParseUint(rest[0], 10, 64)
+		if len(rest) == 2 {
+			switch rest[1] {
+			case "up":
+			case "down":
+				cmd.applied = false
+			default:
+				return cmd, errors.New(MigrateUsage)
+			}
+		}
+	default:
+		return cmd, errors.New(MigrateUsage)
+	}
+	if err != nil {
+		return cmd, fmt.Errorf("bad %s argument: %w", cmd.name, err)
+	}
+	return cmd, nil
+}
+
+// Migrate 执行 migrate 子命令，只连接数据库，不初始化其他组件
+func Migrate(cfg *conf.Config, args []string) error {
+	cmd, err := parseMigrateArgs(args)
+	if err != nil {
+		return err
+	}
+
+	db, err := InitDB(cfg.MySQL, cfg.System.Env)
+	if err != nil {
+		return fmt.Errorf("init database failed: %w", err)
+	}
+	defer CloseDB(db)
+
+	migrator, err := migrate.New(db, migrations.FS, log.Default())
+	if err != nil {
+		return err
+	}
+
+	switch cmd.name {
+	case "up":
+		done, err := migrator.Up(cmd.version)
+		log.Printf("[迁移] 执行了 %d 个迁移\n", len(done))
+		return err
+	case "down":
+		done, err := migrator.Down(cmd.steps)
+		log.Printf("[迁移] 回滚了 %d 个迁移\n", len(done))
+		return err
+	case "force":
+		return migrator.Force(cmd.version, cmd.applied)
+	default:
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		return printMigrateStatus(os.Stdout, statuses)
+	}
+}
+
+// printMigrateStatus 以表格输出各版本的执行状态
+func printMigrateStatus(w io.Writer, statuses []migrate.Status) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, s := range statuses {
+		state := "pending"
+		switch {
+		case s.Dirty:
+			state = "dirty"
+		case s.Missing:
+			state = "applied (missing file)"
+		case s.Applied:
+			state = "applied"
+		}
+		appliedAt := "-"
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(tw, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,59 @@
+package initialize
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"claimask/pkg/migrate"
+)
+
+// 测试 migrate 子命令参数解析
+func TestParseMigrateArgs(t *testing.T) {
+	cases := map[string]migrateCommand{
+		"up":           {name: "up", steps: 1, applied: true},
+		"up 3":         {name: "up", version: 3, steps: 1, applied: true},
+		"down":         {name: "down", steps: 1, applied: true},
+		"down 2":       {name: "down", steps: 2, applied: true},
+		"status":       {name: "status", steps: 1, applied: true},
+		"force 4":      {name: "force", version: 4, steps: 1, applied: true},
+		"force 4 down": {name: "force", version: 4, steps: 1, applied: false},
+	}
+	for args, want := range cases {
+		got, err := parseMigrateArgs(strings.Fields(args))
+		if err != nil || got != want {
+			t.Errorf("%q: got %+v, %v", args, got, err)
+		}
+	}
+
+	for _, args := range []string{"", "sideways", "up x", "down 0", "down -1", "status 1", "force", "force 4 maybe"} {
+		if _, err := parseMigrateArgs(strings.Fields(args)); err == nil {
+			t.Errorf("%q: expected error", args)
+		}
+	}
+}
+
+// 测试迁移状态表格输出
+func TestPrintMigrateStatus(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local)
+	var buf bytes.Buffer
+	err := printMigrateStatus(&buf, []migrate.Status{
+		{Version: 1, Name: "create_order_id", Applied: true, AppliedAt: &at},
+		{Version: 2, Name: "create_rich_reward_log", Applied: true, Dirty: true, AppliedAt: &at},
+		{Version: 3, Name: "create_claim_order"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Unexpected output:\n%s", buf.String())
+	}
+	for i, want := range []string{"0001 create_order_id applied 2024-01-02 03:04:05", "dirty", "pending"} {
+		if !strings.Contains(strings.Join(strings.Fields(lines[i+1]), " "), want) {
+			t.Errorf("line %d: expected %q in %q", i+1, want, lines[i+1])
+		}
+	}
+}
@@ -1,11 +1,17 @@
 package middleware
 
 import (
+	"claimask/comm/errno"
 	"claimask/comm/initialize"
+	"crypto/subtle"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
+// AdminTokenHeader 管理接口鉴权请求头
+const AdminTokenHeader = "X-Admin-Token"
+
 // Placeholder file for middleware package.
 
 // InitMiddleware 初始化全局中间件
@@ -18,3 +24,16 @@ func InitMiddleware(server *initialize.Server) {
 func AuthMiddleware(actions ...string) gin.HandlerFunc {
 	return nil
 }
+
+// AdminAuth 管理接口鉴权，校验请求头中的管理令牌
+// 未配置令牌时拒绝所有请求，避免管理接口在默认配置下暴露
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := c.GetHeader(AdminTokenHeader)
+		if token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, errno.ERR_UNAUTHORIZED)
+			return
+		}
+		c.Next()
+	}
+}
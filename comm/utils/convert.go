@@ -1,11 +1,13 @@
 package utils
 
-import "claimask/comm/constant"
+import "claimask/pkg/money"
 
+// DogeToElon 将浮点 DOGE 数四舍五入转换为 ELON
 func DogeToElon(amount float64) int64 {
-	return int64(amount * constant.DOGE_TO_ELON)
+	return money.FromDogeFloat(amount).Elon()
 }
 
+// ElonToDoge 将 ELON 转换为浮点 DOGE 数，仅用于展示
 func ElonToDoge(value int64) float64 {
-	return float64(value) * constant.ELON_TO_DOGE
+	return money.FromElon(value).DogeFloat()
 }
@@ -3,15 +3,16 @@ package utils
 import (
 	"os"
 
-	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+// logLevel 全局日志级别，支持运行时调整
+var logLevel = zap.NewAtomicLevel()
+
 // InitLogger 初始化日志
-func InitLogger() {
-	// 从配置获取日志级别
-	logLevel := getLogLevel(viper.GetString("log.level"))
+func InitLogger(level string) {
+	SetLogLevel(level)
 
 	// 创建基础配置
 	core := zapcore.NewCore(
@@ -27,6 +28,11 @@ func InitLogger() {
 	zap.ReplaceGlobals(logger)
 }
 
+// SetLogLevel 调整日志级别，用于配置热更新
+func SetLogLevel(level string) {
+	logLevel.SetLevel(getLogLevel(level))
+}
+
 // getLogLevel 获取日志级别
 func getLogLevel(level string) zapcore.Level {
 	switch level {
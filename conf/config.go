@@ -1,39 +1,413 @@
+// Package conf 服务配置
+// 配置按 默认值 < 配置文件 < 环境变量 < 命令行参数 的优先级合并后解析到 Config，启动时统一校验。
+// 环境变量以 CLAIMASK_ 为前缀，层级用下划线连接，如 CLAIMASK_MYSQL_PASSWORD 覆盖 mysql.password
 package conf
 
 import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
-	"go.uber.org/zap"
 )
 
+// DefaultPath 默认配置文件路径
+const DefaultPath = "./conf/config.yaml"
+
+// envPrefix 环境变量前缀
+const envPrefix = "CLAIMASK"
+
+// redacted 敏感配置在日志和序列化中的占位
+const redacted = "******"
+
+// Secret 敏感配置，打印和序列化时输出占位符，使用时通过 Reveal 取值
+type Secret string
+
+// Reveal 返回明文
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+// String 实现 fmt.Stringer，非空时输出占位符
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// MarshalJSON 序列化时输出占位符
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// SystemConfig 运行环境
+type SystemConfig struct {
+	Env string `mapstructure:"env"` // production 时关闭SQL日志
+}
+
+// LogConfig 日志配置，可热更新
+type LogConfig struct {
+	Level string `mapstructure:"level"` // debug / info / warn / error
+}
+
+// ServerConfig HTTP服务配置
+type ServerConfig struct {
+	Port            int           `mapstructure:"port"`
+	ShutdownTimeout time.Duration `mapstructure:"shutdownTimeout"` // 优雅关闭的总超时
+}
+
+// AdminConfig 管理接口配置
+type AdminConfig struct {
+	Token Secret `mapstructure:"token"` // 为空时管理接口不可用
+}
+
+// RPCConfig Dogecoin节点RPC配置
 type RPCConfig struct {
-	IP       string
-	Port     int
-	User     string
-	Password string
+	IP       string `mapstructure:"ip"`
+	Port     int    `mapstructure:"port"`
+	User     string `mapstructure:"user"`
+	Password Secret `mapstructure:"password"`
 }
 
+// RedisConfig Redis配置
 type RedisConfig struct {
-	Addr     string
-	Password string
-	DB       int
+	Addr     string `mapstructure:"addr"`
+	Password Secret `mapstructure:"password"`
+	DB       int    `mapstructure:"db"`
 }
 
-type ServerConfig struct {
-	Port string
+// MySQLConfig MySQL配置
+type MySQLConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	User     string `mapstructure:"user"`
+	Password Secret `mapstructure:"password"`
+	Database string `mapstructure:"database"`
 }
 
+// MQConfig 消息总线配置
+type MQConfig struct {
+	Driver     string        `mapstructure:"driver"` // kafka / redis / memory
+	Dev        bool          `mapstructure:"dev"`    // 开发环境，只有开启时才允许不持久化的 memory 驱动
+	Brokers    []string      `mapstructure:"brokers"`
+	MaxLen     int64         `mapstructure:"maxLen"`
+	RetryDelay time.Duration `mapstructure:"retryDelay"`
+}
+
+// QueueConfig 转账队列的速率限制，可热更新
+type QueueConfig struct {
+	MaxConcurrent int           `mapstructure:"maxConcurrent"` // 最大并发转账数
+	MaxRetries    int           `mapstructure:"maxRetries"`    // 单个任务最大重试次数
+	BaseDelay     time.Duration `mapstructure:"baseDelay"`     // 首次重试等待时间
+	MaxDelay      time.Duration `mapstructure:"maxDelay"`      // 重试等待上限
+}
+
+// ClaimaskConfig 奖品领取配置
+type ClaimaskConfig struct {
+	PrizeAmount int64 `mapstructure:"prizeAmount"` // 每份奖品数额（ELON）
+}
+
+// PayoutConfig 打款任务配置
+type PayoutConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	Address         string        `mapstructure:"address"`
+	PrivateKey      Secret        `mapstructure:"privateKey"` // 用 claimask keys seal 加密后的私钥，signer.mode 为 local 时使用
+	Interval        time.Duration `mapstructure:"interval"`
+	BatchSize       int           `mapstructure:"batchSize"`
+	MaxOutputs      int           `mapstructure:"maxOutputs"`
+	MaxBatchValue   int64         `mapstructure:"maxBatchValue"`
+	FeeRate         int64         `mapstructure:"feeRate"`
+	Confirmations   int64         `mapstructure:"confirmations"`
+	AddressCooldown time.Duration `mapstructure:"addressCooldown"` // 同一收款地址两次排队的最小间隔，0为不限制
+	RecoverAfter    time.Duration `mapstructure:"recoverAfter"`    // 排队超过该时长仍未广播的订单重新广播或退回
+}
+
+// ChainSignConfig 链上领取请求签名密钥
+type ChainSignConfig struct {
+	ActiveKey string            `mapstructure:"activeKey"`
+	Keys      map[string]Secret `mapstructure:"keys"`
+}
+
+// RichxChainConfig 链上领取服务
+type RichxChainConfig struct {
+	URL         string        `mapstructure:"url"`
+	Timeout     time.Duration `mapstructure:"timeout"`
+	MaxAttempts int           `mapstructure:"maxAttempts"`
+}
+
+// RichxConsumerConfig 领取消息消费
+type RichxConsumerConfig struct {
+	GroupID string `mapstructure:"groupId"`
+}
+
+// RichxOutboxConfig 领取消息外发
+type RichxOutboxConfig struct {
+	Interval  time.Duration `mapstructure:"interval"`
+	BatchSize int           `mapstructure:"batchSize"`
+	Retention time.Duration `mapstructure:"retention"`
+}
+
+// RichxReconcileConfig 处理中订单对账
+type RichxReconcileConfig struct {
+	Interval   time.Duration `mapstructure:"interval"`
+	StaleAfter time.Duration `mapstructure:"staleAfter"`
+}
+
+// RichxAccrualConfig 每日收益累加
+type RichxAccrualConfig struct {
+	Interval  time.Duration     `mapstructure:"interval"`
+	StartDate string            `mapstructure:"startDate"`
+	Rates     map[string]string `mapstructure:"rates"`
+}
+
+// RichxConfig 富豪奖励模块配置
+type RichxConfig struct {
+	Enabled   bool                 `mapstructure:"enabled"`
+	Timezone  string               `mapstructure:"timezone"`
+	ResetHour int                  `mapstructure:"resetHour"`
+	ChainSign ChainSignConfig      `mapstructure:"chainSign"`
+	Chain     RichxChainConfig     `mapstructure:"chain"`
+	Consumer  RichxConsumerConfig  `mapstructure:"consumer"`
+	Outbox    RichxOutboxConfig    `mapstructure:"outbox"`
+	Reconcile RichxReconcileConfig `mapstructure:"reconcile"`
+	Accrual   RichxAccrualConfig   `mapstructure:"accrual"`
+}
+
+// WalletGroup 收款钱包组
 type WalletGroup struct {
-	Group          int
-	Receive        string
-	ReceivePrivate string
+	Group          int    `mapstructure:"group"`
+	Receive        string `mapstructure:"receive"`
+	ReceivePrivate Secret `mapstructure:"receivePrivate"` // 用 claimask keys seal 加密后的私钥
 }
 
-func LoadConfig() {
-	viper.SetConfigName("config")
-	viper.AddConfigPath("./conf")
-	viper.AutomaticEnv()
+// KeystoreConfig 私钥加密的主密钥来源，主密钥本身不写入配置文件
+type KeystoreConfig struct {
+	MasterKeyEnv  string `mapstructure:"masterKeyEnv"`  // 主密钥所在的环境变量，优先于文件
+	MasterKeyFile string `mapstructure:"masterKeyFile"` // 主密钥文件
+	ActiveKey     string `mapstructure:"activeKey"`     // 加密新私钥使用的主密钥ID，只有一个主密钥时可不填
+}
+
+// SignerConfig 交易签名方式
+type SignerConfig struct {
+	Mode    string        `mapstructure:"mode"`    // local：进程内用 keystore 签名；remote：调用独立的签名进程 signerd
+	URL     string        `mapstructure:"url"`     // 签名进程地址
+	Token   Secret        `mapstructure:"token"`   // 签名进程访问令牌
+	Timeout time.Duration `mapstructure:"timeout"` // 单次签名请求超时
+}
+
+// VelocityConfig 转出频率上限
+type VelocityConfig struct {
+	Count  int           `mapstructure:"count"`  // 每个转出钱包在 window 内最多签名的交易数，0为不限
+	Window time.Duration `mapstructure:"window"` // 统计窗口
+}
+
+// PolicyConfig 转出策略，所有转出在签名前校验，金额单位为 ELON，0为不限，可热更新
+type PolicyConfig struct {
+	MaxTx             int64          `mapstructure:"maxTx"`             // 单笔交易转出上限
+	DailyPerWallet    int64          `mapstructure:"dailyPerWallet"`    // 每个转出钱包每日转出上限
+	DailyPerAddress   int64          `mapstructure:"dailyPerAddress"`   // 每个收款地址每日收款上限
+	Velocity          VelocityConfig `mapstructure:"velocity"`          // 转出频率上限
+	Allow             []string       `mapstructure:"allow"`             // 非空时只允许转给名单中的地址
+	Deny              []string       `mapstructure:"deny"`              // 禁止转给名单中的地址
+	ApprovalThreshold int64          `mapstructure:"approvalThreshold"` // 单笔超过该值需运营审批
+}
+
+// IndexerConfig UTXO索引，扫描区块记录监控地址的输出，节点需支持 getblock verbosity 2
+type IndexerConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`     // 多实例部署时只在一个实例开启
+	StartHeight int64         `mapstructure:"startHeight"` // 首次索引的起始高度，应不晚于监控地址首次收款的区块，0为从最新区块开始
+	Interval    time.Duration `mapstructure:"interval"`    // 同步间隔
+	ReorgDepth  int64         `mapstructure:"reorgDepth"`  // 可回滚的最大链重组深度
+}
+
+// MonitorConfig 链上交易监控配置
+type MonitorConfig struct {
+	WalletRefresh     time.Duration `mapstructure:"walletRefresh"`     // 从数据库刷新监控地址的间隔
+	BlockPollInterval time.Duration `mapstructure:"blockPollInterval"` // 区块轮询间隔
+	WebsocketEndpoint string        `mapstructure:"websocketEndpoint"`
+	Indexer           IndexerConfig `mapstructure:"indexer"`
+}
+
+// DepositConfig 订单收款地址，由账户扩展公钥按 BIP44 路径 m/44'/3'/account'/0/i 派生
+type DepositConfig struct {
+	XPub           string        `mapstructure:"xpub"`           // 账户层扩展公钥（dgub/xpub），为空时不分配订单收款地址
+	GapLimit       uint32        `mapstructure:"gapLimit"`       // 允许连续未收款的地址数，不能超过钱包恢复时的扫描间隔
+	ReuseAfter     time.Duration `mapstructure:"reuseAfter"`     // 达到间隔上限时，分配超过该时长仍未收款的地址可改派给新订单，0为不改派
+	AllowBeyondGap bool          `mapstructure:"allowBeyondGap"` // 达到间隔上限且没有可改派的地址时继续派生，默认拒绝分配
+}
+
+// TreasuryAlertConfig 资金告警
+type TreasuryAlertConfig struct {
+	Repeat  time.Duration `mapstructure:"repeat"`  // 持续低于下限时重复告警的间隔
+	Webhook string        `mapstructure:"webhook"` // 告警 POST 地址，为空时只写日志
+	Timeout time.Duration `mapstructure:"timeout"` // webhook 请求超时
+}
+
+// TreasuryConfig 资金监控：统计热钱包和钱包组余额，与待打款订单金额比较
+type TreasuryConfig struct {
+	Enabled          bool                `mapstructure:"enabled"`
+	Interval         time.Duration       `mapstructure:"interval"`         // 统计间隔
+	MinConfirmations int64               `mapstructure:"minConfirmations"` // 计入已确认余额所需的确认数
+	MinCoverage      float64             `mapstructure:"minCoverage"`      // 已确认余额与待打款金额之比的下限
+	Alert            TreasuryAlertConfig `mapstructure:"alert"`
+}
+
+// NFTConfig NFT监控配置
+type NFTConfig struct {
+	Tax        float64 `mapstructure:"tax"`
+	MonitorURL string  `mapstructure:"monitorUrl"`
+}
+
+// Config 服务配置
+type Config struct {
+	System   SystemConfig   `mapstructure:"system"`
+	Log      LogConfig      `mapstructure:"log"`
+	Server   ServerConfig   `mapstructure:"server"`
+	Admin    AdminConfig    `mapstructure:"admin"`
+	RPC      RPCConfig      `mapstructure:"rpc"`
+	Redis    RedisConfig    `mapstructure:"redis"`
+	MySQL    MySQLConfig    `mapstructure:"mysql"`
+	MQ       MQConfig       `mapstructure:"mq"`
+	Queue    QueueConfig    `mapstructure:"queue"`
+	Claimask ClaimaskConfig `mapstructure:"claimask"`
+	Payout   PayoutConfig   `mapstructure:"payout"`
+	Richx    RichxConfig    `mapstructure:"richx"`
+	Keystore KeystoreConfig `mapstructure:"keystore"`
+	Signer   SignerConfig   `mapstructure:"signer"`
+	Policy   PolicyConfig   `mapstructure:"policy"`
+	Wallets  []WalletGroup  `mapstructure:"wallets"` // 启动时写入数据库，之后以数据库为准
+	Monitor  MonitorConfig  `mapstructure:"monitor"`
+	Deposit  DepositConfig  `mapstructure:"deposit"`
+	Treasury TreasuryConfig `mapstructure:"treasury"`
+	NFT      NFTConfig      `mapstructure:"nft"`
+
+	source *viper.Viper // 热更新时重新读取
+}
+
+// setDefaults 未配置时使用的默认值
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("log.level", "info")
+	v.SetDefault("server.port", 8888)
+	v.SetDefault("server.shutdownTimeout", "15s")
+	v.SetDefault("mq.driver", "redis")
+	v.SetDefault("mq.retryDelay", "1s")
+	v.SetDefault("queue.maxConcurrent", 10)
+	v.SetDefault("queue.maxRetries", 3)
+	v.SetDefault("queue.baseDelay", "5s")
+	v.SetDefault("queue.maxDelay", "15s")
+	v.SetDefault("keystore.masterKeyEnv", "CLAIMASK_MASTER_KEY")
+	v.SetDefault("signer.mode", "local")
+	v.SetDefault("signer.timeout", "10s")
+	v.SetDefault("policy.velocity.window", "1h")
+	v.SetDefault("monitor.walletRefresh", "30s")
+	v.SetDefault("monitor.blockPollInterval", "60s")
+	v.SetDefault("monitor.websocketEndpoint", "wss://ws.dogechain.info/")
+	v.SetDefault("monitor.indexer.interval", "30s")
+	v.SetDefault("monitor.indexer.reorgDepth", 100)
+	v.SetDefault("deposit.gapLimit", 20)
+	v.SetDefault("deposit.reuseAfter", 72*time.Hour)
+	v.SetDefault("treasury.interval", "5m")
+	v.SetDefault("treasury.minConfirmations", 1)
+	v.SetDefault("treasury.minCoverage", 1.2)
+	v.SetDefault("treasury.alert.repeat", "1h")
+	v.SetDefault("treasury.alert.timeout", "10s")
+}
+
+// Load 解析命令行参数，按优先级合并默认值、配置文件、环境变量和命令行参数后校验，
+// 返回配置和去掉选项后的剩余参数（如子命令）
+func Load(args []string) (*Config, []string, error) {
+	flags := pflag.NewFlagSet("claimask", pflag.ContinueOnError)
+	configPath := flags.StringP("config", "c", DefaultPath, "配置文件路径")
+	port := flags.Int("port", 0, "HTTP服务端口，覆盖 server.port")
+	logLevel := flags.String("log-level", "", "日志级别，覆盖 log.level")
+	sets := flags.StringArray("set", nil, "覆盖任意配置项，格式 key=value，可重复")
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	v := viper.New()
+	setDefaults(v)
+	v.SetConfigFile(*configPath)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, nil, fmt.Errorf("config: read %s failed: %w", *configPath, err)
+	}
+	bindEnv(v)
+
+	if flags.Changed("port") {
+		v.Set("server.port", *port)
+	}
+	if flags.Changed("log-level") {
+		v.Set("log.level", *logLevel)
+	}
+	for _, kv := range *sets {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || key == "" {
+			return nil, nil, fmt.Errorf("config: bad --set %q, want key=value", kv)
+		}
+		v.Set(key, value)
+	}
+
+	cfg, err := decode(v)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return cfg, flags.Args(), nil
+}
+
+// Parse 从YAML内容解析配置，只应用默认值，不读取环境变量和命令行参数，也不校验
+func Parse(data string) (*Config, error) {
+	v := viper.New()
+	setDefaults(v)
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("config: parse failed: %w", err)
+	}
+	return decode(v)
+}
+
+// decode 将合并后的配置解析到 Config
+func decode(v *viper.Viper) (*Config, error) {
+	cfg := &Config{source: v}
+	if err := v.Unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("config: decode failed: %w", err)
+	}
+	return cfg, nil
+}
+
+// bindEnv 为 Config 中的每个标量配置项绑定环境变量
+// viper 只对已知的键读取环境变量，配置文件中没有出现的键也需要显式绑定
+func bindEnv(v *viper.Viper) {
+	v.SetEnvPrefix(envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	for _, key := range leafKeys(reflect.TypeOf(Config{}), "") {
+		_ = v.BindEnv(key)
+	}
+}
 
-	if err := viper.ReadInConfig(); err != nil {
-		zap.L().Fatal("Failed to read config file", zap.Error(err))
+// leafKeys 按 mapstructure 标签列出结构体中的标量配置项，跳过 map 和切片
+func leafKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("mapstructure")
+		if tag == "" || !field.IsExported() {
+			continue
+		}
+		key := prefix + tag
+		switch field.Type.Kind() {
+		case reflect.Struct:
+			keys = append(keys, leafKeys(field.Type, key+".")...)
+		case reflect.Map, reflect.Slice:
+		default:
+			keys = append(keys, key)
+		}
 	}
+	return keys
 }
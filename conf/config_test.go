@@ -0,0 +1,223 @@
+package conf
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const testConfig = `
+rpc:
+  ip: "127.0.0.1"
+  port: 22555
+  user: "rpcuser"
+  password: "rpc-secret"
+redis:
+  addr: "localhost:6379"
+mysql:
+  host: "localhost"
+  port: 3306
+  user: "root"
+  password: "mysql-secret"
+  database: "claimask"
+server:
+  port: 8888
+claimask:
+  prizeAmount: 100000000
+payout:
+  privateKey: "payout-secret"
+wallets:
+  - group: 1
+    receive: "DAddress1"
+    receivePrivate: "enc:v1:wallet-secret"
+`
+
+// writeConfig 将配置写入临时文件并返回路径
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// 测试仓库自带的配置文件可以通过校验
+func TestLoadRepositoryConfig(t *testing.T) {
+	cfg, args, err := Load([]string{"--config", "config.yaml"})
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(args) != 0 {
+		t.Errorf("Unexpected args %v", args)
+	}
+	if cfg.Server.Port != 8888 || cfg.Server.ShutdownTimeout != 15*time.Second {
+		t.Errorf("Unexpected server config %+v", cfg.Server)
+	}
+	if len(cfg.Wallets) != 2 || cfg.Wallets[1].Receive != "DAddress2" {
+		t.Errorf("Unexpected wallets %+v", cfg.Wallets)
+	}
+	if cfg.Richx.Accrual.Rates["legendary"] != "10" || cfg.Richx.ChainSign.Keys["dev"] == "" {
+		t.Errorf("Unexpected richx config %+v", cfg.Richx)
+	}
+}
+
+// 测试环境变量覆盖配置文件，命令行参数覆盖环境变量，剩余参数原样返回
+func TestLoadOverrides(t *testing.T) {
+	path := writeConfig(t, testConfig)
+	t.Setenv("CLAIMASK_MYSQL_PASSWORD", "from-env")
+	t.Setenv("CLAIMASK_SYSTEM_ENV", "production") // 配置文件中没有的键
+	t.Setenv("CLAIMASK_SERVER_PORT", "7000")
+
+	cfg, args, err := Load([]string{"migrate", "--config", path, "--port", "9000", "--set", "rpc.user=from-flag", "up"})
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if !reflect.DeepEqual(args, []string{"migrate", "up"}) {
+		t.Errorf("Unexpected args %v", args)
+	}
+	if cfg.MySQL.Password.Reveal() != "from-env" || cfg.System.Env != "production" {
+		t.Errorf("Expected env overrides, got %q %q", cfg.MySQL.Password.Reveal(), cfg.System.Env)
+	}
+	if cfg.Server.Port != 9000 || cfg.RPC.User != "from-flag" {
+		t.Errorf("Expected flag overrides, got %d %q", cfg.Server.Port, cfg.RPC.User)
+	}
+	if cfg.Queue.MaxConcurrent != 10 || cfg.Log.Level != "info" {
+		t.Errorf("Expected defaults, got %+v %+v", cfg.Queue, cfg.Log)
+	}
+}
+
+// 测试校验失败时列出所有不合法的配置项
+func TestValidate(t *testing.T) {
+	data := strings.NewReplacer(
+		`user: "rpcuser"`, `user: ""`,
+		`password: "rpc-secret"`, `password: ""`,
+		"port: 8888", "port: 70000",
+		`addr: "localhost:6379"`, `addr: "localhost"`,
+		"enc:v1:wallet-secret", "wallet-secret",
+	).Replace(testConfig)
+	cfg, err := Parse(data + "\nmq:\n  driver: kafka\nrichx:\n  enabled: true\n  chain:\n    url: \"localhost:7777\"\nsigner:\n  mode: remote\ndeposit:\n  xpub: dgub-not-a-key\npolicy:\n  deny: [\"DNotAnAddress\"]\n  velocity:\n    count: 3\n    window: 0s\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cfg.Validate()
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	for _, want := range []string{
+		"rpc.user is required",
+		"rpc.password is required",
+		"server.port must be between 1 and 65535, got 70000",
+		"redis.addr must be host:port",
+		"mq.brokers is required by the kafka driver",
+		"richx.chain.url must be an http(s) url",
+		"richx.chainSign.activeKey",
+		"wallets[0].receivePrivate must be sealed",
+		"signer.url must be an http(s) url",
+		"signer.token is required",
+		"deposit.xpub must be an account level extended public key",
+		"policy.velocity.window must be positive",
+		"policy.deny[0] must be a dogecoin address",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in:\n%v", want, err)
+		}
+	}
+
+	if _, _, err := Load([]string{"--config", writeConfig(t, data)}); err == nil {
+		t.Error("Expected Load to reject invalid config")
+	}
+}
+
+// 测试默认使用持久化的 redis 驱动，memory 驱动只在开发环境允许
+func TestValidateMemoryDriver(t *testing.T) {
+	cfg, err := Parse(testConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MQ.Driver != "redis" {
+		t.Errorf("Expected default driver redis, got %q", cfg.MQ.Driver)
+	}
+
+	cfg, err = Parse(testConfig + "mq:\n  driver: memory\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "set mq.dev") {
+		t.Errorf("Expected memory driver to require mq.dev, got %v", err)
+	}
+
+	cfg, err = Parse(testConfig + "mq:\n  driver: memory\n  dev: true\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected memory driver with mq.dev to pass, got %v", err)
+	}
+}
+
+// 测试敏感配置在日志输出和序列化时被隐藏
+func TestSecretsRedacted(t *testing.T) {
+	cfg, err := Parse(testConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, out := range []string{string(data), fmt.Sprintf("%+v", *cfg)} {
+		for _, secret := range []string{"rpc-secret", "mysql-secret", "payout-secret", "wallet-secret"} {
+			if strings.Contains(out, secret) {
+				t.Errorf("Secret %q leaked in %s", secret, out)
+			}
+		}
+	}
+	if cfg.RPC.Password.Reveal() != "rpc-secret" || cfg.Admin.Token.String() != "" {
+		t.Errorf("Unexpected secret values %q %q", cfg.RPC.Password.Reveal(), cfg.Admin.Token.String())
+	}
+}
+
+// 测试配置文件变化后只热更新可热更新的配置项，校验失败的修改被忽略
+func TestWatch(t *testing.T) {
+	path := writeConfig(t, testConfig)
+	cfg, _, err := Load([]string{"--config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied := make(chan *Config, 4)
+	cfg.Watch(zap.NewNop(), func(c *Config) { applied <- c })
+
+	rewrite := func(data string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rewrite(testConfig + "log:\n  level: debug\nqueue:\n  maxConcurrent: 3\npolicy:\n  maxTx: 500\n")
+	select {
+	case next := <-applied:
+		if next.Log.Level != "debug" || next.Queue.MaxConcurrent != 3 || next.Policy.MaxTx != 500 {
+			t.Errorf("Unexpected reloaded config %+v %+v %+v", next.Log, next.Queue, next.Policy)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected config to be reloaded")
+	}
+
+	rewrite(testConfig + "log:\n  level: loud\n")
+	select {
+	case next := <-applied:
+		t.Errorf("Expected invalid config to be ignored, got %+v", next.Log)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
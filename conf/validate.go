@@ -0,0 +1,191 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"claimask/pkg/dogechain"
+	"claimask/pkg/keystore"
+)
+
+// validator 收集校验失败的配置项
+type validator struct {
+	errs []error
+}
+
+// check 条件不成立时记录配置项和原因
+func (v *validator) check(ok bool, key, format string, args ...interface{}) {
+	if !ok {
+		v.errs = append(v.errs, fmt.Errorf("config: %s %s", key, fmt.Sprintf(format, args...)))
+	}
+}
+
+// required 字符串配置项不能为空
+func (v *validator) required(value, key string) {
+	v.check(value != "", key, "is required")
+}
+
+// port 端口号在 1-65535 之间
+func (v *validator) port(port int, key string) {
+	v.check(port > 0 && port <= 65535, key, "must be between 1 and 65535, got %d", port)
+}
+
+// sealed 私钥必须是信封加密后的密文，不允许明文
+func (v *validator) sealed(value Secret, key string) {
+	v.check(value == "" || keystore.IsSealed(value.Reveal()), key, "must be sealed with `claimask keys seal`, plaintext keys are not allowed")
+}
+
+// positive 数值必须大于0
+func (v *validator) positive(value int64, key string) {
+	v.check(value > 0, key, "must be positive, got %d", value)
+}
+
+// Validate 校验配置，返回所有不合法的配置项
+// 只校验启动必需的连接信息和已启用模块的配置，模块内部的细节由模块创建时校验
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	v.check(validLogLevel(c.Log.Level), "log.level", "must be one of debug/info/warn/error, got %q", c.Log.Level)
+	v.port(c.Server.Port, "server.port")
+	v.check(c.Server.ShutdownTimeout >= 0, "server.shutdownTimeout", "must not be negative")
+
+	v.required(c.RPC.IP, "rpc.ip")
+	v.port(c.RPC.Port, "rpc.port")
+	v.required(c.RPC.User, "rpc.user")
+	v.required(c.RPC.Password.Reveal(), "rpc.password")
+
+	v.required(c.Redis.Addr, "redis.addr")
+	if c.Redis.Addr != "" {
+		_, redisPort, err := net.SplitHostPort(c.Redis.Addr)
+		n, _ := strconv.Atoi(redisPort)
+		v.check(err == nil && n > 0 && n <= 65535, "redis.addr", "must be host:port, got %q", c.Redis.Addr)
+	}
+	v.check(c.Redis.DB >= 0, "redis.db", "must not be negative")
+
+	v.required(c.MySQL.Host, "mysql.host")
+	v.port(c.MySQL.Port, "mysql.port")
+	v.required(c.MySQL.User, "mysql.user")
+	v.required(c.MySQL.Database, "mysql.database")
+
+	switch c.MQ.Driver {
+	case "redis":
+	case "memory":
+		// 进程内总线不持久化，重启后丢失已被外发表标记为已发送的消息，破坏至少一次投递
+		v.check(c.MQ.Dev, "mq.driver", "memory driver loses messages on restart, set mq.dev to use it for development")
+	case "kafka":
+		v.check(len(c.MQ.Brokers) > 0, "mq.brokers", "is required by the kafka driver")
+	default:
+		v.check(false, "mq.driver", "must be one of kafka/redis/memory, got %q", c.MQ.Driver)
+	}
+
+	v.check(c.Queue.MaxConcurrent > 0, "queue.maxConcurrent", "must be positive, got %d", c.Queue.MaxConcurrent)
+	v.check(c.Queue.MaxRetries >= 0, "queue.maxRetries", "must not be negative")
+	v.check(c.Queue.BaseDelay > 0, "queue.baseDelay", "must be positive")
+	v.check(c.Queue.MaxDelay >= c.Queue.BaseDelay, "queue.maxDelay", "must not be less than queue.baseDelay")
+
+	v.positive(c.Claimask.PrizeAmount, "claimask.prizeAmount")
+
+	if c.Payout.Enabled {
+		v.required(c.Payout.Address, "payout.address")
+		if c.Signer.Mode == "local" {
+			v.required(c.Payout.PrivateKey.Reveal(), "payout.privateKey")
+			v.sealed(c.Payout.PrivateKey, "payout.privateKey")
+		}
+		v.positive(int64(c.Payout.Interval), "payout.interval")
+		v.positive(int64(c.Payout.BatchSize), "payout.batchSize")
+		v.positive(int64(c.Payout.MaxOutputs), "payout.maxOutputs")
+		v.positive(c.Payout.MaxBatchValue, "payout.maxBatchValue")
+		v.positive(c.Payout.FeeRate, "payout.feeRate")
+		v.check(c.Payout.AddressCooldown >= 0, "payout.addressCooldown", "must not be negative")
+		v.check(c.Payout.RecoverAfter == 0 || c.Payout.RecoverAfter > c.Payout.Interval, "payout.recoverAfter", "must be longer than payout.interval")
+	}
+
+	if c.Richx.Enabled {
+		u, err := url.Parse(c.Richx.Chain.URL)
+		v.check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"richx.chain.url", "must be an http(s) url, got %q", c.Richx.Chain.URL)
+		v.check(c.Richx.ResetHour >= 0 && c.Richx.ResetHour <= 23, "richx.resetHour", "must be between 0 and 23, got %d", c.Richx.ResetHour)
+		_, ok := c.Richx.ChainSign.Keys[strings.ToLower(c.Richx.ChainSign.ActiveKey)]
+		v.check(ok, "richx.chainSign.activeKey", "%q not found in richx.chainSign.keys", c.Richx.ChainSign.ActiveKey)
+	}
+
+	v.positive(int64(c.Monitor.WalletRefresh), "monitor.walletRefresh")
+	v.positive(int64(c.Monitor.BlockPollInterval), "monitor.blockPollInterval")
+	if c.Monitor.Indexer.Enabled {
+		v.check(c.Monitor.Indexer.StartHeight >= 0, "monitor.indexer.startHeight", "must not be negative")
+		v.positive(int64(c.Monitor.Indexer.Interval), "monitor.indexer.interval")
+		v.positive(c.Monitor.Indexer.ReorgDepth, "monitor.indexer.reorgDepth")
+	}
+
+	if c.Deposit.XPub != "" {
+		_, err := dogechain.NewHDAccount(c.Deposit.XPub)
+		v.check(err == nil, "deposit.xpub", "must be an account level extended public key: %v", err)
+		v.positive(int64(c.Deposit.GapLimit), "deposit.gapLimit")
+		v.check(c.Deposit.ReuseAfter >= 0, "deposit.reuseAfter", "must not be negative")
+	}
+
+	if c.Treasury.Enabled {
+		v.positive(int64(c.Treasury.Interval), "treasury.interval")
+		v.check(c.Treasury.MinConfirmations >= 0, "treasury.minConfirmations", "must not be negative")
+		v.check(c.Treasury.MinCoverage > 0, "treasury.minCoverage", "must be positive, got %v", c.Treasury.MinCoverage)
+		v.positive(int64(c.Treasury.Alert.Repeat), "treasury.alert.repeat")
+		if c.Treasury.Alert.Webhook != "" {
+			u, err := url.Parse(c.Treasury.Alert.Webhook)
+			v.check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+				"treasury.alert.webhook", "must be an http(s) url, got %q", c.Treasury.Alert.Webhook)
+			v.positive(int64(c.Treasury.Alert.Timeout), "treasury.alert.timeout")
+		}
+	}
+
+	switch c.Signer.Mode {
+	case "local":
+	case "remote":
+		u, err := url.Parse(c.Signer.URL)
+		v.check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"signer.url", "must be an http(s) url, got %q", c.Signer.URL)
+		v.required(c.Signer.Token.Reveal(), "signer.token")
+		v.positive(int64(c.Signer.Timeout), "signer.timeout")
+	default:
+		v.check(false, "signer.mode", "must be one of local/remote, got %q", c.Signer.Mode)
+	}
+
+	v.check(c.Policy.MaxTx >= 0, "policy.maxTx", "must not be negative")
+	v.check(c.Policy.DailyPerWallet >= 0, "policy.dailyPerWallet", "must not be negative")
+	v.check(c.Policy.DailyPerAddress >= 0, "policy.dailyPerAddress", "must not be negative")
+	v.check(c.Policy.ApprovalThreshold >= 0, "policy.approvalThreshold", "must not be negative")
+	v.check(c.Policy.Velocity.Count >= 0, "policy.velocity.count", "must not be negative")
+	if c.Policy.Velocity.Count > 0 {
+		v.positive(int64(c.Policy.Velocity.Window), "policy.velocity.window")
+	}
+	if c.Payout.Enabled && c.Policy.MaxTx > 0 {
+		v.check(c.Payout.MaxBatchValue <= c.Policy.MaxTx, "payout.maxBatchValue", "must not exceed policy.maxTx %d", c.Policy.MaxTx)
+	}
+	for i, addr := range c.Policy.Allow {
+		v.check(dogechain.ValidateAddress(addr) == nil, fmt.Sprintf("policy.allow[%d]", i), "must be a dogecoin address, got %q", addr)
+	}
+	for i, addr := range c.Policy.Deny {
+		v.check(dogechain.ValidateAddress(addr) == nil, fmt.Sprintf("policy.deny[%d]", i), "must be a dogecoin address, got %q", addr)
+	}
+
+	for i, w := range c.Wallets {
+		v.sealed(w.ReceivePrivate, fmt.Sprintf("wallets[%d].receivePrivate", i))
+	}
+	v.check(c.Keystore.MasterKeyEnv != "" || c.Keystore.MasterKeyFile != "", "keystore", "needs masterKeyEnv or masterKeyFile")
+
+	v.check(c.NFT.Tax >= 0 && c.NFT.Tax < 1, "nft.tax", "must be in [0, 1), got %v", c.NFT.Tax)
+
+	return errors.Join(v.errs...)
+}
+
+// validLogLevel 判断日志级别是否合法
+func validLogLevel(level string) bool {
+	switch level {
+	case "debug", "info", "warn", "error":
+		return true
+	}
+	return false
+}
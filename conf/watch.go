@@ -0,0 +1,71 @@
+package conf
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// Reloadable 可热更新的配置项，配置文件变化后只有这些字段会生效，其他字段的修改需重启服务
+type Reloadable struct {
+	Log    LogConfig
+	Queue  QueueConfig
+	Policy PolicyConfig
+}
+
+// Reloadable 返回可热更新的配置项
+func (c *Config) Reloadable() Reloadable {
+	return Reloadable{Log: c.Log, Queue: c.Queue, Policy: c.Policy}
+}
+
+// Watch 监听配置文件变化，重新加载并校验通过后，可热更新的配置项有变化时以新配置调用 apply。
+// 新配置校验失败时保留当前配置；其他配置项的修改不会生效，只记录需重启的配置节
+func (c *Config) Watch(logger *zap.Logger, apply func(*Config)) {
+	var mu sync.Mutex
+	applied := c.Reloadable()
+
+	c.source.OnConfigChange(func(e fsnotify.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		next, err := decode(c.source)
+		if err == nil {
+			err = next.Validate()
+		}
+		if err != nil {
+			logger.Error("配置重新加载失败，保留当前配置", zap.String("file", e.Name), zap.Error(err))
+			return
+		}
+		if sections := c.restartRequired(next); len(sections) > 0 {
+			logger.Warn("配置修改需重启服务后生效", zap.Strings("sections", sections))
+		}
+		if reflect.DeepEqual(next.Reloadable(), applied) {
+			return
+		}
+
+		applied = next.Reloadable()
+		logger.Info("配置已热更新", zap.Any("log", next.Log), zap.Any("queue", next.Queue), zap.Any("policy", next.Policy))
+		apply(next)
+	})
+	c.source.WatchConfig()
+}
+
+// restartRequired 返回与启动时相比有变化、且不能热更新的配置节
+func (c *Config) restartRequired(next *Config) []string {
+	reloadable := map[string]bool{"log": true, "queue": true, "policy": true}
+
+	var sections []string
+	cur, nxt := reflect.ValueOf(c).Elem(), reflect.ValueOf(next).Elem()
+	for i := 0; i < cur.NumField(); i++ {
+		tag := cur.Type().Field(i).Tag.Get("mapstructure")
+		if tag == "" || reloadable[tag] {
+			continue
+		}
+		if !reflect.DeepEqual(cur.Field(i).Interface(), nxt.Field(i).Interface()) {
+			sections = append(sections, tag)
+		}
+	}
+	return sections
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"claimask/comm/response"
+	"claimask/internal/claimask/model/dto"
+	"claimask/pkg/policy"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ApprovalAPI 转出审批
+type ApprovalAPI struct {
+	Engine *policy.Engine
+}
+
+// NewApprovalAPI 创建ApprovalAPI实例
+func NewApprovalAPI(engine *policy.Engine) *ApprovalAPI {
+	return &ApprovalAPI{Engine: engine}
+}
+
+// ListApprovals 查询审批单，默认只查询等待审批的，status=all 时查询全部
+func (api *ApprovalAPI) ListApprovals(ctx *gin.Context) {
+	var param dto.ApprovalListParam
+	if err := ctx.ShouldBindQuery(&param); err != nil {
+		response.FailWithMessage(ctx, response.ERROR, "参数绑定失败: "+err.Error())
+		return
+	}
+	status := policy.ApprovalStatus(param.Status)
+	switch status {
+	case "":
+		status = policy.ApprovalPending
+	case "all":
+		status = ""
+	}
+
+	approvals, err := api.Engine.Approvals(status, param.Limit)
+	if err != nil {
+		response.FailWithMessage(ctx, response.ERROR, "审批单查询失败: "+err.Error())
+		return
+	}
+	response.OkWithData(ctx, approvals)
+}
+
+// Approve 批准等待审批的转出，下一轮打款时签名
+func (api *ApprovalAPI) Approve(ctx *gin.Context) {
+	api.decide(ctx, true)
+}
+
+// Reject 拒绝等待审批的转出，对应订单在下一轮打款时置为失败
+func (api *ApprovalAPI) Reject(ctx *gin.Context) {
+	api.decide(ctx, false)
+}
+
+// decide 批准或拒绝审批单
+func (api *ApprovalAPI) decide(ctx *gin.Context, approve bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		response.FailWithMessage(ctx, response.ERROR, "无效的审批单号")
+		return
+	}
+	var req dto.ApprovalDecision
+	if err := ctx.ShouldBindJSON(&req); err != nil && ctx.Request.ContentLength > 0 {
+		response.FailWithMessage(ctx, response.ERROR, "参数绑定失败: "+err.Error())
+		return
+	}
+
+	err = api.Engine.Decide(id, approve, req.Note)
+	switch {
+	case errors.Is(err, policy.ErrApprovalNotFound):
+		response.FailWithMessage(ctx, response.ERROR, "审批单不存在")
+	case errors.Is(err, policy.ErrApprovalConflict):
+		response.FailWithMessage(ctx, response.ERROR, "审批单已处理")
+	case err != nil:
+		response.FailWithMessage(ctx, response.ERROR, "审批失败: "+err.Error())
+	default:
+		response.Ok(ctx)
+	}
+}
@@ -35,12 +35,16 @@ func (api *ClaimAPI) Claim(ctx *gin.Context) {
 	}
 
 	// 创建订单
-	if err := api.ClaimService.CreateOrder(param.Address); err != nil {
+	orderID, err := api.ClaimService.CreateOrder(param.Address, param.Campaign, param.Proof)
+	if err != nil {
 		response.FailWithMessage(ctx, response.ERROR, "订单创建失败: "+err.Error())
 		return
 	}
 
-	response.OkWithData(ctx, param)
+	response.OkWithData(ctx, gin.H{
+		"orderId": strconv.FormatUint(orderID, 10),
+		"address": param.Address,
+	})
 }
 
 // Query 处理奖品数量查询请求
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"claimask/comm/response"
+	"claimask/internal/claimask/dao"
+	"claimask/internal/claimask/model/dto"
+	"claimask/internal/claimask/service"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OrderAPI 订单查询服务
+type OrderAPI struct {
+	OrderService service.OrderService
+}
+
+// NewOrderAPI 创建OrderAPI实例
+func NewOrderAPI(orderService service.OrderService) *OrderAPI {
+	return &OrderAPI{OrderService: orderService}
+}
+
+// GetOrder 查询单个订单详情
+func (api *OrderAPI) GetOrder(ctx *gin.Context) {
+	orderID, err := strconv.ParseUint(ctx.Param("orderId"), 10, 64)
+	if err != nil {
+		response.FailWithMessage(ctx, response.ERROR, "无效的订单号")
+		return
+	}
+
+	order, err := api.OrderService.GetOrder(orderID)
+	if err != nil {
+		if errors.Is(err, dao.ErrOrderNotFound) {
+			response.FailWithMessage(ctx, response.ERROR, "订单不存在")
+			return
+		}
+		response.FailWithMessage(ctx, response.ERROR, "订单查询失败: "+err.Error())
+		return
+	}
+
+	response.OkWithData(ctx, order)
+}
+
+// ListOrders 用户查询自己地址的订单，按状态、时间范围分页，address 必填
+func (api *OrderAPI) ListOrders(ctx *gin.Context) {
+	var param dto.OrderListParam
+	if err := ctx.ShouldBindQuery(&param); err != nil {
+		response.FailWithMessage(ctx, response.ERROR, "参数绑定失败: "+err.Error())
+		return
+	}
+	if param.Address == "" {
+		response.FailWithMessage(ctx, response.ERROR, "address 为必填参数")
+		return
+	}
+	api.listOrders(ctx, param)
+}
+
+// ListAllOrders 运营视角：按地址、状态、时间范围分页查询所有订单，address 可不填
+func (api *OrderAPI) ListAllOrders(ctx *gin.Context) {
+	var param dto.OrderListParam
+	if err := ctx.ShouldBindQuery(&param); err != nil {
+		response.FailWithMessage(ctx, response.ERROR, "参数绑定失败: "+err.Error())
+		return
+	}
+	api.listOrders(ctx, param)
+}
+
+// listOrders 分页查询订单并返回
+func (api *OrderAPI) listOrders(ctx *gin.Context, param dto.OrderListParam) {
+	page, err := api.OrderService.ListOrders(param)
+	if err != nil {
+		response.FailWithMessage(ctx, response.ERROR, "订单查询失败: "+err.Error())
+		return
+	}
+
+	response.OkWithData(ctx, page)
+}
+
+// GetOrderStats 运营视角：按状态和活动统计订单数量
+func (api *OrderAPI) GetOrderStats(ctx *gin.Context) {
+	stats, err := api.OrderService.GetOrderStats(ctx.Query("from"), ctx.Query("to"))
+	if err != nil {
+		response.FailWithMessage(ctx, response.ERROR, "订单统计失败: "+err.Error())
+		return
+	}
+
+	response.OkWithData(ctx, stats)
+}
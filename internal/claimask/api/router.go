@@ -19,3 +19,38 @@ func RegisterClaimRoutes(r *gin.RouterGroup, api *ClaimAPI) {
 		claimGroup.GET("/initialize/:quantity", api.Init)
 	}
 }
+
+// RegisterOrderRoutes 设置订单查询相关路由
+// adminAuth 用于保护运营接口
+func RegisterOrderRoutes(r *gin.RouterGroup, api *OrderAPI, adminAuth gin.HandlerFunc) {
+	orderGroup := r.Group("/orders")
+	{
+		// 订单列表查询接口，只能按地址查询
+		orderGroup.GET("", api.ListOrders)
+
+		// 订单详情查询接口
+		orderGroup.GET("/:orderId", api.GetOrder)
+	}
+
+	adminGroup := r.Group("/admin/orders", adminAuth)
+	{
+		// 运营订单列表接口，可不按地址查询
+		adminGroup.GET("", api.ListAllOrders)
+
+		// 运营统计接口
+		adminGroup.GET("/stats", api.GetOrderStats)
+	}
+}
+
+// RegisterApprovalRoutes 设置转出审批路由，均为运营接口
+func RegisterApprovalRoutes(r *gin.RouterGroup, api *ApprovalAPI, adminAuth gin.HandlerFunc) {
+	approvalGroup := r.Group("/admin/approvals", adminAuth)
+	{
+		// 审批单列表查询接口
+		approvalGroup.GET("", api.ListApprovals)
+
+		// 批准和拒绝接口
+		approvalGroup.POST("/:id/approve", api.Approve)
+		approvalGroup.POST("/:id/reject", api.Reject)
+	}
+}
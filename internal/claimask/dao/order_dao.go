@@ -2,13 +2,62 @@ package dao
 
 import (
 	"claimask/internal/claimask/model/po"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
-	"github.com/jinzhu/gorm"
+	"gorm.io/gorm"
 )
 
+const (
+	orderTable         = "order_id"
+	maxFailReasonBytes = 255
+)
+
+var (
+	// ErrOrderNotFound 订单不存在
+	ErrOrderNotFound = errors.New("order not found")
+	// ErrIllegalTransition 非法的状态迁移
+	ErrIllegalTransition = errors.New("illegal order status transition")
+	// ErrStatusConflict 状态已被其他进程修改
+	ErrStatusConflict = errors.New("order status changed concurrently")
+)
+
+// OrderQuery 订单列表查询条件
+type OrderQuery struct {
+	Address string
+	Status  po.OrderStatus
+	From    *time.Time // 创建时间下界（含）
+	To      *time.Time // 创建时间上界（不含）
+	Cursor  uint       // 上一页最后一条记录的自增ID，0表示第一页
+	Limit   int
+}
+
+// OrderStat 按活动和状态聚合的订单数量
+type OrderStat struct {
+	Campaign string         `gorm:"column:campaign"`
+	Status   po.OrderStatus `gorm:"column:status"`
+	Count    int64          `gorm:"column:count"`
+}
+
+// OrderSum 订单数量和领取数额合计
+type OrderSum struct {
+	Count  int64 `gorm:"column:count"`
+	Amount int64 `gorm:"column:amount"` // 单位：ELON
+}
+
 // OrderDAO 订单DAO接口
 type OrderDAO interface {
 	CreateOrder(order *po.Order) error
+	GetOrder(orderID uint64) (*po.Order, error)
+	ListOrders(query OrderQuery) ([]po.Order, error)
+	ListByStatus(status po.OrderStatus, limit int) ([]po.Order, error)
+	CountByStatusAndCampaign(from, to *time.Time) ([]OrderStat, error)
+	SumAmountByStatus(statuses ...po.OrderStatus) (OrderSum, error)
+	TransitionStatus(orderID uint64, to po.OrderStatus, change po.StatusChange) error
+	AttachTx(orderIDs []uint64, txid, rawTx string) error
+	ListQueuedBefore(before time.Time, limit int) ([]po.Order, error)
 }
 
 // OrderDAOImpl 订单DAO实现
@@ -23,5 +72,176 @@ func NewOrderDAO(db *gorm.DB) OrderDAO {
 
 // CreateOrder 在数据库中创建订单
 func (dao *OrderDAOImpl) CreateOrder(order *po.Order) error {
-	return dao.DB.Table("order_id").Create(order).Error
+	if order.Status == "" {
+		order.Status = po.OrderStatusCreated
+	}
+	return dao.DB.Table(orderTable).Create(order).Error
+}
+
+// GetOrder 根据订单号查询订单
+func (dao *OrderDAOImpl) GetOrder(orderID uint64) (*po.Order, error) {
+	var order po.Order
+	if err := dao.DB.Table(orderTable).Where("order_id = ?", orderID).First(&order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+	return &order, nil
+}
+
+// ListOrders 按条件分页查询订单，按自增ID倒序，使用游标翻页
+func (dao *OrderDAOImpl) ListOrders(query OrderQuery) ([]po.Order, error) {
+	db := dao.DB.Table(orderTable)
+	if query.Address != "" {
+		db = db.Where("address = ?", query.Address)
+	}
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	if query.From != nil {
+		db = db.Where("insert_time >= ?", *query.From)
+	}
+	if query.To != nil {
+		db = db.Where("insert_time < ?", *query.To)
+	}
+	if query.Cursor > 0 {
+		db = db.Where("id < ?", query.Cursor)
+	}
+
+	var orders []po.Order
+	if err := db.Order("id DESC").Limit(query.Limit).Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// ListByStatus 按创建顺序查询指定状态的订单，供后台任务使用
+func (dao *OrderDAOImpl) ListByStatus(status po.OrderStatus, limit int) ([]po.Order, error) {
+	var orders []po.Order
+	err := dao.DB.Table(orderTable).
+		Where("status = ?", status).
+		Order("id ASC").
+		Limit(limit).
+		Find(&orders).Error
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// CountByStatusAndCampaign 统计各活动各状态的订单数量
+func (dao *OrderDAOImpl) CountByStatusAndCampaign(from, to *time.Time) ([]OrderStat, error) {
+	db := dao.DB.Table(orderTable)
+	if from != nil {
+		db = db.Where("insert_time >= ?", *from)
+	}
+	if to != nil {
+		db = db.Where("insert_time < ?", *to)
+	}
+
+	var stats []OrderStat
+	err := db.Select("campaign, status, COUNT(*) AS count").
+		Group("campaign, status").
+		Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// SumAmountByStatus 合计指定状态订单的数量和领取数额，数额存储在 json 列的 amount 字段中
+func (dao *OrderDAOImpl) SumAmountByStatus(statuses ...po.OrderStatus) (OrderSum, error) {
+	var sum OrderSum
+	err := dao.DB.Table(orderTable).
+		Select("COUNT(*) AS count, COALESCE(SUM(CAST(JSON_EXTRACT(`json`, '$.amount') AS SIGNED)), 0) AS amount").
+		Where("status IN ?", statuses).
+		Scan(&sum).Error
+	return sum, err
+}
+
+// TransitionStatus 迁移订单状态
+// 先校验迁移是否合法，再以当前状态为条件更新，避免并发覆盖。
+// 失败的订单已有签名交易或曾经广播时不能重新排队：原交易仍可能上链，重新签名打款会重复支付
+func (dao *OrderDAOImpl) TransitionStatus(orderID uint64, to po.OrderStatus, change po.StatusChange) error {
+	order, err := dao.GetOrder(orderID)
+	if err != nil {
+		return err
+	}
+	if !order.Status.CanTransitionTo(to) {
+		return fmt.Errorf("%w: %s -> %s", ErrIllegalTransition, order.Status, to)
+	}
+	if order.Status == po.OrderStatusFailed && to == po.OrderStatusQueued && (order.TxID != "" || order.BroadcastTime != nil) {
+		return fmt.Errorf("%w: order %d already has tx %q, requeue would pay twice", ErrIllegalTransition, orderID, order.TxID)
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":      to,
+		"update_time": now,
+	}
+	if column := to.TimeColumn(); column != "" {
+		updates[column] = now
+	}
+	if change.TxID != "" {
+		updates["txid"] = change.TxID
+	}
+	// 重新排队的订单将构建新的交易，清除排队时残留的交易字段
+	if to == po.OrderStatusQueued {
+		updates["txid"] = ""
+		updates["raw_tx"] = nil
+	}
+	if change.FailReason != "" {
+		reason := change.FailReason
+		if len(reason) > maxFailReasonBytes {
+			reason = strings.ToValidUTF8(reason[:maxFailReasonBytes], "")
+		}
+		updates["fail_reason"] = reason
+	}
+
+	result := dao.DB.Table(orderTable).
+		Where("order_id = ? AND status = ?", orderID, order.Status).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+// AttachTx 在广播前为一批排队中的订单写入已签名的打款交易
+// 任一订单已不在排队中或已有交易时整体不写入，返回 ErrStatusConflict
+func (dao *OrderDAOImpl) AttachTx(orderIDs []uint64, txid, rawTx string) error {
+	return dao.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Table(orderTable).
+			Where("order_id IN ? AND status = ? AND (txid IS NULL OR txid = '')", orderIDs, po.OrderStatusQueued).
+			Updates(map[string]interface{}{
+				"txid":        txid,
+				"raw_tx":      rawTx,
+				"update_time": time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != int64(len(orderIDs)) {
+			return ErrStatusConflict
+		}
+		return nil
+	})
+}
+
+// ListQueuedBefore 查询排队时间早于 before 的排队中订单，供打款任务恢复崩溃或广播结果不明的订单
+func (dao *OrderDAOImpl) ListQueuedBefore(before time.Time, limit int) ([]po.Order, error) {
+	var orders []po.Order
+	err := dao.DB.Table(orderTable).
+		Where("status = ? AND queued_time < ?", po.OrderStatusQueued, before).
+		Order("id ASC").
+		Limit(limit).
+		Find(&orders).Error
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
 }
@@ -0,0 +1,115 @@
+package dao
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"claimask/internal/claimask/model/po"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// newTestDB 基于内存 sqlite 的订单表
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内存数据库每个连接独立，只保留一个连接
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.Table(orderTable).AutoMigrate(&po.Order{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// 测试状态迁移校验合法性、写入时间列和失败原因，并以读取时的状态为条件更新
+func TestTransitionStatus(t *testing.T) {
+	db := newTestDB(t)
+	orderDAO := NewOrderDAO(db)
+	for id := uint64(1); id <= 2; id++ {
+		if err := orderDAO.CreateOrder(&po.Order{OrderID: id, Address: "DAddr"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := orderDAO.TransitionStatus(1, po.OrderStatusConfirmed, po.StatusChange{}); !errors.Is(err, ErrIllegalTransition) {
+		t.Errorf("Expected ErrIllegalTransition, got %v", err)
+	}
+	if err := orderDAO.TransitionStatus(9, po.OrderStatusQueued, po.StatusChange{}); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("Expected ErrOrderNotFound, got %v", err)
+	}
+
+	if err := orderDAO.TransitionStatus(1, po.OrderStatusQueued, po.StatusChange{}); err != nil {
+		t.Fatal(err)
+	}
+	reason := strings.Repeat("拒", maxFailReasonBytes)
+	if err := orderDAO.TransitionStatus(1, po.OrderStatusFailed, po.StatusChange{FailReason: reason}); err != nil {
+		t.Fatal(err)
+	}
+	order, _ := orderDAO.GetOrder(1)
+	if order.Status != po.OrderStatusFailed || order.QueuedTime == nil || order.FailedTime == nil {
+		t.Errorf("Expected failed order with queued and failed time, got %+v", order)
+	}
+	if len(order.FailReason) > maxFailReasonBytes || !strings.HasPrefix(reason, order.FailReason) {
+		t.Errorf("Expected fail reason truncated to valid UTF-8, got %d bytes", len(order.FailReason))
+	}
+
+	// 读取后、更新前订单被其他进程迁移，条件更新不命中
+	race := func(tx *gorm.DB) {
+		tx.Session(&gorm.Session{NewDB: true}).Exec("UPDATE order_id SET status = ? WHERE order_id = ?", po.OrderStatusFailed, 2)
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("test:race", race); err != nil {
+		t.Fatal(err)
+	}
+	if err := orderDAO.TransitionStatus(2, po.OrderStatusQueued, po.StatusChange{}); !errors.Is(err, ErrStatusConflict) {
+		t.Errorf("Expected ErrStatusConflict, got %v", err)
+	}
+	if err := db.Callback().Update().Remove("test:race"); err != nil {
+		t.Fatal(err)
+	}
+	if order, _ := orderDAO.GetOrder(2); order.Status != po.OrderStatusFailed || order.QueuedTime != nil {
+		t.Errorf("Expected concurrent change kept, got %+v", order)
+	}
+}
+
+// 测试失败的订单只有从未签名出交易时才能重新排队，已有交易的订单重新打款会重复支付
+func TestTransitionStatusRequeueFailed(t *testing.T) {
+	orderDAO := NewOrderDAO(newTestDB(t))
+	transition := func(id uint64, to po.OrderStatus, change po.StatusChange) {
+		t.Helper()
+		if err := orderDAO.TransitionStatus(id, to, change); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for id := uint64(1); id <= 3; id++ {
+		if err := orderDAO.CreateOrder(&po.Order{OrderID: id, Address: "DAddr"}); err != nil {
+			t.Fatal(err)
+		}
+		transition(id, po.OrderStatusQueued, po.StatusChange{})
+	}
+	// 1 签名前失败；2 签名后广播被拒绝；3 广播后失败
+	transition(1, po.OrderStatusFailed, po.StatusChange{FailReason: "insufficient funds"})
+	if err := orderDAO.AttachTx([]uint64{2, 3}, "tx", "raw"); err != nil {
+		t.Fatal(err)
+	}
+	transition(2, po.OrderStatusFailed, po.StatusChange{FailReason: "rejected"})
+	transition(3, po.OrderStatusBroadcast, po.StatusChange{TxID: "tx"})
+	transition(3, po.OrderStatusFailed, po.StatusChange{FailReason: "dropped"})
+
+	if err := orderDAO.TransitionStatus(1, po.OrderStatusQueued, po.StatusChange{}); err != nil {
+		t.Errorf("Expected order without tx to be requeued, got %v", err)
+	}
+	for id := uint64(2); id <= 3; id++ {
+		if err := orderDAO.TransitionStatus(id, po.OrderStatusQueued, po.StatusChange{}); !errors.Is(err, ErrIllegalTransition) {
+			t.Errorf("Order %d: expected requeue with a signed tx rejected, got %v", id, err)
+		}
+		if order, _ := orderDAO.GetOrder(id); order.Status != po.OrderStatusFailed || order.TxID != "tx" {
+			t.Errorf("Order %d: expected failed order to keep its tx, got %+v", id, order)
+		}
+	}
+}
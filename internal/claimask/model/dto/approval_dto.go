@@ -0,0 +1,12 @@
+package dto
+
+// ApprovalListParam 审批单查询参数
+type ApprovalListParam struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending approved rejected used all"` // 默认 pending
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+// ApprovalDecision 审批意见
+type ApprovalDecision struct {
+	Note string `json:"note" binding:"max=255"`
+}
@@ -2,5 +2,7 @@ package dto
 
 // ClaimParam defines the structure for claim request parameters
 type ClaimParam struct {
-	Address string `json:"address"`
+	Address  string `json:"address"`
+	Campaign string `json:"campaign"`
+	Proof    string `json:"proof"`
 }
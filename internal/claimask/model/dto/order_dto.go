@@ -0,0 +1,55 @@
+package dto
+
+import (
+	"time"
+
+	"claimask/pkg/money"
+)
+
+// OrderListParam 订单列表查询参数
+type OrderListParam struct {
+	Address string `form:"address"`
+	Status  string `form:"status"`
+	From    string `form:"from"`   // RFC3339 或 2006-01-02
+	To      string `form:"to"`     // RFC3339 或 2006-01-02
+	Cursor  string `form:"cursor"` // 上一页返回的 nextCursor
+	Limit   int    `form:"limit"`
+}
+
+// OrderView 订单详情
+type OrderView struct {
+	OrderID       string       `json:"orderId"`
+	Address       string       `json:"address"`
+	Campaign      string       `json:"campaign"`
+	Amount        money.Amount `json:"amount"` // 单位：DOGE，十进制字符串
+	Status        string       `json:"status"`
+	TxID          string       `json:"txid,omitempty"`
+	FailReason    string       `json:"failReason,omitempty"`
+	CreatedAt     time.Time    `json:"createdAt"`
+	QueuedAt      *time.Time   `json:"queuedAt,omitempty"`
+	BroadcastAt   *time.Time   `json:"broadcastAt,omitempty"`
+	ConfirmedAt   *time.Time   `json:"confirmedAt,omitempty"`
+	FailedAt      *time.Time   `json:"failedAt,omitempty"`
+	RefundedAt    *time.Time   `json:"refundedAt,omitempty"`
+	LastUpdatedAt time.Time    `json:"lastUpdatedAt"`
+}
+
+// OrderPage 订单分页结果
+type OrderPage struct {
+	List       []OrderView `json:"list"`
+	NextCursor string      `json:"nextCursor,omitempty"` // 为空表示没有更多数据
+}
+
+// CampaignStat 单个活动的订单统计
+type CampaignStat struct {
+	Campaign string           `json:"campaign"`
+	Total    int64            `json:"total"`
+	ByStatus map[string]int64 `json:"byStatus"`
+}
+
+// OrderStats 运营视角的订单统计
+type OrderStats struct {
+	Total      int64            `json:"total"`
+	ByStatus   map[string]int64 `json:"byStatus"`
+	ByCampaign []CampaignStat   `json:"byCampaign"`
+}
@@ -1,15 +1,128 @@
 package po
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 )
 
+// OrderStatus 订单状态
+type OrderStatus string
+
+// 订单生命周期：created → queued → broadcast → confirmed，任一未终结状态都可能进入 failed，
+// failed 的订单可以重新排队或退款
+const (
+	OrderStatusCreated   OrderStatus = "created"   // 已创建，等待排队
+	OrderStatusQueued    OrderStatus = "queued"    // 已进入打款队列
+	OrderStatusBroadcast OrderStatus = "broadcast" // 交易已广播，等待确认
+	OrderStatusConfirmed OrderStatus = "confirmed" // 链上已确认
+	OrderStatusFailed    OrderStatus = "failed"    // 处理失败
+	OrderStatusRefunded  OrderStatus = "refunded"  // 已退款
+)
+
+// orderTransitions 定义合法的状态迁移
+// 排队中的订单在签名前被转出策略暂缓（超出额度、等待审批）时退回已创建，之后重新排队；
+// 失败的订单只有从未签名出交易时才能重新排队，见 OrderDAO.TransitionStatus
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusCreated:   {OrderStatusQueued, OrderStatusFailed},
+	OrderStatusQueued:    {OrderStatusBroadcast, OrderStatusFailed, OrderStatusCreated},
+	OrderStatusBroadcast: {OrderStatusConfirmed, OrderStatusFailed},
+	OrderStatusFailed:    {OrderStatusQueued, OrderStatusRefunded},
+}
+
+// CanTransitionTo 判断是否允许从当前状态迁移到目标状态
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range orderTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFinal 判断是否为终结状态
+func (s OrderStatus) IsFinal() bool {
+	return len(orderTransitions[s]) == 0
+}
+
+// TimeColumn 返回进入该状态时需要记录时间的列名
+func (s OrderStatus) TimeColumn() string {
+	switch s {
+	case OrderStatusQueued:
+		return "queued_time"
+	case OrderStatusBroadcast:
+		return "broadcast_time"
+	case OrderStatusConfirmed:
+		return "confirmed_time"
+	case OrderStatusFailed:
+		return "failed_time"
+	case OrderStatusRefunded:
+		return "refunded_time"
+	default:
+		return ""
+	}
+}
+
+// OrderPayload 订单业务数据，以JSON形式存储在json列
+type OrderPayload struct {
+	Campaign         string `json:"campaign"`         // 活动标识
+	Amount           int64  `json:"amount"`           // 领取数额（ELON）
+	EligibilityProof string `json:"eligibilityProof"` // 领取资格证明
+}
+
+// Value 实现 driver.Valuer 接口
+func (p OrderPayload) Value() (driver.Value, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan 实现 sql.Scanner 接口
+func (p *OrderPayload) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*p = OrderPayload{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported order payload type: %T", src)
+	}
+	*p = OrderPayload{}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, p)
+}
+
+// StatusChange 状态迁移时附带写入的字段
+type StatusChange struct {
+	TxID       string // 广播后的交易ID
+	FailReason string // 失败原因
+}
+
 // Order 定义订单结构体
 type Order struct {
-	ID         uint      `gorm:"primary_key"`
-	OrderID    uint64    `gorm:"column:order_id"`
-	Address    string    `gorm:"column:address"`
-	Json       string    `gorm:"column:json"`
-	InsertTime time.Time `gorm:"column:insert_time"`
-	UpdateTime time.Time `gorm:"column:update_time"`
+	ID            uint         `gorm:"primaryKey"`
+	OrderID       uint64       `gorm:"column:order_id"`
+	Address       string       `gorm:"column:address"`
+	Campaign      string       `gorm:"column:campaign;type:varchar(64)"` // 冗余自Payload，便于按活动统计
+	Payload       OrderPayload `gorm:"column:json;type:text"`
+	Status        OrderStatus  `gorm:"column:status;type:varchar(16)"`
+	TxID          string       `gorm:"column:txid;type:varchar(64)"`
+	RawTx         string       `gorm:"column:raw_tx;type:mediumtext"` // 已签名的打款交易，广播前写入，恢复时重新广播
+	FailReason    string       `gorm:"column:fail_reason;type:varchar(255)"`
+	QueuedTime    *time.Time   `gorm:"column:queued_time"`
+	BroadcastTime *time.Time   `gorm:"column:broadcast_time"`
+	ConfirmedTime *time.Time   `gorm:"column:confirmed_time"`
+	FailedTime    *time.Time   `gorm:"column:failed_time"`
+	RefundedTime  *time.Time   `gorm:"column:refunded_time"`
+	InsertTime    time.Time    `gorm:"column:insert_time"`
+	UpdateTime    time.Time    `gorm:"column:update_time"`
 }
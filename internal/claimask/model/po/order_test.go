@@ -0,0 +1,61 @@
+package po
+
+import "testing"
+
+// 测试订单状态迁移规则
+func TestOrderStatusTransitions(t *testing.T) {
+	cases := []struct {
+		from, to OrderStatus
+		allowed  bool
+	}{
+		{OrderStatusCreated, OrderStatusQueued, true},
+		{OrderStatusCreated, OrderStatusBroadcast, false},
+		{OrderStatusQueued, OrderStatusBroadcast, true},
+		{OrderStatusQueued, OrderStatusCreated, true},
+		{OrderStatusBroadcast, OrderStatusCreated, false},
+		{OrderStatusBroadcast, OrderStatusConfirmed, true},
+		{OrderStatusBroadcast, OrderStatusFailed, true},
+		{OrderStatusFailed, OrderStatusQueued, true},
+		{OrderStatusFailed, OrderStatusRefunded, true},
+		{OrderStatusConfirmed, OrderStatusFailed, false},
+		{OrderStatusRefunded, OrderStatusQueued, false},
+	}
+
+	for _, c := range cases {
+		if got := c.from.CanTransitionTo(c.to); got != c.allowed {
+			t.Errorf("%s -> %s: expected %v, got %v", c.from, c.to, c.allowed, got)
+		}
+	}
+
+	if !OrderStatusConfirmed.IsFinal() || !OrderStatusRefunded.IsFinal() {
+		t.Error("confirmed and refunded should be final")
+	}
+	if OrderStatusFailed.IsFinal() {
+		t.Error("failed should not be final")
+	}
+}
+
+// 测试订单数据的序列化与反序列化
+func TestOrderPayloadScanValue(t *testing.T) {
+	payload := OrderPayload{Campaign: "genesis", Amount: 100000000, EligibilityProof: "proof"}
+	v, err := payload.Value()
+	if err != nil {
+		t.Fatalf("Value failed: %v", err)
+	}
+
+	var decoded OrderPayload
+	if err := decoded.Scan([]byte(v.(string))); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if decoded != payload {
+		t.Errorf("Expected %+v, got %+v", payload, decoded)
+	}
+
+	// 兼容历史占位数据
+	if err := decoded.Scan(`{"key": "value"}`); err != nil {
+		t.Fatalf("Scan legacy payload failed: %v", err)
+	}
+	if decoded != (OrderPayload{}) {
+		t.Errorf("Expected empty payload, got %+v", decoded)
+	}
+}
@@ -0,0 +1,143 @@
+// Package claimask 奖品领取模块：领取接口、订单查询接口和打款任务
+package claimask
+
+import (
+	"context"
+	"fmt"
+
+	"claimask/comm/initialize"
+	"claimask/comm/middleware"
+	"claimask/conf"
+	"claimask/internal/claimask/api"
+	"claimask/internal/claimask/dao"
+	"claimask/internal/claimask/service"
+	monitordao "claimask/internal/monitor/dao"
+	monitorservice "claimask/internal/monitor/service"
+	"claimask/pkg/keystore"
+	"claimask/pkg/policy"
+	"claimask/pkg/signer"
+
+	"go.uber.org/zap"
+)
+
+// Module 奖品领取模块
+type Module struct {
+	payoutWorker *service.PayoutWorker
+}
+
+// NewModule 创建奖品领取模块
+func NewModule() *Module {
+	return &Module{}
+}
+
+// Name 模块名称
+func (m *Module) Name() string {
+	return "claimask"
+}
+
+// Init 创建服务并注册 /api 下的路由，payout.enabled 时创建打款任务
+func (m *Module) Init(s *initialize.Server) error {
+	cfg := s.Config()
+	orderDAO := dao.NewOrderDAO(s.DB())
+	claimService := service.NewClaimService(orderDAO, s.Redis(), cfg.Claimask.PrizeAmount)
+	claimAPI := api.NewClaimAPI(claimService)
+	orderAPI := api.NewOrderAPI(service.NewOrderService(orderDAO))
+
+	// 转出策略随配置热更新，审批单保存在数据库中
+	engine := policy.NewEngine(policyRules(cfg.Policy), policy.NewGormStore(s.DB()))
+	s.OnReload(func(c *conf.Config) {
+		engine.SetRules(policyRules(c.Policy))
+	})
+
+	adminAuth := middleware.AdminAuth(cfg.Admin.Token.Reveal())
+	apiGroup := s.Engine.Group("/api")
+	api.RegisterClaimRoutes(apiGroup, claimAPI)
+	api.RegisterOrderRoutes(apiGroup, orderAPI, adminAuth)
+	api.RegisterApprovalRoutes(apiGroup, api.NewApprovalAPI(engine), adminAuth)
+
+	if cfg.Payout.Enabled {
+		txSigner, err := payoutSigner(s, engine)
+		if err != nil {
+			return err
+		}
+		m.payoutWorker = service.NewPayoutWorker(orderDAO, payoutChain(s), service.PayoutConfig{
+			Address:         cfg.Payout.Address,
+			Signer:          txSigner,
+			Policy:          engine,
+			Interval:        cfg.Payout.Interval,
+			BatchSize:       cfg.Payout.BatchSize,
+			MaxOutputs:      cfg.Payout.MaxOutputs,
+			MaxBatchValue:   cfg.Payout.MaxBatchValue,
+			FeeRate:         cfg.Payout.FeeRate,
+			Confirmations:   cfg.Payout.Confirmations,
+			AddressCooldown: cfg.Payout.AddressCooldown,
+			RecoverAfter:    cfg.Payout.RecoverAfter,
+		})
+	}
+	return nil
+}
+
+// payoutChain 打款任务的链上接口
+// 热钱包地址不在节点钱包中时 listunspent 查不到其UTXO，开启UTXO索引时从索引读取，与资金监控一致；索引由监控模块同步，这里只读
+func payoutChain(s *initialize.Server) service.PayoutChain {
+	cfg := s.Config()
+	if !cfg.Monitor.Indexer.Enabled {
+		zap.L().Warn("未开启UTXO索引，打款依赖节点钱包的 listunspent，热钱包需导入节点钱包")
+		return s.RPC()
+	}
+	return service.IndexedPayoutChain{
+		PayoutChain: s.RPC(),
+		Index: monitorservice.NewUTXOIndexer(monitordao.NewUTXODao(s.DB()), s.RPC(), monitorservice.IndexerConfig{
+			ReorgDepth: cfg.Monitor.Indexer.ReorgDepth,
+		}),
+	}
+}
+
+// payoutSigner 按 signer.mode 创建热钱包的签名方，两种模式都在签名前校验转出策略
+// remote 模式下私钥和签名前的策略校验都在签名进程中，两个进程共用数据库中的审批单和转出记录，
+// 本进程只预检和回写广播结果；local 模式下热钱包私钥以密文保存在内存中，只在签名时解密
+func payoutSigner(s *initialize.Server, engine *policy.Engine) (signer.Signer, error) {
+	cfg := s.Config()
+	if cfg.Signer.Mode == "remote" {
+		return signer.NewRemote(cfg.Signer.URL, cfg.Signer.Token.Reveal(), cfg.Signer.Timeout), nil
+	}
+
+	keyring, err := s.Keyring()
+	if err != nil {
+		return nil, fmt.Errorf("local signer needs the master key: %w", err)
+	}
+	keys := keystore.New(keyring, keystore.NewMemoryBackend(map[string]string{
+		cfg.Payout.Address: cfg.Payout.PrivateKey.Reveal(),
+	}))
+	return signer.NewLocal(keys, engine), nil
+}
+
+// policyRules 转换转出策略配置
+func policyRules(c conf.PolicyConfig) policy.Rules {
+	return policy.Rules{
+		MaxTx:             c.MaxTx,
+		DailyPerWallet:    c.DailyPerWallet,
+		DailyPerAddress:   c.DailyPerAddress,
+		VelocityCount:     c.Velocity.Count,
+		VelocityWindow:    c.Velocity.Window,
+		Allow:             c.Allow,
+		Deny:              c.Deny,
+		ApprovalThreshold: c.ApprovalThreshold,
+	}
+}
+
+// Start 启动打款任务
+func (m *Module) Start() error {
+	if m.payoutWorker != nil {
+		m.payoutWorker.Start()
+	}
+	return nil
+}
+
+// Stop 停止打款任务，等待正在进行的一轮打款完成
+func (m *Module) Stop(ctx context.Context) error {
+	if m.payoutWorker == nil {
+		return nil
+	}
+	return initialize.StopFunc(ctx, m.payoutWorker.Stop)
+}
@@ -30,22 +30,24 @@ var (
 type ClaimService interface {
 	ClaimPrize() error
 	ClaimPrizeV2() error
-	CreateOrder(address string) error
+	CreateOrder(address, campaign, proof string) (uint64, error)
 	QueryPrizes() (int, error)
 	InitPrizes(quantity int)
 }
 
 // ClaimServiceImpl 实现订单服务接口
 type ClaimServiceImpl struct {
-	orderDAO dao.OrderDAO
-	redisCli *redis.Client
+	orderDAO    dao.OrderDAO
+	redisCli    *redis.Client
+	prizeAmount int64 // 每份奖品的数额（ELON）
 }
 
 // NewClaimService 创建订单服务实例
-func NewClaimService(orderDAO dao.OrderDAO, rd *redis.Client) ClaimService {
+func NewClaimService(orderDAO dao.OrderDAO, rd *redis.Client, prizeAmount int64) ClaimService {
 	return &ClaimServiceImpl{
-		orderDAO: orderDAO,
-		redisCli: rd,
+		orderDAO:    orderDAO,
+		redisCli:    rd,
+		prizeAmount: prizeAmount,
 	}
 }
 
@@ -153,25 +155,31 @@ func (s *ClaimServiceImpl) ClaimPrizeV2() error {
 	return nil
 }
 
-// CreateOrder 创建订单
-func (s *ClaimServiceImpl) CreateOrder(address string) error {
+// CreateOrder 创建订单，返回订单号
+func (s *ClaimServiceImpl) CreateOrder(address, campaign, proof string) (uint64, error) {
 	orderID, err := generateOrderID()
 	if err != nil {
-		return fmt.Errorf("generate order id failed: %w", err)
+		return 0, fmt.Errorf("generate order id failed: %w", err)
 	}
 
 	order := &po.Order{
-		OrderID:    orderID,
-		Address:    address,
-		Json:       `{"key": "value"}`, // 建议改为配置项或参数传入
+		OrderID:  orderID,
+		Address:  address,
+		Campaign: campaign,
+		Payload: po.OrderPayload{
+			Campaign:         campaign,
+			Amount:           s.prizeAmount,
+			EligibilityProof: proof,
+		},
+		Status:     po.OrderStatusCreated,
 		InsertTime: time.Now(),
 		UpdateTime: time.Now(),
 	}
 
 	if err := s.orderDAO.CreateOrder(order); err != nil {
-		return fmt.Errorf("create order failed: %w", err)
+		return 0, fmt.Errorf("create order failed: %w", err)
 	}
-	return nil
+	return orderID, nil
 }
 
 // generateOrderID 生成分布式唯一ID
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"claimask/internal/claimask/dao"
+	"claimask/internal/claimask/model/dto"
+	"claimask/internal/claimask/model/po"
+	"claimask/pkg/money"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// 分页参数
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// ErrInvalidQuery 查询参数不合法
+var ErrInvalidQuery = errors.New("invalid query")
+
+// OrderService 订单查询服务接口
+type OrderService interface {
+	GetOrder(orderID uint64) (*dto.OrderView, error)
+	ListOrders(param dto.OrderListParam) (*dto.OrderPage, error)
+	GetOrderStats(from, to string) (*dto.OrderStats, error)
+}
+
+// OrderServiceImpl 实现订单查询服务接口
+type OrderServiceImpl struct {
+	orderDAO dao.OrderDAO
+}
+
+// NewOrderService 创建订单查询服务实例
+func NewOrderService(orderDAO dao.OrderDAO) OrderService {
+	return &OrderServiceImpl{orderDAO: orderDAO}
+}
+
+// GetOrder 查询单个订单
+func (s *OrderServiceImpl) GetOrder(orderID uint64) (*dto.OrderView, error) {
+	order, err := s.orderDAO.GetOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	view := toOrderView(order)
+	return &view, nil
+}
+
+// ListOrders 按地址、状态、时间范围分页查询订单
+func (s *OrderServiceImpl) ListOrders(param dto.OrderListParam) (*dto.OrderPage, error) {
+	query := dao.OrderQuery{
+		Address: param.Address,
+		Status:  po.OrderStatus(param.Status),
+		Limit:   param.Limit,
+	}
+	if query.Limit <= 0 {
+		query.Limit = defaultPageSize
+	}
+	if query.Limit > maxPageSize {
+		query.Limit = maxPageSize
+	}
+	if param.Status != "" && !isKnownStatus(query.Status) {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidQuery, param.Status)
+	}
+
+	var err error
+	if query.From, err = parseTimeParam(param.From); err != nil {
+		return nil, err
+	}
+	if query.To, err = parseTimeParam(param.To); err != nil {
+		return nil, err
+	}
+	if param.Cursor != "" {
+		cursor, err := strconv.ParseUint(param.Cursor, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad cursor", ErrInvalidQuery)
+		}
+		query.Cursor = uint(cursor)
+	}
+
+	orders, err := s.orderDAO.ListOrders(query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &dto.OrderPage{List: make([]dto.OrderView, 0, len(orders))}
+	for i := range orders {
+		page.List = append(page.List, toOrderView(&orders[i]))
+	}
+	// 满页时才返回游标，最后一页不再返回
+	if len(orders) == query.Limit {
+		page.NextCursor = strconv.FormatUint(uint64(orders[len(orders)-1].ID), 10)
+	}
+	return page, nil
+}
+
+// GetOrderStats 统计各状态、各活动的订单数量
+func (s *OrderServiceImpl) GetOrderStats(from, to string) (*dto.OrderStats, error) {
+	fromTime, err := parseTimeParam(from)
+	if err != nil {
+		return nil, err
+	}
+	toTime, err := parseTimeParam(to)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.orderDAO.CountByStatusAndCampaign(fromTime, toTime)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &dto.OrderStats{ByStatus: make(map[string]int64)}
+	campaigns := make(map[string]*dto.CampaignStat)
+	for _, row := range rows {
+		stats.Total += row.Count
+		stats.ByStatus[string(row.Status)] += row.Count
+
+		cs, ok := campaigns[row.Campaign]
+		if !ok {
+			cs = &dto.CampaignStat{Campaign: row.Campaign, ByStatus: make(map[string]int64)}
+			campaigns[row.Campaign] = cs
+		}
+		cs.Total += row.Count
+		cs.ByStatus[string(row.Status)] += row.Count
+	}
+
+	stats.ByCampaign = make([]dto.CampaignStat, 0, len(campaigns))
+	for _, cs := range campaigns {
+		stats.ByCampaign = append(stats.ByCampaign, *cs)
+	}
+	sort.Slice(stats.ByCampaign, func(i, j int) bool {
+		return stats.ByCampaign[i].Campaign < stats.ByCampaign[j].Campaign
+	})
+	return stats, nil
+}
+
+// toOrderView 将订单持久化对象转换为展示对象
+func toOrderView(order *po.Order) dto.OrderView {
+	return dto.OrderView{
+		OrderID:       strconv.FormatUint(order.OrderID, 10),
+		Address:       order.Address,
+		Campaign:      order.Campaign,
+		Amount:        money.FromElon(order.Payload.Amount),
+		Status:        string(order.Status),
+		TxID:          order.TxID,
+		FailReason:    order.FailReason,
+		CreatedAt:     order.InsertTime,
+		QueuedAt:      order.QueuedTime,
+		BroadcastAt:   order.BroadcastTime,
+		ConfirmedAt:   order.ConfirmedTime,
+		FailedAt:      order.FailedTime,
+		RefundedAt:    order.RefundedTime,
+		LastUpdatedAt: order.UpdateTime,
+	}
+}
+
+// isKnownStatus 判断是否为已定义的订单状态
+func isKnownStatus(status po.OrderStatus) bool {
+	switch status {
+	case po.OrderStatusCreated, po.OrderStatusQueued, po.OrderStatusBroadcast,
+		po.OrderStatusConfirmed, po.OrderStatusFailed, po.OrderStatusRefunded:
+		return true
+	}
+	return false
+}
+
+// parseTimeParam 解析时间参数，支持 RFC3339 和 2006-01-02 两种格式
+func parseTimeParam(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad time %q", ErrInvalidQuery, value)
+	}
+	return &t, nil
+}
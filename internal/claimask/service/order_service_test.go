@@ -0,0 +1,129 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"claimask/internal/claimask/model/dto"
+	"claimask/internal/claimask/model/po"
+)
+
+// 测试订单列表按地址、状态过滤，游标翻页和参数校验
+func TestOrderServiceListOrders(t *testing.T) {
+	orderDAO, _ := newTestOrderDAO(t)
+	service := NewOrderService(orderDAO)
+	alice, bob := testAddress(t), testAddress(t)
+	for id := uint64(1); id <= 5; id++ {
+		order := &po.Order{OrderID: id, Address: alice, Campaign: "a", Payload: po.OrderPayload{Amount: 100}}
+		if id == 5 {
+			order.Address = bob
+		}
+		if id%2 == 0 {
+			order.Status = po.OrderStatusQueued
+		}
+		if err := orderDAO.CreateOrder(order); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 按地址翻页，订单号倒序，最后一页不返回游标
+	var got []string
+	param := dto.OrderListParam{Address: alice, Limit: 3}
+	for page := 0; ; page++ {
+		result, err := service.ListOrders(param)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, view := range result.List {
+			if view.Address != alice {
+				t.Errorf("Expected only %s's orders, got %s", alice, view.Address)
+			}
+			if view.Amount.String() != "0.000001" {
+				t.Errorf("Expected amount as DOGE string, got %s", view.Amount)
+			}
+			got = append(got, view.OrderID)
+		}
+		if result.NextCursor == "" {
+			break
+		}
+		if page > 1 {
+			t.Fatal("Expected paging to stop")
+		}
+		param.Cursor = result.NextCursor
+	}
+	if len(got) != 4 || got[0] != "4" || got[3] != "1" {
+		t.Errorf("Expected orders 4..1, got %v", got)
+	}
+
+	result, err := service.ListOrders(dto.OrderListParam{Address: alice, Status: string(po.OrderStatusQueued)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.List) != 2 || result.NextCursor != "" {
+		t.Errorf("Expected 2 queued orders on one page, got %d (cursor %q)", len(result.List), result.NextCursor)
+	}
+
+	result, err = service.ListOrders(dto.OrderListParam{Limit: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.List) != 5 {
+		t.Errorf("Expected all orders without address filter, got %d", len(result.List))
+	}
+
+	for _, bad := range []dto.OrderListParam{
+		{Address: alice, Status: "paid"},
+		{Address: alice, From: "yesterday"},
+		{Address: alice, Cursor: "abc"},
+	} {
+		if _, err := service.ListOrders(bad); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("%+v: expected ErrInvalidQuery, got %v", bad, err)
+		}
+	}
+}
+
+// 测试运营统计按状态和活动汇总
+func TestOrderServiceGetOrderStats(t *testing.T) {
+	orderDAO, _ := newTestOrderDAO(t)
+	service := NewOrderService(orderDAO)
+	orders := []po.Order{
+		{OrderID: 1, Campaign: "b", Status: po.OrderStatusCreated},
+		{OrderID: 2, Campaign: "a", Status: po.OrderStatusCreated},
+		{OrderID: 3, Campaign: "a", Status: po.OrderStatusConfirmed},
+		{OrderID: 4, Campaign: "a", Status: po.OrderStatusConfirmed},
+	}
+	for i := range orders {
+		// 线上由数据库默认值填充创建时间
+		orders[i].Address, orders[i].InsertTime = testAddress(t), time.Now()
+		if err := orderDAO.CreateOrder(&orders[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := service.GetOrderStats("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Total != 4 || stats.ByStatus["created"] != 2 || stats.ByStatus["confirmed"] != 2 {
+		t.Errorf("Unexpected totals %+v", stats)
+	}
+	if len(stats.ByCampaign) != 2 || stats.ByCampaign[0].Campaign != "a" {
+		t.Fatalf("Expected campaigns sorted a, b, got %+v", stats.ByCampaign)
+	}
+	if a := stats.ByCampaign[0]; a.Total != 3 || a.ByStatus["confirmed"] != 2 || a.ByStatus["created"] != 1 {
+		t.Errorf("Unexpected campaign a stats %+v", a)
+	}
+
+	// 截止时间在所有订单之前时没有数据
+	stats, err = service.GetOrderStats("", "2000-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Total != 0 || len(stats.ByCampaign) != 0 {
+		t.Errorf("Expected empty stats, got %+v", stats)
+	}
+	if _, err := service.GetOrderStats("bad", ""); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Expected ErrInvalidQuery, got %v", err)
+	}
+}
@@ -0,0 +1,528 @@
+package service
+
+import (
+	"claimask/comm/constant"
+	"claimask/comm/errno"
+	"claimask/internal/claimask/dao"
+	"claimask/internal/claimask/model/po"
+	"claimask/pkg/dogechain"
+	"claimask/pkg/policy"
+	"claimask/pkg/queues"
+	"claimask/pkg/signer"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+	"go.uber.org/zap"
+)
+
+// PayoutConfig 打款任务配置
+type PayoutConfig struct {
+	Address         string        // 热钱包地址
+	Signer          signer.Signer // 签名热钱包的交易，私钥不经过打款任务
+	Policy          PayoutPolicy  // 排队前逐笔预检转出策略并回写广播结果，为nil时不预检
+	Interval        time.Duration // 轮询间隔
+	BatchSize       int           // 单次拉取的待打款订单数
+	MaxOutputs      int           // 单笔交易最多输出数
+	MaxBatchValue   int64         // 单笔交易打款总额上限（ELON）
+	FeeRate         int64         // 手续费率（ELON/byte）
+	Confirmations   int64         // 判定确认所需的区块确认数
+	AddressCooldown time.Duration // 同一收款地址两次排队的最小间隔，0为不限制
+	RecoverAfter    time.Duration // 排队超过该时长仍未广播的订单由恢复任务处理
+}
+
+// PayoutChain 打款任务依赖的链上接口
+type PayoutChain interface {
+	GetAddressUTXOs(address string) ([]dogechain.UTXO, error)
+	SendRawTransaction(txHex string) (string, error)
+	GetTransaction(txid string) (*dogechain.TxDetail, error)
+	GetTxOut(txid string, vout uint32) (*dogechain.UTXO, error)
+}
+
+// UTXOSource 地址UTXO的来源
+type UTXOSource interface {
+	GetAddressUTXOs(address string) ([]dogechain.UTXO, error)
+}
+
+// IndexedPayoutChain 从UTXO索引读取热钱包的UTXO，广播和交易查询仍走节点
+// 热钱包地址不在节点钱包中时 listunspent 查不到其UTXO；索引只包含已确认的区块，
+// 已被内存池中交易（如上一批尚未确认的打款）花费的输出经节点 gettxout 过滤，避免构建双花交易。
+// 未确认交易的找零要等确认后才能使用
+type IndexedPayoutChain struct {
+	PayoutChain
+	Index UTXOSource
+}
+
+// GetAddressUTXOs 查询地址在索引中且未被内存池交易花费的UTXO，按金额从大到小排序
+func (c IndexedPayoutChain) GetAddressUTXOs(address string) ([]dogechain.UTXO, error) {
+	indexed, err := c.Index.GetAddressUTXOs(address)
+	if err != nil {
+		return nil, err
+	}
+	utxos := make([]dogechain.UTXO, 0, len(indexed))
+	for _, utxo := range indexed {
+		out, err := c.PayoutChain.GetTxOut(utxo.TxHash, utxo.Index)
+		if err != nil {
+			return nil, err
+		}
+		if out != nil {
+			utxos = append(utxos, utxo)
+		}
+	}
+	sort.Slice(utxos, func(i, j int) bool {
+		return utxos[i].Value > utxos[j].Value
+	})
+	return utxos, nil
+}
+
+// PayoutPolicy 打款任务使用的转出策略
+// 签名方在签名前预留额度，打款任务在广播后确认，确定未广播时归还额度和审批单
+type PayoutPolicy interface {
+	policy.Screener
+	Commit(tx *wire.MsgTx) error
+	Release(tx *wire.MsgTx) error
+}
+
+// signedPayout 已签名、待广播的打款交易
+type signedPayout struct {
+	tx    *wire.MsgTx
+	txid  string
+	txHex string
+}
+
+// PayoutWorker 打款任务
+// 周期性拉取已创建的订单，经 SlowSpeedBox 预检和地址冷却后按单笔金额上限分批构建多输出交易，
+// 签名后先将交易写入订单再广播，广播结果不明的订单保持排队，由恢复任务查询并重新广播同一笔交易，
+// 并跟踪已广播交易的确认情况
+type PayoutWorker struct {
+	orderDAO dao.OrderDAO
+	chain    PayoutChain
+	cfg      PayoutConfig
+	box      *queues.SlowSpeedBox
+	pending  map[string]po.Order // 本轮已进入 box 的订单，按订单号索引，只在 RunOnce 中访问
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewPayoutWorker 创建打款任务
+func NewPayoutWorker(orderDAO dao.OrderDAO, chain PayoutChain, cfg PayoutConfig) *PayoutWorker {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.MaxOutputs <= 0 {
+		cfg.MaxOutputs = 50
+	}
+	if cfg.FeeRate <= 0 {
+		cfg.FeeRate = constant.DEFAULT_FEE_RATE
+	}
+	if cfg.Confirmations <= 0 {
+		cfg.Confirmations = 6
+	}
+	if cfg.RecoverAfter <= 0 {
+		cfg.RecoverAfter = 10 * time.Minute
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &PayoutWorker{
+		orderDAO: orderDAO,
+		chain:    chain,
+		cfg:      cfg,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	w.box = queues.NewManualSlowSpeedBox(w.payMessages, cfg.Address, cfg.Signer, cfg.Policy, cfg.AddressCooldown)
+	return w
+}
+
+// Start 启动打款任务
+func (w *PayoutWorker) Start() {
+	w.done = make(chan struct{})
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(w.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := w.RunOnce(); err != nil {
+					zap.L().Warn("打款任务执行失败", zap.Error(err))
+				}
+			case <-w.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止打款任务，等待正在进行的一轮打款完成
+func (w *PayoutWorker) Stop() {
+	w.cancel()
+	if w.done != nil {
+		<-w.done
+	}
+}
+
+// RunOnce 执行一轮恢复、打款和确认跟踪
+func (w *PayoutWorker) RunOnce() error {
+	if err := w.recoverQueued(); err != nil {
+		return err
+	}
+	if err := w.payPending(); err != nil {
+		return err
+	}
+	return w.confirmBroadcast()
+}
+
+// payPending 拉取待打款订单放入 box，再同步处理本轮的批次
+func (w *PayoutWorker) payPending() error {
+	orders, err := w.orderDAO.ListByStatus(po.OrderStatusCreated, w.cfg.BatchSize)
+	if err != nil {
+		return fmt.Errorf("list pending orders failed: %w", err)
+	}
+
+	w.pending = make(map[string]po.Order, len(orders))
+	for _, order := range orders {
+		if order.Payload.Amount <= 0 || order.Payload.Amount > w.cfg.MaxBatchValue {
+			w.fail(order.OrderID, fmt.Sprintf("invalid payout amount %d", order.Payload.Amount))
+			continue
+		}
+		w.enqueue(order)
+	}
+	w.box.Flush()
+	return nil
+}
+
+// enqueue 将订单放入 box，box 按转出策略预检并限制同一地址的排队频率
+// 违反策略的订单置为失败，等待审批或地址冷却中的订单保持已创建，下一轮再排队
+func (w *PayoutWorker) enqueue(order po.Order) {
+	ref := strconv.FormatUint(order.OrderID, 10)
+	err := w.box.Enqueue(queues.Message{Address: order.Address, Value: int(order.Payload.Amount), Ref: ref})
+	switch {
+	case err == nil:
+		w.pending[ref] = order
+	case errors.Is(err, policy.ErrDenied):
+		w.fail(order.OrderID, err.Error())
+	case errors.Is(err, policy.ErrPendingApproval):
+		zap.L().Debug("订单等待审批", zap.Uint64("orderId", order.OrderID), zap.Error(err))
+	case errors.Is(err, queues.ErrAddressBanned), errors.Is(err, queues.ErrAddressQueued):
+		zap.L().Debug("收款地址冷却中，下一轮再排队", zap.Uint64("orderId", order.OrderID), zap.Error(err))
+	default:
+		zap.L().Warn("订单策略预检失败", zap.Uint64("orderId", order.OrderID), zap.Error(err))
+	}
+}
+
+// payMessages box 的批次处理函数：将订单置为排队中，再按金额上限和输出数分批打款
+// 抢占失败的订单由其他实例处理
+func (w *PayoutWorker) payMessages(address string, txSigner signer.Signer, messages []queues.Message) {
+	queued := make([]po.Order, 0, len(messages))
+	for _, message := range messages {
+		order, ok := w.pending[message.Ref]
+		if !ok {
+			continue
+		}
+		if err := w.orderDAO.TransitionStatus(order.OrderID, po.OrderStatusQueued, po.StatusChange{}); err != nil {
+			if !errors.Is(err, dao.ErrStatusConflict) {
+				zap.L().Warn("订单排队失败", zap.Uint64("orderId", order.OrderID), zap.Error(err))
+			}
+			continue
+		}
+		queued = append(queued, order)
+	}
+
+	for _, batch := range splitPayoutBatches(queued, w.cfg.MaxBatchValue, w.cfg.MaxOutputs) {
+		w.payBatch(address, txSigner, batch)
+	}
+}
+
+// payBatch 为一批订单构建、签名并广播一笔交易
+// 签名前失败的订单没有交易离开本进程：被转出策略暂缓的退回已创建，其余置为失败。
+// 签名后先将交易写入订单，写入失败时不广播并退回已创建
+func (w *PayoutWorker) payBatch(address string, txSigner signer.Signer, batch []po.Order) {
+	payout, err := w.sign(address, txSigner, batch)
+	if errors.Is(err, policy.ErrLimited) || errors.Is(err, policy.ErrPendingApproval) {
+		zap.L().Warn("批量打款被转出策略暂缓", zap.Int("orders", len(batch)), zap.Error(err))
+		w.requeue(batch)
+		return
+	}
+	if err != nil {
+		zap.L().Error("批量打款失败", zap.Int("orders", len(batch)), zap.Error(err))
+		for _, order := range batch {
+			w.fail(order.OrderID, err.Error())
+		}
+		return
+	}
+
+	orderIDs := make([]uint64, len(batch))
+	for i, order := range batch {
+		orderIDs[i] = order.OrderID
+	}
+	if err := w.orderDAO.AttachTx(orderIDs, payout.txid, payout.txHex); err != nil {
+		zap.L().Error("写入打款交易失败，交易未广播", zap.String("txid", payout.txid), zap.Error(err))
+		w.release(payout.tx)
+		w.requeue(batch)
+		return
+	}
+
+	if _, err := w.chain.SendRawTransaction(payout.txHex); err != nil {
+		w.settle(batch, payout.tx, err)
+		return
+	}
+	zap.L().Info("批量打款已广播", zap.String("txid", payout.txid), zap.Int("orders", len(batch)))
+	w.markBroadcast(batch, payout.tx)
+}
+
+// sign 构建并签名打款交易
+func (w *PayoutWorker) sign(address string, txSigner signer.Signer, batch []po.Order) (*signedPayout, error) {
+	outputs := make([]dogechain.PayOutput, 0, len(batch))
+	for _, order := range batch {
+		outputs = append(outputs, dogechain.PayOutput{Address: order.Address, Value: order.Payload.Amount})
+	}
+
+	utxos, err := w.chain.GetAddressUTXOs(address)
+	if err != nil {
+		return nil, errno.NewError(errno.RPCConnectionError, err.Error())
+	}
+
+	payout, err := dogechain.BuildPayoutTx(address, utxos, outputs, w.cfg.FeeRate, constant.MINIMUM_UTXO_VALUE)
+	if err != nil {
+		if errors.Is(err, dogechain.ErrInsufficientFunds) {
+			return nil, errno.NewError(errno.UTXOInsufficientError, err.Error())
+		}
+		return nil, err
+	}
+
+	signed, err := txSigner.SignTx(w.ctx, address, payout.Tx, payout.Prevouts)
+	if err != nil {
+		// 签名失败时交易不会被广播，归还签名前可能已预留的额度
+		w.release(payout.Tx)
+		return nil, fmt.Errorf("sign payout tx failed: %w", err)
+	}
+	txHex, err := dogechain.EncodeTx(signed)
+	if err != nil {
+		w.release(payout.Tx)
+		return nil, err
+	}
+	return &signedPayout{tx: signed, txid: signed.TxHash().String(), txHex: txHex}, nil
+}
+
+// settle 处理广播报错的交易
+// 交易已在内存池或链上时按已广播处理；节点明确拒绝时交易不会出现在网络中，订单置为失败；
+// 输入已被其他交易花费时交易永远无法上链，订单置为失败并告警，不再反复重新广播；
+// 其余错误（超时、连接失败等）无法确定交易是否已发出，订单保持排队，由恢复任务重新广播同一笔交易
+func (w *PayoutWorker) settle(orders []po.Order, tx *wire.MsgTx, broadcastErr error) {
+	txid := tx.TxHash().String()
+	_, err := w.chain.GetTransaction(txid)
+	switch {
+	case err == nil:
+		zap.L().Info("广播报错但交易已在网络中", zap.String("txid", txid), zap.NamedError("broadcastError", broadcastErr))
+		w.markBroadcast(orders, tx)
+	case !errors.Is(err, dogechain.ErrTxNotFound):
+		zap.L().Warn("广播结果不明，等待恢复任务处理", zap.String("txid", txid), zap.NamedError("broadcastError", broadcastErr), zap.Error(err))
+	case dogechain.IsTxRejected(broadcastErr):
+		zap.L().Error("打款交易被节点拒绝", zap.String("txid", txid), zap.Int("orders", len(orders)), zap.Error(broadcastErr))
+		w.release(tx)
+		reason := errno.NewError(errno.TransactionBroadcastError, broadcastErr.Error()).Error()
+		for _, order := range orders {
+			w.fail(order.OrderID, reason)
+		}
+	case dogechain.IsMissingInputs(broadcastErr):
+		w.settleMissingInputs(orders, tx, broadcastErr)
+	default:
+		zap.L().Warn("广播结果不明，等待恢复任务处理", zap.String("txid", txid), zap.Error(broadcastErr))
+	}
+}
+
+// settleMissingInputs 处理输入不存在或已花费的交易
+// 输入的父交易存在但输出已被花费，说明输入被其他交易花费，订单置为失败；
+// 父交易不存在时可能尚未被节点看到，订单保持排队，由恢复任务重新广播
+func (w *PayoutWorker) settleMissingInputs(orders []po.Order, tx *wire.MsgTx, broadcastErr error) {
+	txid := tx.TxHash().String()
+	spender, err := w.spentInput(tx)
+	if err != nil {
+		zap.L().Warn("查询打款交易输入失败，等待恢复任务处理", zap.String("txid", txid), zap.NamedError("broadcastError", broadcastErr), zap.Error(err))
+		return
+	}
+	if spender == "" {
+		zap.L().Warn("打款交易的输入尚不存在，等待恢复任务处理", zap.String("txid", txid), zap.Error(broadcastErr))
+		return
+	}
+
+	zap.L().Error("打款交易的输入已被其他交易花费，订单置为失败，需人工核对是否已打款",
+		zap.String("txid", txid), zap.String("input", spender), zap.Int("orders", len(orders)), zap.Error(broadcastErr))
+	w.release(tx)
+	reason := errno.NewError(errno.TransactionBroadcastError, "input "+spender+" spent by another tx").Error()
+	for _, order := range orders {
+		w.fail(order.OrderID, reason)
+	}
+}
+
+// spentInput 返回交易中已被其他交易花费的输入，没有时返回空
+// 调用方已确认交易本身不在网络中，输出不存在而父交易存在即说明输出已被花费
+func (w *PayoutWorker) spentInput(tx *wire.MsgTx) (string, error) {
+	for _, in := range tx.TxIn {
+		prev := in.PreviousOutPoint
+		out, err := w.chain.GetTxOut(prev.Hash.String(), prev.Index)
+		if err != nil {
+			return "", err
+		}
+		if out != nil {
+			continue
+		}
+		_, err = w.chain.GetTransaction(prev.Hash.String())
+		if errors.Is(err, dogechain.ErrTxNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return prev.String(), nil
+	}
+	return "", nil
+}
+
+// recoverQueued 处理排队超过 RecoverAfter 仍未广播的订单，包括进程在广播前后崩溃和广播结果不明的订单
+// 没有交易的订单从未签名广播，退回已创建；有交易的订单重新广播已保存的同一笔交易，不会重复打款
+func (w *PayoutWorker) recoverQueued() error {
+	orders, err := w.orderDAO.ListQueuedBefore(time.Now().Add(-w.cfg.RecoverAfter), w.cfg.BatchSize)
+	if err != nil {
+		return fmt.Errorf("list stale queued orders failed: %w", err)
+	}
+
+	var unsigned []po.Order
+	byTx := make(map[string][]po.Order)
+	var txids []string
+	for _, order := range orders {
+		if order.TxID == "" {
+			unsigned = append(unsigned, order)
+			continue
+		}
+		if _, ok := byTx[order.TxID]; !ok {
+			txids = append(txids, order.TxID)
+		}
+		byTx[order.TxID] = append(byTx[order.TxID], order)
+	}
+
+	if len(unsigned) > 0 {
+		zap.L().Warn("恢复未签名的排队订单", zap.Int("orders", len(unsigned)))
+		w.requeue(unsigned)
+	}
+	for _, txid := range txids {
+		batch := byTx[txid]
+		tx, err := dogechain.DecodeTx(batch[0].RawTx)
+		if err != nil {
+			zap.L().Error("排队订单的已签名交易无法解析，需人工处理", zap.String("txid", txid), zap.Error(err))
+			continue
+		}
+		zap.L().Warn("重新广播排队中的打款交易", zap.String("txid", txid), zap.Int("orders", len(batch)))
+		if _, err := w.chain.SendRawTransaction(batch[0].RawTx); err != nil {
+			w.settle(batch, tx, err)
+			continue
+		}
+		w.markBroadcast(batch, tx)
+	}
+	return nil
+}
+
+// markBroadcast 确认交易预留的额度，将订单置为已广播并回写交易ID，失败的订单保持排队，由恢复任务重试
+func (w *PayoutWorker) markBroadcast(orders []po.Order, tx *wire.MsgTx) {
+	txid := tx.TxHash().String()
+	if w.cfg.Policy != nil {
+		if err := w.cfg.Policy.Commit(tx); err != nil {
+			zap.L().Error("确认转出额度失败", zap.String("txid", txid), zap.Error(err))
+		}
+	}
+	for _, order := range orders {
+		if err := w.orderDAO.TransitionStatus(order.OrderID, po.OrderStatusBroadcast, po.StatusChange{TxID: txid}); err != nil {
+			zap.L().Error("回写打款交易失败", zap.Uint64("orderId", order.OrderID), zap.String("txid", txid), zap.Error(err))
+		}
+	}
+}
+
+// release 交易确定未广播，归还预留的额度和审批单
+func (w *PayoutWorker) release(tx *wire.MsgTx) {
+	if w.cfg.Policy == nil {
+		return
+	}
+	if err := w.cfg.Policy.Release(tx); err != nil {
+		zap.L().Error("归还转出额度失败", zap.String("txid", tx.TxHash().String()), zap.Error(err))
+	}
+}
+
+// requeue 将未广播的订单退回已创建并解除地址冷却，下一轮重新排队
+func (w *PayoutWorker) requeue(orders []po.Order) {
+	for _, order := range orders {
+		if err := w.orderDAO.TransitionStatus(order.OrderID, po.OrderStatusCreated, po.StatusChange{}); err != nil {
+			zap.L().Error("订单退回已创建失败", zap.Uint64("orderId", order.OrderID), zap.Error(err))
+			continue
+		}
+		w.box.Release(order.Address)
+	}
+}
+
+// confirmBroadcast 检查已广播订单的确认数，达到阈值后置为已确认
+func (w *PayoutWorker) confirmBroadcast() error {
+	orders, err := w.orderDAO.ListByStatus(po.OrderStatusBroadcast, w.cfg.BatchSize)
+	if err != nil {
+		return fmt.Errorf("list broadcast orders failed: %w", err)
+	}
+
+	// 同一笔交易只查询一次
+	confirmed := make(map[string]bool)
+	for _, order := range orders {
+		ok, checked := confirmed[order.TxID]
+		if !checked {
+			tx, err := w.chain.GetTransaction(order.TxID)
+			if err != nil {
+				zap.L().Warn("查询打款交易失败", zap.String("txid", order.TxID), zap.Error(err))
+				continue
+			}
+			ok = tx.Confirmations >= w.cfg.Confirmations
+			confirmed[order.TxID] = ok
+		}
+		if !ok {
+			continue
+		}
+		if err := w.orderDAO.TransitionStatus(order.OrderID, po.OrderStatusConfirmed, po.StatusChange{}); err != nil {
+			zap.L().Warn("订单确认失败", zap.Uint64("orderId", order.OrderID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// fail 将订单置为失败
+func (w *PayoutWorker) fail(orderID uint64, reason string) {
+	if err := w.orderDAO.TransitionStatus(orderID, po.OrderStatusFailed, po.StatusChange{FailReason: reason}); err != nil {
+		zap.L().Error("订单置为失败状态失败", zap.Uint64("orderId", orderID), zap.Error(err))
+	}
+}
+
+// splitPayoutBatches 按顺序将订单切分为多个批次
+// 每批的打款总额不超过 maxValue，输出数不超过 maxOutputs
+func splitPayoutBatches(orders []po.Order, maxValue int64, maxOutputs int) [][]po.Order {
+	var batches [][]po.Order
+	var current []po.Order
+	var sum int64
+
+	for _, order := range orders {
+		amount := order.Payload.Amount
+		if len(current) > 0 && (sum+amount > maxValue || len(current) >= maxOutputs) {
+			batches = append(batches, current)
+			current, sum = nil, 0
+		}
+		current = append(current, order)
+		sum += amount
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+	return batches
+}
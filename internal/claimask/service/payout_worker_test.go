@@ -0,0 +1,403 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"claimask/internal/claimask/dao"
+	"claimask/internal/claimask/model/po"
+	"claimask/pkg/dogechain"
+	"claimask/pkg/keystore"
+	"claimask/pkg/policy"
+	"claimask/pkg/signer"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// 测试按金额上限和输出数切分打款批次
+func TestSplitPayoutBatches(t *testing.T) {
+	newOrder := func(id uint64, amount int64) po.Order {
+		return po.Order{OrderID: id, Payload: po.OrderPayload{Amount: amount}}
+	}
+	orders := []po.Order{
+		newOrder(1, 400), newOrder(2, 500), newOrder(3, 200),
+		newOrder(4, 1000), newOrder(5, 100), newOrder(6, 100),
+	}
+
+	batches := splitPayoutBatches(orders, 1000, 2)
+	expected := [][]uint64{{1, 2}, {3}, {4}, {5, 6}}
+	if len(batches) != len(expected) {
+		t.Fatalf("Expected %d batches, got %d", len(expected), len(batches))
+	}
+	for i, batch := range batches {
+		if len(batch) != len(expected[i]) {
+			t.Fatalf("Batch %d: expected %d orders, got %d", i, len(expected[i]), len(batch))
+		}
+		var sum int64
+		for j, order := range batch {
+			if order.OrderID != expected[i][j] {
+				t.Errorf("Batch %d: expected order %d, got %d", i, expected[i][j], order.OrderID)
+			}
+			sum += order.Payload.Amount
+		}
+		if sum > 1000 {
+			t.Errorf("Batch %d exceeds cap: %d", i, sum)
+		}
+	}
+
+	if batches := splitPayoutBatches(nil, 1000, 2); len(batches) != 0 {
+		t.Errorf("Expected no batches, got %d", len(batches))
+	}
+}
+
+// newTestOrderDAO 基于内存 sqlite 的订单DAO
+func newTestOrderDAO(t *testing.T) (dao.OrderDAO, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内存数据库每个连接独立，只保留一个连接
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.Table("order_id").AutoMigrate(&po.Order{}); err != nil {
+		t.Fatal(err)
+	}
+	return dao.NewOrderDAO(db), db
+}
+
+// testAddress 生成一个随机的 Dogecoin 地址
+func testAddress(t *testing.T) string {
+	t.Helper()
+	key, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(key.PubKey().SerializeCompressed()), &dogechain.DogeMainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return addr.EncodeAddress()
+}
+
+// newTestHotWallet 生成一个热钱包，返回地址和保存其私钥的 keystore
+func newTestHotWallet(t *testing.T) (string, keystore.Keystore) {
+	t.Helper()
+	key, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wif, err := btcutil.NewWIF(key, &dogechain.DogeMainNetParams, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(wif.SerializePubKey()), &dogechain.DogeMainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, _ := keystore.GenerateMasterKey("k1")
+	keyring, err := keystore.ParseKeyring(entry, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := keystore.New(keyring, keystore.NewMemoryBackend(nil))
+	if err := keys.Put(addr.EncodeAddress(), []byte(wif.String())); err != nil {
+		t.Fatal(err)
+	}
+	return addr.EncodeAddress(), keys
+}
+
+// nopSigner 原样返回交易，打款任务只关心交易ID和序列化结果
+type nopSigner struct{}
+
+func (nopSigner) SignTx(ctx context.Context, address string, tx *wire.MsgTx, prevouts []dogechain.Prevout) (*wire.MsgTx, error) {
+	return tx, nil
+}
+
+// fakeFundingTx 测试热钱包唯一UTXO所在的交易
+const fakeFundingTx = "a1075db55d416d3ca199f55b6084e2115b9345e16c5cf302fc80e9d5fbf5d48d"
+
+// fakePayoutChain 记录广播的交易，sendErr 不为nil时广播报错，accept 为真时报错前交易仍进入内存池，
+// spent 中的输出已被其他交易花费，键为 txid:vout
+type fakePayoutChain struct {
+	sent    []string
+	mempool map[string]bool
+	sendErr error
+	accept  bool
+	spent   map[string]bool
+}
+
+func newFakePayoutChain() *fakePayoutChain {
+	return &fakePayoutChain{mempool: make(map[string]bool), spent: make(map[string]bool)}
+}
+
+func (c *fakePayoutChain) GetAddressUTXOs(address string) ([]dogechain.UTXO, error) {
+	return []dogechain.UTXO{{
+		TxHash:        fakeFundingTx,
+		Value:         100000000000,
+		Confirmations: 10,
+	}}, nil
+}
+
+func (c *fakePayoutChain) SendRawTransaction(txHex string) (string, error) {
+	c.sent = append(c.sent, txHex)
+	tx, err := dogechain.DecodeTx(txHex)
+	if err != nil {
+		return "", err
+	}
+	txid := tx.TxHash().String()
+	if c.sendErr != nil && !c.accept {
+		return "", c.sendErr
+	}
+	c.mempool[txid] = true
+	if c.sendErr != nil {
+		return "", c.sendErr
+	}
+	return txid, nil
+}
+
+func (c *fakePayoutChain) GetTransaction(txid string) (*dogechain.TxDetail, error) {
+	if !c.mempool[txid] {
+		return nil, dogechain.ErrTxNotFound
+	}
+	return &dogechain.TxDetail{Txid: txid}, nil
+}
+
+func (c *fakePayoutChain) GetTxOut(txid string, vout uint32) (*dogechain.UTXO, error) {
+	if c.spent[fmt.Sprintf("%s:%d", txid, vout)] {
+		return nil, nil
+	}
+	return &dogechain.UTXO{TxHash: txid, Index: vout, Value: 100000000000, Confirmations: 10}, nil
+}
+
+// 测试一轮打款中广播成功、结果不明、被节点拒绝、输入不存在或已花费时的订单状态和转出额度
+// 额度在签名前预留，只有确定未广播的交易归还额度
+func TestPayoutWorkerRunOnce(t *testing.T) {
+	timeout := errors.New("rpc timeout")
+	rejected := &dogechain.RPCError{Code: dogechain.RPCVerifyRejected, Message: "min relay fee not met"}
+	missing := &dogechain.RPCError{Code: dogechain.RPCVerifyError, Message: "bad-txns-inputs-missingorspent"}
+
+	cases := []struct {
+		name    string
+		sendErr error
+		accept  bool
+		inputs  string // 广播时热钱包UTXO的状态："" 未花费，missing 父交易未知，spent 已被其他交易花费
+		status  po.OrderStatus
+		spent   bool
+	}{
+		{"broadcast", nil, false, "", po.OrderStatusBroadcast, true},
+		{"ambiguous error with tx in mempool", timeout, true, "", po.OrderStatusBroadcast, true},
+		{"ambiguous error", timeout, false, "", po.OrderStatusQueued, true},
+		{"rejected", rejected, false, "", po.OrderStatusFailed, false},
+		{"inputs not yet seen", missing, false, "missing", po.OrderStatusQueued, true},
+		{"inputs spent by another tx", missing, false, "spent", po.OrderStatusFailed, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			orderDAO, _ := newTestOrderDAO(t)
+			chain := newFakePayoutChain()
+			chain.sendErr, chain.accept = c.sendErr, c.accept
+			// 构建交易时仍拿到该UTXO，广播前已被其他交易花费
+			chain.spent[fakeFundingTx+":0"] = c.inputs != ""
+			chain.mempool[fakeFundingTx] = c.inputs == "spent"
+			store := policy.NewMemoryStore()
+			engine := policy.NewEngine(policy.Rules{}, store)
+			hot, keys := newTestHotWallet(t)
+			worker := NewPayoutWorker(orderDAO, chain, PayoutConfig{
+				Address:       hot,
+				Signer:        signer.NewLocal(keys, engine),
+				Policy:        engine,
+				MaxBatchValue: 10000000000,
+				FeeRate:       1000,
+			})
+			for id := uint64(1); id <= 2; id++ {
+				if err := orderDAO.CreateOrder(&po.Order{OrderID: id, Address: testAddress(t), Payload: po.OrderPayload{Amount: 1000000000}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := worker.RunOnce(); err != nil {
+				t.Fatal(err)
+			}
+			if len(chain.sent) != 1 {
+				t.Fatalf("Expected one payout tx for both orders, got %d", len(chain.sent))
+			}
+			tx, _ := dogechain.DecodeTx(chain.sent[0])
+			for id := uint64(1); id <= 2; id++ {
+				order, _ := orderDAO.GetOrder(id)
+				if order.Status != c.status {
+					t.Errorf("Order %d: expected %s, got %s (%s)", id, c.status, order.Status, order.FailReason)
+				}
+				// 交易在广播前写入订单
+				if order.TxID != tx.TxHash().String() || order.RawTx != chain.sent[0] {
+					t.Errorf("Order %d: expected signed tx %s persisted, got %q", id, tx.TxHash(), order.TxID)
+				}
+			}
+			if spent, _ := store.Reserved(policy.SpendKey(tx)); spent != c.spent {
+				t.Errorf("Expected quota spent %v, got %v", c.spent, spent)
+			}
+		})
+	}
+}
+
+// 测试恢复任务重新广播结果不明的同一笔交易，并将崩溃时未签名的排队订单退回重新打款
+func TestPayoutWorkerRecover(t *testing.T) {
+	orderDAO, db := newTestOrderDAO(t)
+	chain := newFakePayoutChain()
+	chain.sendErr = errors.New("rpc timeout")
+	worker := NewPayoutWorker(orderDAO, chain, PayoutConfig{
+		Address:       testAddress(t),
+		Signer:        nopSigner{},
+		MaxBatchValue: 10000000000,
+		FeeRate:       1000,
+		RecoverAfter:  time.Hour,
+	})
+	if err := orderDAO.CreateOrder(&po.Order{OrderID: 1, Address: testAddress(t), Payload: po.OrderPayload{Amount: 1000000000}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := worker.RunOnce(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 另一订单排队后进程崩溃，没有签名交易
+	stale := time.Now().Add(-2 * time.Hour)
+	if err := orderDAO.CreateOrder(&po.Order{OrderID: 2, Address: testAddress(t), Status: po.OrderStatusQueued, Payload: po.OrderPayload{Amount: 2000000000}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Table("order_id").Where("1 = 1").Update("queued_time", stale).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 节点恢复后，恢复任务重新广播保存的交易，不重新构建
+	chain.sendErr = nil
+	if err := worker.RunOnce(); err != nil {
+		t.Fatal(err)
+	}
+	if len(chain.sent) != 3 || chain.sent[1] != chain.sent[0] {
+		t.Fatalf("Expected the saved tx rebroadcast before paying order 2, got %d broadcasts", len(chain.sent))
+	}
+	for id := uint64(1); id <= 2; id++ {
+		order, _ := orderDAO.GetOrder(id)
+		if order.Status != po.OrderStatusBroadcast {
+			t.Errorf("Order %d: expected broadcast, got %s", id, order.Status)
+		}
+	}
+	if order, _ := orderDAO.GetOrder(2); order.RawTx != chain.sent[2] {
+		t.Errorf("Expected order 2 paid by a new tx, got %q", order.TxID)
+	}
+}
+
+// 测试地址冷却期内同一地址的订单留到下一轮
+func TestPayoutWorkerAddressCooldown(t *testing.T) {
+	orderDAO, _ := newTestOrderDAO(t)
+	chain := newFakePayoutChain()
+	worker := NewPayoutWorker(orderDAO, chain, PayoutConfig{
+		Address:         testAddress(t),
+		Signer:          nopSigner{},
+		MaxBatchValue:   10000000000,
+		FeeRate:         1000,
+		AddressCooldown: time.Hour,
+	})
+	receiver := testAddress(t)
+	for id := uint64(1); id <= 2; id++ {
+		if err := orderDAO.CreateOrder(&po.Order{OrderID: id, Address: receiver, Payload: po.OrderPayload{Amount: 1000000000}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := worker.RunOnce(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first, _ := orderDAO.GetOrder(1)
+	second, _ := orderDAO.GetOrder(2)
+	if first.Status != po.OrderStatusBroadcast || second.Status != po.OrderStatusCreated {
+		t.Errorf("Expected only the first order paid within cooldown, got %s and %s", first.Status, second.Status)
+	}
+}
+
+// 测试 remote 模式下签名进程的策略拒绝：额度不足和等待审批的订单退回已创建下一轮重试，违反策略的订单置为失败
+// 签名进程与业务进程使用各自的策略引擎，共用转出记录
+func TestPayoutWorkerRemoteSigner(t *testing.T) {
+	cases := []struct {
+		name   string
+		rules  policy.Rules
+		status po.OrderStatus
+	}{
+		{"signed", policy.Rules{}, po.OrderStatusBroadcast},
+		{"daily limit", policy.Rules{DailyPerWallet: 1500000000}, po.OrderStatusCreated},
+		{"pending approval", policy.Rules{ApprovalThreshold: 500000000}, po.OrderStatusCreated},
+		{"denied", policy.Rules{MaxTx: 1500000000}, po.OrderStatusFailed},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			orderDAO, _ := newTestOrderDAO(t)
+			chain := newFakePayoutChain()
+			store := policy.NewMemoryStore()
+			hot, keys := newTestHotWallet(t)
+			signerd := httptest.NewServer(signer.NewHandler(signer.NewLocal(keys, policy.NewEngine(c.rules, store)), "token"))
+			defer signerd.Close()
+
+			worker := NewPayoutWorker(orderDAO, chain, PayoutConfig{
+				Address:       hot,
+				Signer:        signer.NewRemote(signerd.URL, "token", 5*time.Second),
+				Policy:        policy.NewEngine(policy.Rules{}, store),
+				MaxBatchValue: 10000000000,
+				FeeRate:       1000,
+			})
+			for id := uint64(1); id <= 2; id++ {
+				if err := orderDAO.CreateOrder(&po.Order{OrderID: id, Address: testAddress(t), Payload: po.OrderPayload{Amount: 1000000000}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := worker.RunOnce(); err != nil {
+				t.Fatal(err)
+			}
+			for id := uint64(1); id <= 2; id++ {
+				order, _ := orderDAO.GetOrder(id)
+				if order.Status != c.status {
+					t.Errorf("Order %d: expected %s, got %s (%s)", id, c.status, order.Status, order.FailReason)
+				}
+			}
+			if broadcast := c.status == po.OrderStatusBroadcast; broadcast != (len(chain.sent) == 1) {
+				t.Errorf("Expected broadcast %v, got %d txs", broadcast, len(chain.sent))
+			}
+		})
+	}
+}
+
+// fakeUTXOIndex 固定返回的索引UTXO
+type fakeUTXOIndex []dogechain.UTXO
+
+func (f fakeUTXOIndex) GetAddressUTXOs(address string) ([]dogechain.UTXO, error) {
+	return f, nil
+}
+
+// 测试从索引读取UTXO时过滤已被内存池交易花费的输出
+func TestIndexedPayoutChain(t *testing.T) {
+	inner := newFakePayoutChain()
+	inner.spent[fakeFundingTx+":1"] = true
+	chain := IndexedPayoutChain{PayoutChain: inner, Index: fakeUTXOIndex{
+		{TxHash: fakeFundingTx, Index: 0, Value: 100},
+		{TxHash: fakeFundingTx, Index: 1, Value: 300},
+		{TxHash: fakeFundingTx, Index: 2, Value: 200},
+	}}
+
+	utxos, err := chain.GetAddressUTXOs(testAddress(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(utxos) != 2 || utxos[0].Index != 2 || utxos[1].Index != 0 {
+		t.Errorf("Expected unspent outputs 2 and 0 by value, got %+v", utxos)
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"errors"
+
+	"claimask/internal/monitor/dao"
+	"claimask/internal/monitor/model/dto"
+	"claimask/internal/monitor/model/po"
+	"claimask/internal/monitor/service"
+	"claimask/pkg/dogechain"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// DepositHandler 订单收款地址
+type DepositHandler struct {
+	allocator *service.DepositAllocator
+	account   *dogechain.HDAccount
+}
+
+// NewDepositHandler 创建订单收款地址处理器，allocator 为nil时未配置扩展公钥
+func NewDepositHandler(allocator *service.DepositAllocator, account *dogechain.HDAccount) *DepositHandler {
+	return &DepositHandler{allocator: allocator, account: account}
+}
+
+// AllocateDeposit 为订单分配收款地址，同一订单重复调用返回同一地址
+func (h *DepositHandler) AllocateDeposit(c *gin.Context) {
+	if h.allocator == nil {
+		c.JSON(503, gin.H{"code": 5003, "msg": "未配置收款地址扩展公钥"})
+		return
+	}
+	var req dto.AllocateDepositRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"code": 4001, "msg": "参数格式错误"})
+		return
+	}
+
+	deposit, err := h.allocator.Allocate(req.OrderNo, req.UserID)
+	if errors.Is(err, service.ErrGapLimit) {
+		c.JSON(503, gin.H{"code": 5003, "msg": "收款地址达到间隔上限，暂无可分配的地址"})
+		return
+	}
+	if err != nil {
+		zap.L().Warn("分配订单收款地址失败", zap.String("orderNo", req.OrderNo), zap.Error(err))
+		c.JSON(500, gin.H{"code": 5001, "msg": "分配收款地址失败"})
+		return
+	}
+	c.JSON(200, gin.H{"code": 0, "msg": "success", "data": h.depositDTO(deposit)})
+}
+
+// GetDeposit 查询订单的收款地址和收款状态
+func (h *DepositHandler) GetDeposit(c *gin.Context) {
+	if h.allocator == nil {
+		c.JSON(503, gin.H{"code": 5003, "msg": "未配置收款地址扩展公钥"})
+		return
+	}
+	orderNo := c.Param("orderNo")
+	deposit, err := h.allocator.Get(orderNo)
+	if errors.Is(err, dao.ErrDepositNotFound) {
+		c.JSON(404, gin.H{"code": 4004, "msg": "订单没有分配收款地址"})
+		return
+	}
+	if err != nil {
+		zap.L().Warn("查询订单收款地址失败", zap.String("orderNo", orderNo), zap.Error(err))
+		c.JSON(500, gin.H{"code": 5001, "msg": "查询收款地址失败"})
+		return
+	}
+	c.JSON(200, gin.H{"code": 0, "msg": "success", "data": h.depositDTO(deposit)})
+}
+
+// depositDTO 转换为订单收款地址信息
+func (h *DepositHandler) depositDTO(deposit *po.DepositAddressPO) dto.DepositAddress {
+	return dto.DepositAddress{
+		OrderNo:   deposit.OrderNo,
+		UserID:    deposit.UserID,
+		Address:   deposit.Address,
+		Path:      h.account.Path(deposit.AddrIndex),
+		Status:    deposit.Status,
+		TxHash:    deposit.TxHash,
+		Received:  deposit.Received,
+		CreatedAt: deposit.CreatedAt,
+		PaidAt:    deposit.PaidAt,
+	}
+}
@@ -1,6 +1,9 @@
 package api
 
 import (
+	"errors"
+
+	"claimask/internal/monitor/dao"
 	"claimask/internal/monitor/service"
 
 	"github.com/gin-gonic/gin"
@@ -49,6 +52,10 @@ func (h *PaymentHandler) GetNFTStatus(c *gin.Context) {
 	}
 
 	status, err := h.monitorSvc.GetNFTStatus(c.Request.Context(), txid)
+	if errors.Is(err, dao.ErrNFTNotFound) {
+		c.JSON(404, gin.H{"code": 4004, "msg": "NFT不存在"})
+		return
+	}
 	if err != nil {
 		zap.L().Warn("获取NFT状态失败", zap.String("txid", txid), zap.Error(err))
 		c.JSON(500, gin.H{"code": 5001, "msg": "获取NFT状态失败"})
@@ -2,29 +2,40 @@ package api
 
 import (
 	"claimask/internal/monitor/service"
-	"claimask/pkg/dogechain"
-
-	"time"
+	"claimask/pkg/keystore"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-redis/redis"
 )
 
-func RegisterRoutes(router *gin.Engine, rpcClient interface{}, redisClient interface{}) {
-	monitorSvc := service.NewMonitorService(
-		service.NewTxMonitor(rpcClient.(*dogechain.RPCClient), &service.MonitorConfig{
-			WalletGroups:      []string{"DTcuJ6N5QEoQUygTv8CnKzn3DUS7KhaDR2"},
-			BlockPollInterval: 60 * time.Second,
-			WebsocketEndpoint: "wss://ws.dogechain.info/",
-		}),
-		service.NewQueueManager(redisClient.(*redis.Client)),
-	)
-
+// RegisterRoutes 注册监控服务路由，钱包组、订单收款地址管理和UTXO查询接口需经 adminAuth 鉴权
+func RegisterRoutes(router *gin.Engine, monitorSvc service.MonitorService, registry *service.WalletRegistry, keys *keystore.Store,
+	deposits *DepositHandler, utxos *UTXOHandler, adminAuth gin.HandlerFunc) {
 	handler := NewPaymentHandler(monitorSvc)
+	walletHandler := NewWalletHandler(registry, keys, utxos.indexer)
 
 	v1 := router.Group("/api/v1")
 	{
 		v1.POST("/pay-callback", handler.HandlePaymentCallback)
 		v1.GET("/nft-status/:txid", handler.GetNFTStatus)
 	}
+
+	admin := v1.Group("/admin/wallets", adminAuth)
+	{
+		admin.GET("", walletHandler.ListWallets)
+		admin.POST("", walletHandler.AddWallet)
+		admin.DELETE("/:address", walletHandler.RetireWallet)
+		admin.POST("/rotate-keys", walletHandler.RotateKeys)
+	}
+
+	deposit := v1.Group("/admin/deposits", adminAuth)
+	{
+		deposit.POST("", deposits.AllocateDeposit)
+		deposit.GET("/:orderNo", deposits.GetDeposit)
+	}
+
+	utxo := v1.Group("/admin/utxos", adminAuth)
+	{
+		utxo.GET("/:address", utxos.GetAddressUTXOs)
+		utxo.POST("/rescan", utxos.Rescan)
+	}
 }
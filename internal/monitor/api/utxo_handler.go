@@ -0,0 +1,85 @@
+package api
+
+import (
+	"strconv"
+
+	"claimask/internal/monitor/model/dto"
+	"claimask/internal/monitor/service"
+	"claimask/pkg/dogechain"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// UTXOHandler 地址余额和UTXO查询
+type UTXOHandler struct {
+	indexer *service.UTXOIndexer
+}
+
+// NewUTXOHandler 创建UTXO查询处理器，indexer 为nil时未开启UTXO索引
+func NewUTXOHandler(indexer *service.UTXOIndexer) *UTXOHandler {
+	return &UTXOHandler{indexer: indexer}
+}
+
+// GetAddressUTXOs 查询地址的余额和未花费输出，minConf 默认为1
+func (h *UTXOHandler) GetAddressUTXOs(c *gin.Context) {
+	if h.indexer == nil {
+		c.JSON(503, gin.H{"code": 5003, "msg": "未开启UTXO索引"})
+		return
+	}
+	address := c.Param("address")
+	if err := dogechain.ValidateAddress(address); err != nil {
+		c.JSON(400, gin.H{"code": 4001, "msg": "无效的地址"})
+		return
+	}
+	minConf := int64(1)
+	if s := c.Query("minConf"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n < 0 {
+			c.JSON(400, gin.H{"code": 4001, "msg": "minConf 必须为非负整数"})
+			return
+		}
+		minConf = n
+	}
+
+	balance, err := h.indexer.Balance(address, minConf)
+	if err != nil {
+		zap.L().Warn("查询地址UTXO失败", zap.String("address", address), zap.Error(err))
+		c.JSON(500, gin.H{"code": 5001, "msg": "查询UTXO失败"})
+		return
+	}
+	data := dto.AddressBalance{
+		Address:     balance.Address,
+		Height:      balance.Height,
+		MinConf:     minConf,
+		Confirmed:   balance.Confirmed,
+		Unconfirmed: balance.Unconfirmed,
+		UTXOs:       make([]dto.UTXO, len(balance.UTXOs)),
+	}
+	for i, utxo := range balance.UTXOs {
+		data.UTXOs[i] = dto.UTXO{TxHash: utxo.TxHash, Index: utxo.Index, Value: utxo.Value, Confirmations: utxo.Confirmations}
+	}
+	c.JSON(200, gin.H{"code": 0, "msg": "success", "data": data})
+}
+
+// Rescan 登记UTXO索引补扫，从 fromHeight 起补记地址加入监控前收到的输出，地址需已在监控中
+func (h *UTXOHandler) Rescan(c *gin.Context) {
+	if h.indexer == nil {
+		c.JSON(503, gin.H{"code": 5003, "msg": "未开启UTXO索引"})
+		return
+	}
+	var req dto.RescanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"code": 4001, "msg": "参数格式错误"})
+		return
+	}
+	for _, address := range req.Addresses {
+		if err := dogechain.ValidateAddress(address); err != nil {
+			c.JSON(400, gin.H{"code": 4001, "msg": "无效的地址"})
+			return
+		}
+	}
+
+	h.indexer.ScheduleRescan(req.FromHeight, req.Addresses...)
+	c.JSON(200, gin.H{"code": 0, "msg": "success"})
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"errors"
+
+	"claimask/internal/monitor/dao"
+	"claimask/internal/monitor/model/dto"
+	"claimask/internal/monitor/model/po"
+	"claimask/internal/monitor/service"
+	"claimask/pkg/keystore"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// WalletHandler 监控钱包组管理
+type WalletHandler struct {
+	registry *service.WalletRegistry
+	keys     *keystore.Store
+	indexer  *service.UTXOIndexer
+}
+
+// NewWalletHandler 创建监控钱包组管理处理器，keys 为nil时不能轮换私钥，indexer 为nil时不补扫新地址
+func NewWalletHandler(registry *service.WalletRegistry, keys *keystore.Store, indexer *service.UTXOIndexer) *WalletHandler {
+	return &WalletHandler{registry: registry, keys: keys, indexer: indexer}
+}
+
+// ListWallets 查询钱包组，all=true 时包含已停用的
+func (h *WalletHandler) ListWallets(c *gin.Context) {
+	wallets, err := h.registry.List(c.Query("all") == "true")
+	if err != nil {
+		zap.L().Warn("查询钱包组失败", zap.Error(err))
+		c.JSON(500, gin.H{"code": 5001, "msg": "查询钱包组失败"})
+		return
+	}
+
+	data := make([]dto.WalletGroup, 0, len(wallets))
+	for i := range wallets {
+		data = append(data, walletDTO(&wallets[i]))
+	}
+	c.JSON(200, gin.H{"code": 0, "msg": "success", "data": data})
+}
+
+// AddWallet 将地址加入监控，立即生效；rescanFrom 大于0时UTXO索引从该高度补扫地址的历史输出
+func (h *WalletHandler) AddWallet(c *gin.Context) {
+	var req dto.AddWalletRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"code": 4001, "msg": "参数格式错误"})
+		return
+	}
+
+	wallet, err := h.registry.Add(req.Group, req.Address)
+	switch {
+	case errors.Is(err, service.ErrWalletExists):
+		c.JSON(409, gin.H{"code": 4009, "msg": "地址已在监控中"})
+		return
+	case errors.Is(err, service.ErrInvalidAddress):
+		c.JSON(400, gin.H{"code": 4001, "msg": err.Error()})
+		return
+	case err != nil:
+		zap.L().Warn("添加钱包组失败", zap.String("address", req.Address), zap.Error(err))
+		c.JSON(500, gin.H{"code": 5001, "msg": "添加钱包组失败"})
+		return
+	}
+	if h.indexer != nil && req.RescanFrom > 0 {
+		h.indexer.ScheduleRescan(req.RescanFrom, wallet.ReceiveAddr)
+	}
+	c.JSON(200, gin.H{"code": 0, "msg": "success", "data": walletDTO(wallet)})
+}
+
+// RetireWallet 停用地址，立即停止监控
+func (h *WalletHandler) RetireWallet(c *gin.Context) {
+	address := c.Param("address")
+	err := h.registry.Retire(address)
+	if errors.Is(err, dao.ErrWalletNotFound) {
+		c.JSON(404, gin.H{"code": 4004, "msg": "钱包组不存在"})
+		return
+	}
+	if err != nil {
+		zap.L().Warn("停用钱包组失败", zap.String("address", address), zap.Error(err))
+		c.JSON(500, gin.H{"code": 5001, "msg": "停用钱包组失败"})
+		return
+	}
+	c.JSON(200, gin.H{"code": 0, "msg": "success"})
+}
+
+// RotateKeys 用当前主密钥重新加密所有钱包组私钥的数据密钥
+// 更换主密钥时先将新主密钥设为 activeKey 并保留旧主密钥重启，调用本接口后再移除旧主密钥
+// 启用加密前已写入数据库的明文私钥也由本接口加密
+func (h *WalletHandler) RotateKeys(c *gin.Context) {
+	if h.keys == nil {
+		c.JSON(503, gin.H{"code": 5003, "msg": "未配置主密钥"})
+		return
+	}
+	rotated, err := h.keys.Rotate()
+	if err != nil {
+		zap.L().Error("轮换钱包组私钥失败", zap.Strings("rotated", rotated), zap.Error(err))
+		c.JSON(500, gin.H{"code": 5001, "msg": "轮换钱包组私钥失败", "data": gin.H{"rotated": rotated}})
+		return
+	}
+	zap.L().Info("钱包组私钥轮换完成", zap.Int("count", len(rotated)))
+	c.JSON(200, gin.H{"code": 0, "msg": "success", "data": gin.H{"rotated": rotated}})
+}
+
+// walletDTO 转换为不含私钥的钱包组信息
+func walletDTO(wallet *po.WalletGroupPO) dto.WalletGroup {
+	return dto.WalletGroup{
+		Group:     wallet.GroupID,
+		Address:   wallet.ReceiveAddr,
+		Status:    wallet.Status,
+		CreatedAt: wallet.CreatedAt,
+		RetiredAt: wallet.RetiredAt,
+	}
+}
@@ -0,0 +1,154 @@
+package dao
+
+import (
+	"errors"
+	"time"
+
+	"claimask/internal/monitor/model/po"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrDepositNotFound 订单没有分配收款地址
+var ErrDepositNotFound = errors.New("deposit address not found")
+
+// DeriveFunc 按下一个派生序号和最后一个已收款地址的序号（没有时为-1）生成收款地址
+type DeriveFunc func(next uint32, lastPaid int64) (string, error)
+
+type DepositAddressDao interface {
+	GetByOrderNo(orderNo string) (*po.DepositAddressPO, error)
+	ListDepositAddresses() ([]po.DepositAddressPO, error)
+	AllocateDepositAddress(orderNo, userID string, derive DeriveFunc) (*po.DepositAddressPO, error)
+	ReassignDepositAddress(orderNo, userID string, before time.Time) (*po.DepositAddressPO, error)
+	MarkDepositPaid(address, txHash string, amount int64, paidAt time.Time) (bool, error)
+}
+
+type DepositAddressDaoImpl struct {
+	db *gorm.DB
+}
+
+func NewDepositAddressDao(db *gorm.DB) DepositAddressDao {
+	return &DepositAddressDaoImpl{db: db}
+}
+
+// GetByOrderNo 按订单号查询收款地址
+func (d *DepositAddressDaoImpl) GetByOrderNo(orderNo string) (*po.DepositAddressPO, error) {
+	var deposit po.DepositAddressPO
+	if err := d.db.Where("order_no = ?", orderNo).First(&deposit).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDepositNotFound
+		}
+		return nil, err
+	}
+	return &deposit, nil
+}
+
+// ListDepositAddresses 按派生序号顺序查询所有已分配的收款地址
+func (d *DepositAddressDaoImpl) ListDepositAddresses() ([]po.DepositAddressPO, error) {
+	var deposits []po.DepositAddressPO
+	if err := d.db.Order("addr_index ASC").Find(&deposits).Error; err != nil {
+		return nil, err
+	}
+	return deposits, nil
+}
+
+// AllocateDepositAddress 在事务中为订单分配下一个派生序号
+// 锁住序号最大的一行，多个实例同时分配时串行执行；表为空时并发插入由 addr_index 唯一索引兜底
+func (d *DepositAddressDaoImpl) AllocateDepositAddress(orderNo, userID string, derive DeriveFunc) (*po.DepositAddressPO, error) {
+	var deposit *po.DepositAddressPO
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		var next uint32
+		var last po.DepositAddressPO
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Order("addr_index DESC").First(&last).Error
+		switch {
+		case err == nil:
+			next = last.AddrIndex + 1
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+
+		var lastPaid struct{ Max *int64 }
+		if err := tx.Model(&po.DepositAddressPO{}).
+			Select("MAX(addr_index) AS max").
+			Where("status = ?", po.DepositStatusPaid).
+			Scan(&lastPaid).Error; err != nil {
+			return err
+		}
+		paid := int64(-1)
+		if lastPaid.Max != nil {
+			paid = *lastPaid.Max
+		}
+
+		address, err := derive(next, paid)
+		if err != nil {
+			return err
+		}
+		deposit = &po.DepositAddressPO{
+			Address:   address,
+			AddrIndex: next,
+			OrderNo:   orderNo,
+			UserID:    userID,
+			Status:    po.DepositStatusAssigned,
+		}
+		return tx.Create(deposit).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deposit, nil
+}
+
+// ReassignDepositAddress 将序号最小的、分配时间早于 before 且未收款的地址改派给订单，没有时返回 ErrDepositNotFound
+// 锁住改派的行，以未收款为条件更新，与同时到账的首笔支付互斥
+func (d *DepositAddressDaoImpl) ReassignDepositAddress(orderNo, userID string, before time.Time) (*po.DepositAddressPO, error) {
+	var deposit po.DepositAddressPO
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("status = ? AND created_at < ?", po.DepositStatusAssigned, before).
+			Order("addr_index ASC").
+			First(&deposit).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrDepositNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		deposit.OrderNo, deposit.UserID, deposit.CreatedAt = orderNo, userID, time.Now()
+		result := tx.Model(&po.DepositAddressPO{}).
+			Where("id = ? AND status = ?", deposit.ID, po.DepositStatusAssigned).
+			Updates(map[string]interface{}{
+				"order_no":   deposit.OrderNo,
+				"user_id":    deposit.UserID,
+				"created_at": deposit.CreatedAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrDepositNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &deposit, nil
+}
+
+// MarkDepositPaid 记录收款地址的首笔支付，已支付过的地址不覆盖，返回是否为首笔支付
+func (d *DepositAddressDaoImpl) MarkDepositPaid(address, txHash string, amount int64, paidAt time.Time) (bool, error) {
+	result := d.db.Model(&po.DepositAddressPO{}).
+		Where("address = ? AND status = ?", address, po.DepositStatusAssigned).
+		Updates(map[string]interface{}{
+			"status":   po.DepositStatusPaid,
+			"tx_hash":  txHash,
+			"received": amount,
+			"paid_at":  paidAt,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
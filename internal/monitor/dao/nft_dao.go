@@ -1,11 +1,16 @@
 package dao
 
 import (
+	"errors"
+
 	"claimask/internal/monitor/model/po"
 
 	"gorm.io/gorm"
 )
 
+// ErrNFTNotFound NFT不存在
+var ErrNFTNotFound = errors.New("nft not found")
+
 type NFTDao interface {
 	UpdateNFTStatus(nft *po.NFTPO) error
 	GetNFTByUTXO(utxoHash string) (*po.NFTPO, error)
@@ -30,6 +35,11 @@ func (d *NFTDaoImpl) UpdateNFTStatus(nft *po.NFTPO) error {
 
 func (d *NFTDaoImpl) GetNFTByUTXO(utxoHash string) (*po.NFTPO, error) {
 	var nft po.NFTPO
-	result := d.db.Where("utxo_hash = ?", utxoHash).First(&nft)
-	return &nft, result.Error
+	if err := d.db.Where("utxo_hash = ?", utxoHash).First(&nft).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNFTNotFound
+		}
+		return nil, err
+	}
+	return &nft, nil
 }
@@ -1,14 +1,41 @@
 package dao
 
 import (
+	"errors"
+
 	"claimask/internal/monitor/model/po"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrBlockNotFound 该高度的区块尚未索引或已清理
+var ErrBlockNotFound = errors.New("indexed block not found")
+
+// Spend 区块中的一个交易输入，花费 TxHash:Index
+type Spend struct {
+	TxHash  string
+	Index   uint32
+	SpentTx string // 花费交易哈希
+}
+
 type UTXODao interface {
 	GetAddressValidUtxo(address string) (*po.UTXOPO, error)
 	UpdateUTXOState(utxo *po.UTXOPO) error
+	// ListUnspent 查询地址未花费的输出，按金额从大到小排列，maxHeight 大于0时只查询该高度及以下的输出
+	ListUnspent(address string, maxHeight int64) ([]po.UTXOPO, error)
+	// LastBlock 最后索引的区块，没有时返回 ErrBlockNotFound
+	LastBlock() (*po.UTXOBlockPO, error)
+	// BlockAt 指定高度的已索引区块，没有时返回 ErrBlockNotFound
+	BlockAt(height int64) (*po.UTXOBlockPO, error)
+	// ApplyBlock 在一个事务中写入区块内的输出、标记被花费的输出并记录区块
+	ApplyBlock(block *po.UTXOBlockPO, outputs []po.UTXOPO, spends []Spend) error
+	// ApplyOutputs 补扫已索引的区块：在一个事务中写入输出并标记被花费的输出，不记录区块
+	ApplyOutputs(height int64, outputs []po.UTXOPO, spends []Spend) error
+	// RollbackAbove 撤销高于 height 的区块：删除其中的输出，恢复其中花费的输出
+	RollbackAbove(height int64) error
+	// PruneBlocks 清理低于 height 的区块记录，输出不受影响
+	PruneBlocks(height int64) error
 }
 
 type UTXODaoImpl struct {
@@ -36,3 +63,126 @@ func (d *UTXODaoImpl) UpdateUTXOState(utxo *po.UTXOPO) error {
 		"index":   utxo.Index,
 	}).Error
 }
+
+// ListUnspent 查询地址未花费的输出
+func (d *UTXODaoImpl) ListUnspent(address string, maxHeight int64) ([]po.UTXOPO, error) {
+	query := d.db.Where("address = ? AND spent = ?", address, false)
+	if maxHeight > 0 {
+		query = query.Where("block_height <= ?", maxHeight)
+	}
+	var utxos []po.UTXOPO
+	if err := query.Order("value DESC").Find(&utxos).Error; err != nil {
+		return nil, err
+	}
+	return utxos, nil
+}
+
+// LastBlock 最后索引的区块
+func (d *UTXODaoImpl) LastBlock() (*po.UTXOBlockPO, error) {
+	var block po.UTXOBlockPO
+	err := d.db.Order("height DESC").First(&block).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrBlockNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// BlockAt 指定高度的已索引区块
+func (d *UTXODaoImpl) BlockAt(height int64) (*po.UTXOBlockPO, error) {
+	var block po.UTXOBlockPO
+	err := d.db.Where("height = ?", height).First(&block).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrBlockNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// ApplyBlock 写入区块内的输出和花费
+func (d *UTXODaoImpl) ApplyBlock(block *po.UTXOBlockPO, outputs []po.UTXOPO, spends []Spend) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := applyOutputs(tx, block.Height, outputs, spends); err != nil {
+			return err
+		}
+		return tx.Create(block).Error
+	})
+}
+
+// ApplyOutputs 写入补扫到的输出和花费
+func (d *UTXODaoImpl) ApplyOutputs(height int64, outputs []po.UTXOPO, spends []Spend) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		return applyOutputs(tx, height, outputs, spends)
+	})
+}
+
+// applyOutputs 写入高度 height 的区块内的输出并标记被花费的输出
+// 先写输出再标记花费，同一区块内产生又被花费的输出也能正确标记；重复写入的输出忽略
+func applyOutputs(tx *gorm.DB, height int64, outputs []po.UTXOPO, spends []Spend) error {
+	if len(outputs) > 0 {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&outputs).Error; err != nil {
+			return err
+		}
+	}
+	if len(spends) == 0 {
+		return nil
+	}
+
+	byOutpoint := make(map[string]map[uint32]string, len(spends))
+	hashes := make([]string, 0, len(spends))
+	for _, spend := range spends {
+		if byOutpoint[spend.TxHash] == nil {
+			byOutpoint[spend.TxHash] = make(map[uint32]string)
+			hashes = append(hashes, spend.TxHash)
+		}
+		byOutpoint[spend.TxHash][spend.Index] = spend.SpentTx
+	}
+
+	// 只有花费监控地址输出的输入需要更新，先按交易哈希查出候选输出
+	var candidates []po.UTXOPO
+	if err := tx.Select("id", "tx_hash", "index").
+		Where("tx_hash IN ? AND spent = ?", hashes, false).
+		Find(&candidates).Error; err != nil {
+		return err
+	}
+	for _, utxo := range candidates {
+		spentTx, ok := byOutpoint[utxo.TxHash][utxo.Index]
+		if !ok {
+			continue
+		}
+		if err := tx.Model(&po.UTXOPO{}).Where("id = ?", utxo.ID).Updates(map[string]interface{}{
+			"spent":        true,
+			"spent_tx":     spentTx,
+			"spent_height": height,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RollbackAbove 撤销高于 height 的区块
+func (d *UTXODaoImpl) RollbackAbove(height int64) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("block_height > ?", height).Delete(&po.UTXOPO{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&po.UTXOPO{}).Where("spent_height > ?", height).Updates(map[string]interface{}{
+			"spent":        false,
+			"spent_tx":     "",
+			"spent_height": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("height > ?", height).Delete(&po.UTXOBlockPO{}).Error
+	})
+}
+
+// PruneBlocks 清理低于 height 的区块记录
+func (d *UTXODaoImpl) PruneBlocks(height int64) error {
+	return d.db.Where("height < ?", height).Delete(&po.UTXOBlockPO{}).Error
+}
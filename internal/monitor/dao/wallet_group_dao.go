@@ -0,0 +1,81 @@
+package dao
+
+import (
+	"errors"
+	"time"
+
+	"claimask/internal/monitor/model/po"
+	"claimask/pkg/keystore"
+
+	"gorm.io/gorm"
+)
+
+// ErrWalletNotFound 钱包组不存在
+var ErrWalletNotFound = errors.New("wallet group not found")
+
+type WalletGroupDao interface {
+	ListWalletGroups(includeRetired bool) ([]po.WalletGroupPO, error)
+	GetByAddress(address string) (*po.WalletGroupPO, error)
+	CreateWalletGroup(wallet *po.WalletGroupPO) error
+	UpdateWalletStatus(address, status string, retiredAt *time.Time) error
+}
+
+type WalletGroupDaoImpl struct {
+	db *gorm.DB
+}
+
+func NewWalletGroupDao(db *gorm.DB) WalletGroupDao {
+	return &WalletGroupDaoImpl{db: db}
+}
+
+// ListWalletGroups 按组ID顺序查询钱包组，includeRetired 为 false 时只返回监控中的
+func (d *WalletGroupDaoImpl) ListWalletGroups(includeRetired bool) ([]po.WalletGroupPO, error) {
+	db := d.db.Order("group_id ASC")
+	if !includeRetired {
+		db = db.Where("status = ?", po.WalletStatusActive)
+	}
+	var wallets []po.WalletGroupPO
+	if err := db.Find(&wallets).Error; err != nil {
+		return nil, err
+	}
+	return wallets, nil
+}
+
+// GetByAddress 按收款地址查询钱包组
+func (d *WalletGroupDaoImpl) GetByAddress(address string) (*po.WalletGroupPO, error) {
+	var wallet po.WalletGroupPO
+	if err := d.db.Where("receive_addr = ?", address).First(&wallet).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWalletNotFound
+		}
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+// CreateWalletGroup 新增钱包组，GroupID 为0时自动分配
+func (d *WalletGroupDaoImpl) CreateWalletGroup(wallet *po.WalletGroupPO) error {
+	return d.db.Create(wallet).Error
+}
+
+// UpdateWalletStatus 更新钱包组状态
+func (d *WalletGroupDaoImpl) UpdateWalletStatus(address, status string, retiredAt *time.Time) error {
+	result := d.db.Model(&po.WalletGroupPO{}).
+		Where("receive_addr = ?", address).
+		Updates(map[string]interface{}{
+			"status":     status,
+			"retired_at": retiredAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrWalletNotFound
+	}
+	return nil
+}
+
+// NewWalletKeyBackend 钱包组私钥密文的存储，按收款地址查找
+func NewWalletKeyBackend(db *gorm.DB) keystore.Backend {
+	return keystore.NewGormBackend(db, po.WalletGroupPO{}.TableName(), "receive_addr", "private_key")
+}
@@ -0,0 +1,22 @@
+package dto
+
+import "time"
+
+// AllocateDepositRequest 为订单分配收款地址
+type AllocateDepositRequest struct {
+	OrderNo string `json:"orderNo" binding:"required,max=64"`
+	UserID  string `json:"userId" binding:"max=64"`
+}
+
+// DepositAddress 订单收款地址
+type DepositAddress struct {
+	OrderNo   string     `json:"orderNo"`
+	UserID    string     `json:"userId"`
+	Address   string     `json:"address"`
+	Path      string     `json:"path"`   // BIP44 派生路径
+	Status    string     `json:"status"` // assigned / paid
+	TxHash    string     `json:"txHash,omitempty"`
+	Received  int64      `json:"received"` // 首笔支付金额（ELON）
+	CreatedAt time.Time  `json:"createdAt"`
+	PaidAt    *time.Time `json:"paidAt,omitempty"`
+}
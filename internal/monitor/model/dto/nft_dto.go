@@ -7,3 +7,12 @@ type NFTUpdateRequest struct {
 	TaxStatus    int    `json:"tax_status"` // 0-未缴税 1-已缴税
 	TxAmt        int64  `json:"tx_amt"`     // 交易金额（ELON）
 }
+
+// NFTStatus NFT状态查询结果
+type NFTStatus struct {
+	NFTID        string `json:"nft_id"`
+	UtxoHash     string `json:"txid"`
+	OwnerAddress string `json:"owner"`
+	TaxStatus    int    `json:"tax_status"` // 0-未缴税 1-已缴税
+	TxAmt        int64  `json:"tx_amt"`     // 交易金额（ELON）
+}
@@ -0,0 +1,14 @@
+package dto
+
+import "time"
+
+// PaymentEvent 收到支付后发布到消息总线的事件
+// 付款到订单收款地址时带订单号和收款地址，同一笔交易付给多个订单时每个订单一个事件
+type PaymentEvent struct {
+	From    string    `json:"from"`              // 付款地址
+	Amount  int64     `json:"amount"`            // 支付金额（ELON）
+	TxHash  string    `json:"txHash"`            // 支付交易哈希
+	OrderNo string    `json:"orderNo,omitempty"` // 收款地址所属的订单号
+	Address string    `json:"address,omitempty"` // 订单收款地址
+	Time    time.Time `json:"time"`              // 处理时间
+}
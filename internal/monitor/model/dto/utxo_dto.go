@@ -0,0 +1,25 @@
+package dto
+
+// UTXO 未花费的交易输出
+type UTXO struct {
+	TxHash        string `json:"txHash"`
+	Index         uint32 `json:"index"`
+	Value         int64  `json:"value"` // 金额（ELON）
+	Confirmations int64  `json:"confirmations"`
+}
+
+// AddressBalance 地址余额，按UTXO索引计算
+type AddressBalance struct {
+	Address     string `json:"address"`
+	Height      int64  `json:"height"`      // 已索引到的区块高度
+	MinConf     int64  `json:"minConf"`     // 计入已确认余额所需的确认数
+	Confirmed   int64  `json:"confirmed"`   // 已确认余额（ELON）
+	Unconfirmed int64  `json:"unconfirmed"` // 确认数不足的余额（ELON）
+	UTXOs       []UTXO `json:"utxos"`
+}
+
+// RescanRequest UTXO索引补扫请求
+type RescanRequest struct {
+	Addresses  []string `json:"addresses" binding:"required,min=1"`
+	FromHeight int64    `json:"fromHeight" binding:"min=0"`
+}
@@ -0,0 +1,19 @@
+package dto
+
+import "time"
+
+// AddWalletRequest 加入监控的钱包组
+type AddWalletRequest struct {
+	Group      int    `json:"group"` // 为0时自动分配
+	Address    string `json:"address" binding:"required"`
+	RescanFrom int64  `json:"rescanFrom"` // 大于0时UTXO索引从该高度补扫地址加入前收到的输出
+}
+
+// WalletGroup 钱包组信息，不含私钥
+type WalletGroup struct {
+	Group     int        `json:"group"`
+	Address   string     `json:"address"`
+	Status    string     `json:"status"` // active / retired
+	CreatedAt time.Time  `json:"createdAt"`
+	RetiredAt *time.Time `json:"retiredAt,omitempty"`
+}
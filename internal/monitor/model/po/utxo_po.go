@@ -1,12 +1,37 @@
 package po
 
+import "time"
+
+// UTXOPO 监控地址收到的交易输出，由UTXO索引按区块写入，(TxHash, Index) 唯一
 type UTXOPO struct {
-	ID      uint   `gorm:"primaryKey"`
-	Address string `gorm:"index"`
-	TxHash  string `gorm:"size:64;uniqueIndex"`
-	Index   uint32 `gorm:"index"`
-	Value   int64  // 单位：ELON
-	Spent   bool   `gorm:"default:false"`
+	ID          uint   `gorm:"primaryKey"`
+	Address     string `gorm:"index"`
+	TxHash      string `gorm:"size:64;uniqueIndex:uk_outpoint"`
+	Index       uint32 `gorm:"uniqueIndex:uk_outpoint"`
+	Value       int64  // 单位：ELON
+	BlockHeight int64  `gorm:"index"`   // 所在区块高度
+	BlockHash   string `gorm:"size:64"` // 所在区块哈希
+	Spent       bool   `gorm:"default:false"`
+	SpentTx     string `gorm:"size:64"` // 花费交易哈希
+	SpentHeight int64  `gorm:"index"`   // 花费交易所在区块高度
+}
+
+// TableName 设置UTXOPO表名
+func (UTXOPO) TableName() string {
+	return "utxo"
+}
+
+// UTXOBlockPO UTXO索引已处理的区块，用于识别链重组
+type UTXOBlockPO struct {
+	Height    int64  `gorm:"primaryKey;autoIncrement:false"`
+	Hash      string `gorm:"size:64"`
+	PrevHash  string `gorm:"size:64"`
+	IndexedAt time.Time
+}
+
+// TableName 设置UTXOBlockPO表名
+func (UTXOBlockPO) TableName() string {
+	return "utxo_block"
 }
 
 type NFTPO struct {
@@ -18,10 +43,53 @@ type NFTPO struct {
 	GTID         string `gorm:"size:128"`  // 全局交易标识
 }
 
+// TableName 设置NFTPO表名
+func (NFTPO) TableName() string {
+	return "nft"
+}
+
+// 钱包组状态
+const (
+	WalletStatusActive  = "active"  // 监控中
+	WalletStatusRetired = "retired" // 已停用，保留记录但不再监控
+)
+
 type WalletGroupPO struct {
-	GroupID      int    `gorm:"primaryKey"`
-	ReceiveAddr  string `gorm:"size:34;uniqueIndex"` // Dogecoin地址
-	PrivateKey   string `gorm:"type:text"`           // 加密存储
-	CurrentUTXO  string `gorm:"size:64"`             // 当前使用的UTXO
-	LastSyncTime int64  // 最后同步时间戳
+	GroupID      int        `gorm:"primaryKey"`
+	ReceiveAddr  string     `gorm:"size:34;uniqueIndex"` // Dogecoin地址
+	PrivateKey   string     `gorm:"type:text"`           // 信封加密的私钥密文，见 pkg/keystore
+	CurrentUTXO  string     `gorm:"size:64"`             // 当前使用的UTXO
+	LastSyncTime int64      // 最后同步时间戳
+	Status       string     `gorm:"size:16"` // active / retired
+	CreatedAt    time.Time  // 加入监控时间
+	RetiredAt    *time.Time // 停用时间
+}
+
+// TableName 设置WalletGroupPO表名
+func (WalletGroupPO) TableName() string {
+	return "wallet_group"
+}
+
+// 订单收款地址状态
+const (
+	DepositStatusAssigned = "assigned" // 已分配，等待支付
+	DepositStatusPaid     = "paid"     // 已收到支付
+)
+
+type DepositAddressPO struct {
+	ID        uint       `gorm:"primaryKey"`
+	Address   string     `gorm:"size:34;uniqueIndex"` // Dogecoin地址
+	AddrIndex uint32     `gorm:"uniqueIndex"`         // 派生序号 m/44'/3'/account'/0/addr_index
+	OrderNo   string     `gorm:"size:64;uniqueIndex"` // 订单号
+	UserID    string     `gorm:"size:64;index"`       // 用户ID
+	Status    string     `gorm:"size:16"`             // assigned / paid
+	TxHash    string     `gorm:"size:64"`             // 首笔支付交易哈希
+	Received  int64      // 首笔支付金额（ELON）
+	CreatedAt time.Time  // 分配时间
+	PaidAt    *time.Time // 支付时间
+}
+
+// TableName 设置DepositAddressPO表名
+func (DepositAddressPO) TableName() string {
+	return "deposit_address"
 }
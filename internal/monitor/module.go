@@ -0,0 +1,158 @@
+// Package monitor 链上交易监控模块：支付回调、NFT状态查询和交易监控
+package monitor
+
+import (
+	"context"
+	"errors"
+
+	"claimask/comm/initialize"
+	"claimask/comm/middleware"
+	"claimask/conf"
+	"claimask/internal/monitor/api"
+	"claimask/internal/monitor/dao"
+	"claimask/internal/monitor/model/po"
+	"claimask/internal/monitor/service"
+	"claimask/pkg/dogechain"
+	"claimask/pkg/keystore"
+	"claimask/pkg/queues"
+
+	"go.uber.org/zap"
+)
+
+// Module 链上交易监控模块
+type Module struct {
+	wallets      *service.WalletRegistry
+	deposits     *service.DepositAllocator // 订单收款地址，未配置扩展公钥时为nil
+	indexer      *service.UTXOIndexer      // UTXO索引，未开启时为nil
+	keys         *keystore.Store           // 钱包组私钥，未配置主密钥时为nil
+	txMonitor    *service.TxMonitor
+	queueManager *service.QueueManager
+}
+
+// NewModule 创建链上交易监控模块
+func NewModule() *Module {
+	return &Module{}
+}
+
+// Name 模块名称
+func (m *Module) Name() string {
+	return "monitor"
+}
+
+// Init 加载监控钱包组和订单收款地址，创建交易监控和转账队列并注册 /api/v1 下的路由
+func (m *Module) Init(s *initialize.Server) error {
+	cfg := s.Config()
+	m.wallets = service.NewWalletRegistry(dao.NewWalletGroupDao(s.DB()), cfg.Monitor.WalletRefresh)
+	if err := m.wallets.Seed(seedWallets(cfg.Wallets)); err != nil {
+		return err
+	}
+	keyring, err := s.Keyring()
+	switch {
+	case err == nil:
+		m.keys = keystore.New(keyring, dao.NewWalletKeyBackend(s.DB()))
+	case errors.Is(err, keystore.ErrNoMasterKey):
+		zap.L().Warn("未配置主密钥，钱包组私钥不可用")
+	default:
+		return err
+	}
+
+	var account *dogechain.HDAccount
+	var book service.DepositBook
+	if cfg.Deposit.XPub != "" {
+		if account, err = dogechain.NewHDAccount(cfg.Deposit.XPub); err != nil {
+			return err
+		}
+		m.deposits = service.NewDepositAllocator(dao.NewDepositAddressDao(s.DB()), account, cfg.Deposit.GapLimit, cfg.Deposit.ReuseAfter, cfg.Deposit.AllowBeyondGap, cfg.Monitor.WalletRefresh)
+		if err := m.deposits.Refresh(); err != nil {
+			return err
+		}
+		book = m.deposits
+	}
+
+	m.txMonitor = service.NewTxMonitor(s.RPC(), &service.MonitorConfig{
+		BlockPollInterval: cfg.Monitor.BlockPollInterval,
+		WebsocketEndpoint: cfg.Monitor.WebsocketEndpoint,
+	}, m.wallets, book, s.Publisher())
+	if cfg.Monitor.Indexer.Enabled {
+		m.indexer = service.NewUTXOIndexer(dao.NewUTXODao(s.DB()), s.RPC(), service.IndexerConfig{
+			StartHeight: cfg.Monitor.Indexer.StartHeight,
+			Interval:    cfg.Monitor.Indexer.Interval,
+			ReorgDepth:  cfg.Monitor.Indexer.ReorgDepth,
+		}, ownedAddresses(cfg, m.wallets, m.deposits)...)
+	}
+	m.queueManager = service.NewQueueManager(s.Redis(), queueConfig(cfg.Queue))
+	s.OnReload(func(c *conf.Config) {
+		m.queueManager.SetConfig(queueConfig(c.Queue))
+	})
+
+	monitorSvc := service.NewMonitorService(m.txMonitor, m.queueManager, dao.NewNFTDao(s.DB()))
+	api.RegisterRoutes(s.Engine, monitorSvc, m.wallets, m.keys, api.NewDepositHandler(m.deposits, account),
+		api.NewUTXOHandler(m.indexer), middleware.AdminAuth(cfg.Admin.Token.Reveal()))
+	return nil
+}
+
+// ownedAddresses UTXO索引记录的地址：钱包组、订单收款地址和打款热钱包
+func ownedAddresses(cfg *conf.Config, wallets *service.WalletRegistry, deposits *service.DepositAllocator) []service.AddressSet {
+	owned := []service.AddressSet{wallets}
+	if deposits != nil {
+		owned = append(owned, service.AddressFunc(func(address string) bool {
+			_, ok := deposits.OrderOf(address)
+			return ok
+		}))
+	}
+	if hot := cfg.Payout.Address; hot != "" {
+		owned = append(owned, service.AddressFunc(func(address string) bool {
+			return address == hot
+		}))
+	}
+	return owned
+}
+
+// seedWallets 配置文件中的钱包组，私钥以密文原样写入数据库
+func seedWallets(wallets []conf.WalletGroup) []po.WalletGroupPO {
+	seeds := make([]po.WalletGroupPO, 0, len(wallets))
+	for _, w := range wallets {
+		seeds = append(seeds, po.WalletGroupPO{GroupID: w.Group, ReceiveAddr: w.Receive, PrivateKey: w.ReceivePrivate.Reveal()})
+	}
+	return seeds
+}
+
+// queueConfig 转账队列的速率限制
+func queueConfig(c conf.QueueConfig) queues.Config {
+	return queues.Config{
+		MaxConcurrent: c.MaxConcurrent,
+		RetryPolicy: queues.RetryPolicy{
+			MaxRetries: c.MaxRetries,
+			BaseDelay:  c.BaseDelay,
+			MaxDelay:   c.MaxDelay,
+		},
+	}
+}
+
+// Start 启动监控钱包组和订单收款地址刷新、UTXO索引，以及交易监控
+func (m *Module) Start() error {
+	m.wallets.Start()
+	if m.deposits != nil {
+		m.deposits.Start()
+	}
+	if m.indexer != nil {
+		m.indexer.Start()
+	}
+	m.txMonitor.StartDualMonitor()
+	return nil
+}
+
+// Stop 停止交易监控、UTXO索引、钱包组和订单收款地址刷新，并等待转账队列处理完毕
+func (m *Module) Stop(ctx context.Context) error {
+	m.txMonitor.Stop()
+	if m.indexer != nil {
+		if err := initialize.StopFunc(ctx, m.indexer.Stop); err != nil {
+			return err
+		}
+	}
+	m.wallets.Stop()
+	if m.deposits != nil {
+		m.deposits.Stop()
+	}
+	return m.queueManager.Flush(ctx)
+}
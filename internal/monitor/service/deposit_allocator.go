@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"claimask/internal/monitor/dao"
+	"claimask/internal/monitor/model/po"
+	"claimask/pkg/dogechain"
+
+	"go.uber.org/zap"
+)
+
+// ErrGapLimit 连续未收款的地址数达到间隔上限
+// 从扩展公钥恢复的钱包遇到连续 gapLimit 个未使用的地址就停止扫描，超出的地址收到的款在钱包中不可见
+var ErrGapLimit = errors.New("deposit address gap limit reached")
+
+// DepositBook 订单收款地址簿，交易监控据此将收款归属到订单
+type DepositBook interface {
+	// OrderOf 返回地址所属的订单号
+	OrderOf(address string) (string, bool)
+	// MarkPaid 记录地址收到的支付
+	MarkPaid(address, txHash string, amount int64) error
+}
+
+// DepositAllocator 从账户扩展公钥为订单派生收款地址
+// 以数据库为准，内存中缓存地址到订单的映射；其他实例分配的地址由定时刷新同步
+// 连续未收款的地址数达到间隔上限时，优先改派超过 reuseAfter 仍未收款的地址；
+// 没有可改派的地址时返回 ErrGapLimit，只有开启 allowBeyondGap 时才告警后继续派生
+type DepositAllocator struct {
+	dao            dao.DepositAddressDao
+	account        *dogechain.HDAccount
+	gapLimit       uint32
+	reuseAfter     time.Duration
+	allowBeyondGap bool
+	interval       time.Duration
+
+	mu     sync.RWMutex
+	owners map[string]string // 地址 -> 订单号
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewDepositAllocator 创建收款地址分配器，gapLimit 为允许连续未收款的地址数，
+// reuseAfter 为未收款地址可改派给新订单的时长（0为不改派），allowBeyondGap 为没有可改派的地址时是否继续派生，
+// interval 为从数据库刷新的间隔
+func NewDepositAllocator(depositDao dao.DepositAddressDao, account *dogechain.HDAccount, gapLimit uint32, reuseAfter time.Duration, allowBeyondGap bool, interval time.Duration) *DepositAllocator {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &DepositAllocator{
+		dao:            depositDao,
+		account:        account,
+		gapLimit:       gapLimit,
+		reuseAfter:     reuseAfter,
+		allowBeyondGap: allowBeyondGap,
+		interval:       interval,
+		owners:         make(map[string]string),
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+}
+
+// Allocate 为订单分配收款地址，同一订单重复调用返回同一地址
+// 达到间隔上限且没有可改派的地址时返回 ErrGapLimit，除非开启了 allowBeyondGap
+func (a *DepositAllocator) Allocate(orderNo, userID string) (*po.DepositAddressPO, error) {
+	deposit, err := a.dao.GetByOrderNo(orderNo)
+	if err == nil {
+		return deposit, nil
+	}
+	if !errors.Is(err, dao.ErrDepositNotFound) {
+		return nil, err
+	}
+
+	deposit, err = a.dao.AllocateDepositAddress(orderNo, userID, func(next uint32, lastPaid int64) (string, error) {
+		if int64(next)-lastPaid > int64(a.gapLimit) {
+			return "", fmt.Errorf("%w: index %d, last paid %d, limit %d", ErrGapLimit, next, lastPaid, a.gapLimit)
+		}
+		return a.account.Address(next)
+	})
+	if errors.Is(err, ErrGapLimit) {
+		deposit, err = a.allocateBeyondGap(orderNo, userID, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	a.owners[deposit.Address] = deposit.OrderNo
+	a.mu.Unlock()
+	zap.L().Info("分配订单收款地址",
+		zap.String("orderNo", orderNo),
+		zap.String("address", deposit.Address),
+		zap.String("path", a.account.Path(deposit.AddrIndex)))
+	return deposit, nil
+}
+
+// allocateBeyondGap 达到间隔上限时改派过期未收款的地址；没有时返回 gapErr，开启 allowBeyondGap 时告警并继续派生下一个地址
+// 改派后旧订单迟到的付款会归属到新订单，reuseAfter 应长于订单的支付时限
+func (a *DepositAllocator) allocateBeyondGap(orderNo, userID string, gapErr error) (*po.DepositAddressPO, error) {
+	if a.reuseAfter > 0 {
+		deposit, err := a.dao.ReassignDepositAddress(orderNo, userID, time.Now().Add(-a.reuseAfter))
+		if err == nil {
+			zap.L().Info("改派过期未收款的订单收款地址",
+				zap.String("orderNo", orderNo),
+				zap.String("address", deposit.Address),
+				zap.Uint32("index", deposit.AddrIndex))
+			return deposit, nil
+		}
+		if !errors.Is(err, dao.ErrDepositNotFound) {
+			return nil, err
+		}
+	}
+
+	if !a.allowBeyondGap {
+		zap.L().Error("订单收款地址达到间隔上限，没有可改派的地址", zap.String("orderNo", orderNo), zap.Error(gapErr))
+		return nil, gapErr
+	}
+	zap.L().Warn("订单收款地址超过间隔上限，从扩展公钥恢复钱包时需扩大扫描间隔", zap.String("orderNo", orderNo), zap.Error(gapErr))
+	return a.dao.AllocateDepositAddress(orderNo, userID, func(next uint32, lastPaid int64) (string, error) {
+		return a.account.Address(next)
+	})
+}
+
+// Get 查询订单的收款地址
+func (a *DepositAllocator) Get(orderNo string) (*po.DepositAddressPO, error) {
+	return a.dao.GetByOrderNo(orderNo)
+}
+
+// Refresh 从数据库重新加载地址到订单的映射
+func (a *DepositAllocator) Refresh() error {
+	deposits, err := a.dao.ListDepositAddresses()
+	if err != nil {
+		return err
+	}
+	owners := make(map[string]string, len(deposits))
+	for _, deposit := range deposits {
+		owners[deposit.Address] = deposit.OrderNo
+	}
+
+	a.mu.Lock()
+	a.owners = owners
+	a.mu.Unlock()
+	return nil
+}
+
+// OrderOf 返回地址所属的订单号
+func (a *DepositAllocator) OrderOf(address string) (string, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	orderNo, ok := a.owners[address]
+	return orderNo, ok
+}
+
+// MarkPaid 记录地址收到的首笔支付，之后该地址不再计入连续未收款的地址数
+func (a *DepositAllocator) MarkPaid(address, txHash string, amount int64) error {
+	first, err := a.dao.MarkDepositPaid(address, txHash, amount, time.Now())
+	if err != nil {
+		return err
+	}
+	if !first {
+		zap.L().Warn("收款地址重复收款", zap.String("address", address), zap.String("txHash", txHash))
+	}
+	return nil
+}
+
+// Start 启动定时刷新
+func (a *DepositAllocator) Start() {
+	go func() {
+		ticker := time.NewTicker(a.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := a.Refresh(); err != nil {
+					zap.L().Warn("刷新订单收款地址失败", zap.Error(err))
+				}
+			case <-a.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止定时刷新
+func (a *DepositAllocator) Stop() {
+	a.cancel()
+}
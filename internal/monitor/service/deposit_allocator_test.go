@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"claimask/internal/monitor/dao"
+	"claimask/internal/monitor/model/dto"
+	"claimask/internal/monitor/model/po"
+	"claimask/pkg/dogechain"
+	"claimask/pkg/money"
+	"claimask/pkg/mq"
+
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+// memDepositDao 内存实现的订单收款地址DAO
+type memDepositDao struct {
+	mu       sync.Mutex
+	deposits []po.DepositAddressPO
+}
+
+func (d *memDepositDao) GetByOrderNo(orderNo string) (*po.DepositAddressPO, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, deposit := range d.deposits {
+		if deposit.OrderNo == orderNo {
+			return &deposit, nil
+		}
+	}
+	return nil, dao.ErrDepositNotFound
+}
+
+func (d *memDepositDao) ListDepositAddresses() ([]po.DepositAddressPO, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]po.DepositAddressPO(nil), d.deposits...), nil
+}
+
+func (d *memDepositDao) AllocateDepositAddress(orderNo, userID string, derive dao.DeriveFunc) (*po.DepositAddressPO, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	next, lastPaid := uint32(len(d.deposits)), int64(-1)
+	for _, deposit := range d.deposits {
+		if deposit.Status == po.DepositStatusPaid {
+			lastPaid = int64(deposit.AddrIndex)
+		}
+	}
+	address, err := derive(next, lastPaid)
+	if err != nil {
+		return nil, err
+	}
+	deposit := po.DepositAddressPO{Address: address, AddrIndex: next, OrderNo: orderNo, UserID: userID, Status: po.DepositStatusAssigned, CreatedAt: time.Now()}
+	d.deposits = append(d.deposits, deposit)
+	return &deposit, nil
+}
+
+func (d *memDepositDao) ReassignDepositAddress(orderNo, userID string, before time.Time) (*po.DepositAddressPO, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := range d.deposits {
+		if d.deposits[i].Status == po.DepositStatusAssigned && d.deposits[i].CreatedAt.Before(before) {
+			d.deposits[i].OrderNo, d.deposits[i].UserID, d.deposits[i].CreatedAt = orderNo, userID, time.Now()
+			deposit := d.deposits[i]
+			return &deposit, nil
+		}
+	}
+	return nil, dao.ErrDepositNotFound
+}
+
+func (d *memDepositDao) MarkDepositPaid(address, txHash string, amount int64, paidAt time.Time) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := range d.deposits {
+		if d.deposits[i].Address == address && d.deposits[i].Status == po.DepositStatusAssigned {
+			d.deposits[i].Status, d.deposits[i].TxHash, d.deposits[i].Received = po.DepositStatusPaid, txHash, amount
+			d.deposits[i].PaidAt = &paidAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// testHDAccount 从固定种子派生 m/44'/3'/0' 账户的扩展公钥
+func testHDAccount(t *testing.T) *dogechain.HDAccount {
+	t.Helper()
+	key, err := hdkeychain.NewMaster(make([]byte, 32), &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []uint32{44, 3, 0} {
+		if key, err = key.Derive(hdkeychain.HardenedKeyStart + i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pub, err := key.Neuter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err := dogechain.NewHDAccount(pub.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return account
+}
+
+// 测试同一订单重复分配返回同一地址，连续未收款的地址数达到上限后改派过期未收款的地址，没有可改派的地址时拒绝分配
+func TestDepositAllocatorGapLimit(t *testing.T) {
+	account := testHDAccount(t)
+	depositDao := &memDepositDao{}
+	allocator := NewDepositAllocator(depositDao, account, 2, time.Hour, false, time.Minute)
+
+	first, err := allocator.Allocate("order-1", "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := account.Address(0)
+	if first.Address != want || first.AddrIndex != 0 {
+		t.Errorf("Expected index 0 address %s, got %+v", want, first)
+	}
+	again, err := allocator.Allocate("order-1", "user-1")
+	if err != nil || again.Address != first.Address {
+		t.Errorf("Expected the same address for the same order, got %+v %v", again, err)
+	}
+	if orderNo, ok := allocator.OrderOf(first.Address); !ok || orderNo != "order-1" {
+		t.Errorf("Expected address to belong to order-1, got %q %v", orderNo, ok)
+	}
+	second, err := allocator.Allocate("order-2", "user-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 达到上限，没有过期的地址时拒绝分配
+	if _, err := allocator.Allocate("order-3", "user-3"); !errors.Is(err, ErrGapLimit) {
+		t.Fatalf("Expected ErrGapLimit, got %v", err)
+	}
+
+	// 付款后不计入间隔
+	if err := allocator.MarkPaid(second.Address, "txhash1", 100); err != nil {
+		t.Fatal(err)
+	}
+	if third, err := allocator.Allocate("order-3", "user-3"); err != nil || third.AddrIndex != 2 {
+		t.Fatalf("Expected index 2 after a payment, got %+v %v", third, err)
+	}
+	if _, err := allocator.Allocate("order-4", "user-4"); err != nil {
+		t.Fatal(err)
+	}
+
+	// order-1 的地址超过改派时长仍未收款，改派给新订单
+	depositDao.mu.Lock()
+	depositDao.deposits[0].CreatedAt = time.Now().Add(-2 * time.Hour)
+	depositDao.mu.Unlock()
+	reused, err := allocator.Allocate("order-5", "user-5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reused.Address != first.Address || reused.OrderNo != "order-5" {
+		t.Errorf("Expected order-1's expired address reused, got %+v", reused)
+	}
+	if orderNo, _ := allocator.OrderOf(first.Address); orderNo != "order-5" {
+		t.Errorf("Expected reused address to belong to order-5, got %q", orderNo)
+	}
+	if _, err := allocator.Get("order-1"); !errors.Is(err, dao.ErrDepositNotFound) {
+		t.Errorf("Expected order-1 to lose its address, got %v", err)
+	}
+}
+
+// 测试开启 allowBeyondGap 时没有可改派的地址也继续派生
+func TestDepositAllocatorAllowBeyondGap(t *testing.T) {
+	allocator := NewDepositAllocator(&memDepositDao{}, testHDAccount(t), 1, 0, true, time.Minute)
+	for i, orderNo := range []string{"order-1", "order-2", "order-3"} {
+		deposit, err := allocator.Allocate(orderNo, "user-1")
+		if err != nil || deposit.AddrIndex != uint32(i) {
+			t.Fatalf("Expected index %d beyond the gap limit, got %+v %v", i, deposit, err)
+		}
+	}
+}
+
+// 测试付款到订单收款地址的支付按订单发布事件，付款到钱包组的支付不带订单号
+func TestTxMonitorAttributesOrderPayment(t *testing.T) {
+	bus := mq.NewMemoryBus(10 * time.Millisecond)
+	defer bus.Close()
+
+	depositDao := &memDepositDao{}
+	allocator := NewDepositAllocator(depositDao, testHDAccount(t), 20, 0, false, time.Minute)
+	deposit, err := allocator.Allocate("order-1", "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallet := testAddress(t, 1)
+	registry := NewWalletRegistry(newMemWalletDao(), time.Minute)
+	if _, err := registry.Add(0, wallet); err != nil {
+		t.Fatal(err)
+	}
+	monitor := NewTxMonitor(nil, &MonitorConfig{}, registry, allocator, bus)
+
+	tx := &dogechain.TxDetail{Hash: "txhash1"}
+	tx.Vin = append(tx.Vin, dogechain.TxInput{Addresses: []string{"DSender"}})
+	for _, addr := range []string{deposit.Address, wallet, deposit.Address} {
+		out := dogechain.TxOutput{Value: money.Amount(100000000)}
+		out.ScriptPubKey.Addresses = []string{addr}
+		tx.Vout = append(tx.Vout, out)
+	}
+	if err := monitor.handlePayment(tx); err != nil {
+		t.Fatal(err)
+	}
+
+	// 归集订单收款地址的交易不是支付
+	sweep := &dogechain.TxDetail{Hash: "txhash2"}
+	sweep.Vin = append(sweep.Vin, dogechain.TxInput{Addresses: []string{deposit.Address}})
+	sweep.Vout = append(sweep.Vout, tx.Vout[1])
+	if err := monitor.handlePayment(sweep); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	var events []dto.PaymentEvent
+	_ = bus.Subscribe(ctx, "test", []string{PaymentTopic}, func(ctx context.Context, msg *mq.Message) error {
+		var event dto.PaymentEvent
+		if err := json.Unmarshal(msg.Value, &event); err != nil {
+			t.Error(err)
+		}
+		events = append(events, event)
+		return nil
+	})
+	if len(events) != 2 {
+		t.Fatalf("Expected an order event and a wallet event, got %+v", events)
+	}
+	order, plain := events[0], events[1]
+	if order.OrderNo == "" {
+		order, plain = plain, order
+	}
+	if order.OrderNo != "order-1" || order.Address != deposit.Address || order.Amount != 200000000 || order.From != "DSender" {
+		t.Errorf("Unexpected order event %+v", order)
+	}
+	if plain.OrderNo != "" || plain.Amount != 100000000 {
+		t.Errorf("Unexpected wallet event %+v", plain)
+	}
+	if paid, _ := depositDao.GetByOrderNo("order-1"); paid.Status != po.DepositStatusPaid || paid.TxHash != "txhash1" {
+		t.Errorf("Expected deposit to be marked paid, got %+v", paid)
+	}
+}
@@ -2,6 +2,9 @@ package service
 
 import (
 	"context"
+
+	"claimask/internal/monitor/dao"
+	"claimask/internal/monitor/model/dto"
 )
 
 // MonitorService 监控服务接口
@@ -9,52 +12,43 @@ type MonitorService interface {
 	// ProcessPayment 处理支付回调
 	ProcessPayment(ctx context.Context, userURL string, amount int64, txID string) error
 
-	// GetNFTStatus 获取NFT状态
-	GetNFTStatus(ctx context.Context, txID string) (interface{}, error)
+	// GetNFTStatus 获取NFT状态，不存在时返回 dao.ErrNFTNotFound
+	GetNFTStatus(ctx context.Context, txID string) (*dto.NFTStatus, error)
 }
 
 // monitorServiceImpl 监控服务实现
 type monitorServiceImpl struct {
 	txMonitor    *TxMonitor
 	queueManager *QueueManager
+	nftDao       dao.NFTDao
 }
 
 // NewMonitorService 创建监控服务
-func NewMonitorService(txMonitor *TxMonitor, queueManager *QueueManager) MonitorService {
+func NewMonitorService(txMonitor *TxMonitor, queueManager *QueueManager, nftDao dao.NFTDao) MonitorService {
 	return &monitorServiceImpl{
 		txMonitor:    txMonitor,
 		queueManager: queueManager,
+		nftDao:       nftDao,
 	}
 }
 
 // ProcessPayment 处理支付回调
 func (s *monitorServiceImpl) ProcessPayment(ctx context.Context, userURL string, amount int64, txID string) error {
-	// 调用交易监控处理支付
-	// 简化版实现，根据项目需求可以进一步完善
-
-	// 1. 验证交易是否有效
-	// 2. 处理支付业务逻辑
-	// 3. 更新交易状态
-
-	// 模拟处理支付
-	return nil
+	// 调用交易监控处理支付，支付事件发布到消息总线，由下游订阅处理
+	return s.txMonitor.paymentSvc.Process(ctx, userURL, amount, txID)
 }
 
-// GetNFTStatus 获取NFT状态
-func (s *monitorServiceImpl) GetNFTStatus(ctx context.Context, txID string) (interface{}, error) {
-	// 查询NFT状态
-	// 简化版实现，根据项目需求可以进一步完善
-
-	// 1. 查询交易详情
-	// 2. 解析NFT状态
-	// 3. 返回NFT信息
-
-	// 模拟返回状态
-	return map[string]interface{}{
-		"txid":        txID,
-		"status":      "confirmed",
-		"tax_status":  1,
-		"owner":       "DTcuJ6N5QEoQUygTv8CnKzn3DUS7KhaDR2",
-		"create_time": 1681234567,
+// GetNFTStatus 获取NFT状态，txID 为NFT所在的UTXO交易哈希
+func (s *monitorServiceImpl) GetNFTStatus(ctx context.Context, txID string) (*dto.NFTStatus, error) {
+	nft, err := s.nftDao.GetNFTByUTXO(txID)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.NFTStatus{
+		NFTID:        nft.NFTID,
+		UtxoHash:     nft.UtxoHash,
+		OwnerAddress: nft.OwnerAddress,
+		TaxStatus:    nft.TaxStatus,
+		TxAmt:        nft.TxAmt,
 	}, nil
 }
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"claimask/internal/monitor/model/dto"
+	"claimask/pkg/dogechain"
+	"claimask/pkg/mq"
+)
+
+// 测试支付回调经由进程内消息总线发布支付事件
+func TestProcessPaymentPublishesEvent(t *testing.T) {
+	bus := mq.NewMemoryBus(10 * time.Millisecond)
+	defer bus.Close()
+
+	monitor := NewTxMonitor(nil, &MonitorConfig{}, nil, nil, bus)
+	svc := NewMonitorService(monitor, nil, nil)
+	if err := svc.ProcessPayment(context.Background(), "DSender", 150000000, "txhash1"); err != nil {
+		t.Fatalf("process payment failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var got *mq.Message
+	_ = bus.Subscribe(ctx, "test", []string{PaymentTopic}, func(ctx context.Context, msg *mq.Message) error {
+		got = msg
+		cancel()
+		return nil
+	})
+	if got == nil {
+		t.Fatal("Expected a payment event")
+	}
+	if got.Key != "txhash1" {
+		t.Errorf("Expected key txhash1, got %q", got.Key)
+	}
+
+	var event dto.PaymentEvent
+	if err := json.Unmarshal(got.Value, &event); err != nil {
+		t.Fatalf("unmarshal event failed: %v", err)
+	}
+	if event.From != "DSender" || event.Amount != 150000000 || event.TxHash != "txhash1" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+}
+
+// 测试节点返回的浮点金额按十进制精确解析，NFT标志输出能被识别
+func TestIsNFTOperationExactValue(t *testing.T) {
+	var tx dogechain.TxDetail
+	data := `{"txid":"t1","vout":[{"value":0.29,"n":0},{"value":0.001,"n":1}]}`
+	if err := json.Unmarshal([]byte(data), &tx); err != nil {
+		t.Fatalf("unmarshal tx failed: %v", err)
+	}
+	if tx.Vout[0].Value.Elon() != 29000000 {
+		t.Errorf("Expected 29000000 elon, got %d", tx.Vout[0].Value.Elon())
+	}
+
+	monitor := NewTxMonitor(nil, &MonitorConfig{}, nil, nil, nil)
+	if !monitor.isNFTOperation(&tx) {
+		t.Error("Expected 0.001 DOGE output to mark an NFT operation")
+	}
+}
@@ -10,15 +10,11 @@ import (
 )
 
 // NFTDecoder 解码NFT元数据
-type NFTDecoder struct {
-	monitorAddr string
-}
+type NFTDecoder struct{}
 
 // NewNFTDecoder 创建NFT解码器
 func NewNFTDecoder() *NFTDecoder {
-	return &NFTDecoder{
-		monitorAddr: "DTcuJ6N5QEoQUygTv8CnKzn3DUS7KhaDR2", // 默认监控地址
-	}
+	return &NFTDecoder{}
 }
 
 // GetGTID 从交易Hash获取全局交易ID
@@ -34,20 +30,20 @@ func (d *NFTDecoder) GetGTID(txHash string) (string, error) {
 
 // NFTService NFT服务
 type NFTService struct {
-	decoder     *NFTDecoder
-	dao         dao.NFTDao
-	taxRate     float64
-	cache       sync.Map
-	monitorAddr string
+	decoder      *NFTDecoder
+	dao          dao.NFTDao
+	taxRate      float64
+	cache        sync.Map
+	monitorAddrs AddressSet // 收税地址
 }
 
-// NewNFTService 创建NFT服务
-func NewNFTService(dao dao.NFTDao, tax float64) *NFTService {
+// NewNFTService 创建NFT服务，转入 addrs 中地址的金额计为税收
+func NewNFTService(dao dao.NFTDao, addrs AddressSet, tax float64) *NFTService {
 	return &NFTService{
-		decoder:     NewNFTDecoder(),
-		dao:         dao,
-		taxRate:     tax,
-		monitorAddr: "DTcuJ6N5QEoQUygTv8CnKzn3DUS7KhaDR2", // 默认监控地址
+		decoder:      NewNFTDecoder(),
+		dao:          dao,
+		taxRate:      tax,
+		monitorAddrs: addrs,
 	}
 }
 
@@ -75,7 +71,7 @@ func (s *NFTService) ProcessTransfer(tx *dogechain.TxDetail) error {
 	var owner string
 	for _, out := range tx.Vout {
 		// 检查是否为NFT标记输出
-		if out.Value == 100000 {
+		if out.Value == nftMarkerValue {
 			if len(out.ScriptPubKey.Addresses) > 0 {
 				owner = out.ScriptPubKey.Addresses[0]
 			}
@@ -85,10 +81,10 @@ func (s *NFTService) ProcessTransfer(tx *dogechain.TxDetail) error {
 		if len(out.ScriptPubKey.Addresses) > 0 {
 			addr := out.ScriptPubKey.Addresses[0]
 			if _, exists := inputs[addr]; !exists {
-				if addr == s.monitorAddr {
-					taxAmt += int64(out.Value * 100000000) // 转换为ELON单位
+				if s.monitorAddrs.Contains(addr) {
+					taxAmt += out.Value.Elon()
 				}
-				total += int64(out.Value * 100000000)
+				total += out.Value.Elon()
 			}
 		}
 	}
@@ -15,21 +15,19 @@ type QueueManager struct {
 	redisClient   *redis.Client
 }
 
-func NewQueueManager(redisClient interface{}) *QueueManager {
+func NewQueueManager(redisClient interface{}, config queues.Config) *QueueManager {
 	return &QueueManager{
 		priorityQueue: queues.NewPriorityQueue(100),
-		speedControl: queues.NewSpeedController(queues.Config{
-			MaxConcurrent: 10,
-			RetryPolicy: queues.RetryPolicy{
-				MaxRetries: 3,
-				BaseDelay:  5 * time.Second,
-				MaxDelay:   15 * time.Second,
-			},
-		}),
-		redisClient: redisClient.(*redis.Client),
+		speedControl:  queues.NewSpeedController(config),
+		redisClient:   redisClient.(*redis.Client),
 	}
 }
 
+// SetConfig 调整转账并发上限和重试策略，用于配置热更新
+func (qm *QueueManager) SetConfig(config queues.Config) {
+	qm.speedControl.SetConfig(config)
+}
+
 // EnqueueTransfer 添加交易到队列 [5](@ref)
 func (qm *QueueManager) EnqueueTransfer(priority int64, txData interface{}) {
 	item := &queues.Item{
@@ -42,6 +40,11 @@ func (qm *QueueManager) EnqueueTransfer(priority int64, txData interface{}) {
 	qm.speedControl.Enqueue(item)
 }
 
+// Flush 等待已入队的交易处理完毕
+func (qm *QueueManager) Flush(ctx context.Context) error {
+	return qm.speedControl.Drain(ctx)
+}
+
 // createTransactionHandler creates a handler function for the transaction
 func (qm *QueueManager) createTransactionHandler(txData interface{}) queues.TxHandler {
 	return func(ctx context.Context) error {
@@ -3,36 +3,70 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
+	"claimask/internal/monitor/model/dto"
 	"claimask/pkg/dogechain"
+	"claimask/pkg/money"
+	"claimask/pkg/mq"
 
 	"go.uber.org/zap"
 )
 
+// PaymentTopic 支付事件主题
+const PaymentTopic = "doge-payment"
+
 // MonitorConfig 监控配置
 type MonitorConfig struct {
-	WalletGroups      []string      // 监控的钱包地址组
 	BlockPollInterval time.Duration // 区块轮询间隔
 	WebsocketEndpoint string        // WebSocket端点
 }
 
 // PaymentService 支付服务
 type PaymentService struct {
-	callbackURL string   // 支付回调URL
-	processors  []string // 支付处理器列表
+	callbackURL string       // 支付回调URL
+	processors  []string     // 支付处理器列表
+	publisher   mq.Publisher // 支付事件发布者，为nil时只记录日志
 }
 
-// Process 处理支付
+// Process 处理支付，将支付事件发布到消息总线，以交易哈希为Key
 func (p *PaymentService) Process(ctx context.Context, from string, amount int64, txHash string) error {
-	// 简化版支付处理逻辑
 	zap.L().Info("处理支付交易",
 		zap.String("from", from),
 		zap.Int64("amount", amount),
 		zap.String("txHash", txHash))
 
-	// 实际项目中应该实现支付处理逻辑
+	return p.publish(ctx, dto.PaymentEvent{From: from, Amount: amount, TxHash: txHash})
+}
+
+// ProcessOrder 处理付款到订单收款地址的支付
+func (p *PaymentService) ProcessOrder(ctx context.Context, event dto.PaymentEvent) error {
+	zap.L().Info("处理订单支付交易",
+		zap.String("orderNo", event.OrderNo),
+		zap.String("address", event.Address),
+		zap.Int64("amount", event.Amount),
+		zap.String("txHash", event.TxHash))
+	return p.publish(ctx, event)
+}
+
+// publish 将支付事件发布到消息总线
+func (p *PaymentService) publish(ctx context.Context, event dto.PaymentEvent) error {
+	if p.publisher == nil {
+		return nil
+	}
+
+	event.Time = time.Now()
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if err := p.publisher.Publish(ctx, &mq.Message{Topic: PaymentTopic, Key: event.TxHash, Value: payload}); err != nil {
+		return fmt.Errorf("publish payment event failed: %w", err)
+	}
 	return nil
 }
 
@@ -45,12 +79,13 @@ type TxMonitor struct {
 	ctx           context.Context
 	cancel        context.CancelFunc
 	nftMap        sync.Map
-	monitorAddrs  []string
+	monitorAddrs  AddressSet  // 监控的钱包地址，运行时可增删
+	deposits      DepositBook // 订单收款地址，为nil时不按订单归属
 	lastBlockHash string
 }
 
-// NewTxMonitor 创建交易监控器
-func NewTxMonitor(rpc *dogechain.RPCClient, cfg *MonitorConfig) *TxMonitor {
+// NewTxMonitor 创建交易监控器，监控 addrs 中的地址和 deposits 中的订单收款地址，支付事件通过publisher发布
+func NewTxMonitor(rpc *dogechain.RPCClient, cfg *MonitorConfig, addrs AddressSet, deposits DepositBook, publisher mq.Publisher) *TxMonitor {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &TxMonitor{
@@ -58,16 +93,19 @@ func NewTxMonitor(rpc *dogechain.RPCClient, cfg *MonitorConfig) *TxMonitor {
 		config:       cfg,
 		ctx:          ctx,
 		cancel:       cancel,
-		monitorAddrs: cfg.WalletGroups,
-		paymentSvc:   &PaymentService{callbackURL: "http://localhost/callback"},
+		monitorAddrs: addrs,
+		deposits:     deposits,
+		paymentSvc:   &PaymentService{callbackURL: "http://localhost/callback", publisher: publisher},
 	}
 }
 
+// nftMarkerValue NFT标志输出的金额：100,000 ELON (0.001 DOGE)
+const nftMarkerValue money.Amount = 100000
+
 // isNFTOperation 判断是否为NFT操作
 func (m *TxMonitor) isNFTOperation(tx *dogechain.TxDetail) bool {
 	for _, out := range tx.Vout {
-		// NFT标志：100,000 ELON (0.001 DOGE)
-		if out.Value == 0.001 {
+		if out.Value == nftMarkerValue {
 			return true
 		}
 	}
@@ -75,20 +113,21 @@ func (m *TxMonitor) isNFTOperation(tx *dogechain.TxDetail) bool {
 }
 
 // handlePayment 处理支付
+// 付款到订单收款地址的按地址归属到订单，每个订单发布一个事件；付款到监控钱包组的合计为一个事件
 func (m *TxMonitor) handlePayment(tx *dogechain.TxDetail) error {
-	var inOur, outOur bool
+	var inOur bool
 	var senderAddr string
 
-	// 检查输入是否包含我们的地址
+	// 检查输入是否包含我们的地址（包括归集订单收款地址）
 	for _, in := range tx.Vin {
 		for _, addr := range in.Addresses {
-			if contains(m.monitorAddrs, addr) {
+			if _, ok := m.orderOf(addr); ok || m.watched(addr) {
 				inOur = true
 				break
 			}
 		}
 		if inOur {
-			break
+			return nil
 		}
 		// 记录第一个输入地址作为发送者
 		if len(in.Addresses) > 0 && senderAddr == "" {
@@ -96,28 +135,44 @@ func (m *TxMonitor) handlePayment(tx *dogechain.TxDetail) error {
 		}
 	}
 
-	// 如果输入不是我们的地址，检查输出
-	if !inOur {
-		var total int64
-		for _, out := range tx.Vout {
-			for _, addr := range out.ScriptPubKey.Addresses {
-				if contains(m.monitorAddrs, addr) {
-					outOur = true
-					total += int64(out.Value * 100000000) // 转换为ELON单位
+	// 输入不是我们的地址，检查输出
+	var outOur bool
+	var total int64
+	var orders []*dto.PaymentEvent
+	byAddr := make(map[string]*dto.PaymentEvent)
+	for _, out := range tx.Vout {
+		for _, addr := range out.ScriptPubKey.Addresses {
+			if orderNo, ok := m.orderOf(addr); ok {
+				event, ok := byAddr[addr]
+				if !ok {
+					event = &dto.PaymentEvent{From: senderAddr, TxHash: tx.Hash, OrderNo: orderNo, Address: addr}
+					byAddr[addr] = event
+					orders = append(orders, event)
 				}
+				event.Amount += out.Value.Elon()
+			} else if m.watched(addr) {
+				outOur = true
+				total += out.Value.Elon()
 			}
 		}
+	}
 
-		// 如果输出包含我们的地址，处理为支付
-		if outOur {
-			return m.paymentSvc.Process(context.Background(),
-				senderAddr,
-				total,
-				tx.Hash,
-			)
+	var errs []error
+	for _, event := range orders {
+		if err := m.deposits.MarkPaid(event.Address, event.TxHash, event.Amount); err != nil {
+			zap.L().Warn("记录订单收款失败", zap.String("orderNo", event.OrderNo), zap.Error(err))
+		}
+		if err := m.paymentSvc.ProcessOrder(context.Background(), *event); err != nil {
+			errs = append(errs, err)
 		}
 	}
-	return nil
+	// 输出包含监控钱包组的地址，处理为支付
+	if outOur {
+		if err := m.paymentSvc.Process(context.Background(), senderAddr, total, tx.Hash); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // StartDualMonitor 启动双通道监控
@@ -126,6 +181,11 @@ func (m *TxMonitor) StartDualMonitor() {
 	go m.pollBlockExplorer()
 }
 
+// Stop 停止监控
+func (m *TxMonitor) Stop() {
+	m.cancel()
+}
+
 // processNodeWebsocket 处理节点WebSocket消息
 func (m *TxMonitor) processNodeWebsocket() {
 	ticker := time.NewTicker(5 * time.Second)
@@ -166,12 +226,15 @@ func (m *TxMonitor) checkNewBlocks() error {
 	return nil
 }
 
-// contains 辅助函数：检查数组是否包含指定值
-func contains(arr []string, val string) bool {
-	for _, v := range arr {
-		if v == val {
-			return true
-		}
+// watched 判断地址是否在监控中
+func (m *TxMonitor) watched(addr string) bool {
+	return m.monitorAddrs != nil && m.monitorAddrs.Contains(addr)
+}
+
+// orderOf 返回订单收款地址所属的订单号
+func (m *TxMonitor) orderOf(addr string) (string, bool) {
+	if m.deposits == nil {
+		return "", false
 	}
-	return false
+	return m.deposits.OrderOf(addr)
 }
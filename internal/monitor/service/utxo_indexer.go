@@ -0,0 +1,402 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"claimask/internal/monitor/dao"
+	"claimask/internal/monitor/model/po"
+	"claimask/pkg/dogechain"
+
+	"go.uber.org/zap"
+)
+
+// errChainMoved 索引过程中最长链发生变化，下一轮同步时回滚
+var errChainMoved = errors.New("chain tip moved during indexing")
+
+// BlockSource UTXO索引依赖的节点接口，GetBlock 需返回区块内的交易详情（getblock verbosity 2）
+type BlockSource interface {
+	GetBlockCount() (int64, error)
+	GetBlockHash(height int64) (string, error)
+	GetBlock(hash string) (*dogechain.Block, error)
+}
+
+// AddressFunc 以函数实现 AddressSet
+type AddressFunc func(address string) bool
+
+// Contains 判断地址是否在集合中
+func (f AddressFunc) Contains(address string) bool {
+	return f(address)
+}
+
+// IndexerConfig UTXO索引配置
+type IndexerConfig struct {
+	StartHeight int64         // 首次索引的起始高度，0为从当前最新区块开始
+	Interval    time.Duration // 同步间隔
+	ReorgDepth  int64         // 可回滚的最大重组深度，也是保留的区块记录数
+}
+
+// AddressBalance 地址余额，金额单位为 ELON
+type AddressBalance struct {
+	Address     string
+	Height      int64 // 已索引到的区块高度
+	Confirmed   int64 // 确认数达到 minConf 的余额
+	Unconfirmed int64 // 确认数不足的余额
+	UTXOs       []dogechain.UTXO
+}
+
+// rescanRequest 待执行的补扫请求
+type rescanRequest struct {
+	from      int64
+	addresses []string
+}
+
+// UTXOIndexer UTXO索引
+// 逐个区块扫描最长链，记录付款到监控地址的输出，输出被花费时标记为已花费，
+// 链重组时回滚到分叉点重新索引。余额和UTXO查询不依赖节点钱包的 listunspent
+type UTXOIndexer struct {
+	dao   dao.UTXODao
+	chain BlockSource
+	owned []AddressSet
+	cfg   IndexerConfig
+
+	syncMu sync.Mutex // 同步和补扫互斥执行
+
+	mu      sync.RWMutex
+	height  int64           // 已索引到的区块高度
+	rescans []rescanRequest // 待执行的补扫请求
+
+	wake   chan struct{} // 登记补扫请求后唤醒定时同步
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewUTXOIndexer 创建UTXO索引，记录付款到 owned 中任一集合的地址的输出
+func NewUTXOIndexer(utxoDao dao.UTXODao, chain BlockSource, cfg IndexerConfig, owned ...AddressSet) *UTXOIndexer {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 30 * time.Second
+	}
+	if cfg.ReorgDepth <= 0 {
+		cfg.ReorgDepth = 100
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &UTXOIndexer{
+		dao:    utxoDao,
+		chain:  chain,
+		owned:  owned,
+		cfg:    cfg,
+		wake:   make(chan struct{}, 1),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Sync 同步到最新区块：先识别并回滚链重组，再逐个索引新区块
+func (i *UTXOIndexer) Sync() error {
+	i.syncMu.Lock()
+	defer i.syncMu.Unlock()
+
+	tip, err := i.chain.GetBlockCount()
+	if err != nil {
+		return fmt.Errorf("get block count failed: %w", err)
+	}
+
+	var next int64
+	last, err := i.dao.LastBlock()
+	switch {
+	case errors.Is(err, dao.ErrBlockNotFound):
+		next = i.cfg.StartHeight
+		if next <= 0 {
+			next = tip
+		}
+	case err != nil:
+		return err
+	default:
+		fork, err := i.findFork(last, tip)
+		if err != nil {
+			return err
+		}
+		if fork < last.Height {
+			zap.L().Warn("检测到链重组，回滚UTXO索引", zap.Int64("from", last.Height), zap.Int64("to", fork))
+			if err := i.dao.RollbackAbove(fork); err != nil {
+				return fmt.Errorf("rollback to %d failed: %w", fork, err)
+			}
+		}
+		i.setHeight(fork)
+		next = fork + 1
+	}
+
+	for ; next <= tip; next++ {
+		if i.ctx.Err() != nil {
+			return nil
+		}
+		if err := i.indexBlock(next); err != nil {
+			return fmt.Errorf("index block %d failed: %w", next, err)
+		}
+		i.setHeight(next)
+		if next%i.cfg.ReorgDepth == 0 {
+			if err := i.dao.PruneBlocks(next - i.cfg.ReorgDepth); err != nil {
+				zap.L().Warn("清理已索引区块失败", zap.Error(err))
+			}
+		}
+	}
+	return nil
+}
+
+// findFork 从已索引的最高区块向下查找仍在最长链上的区块，返回其高度
+// 该高度以下没有已索引的区块时返回该高度，即回滚全部更高的区块
+func (i *UTXOIndexer) findFork(last *po.UTXOBlockPO, tip int64) (int64, error) {
+	height := last.Height
+	if height > tip {
+		height = tip
+	}
+	for ; height >= 0 && height > last.Height-i.cfg.ReorgDepth; height-- {
+		block, err := i.dao.BlockAt(height)
+		if errors.Is(err, dao.ErrBlockNotFound) {
+			return height, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		hash, err := i.chain.GetBlockHash(height)
+		if err != nil {
+			return 0, fmt.Errorf("get block hash at %d failed: %w", height, err)
+		}
+		if hash == block.Hash {
+			return height, nil
+		}
+	}
+	return 0, fmt.Errorf("reorg deeper than %d blocks below %d, reindex from a lower start height", i.cfg.ReorgDepth, last.Height)
+}
+
+// indexBlock 索引一个区块，区块不接在已索引的上一区块之后时返回 errChainMoved
+func (i *UTXOIndexer) indexBlock(height int64) error {
+	hash, err := i.chain.GetBlockHash(height)
+	if err != nil {
+		return err
+	}
+	block, err := i.chain.GetBlock(hash)
+	if err != nil {
+		return err
+	}
+	prev, err := i.dao.BlockAt(height - 1)
+	switch {
+	case err == nil && prev.Hash != block.PreviousBlockHash:
+		return errChainMoved
+	case err != nil && !errors.Is(err, dao.ErrBlockNotFound):
+		return err
+	}
+
+	outputs, spends := blockOutputs(block, height, hash, i.owns)
+	return i.dao.ApplyBlock(&po.UTXOBlockPO{
+		Height:    height,
+		Hash:      hash,
+		PrevHash:  block.PreviousBlockHash,
+		IndexedAt: time.Now(),
+	}, outputs, spends)
+}
+
+// blockOutputs 收集区块中付款到 owns 为真的地址的输出，以及区块中所有交易输入花费的输出
+func blockOutputs(block *dogechain.Block, height int64, hash string, owns func(address string) bool) ([]po.UTXOPO, []dao.Spend) {
+	var outputs []po.UTXOPO
+	var spends []dao.Spend
+	for _, tx := range block.Tx {
+		for _, in := range tx.Vin {
+			if in.Txid == "" { // coinbase
+				continue
+			}
+			spends = append(spends, dao.Spend{TxHash: in.Txid, Index: in.Vout, SpentTx: tx.Txid})
+		}
+		for _, out := range tx.Vout {
+			if len(out.ScriptPubKey.Addresses) != 1 || !owns(out.ScriptPubKey.Addresses[0]) {
+				continue
+			}
+			outputs = append(outputs, po.UTXOPO{
+				Address:     out.ScriptPubKey.Addresses[0],
+				TxHash:      tx.Txid,
+				Index:       out.N,
+				Value:       out.Value.Elon(),
+				BlockHeight: height,
+				BlockHash:   hash,
+			})
+		}
+	}
+	return outputs, spends
+}
+
+// Rescan 补扫 from 到已索引高度之间的区块，记录付款到 addresses 的输出
+// 运行中新加入监控的地址在加入前收到的输出不在索引中，地址需先加入监控集合，之后的区块由 Sync 索引；重复补扫不会重复记录
+func (i *UTXOIndexer) Rescan(from int64, addresses ...string) error {
+	i.syncMu.Lock()
+	defer i.syncMu.Unlock()
+
+	targets := make(map[string]bool, len(addresses))
+	for _, address := range addresses {
+		targets[address] = true
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+	if from < 0 {
+		from = 0
+	}
+	to := i.Height()
+	for height := from; height <= to; height++ {
+		if err := i.ctx.Err(); err != nil {
+			return err
+		}
+		hash, err := i.chain.GetBlockHash(height)
+		if err != nil {
+			return fmt.Errorf("get block hash at %d failed: %w", height, err)
+		}
+		block, err := i.chain.GetBlock(hash)
+		if err != nil {
+			return fmt.Errorf("get block %s failed: %w", hash, err)
+		}
+		outputs, spends := blockOutputs(block, height, hash, func(address string) bool { return targets[address] })
+		if err := i.dao.ApplyOutputs(height, outputs, spends); err != nil {
+			return fmt.Errorf("rescan block %d failed: %w", height, err)
+		}
+	}
+	zap.L().Info("UTXO索引补扫完成", zap.Strings("addresses", addresses), zap.Int64("from", from), zap.Int64("to", to))
+	return nil
+}
+
+// ScheduleRescan 登记补扫请求，由定时同步的协程在下一次同步前执行
+func (i *UTXOIndexer) ScheduleRescan(from int64, addresses ...string) {
+	i.mu.Lock()
+	i.rescans = append(i.rescans, rescanRequest{from: from, addresses: addresses})
+	i.mu.Unlock()
+	select {
+	case i.wake <- struct{}{}:
+	default:
+	}
+}
+
+// runRescans 执行已登记的补扫请求，失败的请求留到下一轮重试
+func (i *UTXOIndexer) runRescans() {
+	i.mu.Lock()
+	pending := i.rescans
+	i.rescans = nil
+	i.mu.Unlock()
+
+	var failed []rescanRequest
+	for _, req := range pending {
+		if err := i.Rescan(req.from, req.addresses...); err != nil {
+			zap.L().Warn("UTXO索引补扫失败", zap.Strings("addresses", req.addresses), zap.Int64("from", req.from), zap.Error(err))
+			failed = append(failed, req)
+		}
+	}
+	if len(failed) > 0 {
+		i.mu.Lock()
+		i.rescans = append(failed, i.rescans...)
+		i.mu.Unlock()
+	}
+}
+
+// owns 判断地址是否为监控地址
+func (i *UTXOIndexer) owns(address string) bool {
+	for _, set := range i.owned {
+		if set.Contains(address) {
+			return true
+		}
+	}
+	return false
+}
+
+// Height 已索引到的区块高度，本进程尚未同步时从数据库读取，没有已索引的区块时为0
+func (i *UTXOIndexer) Height() int64 {
+	i.mu.RLock()
+	height := i.height
+	i.mu.RUnlock()
+	if height > 0 {
+		return height
+	}
+	if last, err := i.dao.LastBlock(); err == nil {
+		return last.Height
+	}
+	return 0
+}
+
+// setHeight 更新已索引到的区块高度
+func (i *UTXOIndexer) setHeight(height int64) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.height = height
+}
+
+// GetAddressUTXOs 查询地址未花费的输出，确认数按已索引到的区块高度计算
+func (i *UTXOIndexer) GetAddressUTXOs(address string) ([]dogechain.UTXO, error) {
+	height := i.Height()
+	if height == 0 {
+		return nil, nil
+	}
+	rows, err := i.dao.ListUnspent(address, height)
+	if err != nil {
+		return nil, err
+	}
+	utxos := make([]dogechain.UTXO, len(rows))
+	for n, row := range rows {
+		utxos[n] = dogechain.UTXO{
+			TxHash:        row.TxHash,
+			Index:         row.Index,
+			Value:         row.Value,
+			Confirmations: height - row.BlockHeight + 1,
+		}
+	}
+	return utxos, nil
+}
+
+// Balance 查询地址余额，确认数不少于 minConf 的计入已确认余额
+func (i *UTXOIndexer) Balance(address string, minConf int64) (*AddressBalance, error) {
+	utxos, err := i.GetAddressUTXOs(address)
+	if err != nil {
+		return nil, err
+	}
+	balance := &AddressBalance{Address: address, Height: i.Height(), UTXOs: utxos}
+	for _, utxo := range utxos {
+		if utxo.Confirmations >= minConf {
+			balance.Confirmed += utxo.Value
+		} else {
+			balance.Unconfirmed += utxo.Value
+		}
+	}
+	return balance, nil
+}
+
+// Start 启动定时同步，每次同步前先执行已登记的补扫请求
+func (i *UTXOIndexer) Start() {
+	i.done = make(chan struct{})
+	go func() {
+		defer close(i.done)
+		ticker := time.NewTicker(i.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			i.runRescans()
+			if err := i.Sync(); errors.Is(err, errChainMoved) {
+				zap.L().Info("索引期间最长链变化，下一轮重新同步", zap.Error(err))
+			} else if err != nil {
+				zap.L().Warn("UTXO索引同步失败", zap.Error(err))
+			}
+			select {
+			case <-ticker.C:
+			case <-i.wake:
+			case <-i.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止定时同步，等待正在索引的区块完成
+func (i *UTXOIndexer) Stop() {
+	i.cancel()
+	if i.done != nil {
+		<-i.done
+	}
+}
@@ -0,0 +1,335 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"claimask/internal/monitor/dao"
+	"claimask/internal/monitor/model/po"
+	"claimask/pkg/dogechain"
+	"claimask/pkg/money"
+)
+
+// memUTXODao 内存实现的UTXO索引DAO
+type memUTXODao struct {
+	mu     sync.Mutex
+	utxos  []po.UTXOPO
+	blocks map[int64]po.UTXOBlockPO
+}
+
+func newMemUTXODao() *memUTXODao {
+	return &memUTXODao{blocks: make(map[int64]po.UTXOBlockPO)}
+}
+
+func (d *memUTXODao) GetAddressValidUtxo(address string) (*po.UTXOPO, error) {
+	utxos, _ := d.ListUnspent(address, 0)
+	if len(utxos) == 0 {
+		return nil, errors.New("not found")
+	}
+	return &utxos[0], nil
+}
+
+func (d *memUTXODao) UpdateUTXOState(utxo *po.UTXOPO) error {
+	return nil
+}
+
+func (d *memUTXODao) ListUnspent(address string, maxHeight int64) ([]po.UTXOPO, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var utxos []po.UTXOPO
+	for _, utxo := range d.utxos {
+		if utxo.Address == address && !utxo.Spent && (maxHeight <= 0 || utxo.BlockHeight <= maxHeight) {
+			utxos = append(utxos, utxo)
+		}
+	}
+	sort.Slice(utxos, func(i, j int) bool { return utxos[i].Value > utxos[j].Value })
+	return utxos, nil
+}
+
+func (d *memUTXODao) LastBlock() (*po.UTXOBlockPO, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var last *po.UTXOBlockPO
+	for _, block := range d.blocks {
+		if last == nil || block.Height > last.Height {
+			last = &block
+		}
+	}
+	if last == nil {
+		return nil, dao.ErrBlockNotFound
+	}
+	return last, nil
+}
+
+func (d *memUTXODao) BlockAt(height int64) (*po.UTXOBlockPO, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	block, ok := d.blocks[height]
+	if !ok {
+		return nil, dao.ErrBlockNotFound
+	}
+	return &block, nil
+}
+
+func (d *memUTXODao) ApplyBlock(block *po.UTXOBlockPO, outputs []po.UTXOPO, spends []dao.Spend) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.apply(block.Height, outputs, spends)
+	d.blocks[block.Height] = *block
+	return nil
+}
+
+func (d *memUTXODao) ApplyOutputs(height int64, outputs []po.UTXOPO, spends []dao.Spend) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.apply(height, outputs, spends)
+	return nil
+}
+
+// apply 写入输出并标记花费，(txid, vout) 已存在的输出忽略
+func (d *memUTXODao) apply(height int64, outputs []po.UTXOPO, spends []dao.Spend) {
+	for _, output := range outputs {
+		exists := false
+		for _, utxo := range d.utxos {
+			exists = exists || utxo.TxHash == output.TxHash && utxo.Index == output.Index
+		}
+		if !exists {
+			d.utxos = append(d.utxos, output)
+		}
+	}
+	for _, spend := range spends {
+		for i := range d.utxos {
+			if d.utxos[i].TxHash == spend.TxHash && d.utxos[i].Index == spend.Index && !d.utxos[i].Spent {
+				d.utxos[i].Spent, d.utxos[i].SpentTx, d.utxos[i].SpentHeight = true, spend.SpentTx, height
+			}
+		}
+	}
+}
+
+func (d *memUTXODao) RollbackAbove(height int64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	kept := d.utxos[:0]
+	for _, utxo := range d.utxos {
+		if utxo.BlockHeight > height {
+			continue
+		}
+		if utxo.SpentHeight > height {
+			utxo.Spent, utxo.SpentTx, utxo.SpentHeight = false, "", 0
+		}
+		kept = append(kept, utxo)
+	}
+	d.utxos = kept
+	for h := range d.blocks {
+		if h > height {
+			delete(d.blocks, h)
+		}
+	}
+	return nil
+}
+
+func (d *memUTXODao) PruneBlocks(height int64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for h := range d.blocks {
+		if h < height {
+			delete(d.blocks, h)
+		}
+	}
+	return nil
+}
+
+// fakeBlockChain 内存中的最长链，blocks[i] 为高度 i 的区块
+type fakeBlockChain struct {
+	blocks []*dogechain.Block
+}
+
+func newFakeBlockChain() *fakeBlockChain {
+	chain := &fakeBlockChain{}
+	chain.mine("genesis")
+	return chain
+}
+
+// mine 在最长链末尾追加一个区块，tag 区分同一高度的不同区块
+func (c *fakeBlockChain) mine(tag string, txs ...*dogechain.TxDetail) {
+	block := &dogechain.Block{Height: int64(len(c.blocks)), Hash: fmt.Sprintf("%s-%d", tag, len(c.blocks))}
+	if len(c.blocks) > 0 {
+		block.PreviousBlockHash = c.blocks[len(c.blocks)-1].Hash
+	}
+	for _, tx := range txs {
+		block.Tx = append(block.Tx, *tx)
+	}
+	c.blocks = append(c.blocks, block)
+}
+
+// reorg 丢弃 height 及以上的区块
+func (c *fakeBlockChain) reorg(height int64) {
+	c.blocks = c.blocks[:height]
+}
+
+func (c *fakeBlockChain) GetBlockCount() (int64, error) {
+	return int64(len(c.blocks) - 1), nil
+}
+
+func (c *fakeBlockChain) GetBlockHash(height int64) (string, error) {
+	if height < 0 || height >= int64(len(c.blocks)) {
+		return "", errors.New("block height out of range")
+	}
+	return c.blocks[height].Hash, nil
+}
+
+func (c *fakeBlockChain) GetBlock(hash string) (*dogechain.Block, error) {
+	for _, block := range c.blocks {
+		if block.Hash == hash {
+			return block, nil
+		}
+	}
+	return nil, errors.New("block not found")
+}
+
+// newTestTx 构建花费 spends 并付款到 outputs 的交易，spends 为空时为 coinbase
+func newTestTx(txid string, spends []dogechain.TxInput, outputs ...dogechain.PayOutput) *dogechain.TxDetail {
+	tx := &dogechain.TxDetail{Txid: txid, Hash: txid, Vin: spends}
+	if len(spends) == 0 {
+		tx.Vin = []dogechain.TxInput{{}}
+	}
+	for n, output := range outputs {
+		out := dogechain.TxOutput{Value: money.Amount(output.Value), N: uint32(n)}
+		out.ScriptPubKey.Addresses = []string{output.Address}
+		tx.Vout = append(tx.Vout, out)
+	}
+	return tx
+}
+
+// 测试按 (txid, vout) 记录监控地址的输出、花费后标记，链重组时回滚并按新链重新索引
+func TestUTXOIndexerReorg(t *testing.T) {
+	ours, other := testAddress(t, 1), testAddress(t, 2)
+	chain := newFakeBlockChain()
+	utxoDao := newMemUTXODao()
+	indexer := NewUTXOIndexer(utxoDao, chain, IndexerConfig{StartHeight: 1, ReorgDepth: 10},
+		AddressFunc(func(address string) bool { return address == ours }))
+
+	chain.mine("a", newTestTx("tx1", nil,
+		dogechain.PayOutput{Address: ours, Value: 1000}, dogechain.PayOutput{Address: other, Value: 5}))
+	chain.mine("a",
+		newTestTx("tx2", []dogechain.TxInput{{Txid: "tx1", Vout: 0}}, dogechain.PayOutput{Address: other, Value: 990}),
+		newTestTx("tx3", []dogechain.TxInput{{Txid: "tx9", Vout: 0}},
+			dogechain.PayOutput{Address: ours, Value: 300}, dogechain.PayOutput{Address: ours, Value: 200}))
+	if err := indexer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	utxos, err := indexer.GetAddressUTXOs(ours)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(utxos) != 2 || utxos[0].TxHash != "tx3" || utxos[0].Index != 0 || utxos[1].Index != 1 || utxos[0].Confirmations != 1 {
+		t.Fatalf("Expected both outputs of tx3 unspent, got %+v", utxos)
+	}
+	if spent := utxoDao.utxos[0]; !spent.Spent || spent.SpentTx != "tx2" || spent.SpentHeight != 2 {
+		t.Errorf("Expected tx1:0 spent by tx2, got %+v", spent)
+	}
+
+	// 高度2的区块被替换，tx2 和 tx3 不在新链上
+	chain.reorg(2)
+	chain.mine("b", newTestTx("tx4", []dogechain.TxInput{{Txid: "tx8", Vout: 1}}, dogechain.PayOutput{Address: ours, Value: 40}))
+	chain.mine("b", newTestTx("tx5", []dogechain.TxInput{{Txid: "tx8", Vout: 2}}, dogechain.PayOutput{Address: ours, Value: 7}))
+	if err := indexer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	balance, err := indexer.Balance(ours, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Height != 3 || balance.Confirmed != 1040 || balance.Unconfirmed != 7 || len(balance.UTXOs) != 3 {
+		t.Errorf("Unexpected balance after reorg %+v", balance)
+	}
+	if block, _ := utxoDao.BlockAt(2); block.Hash != "b-2" {
+		t.Errorf("Expected block 2 to be reindexed, got %+v", block)
+	}
+
+	// 再次同步不重复索引
+	if err := indexer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if len(utxoDao.utxos) != 3 {
+		t.Errorf("Expected no duplicate outputs, got %+v", utxoDao.utxos)
+	}
+}
+
+// 测试重组深度超过上限时报错，不回滚
+func TestUTXOIndexerDeepReorg(t *testing.T) {
+	chain := newFakeBlockChain()
+	for i := 0; i < 4; i++ {
+		chain.mine("a")
+	}
+	utxoDao := newMemUTXODao()
+	indexer := NewUTXOIndexer(utxoDao, chain, IndexerConfig{StartHeight: 1, ReorgDepth: 2})
+	if err := indexer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	chain.reorg(1)
+	for i := 0; i < 4; i++ {
+		chain.mine("b")
+	}
+	if err := indexer.Sync(); err == nil {
+		t.Fatal("Expected error for reorg deeper than the limit")
+	}
+	if last, _ := utxoDao.LastBlock(); last.Hash != "a-4" {
+		t.Errorf("Expected index to stay at a-4, got %+v", last)
+	}
+}
+
+// 测试补扫新加入监控的地址：记录加入前收到的输出和之后的花费，重复补扫不重复记录
+func TestUTXOIndexerRescan(t *testing.T) {
+	ours, added := testAddress(t, 1), testAddress(t, 2)
+	owned := map[string]bool{ours: true}
+	chain := newFakeBlockChain()
+	utxoDao := newMemUTXODao()
+	indexer := NewUTXOIndexer(utxoDao, chain, IndexerConfig{StartHeight: 1, ReorgDepth: 10},
+		AddressFunc(func(address string) bool { return owned[address] }))
+
+	chain.mine("a", newTestTx("tx1", nil,
+		dogechain.PayOutput{Address: ours, Value: 100}, dogechain.PayOutput{Address: added, Value: 500}))
+	chain.mine("a", newTestTx("tx2", nil, dogechain.PayOutput{Address: added, Value: 70}))
+	chain.mine("a", newTestTx("tx3", []dogechain.TxInput{{Txid: "tx2", Vout: 0}}, dogechain.PayOutput{Address: ours, Value: 60}))
+	if err := indexer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if utxos, _ := indexer.GetAddressUTXOs(added); len(utxos) != 0 {
+		t.Fatalf("Expected outputs before registration missing, got %+v", utxos)
+	}
+
+	// 地址加入监控后补扫，之后的区块由同步索引
+	owned[added] = true
+	for n := 0; n < 2; n++ {
+		if err := indexer.Rescan(1, added); err != nil {
+			t.Fatal(err)
+		}
+	}
+	chain.mine("a", newTestTx("tx4", nil, dogechain.PayOutput{Address: added, Value: 9}))
+	if err := indexer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	balance, err := indexer.Balance(added, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Confirmed != 509 || len(balance.UTXOs) != 2 {
+		t.Errorf("Expected tx1:1 and tx4:0 unspent after rescan, got %+v", balance)
+	}
+	if len(utxoDao.utxos) != 5 {
+		t.Errorf("Expected no duplicate outputs, got %+v", utxoDao.utxos)
+	}
+	for _, utxo := range utxoDao.utxos {
+		if utxo.TxHash == "tx2" && (!utxo.Spent || utxo.SpentTx != "tx3" || utxo.SpentHeight != 3) {
+			t.Errorf("Expected tx2:0 spent by tx3 at height 3, got %+v", utxo)
+		}
+	}
+}
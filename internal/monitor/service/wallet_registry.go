@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"claimask/internal/monitor/dao"
+	"claimask/internal/monitor/model/po"
+	"claimask/pkg/dogechain"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrWalletExists 地址已在监控中
+	ErrWalletExists = errors.New("wallet already watched")
+	// ErrInvalidAddress 地址不是合法的 Dogecoin 主网地址
+	ErrInvalidAddress = errors.New("invalid address")
+)
+
+// AddressSet 监控地址集合
+type AddressSet interface {
+	// Contains 判断地址是否在监控中
+	Contains(address string) bool
+}
+
+// WalletRegistry 监控钱包组注册表
+// 以数据库为准，内存中缓存监控中的地址；通过管理接口增删的地址立即生效，
+// 其他实例的修改由定时刷新同步
+type WalletRegistry struct {
+	dao      dao.WalletGroupDao
+	interval time.Duration
+
+	mu    sync.RWMutex
+	addrs map[string]struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewWalletRegistry 创建钱包组注册表，interval 为从数据库刷新的间隔
+func NewWalletRegistry(walletDao dao.WalletGroupDao, interval time.Duration) *WalletRegistry {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &WalletRegistry{
+		dao:      walletDao,
+		interval: interval,
+		addrs:    make(map[string]struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Seed 将配置文件中的钱包组写入数据库，已存在的地址（包括已停用的）保持不变
+// 地址不合法的钱包组跳过并记录日志
+func (r *WalletRegistry) Seed(wallets []po.WalletGroupPO) error {
+	for _, wallet := range wallets {
+		if err := dogechain.ValidateAddress(wallet.ReceiveAddr); err != nil {
+			zap.L().Warn("跳过不合法的配置钱包组", zap.Int("group", wallet.GroupID), zap.Error(err))
+			continue
+		}
+		_, err := r.dao.GetByAddress(wallet.ReceiveAddr)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, dao.ErrWalletNotFound) {
+			return err
+		}
+		wallet.Status = po.WalletStatusActive
+		if err := r.dao.CreateWalletGroup(&wallet); err != nil {
+			return fmt.Errorf("seed wallet group %d failed: %w", wallet.GroupID, err)
+		}
+		zap.L().Info("配置钱包组加入监控", zap.Int("group", wallet.GroupID), zap.String("address", wallet.ReceiveAddr))
+	}
+	return r.Refresh()
+}
+
+// Refresh 从数据库重新加载监控中的地址
+func (r *WalletRegistry) Refresh() error {
+	wallets, err := r.dao.ListWalletGroups(false)
+	if err != nil {
+		return err
+	}
+	addrs := make(map[string]struct{}, len(wallets))
+	for _, wallet := range wallets {
+		addrs[wallet.ReceiveAddr] = struct{}{}
+	}
+
+	r.mu.Lock()
+	r.addrs = addrs
+	r.mu.Unlock()
+	return nil
+}
+
+// Contains 判断地址是否在监控中
+func (r *WalletRegistry) Contains(address string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.addrs[address]
+	return ok
+}
+
+// Addresses 返回监控中的地址，按字典序
+func (r *WalletRegistry) Addresses() []string {
+	r.mu.RLock()
+	addrs := make([]string, 0, len(r.addrs))
+	for addr := range r.addrs {
+		addrs = append(addrs, addr)
+	}
+	r.mu.RUnlock()
+	sort.Strings(addrs)
+	return addrs
+}
+
+// List 查询钱包组，includeRetired 为 true 时包含已停用的
+func (r *WalletRegistry) List(includeRetired bool) ([]po.WalletGroupPO, error) {
+	return r.dao.ListWalletGroups(includeRetired)
+}
+
+// Add 将地址加入监控，已停用的地址重新启用，group 为0时自动分配
+func (r *WalletRegistry) Add(group int, address string) (*po.WalletGroupPO, error) {
+	if err := dogechain.ValidateAddress(address); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAddress, err)
+	}
+
+	wallet, err := r.dao.GetByAddress(address)
+	switch {
+	case err == nil:
+		if wallet.Status != po.WalletStatusRetired {
+			return nil, ErrWalletExists
+		}
+		if err := r.dao.UpdateWalletStatus(address, po.WalletStatusActive, nil); err != nil {
+			return nil, err
+		}
+		wallet.Status = po.WalletStatusActive
+		wallet.RetiredAt = nil
+	case errors.Is(err, dao.ErrWalletNotFound):
+		wallet = &po.WalletGroupPO{GroupID: group, ReceiveAddr: address, Status: po.WalletStatusActive}
+		if err := r.dao.CreateWalletGroup(wallet); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.addrs[address] = struct{}{}
+	r.mu.Unlock()
+	zap.L().Info("钱包组加入监控", zap.Int("group", wallet.GroupID), zap.String("address", address))
+	return wallet, nil
+}
+
+// Retire 停用地址，保留记录但不再监控
+func (r *WalletRegistry) Retire(address string) error {
+	now := time.Now()
+	if err := r.dao.UpdateWalletStatus(address, po.WalletStatusRetired, &now); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	delete(r.addrs, address)
+	r.mu.Unlock()
+	zap.L().Info("钱包组停止监控", zap.String("address", address))
+	return nil
+}
+
+// Start 启动定时刷新
+func (r *WalletRegistry) Start() {
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := r.Refresh(); err != nil {
+					zap.L().Warn("刷新监控钱包组失败", zap.Error(err))
+				}
+			case <-r.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止定时刷新
+func (r *WalletRegistry) Stop() {
+	r.cancel()
+}
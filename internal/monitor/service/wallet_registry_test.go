@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"claimask/internal/monitor/dao"
+	"claimask/internal/monitor/model/po"
+	"claimask/pkg/dogechain"
+	"claimask/pkg/money"
+	"claimask/pkg/mq"
+
+	"github.com/btcsuite/btcd/btcutil"
+)
+
+// memWalletDao 内存实现的钱包组DAO
+type memWalletDao struct {
+	mu      sync.Mutex
+	wallets map[string]po.WalletGroupPO
+	nextID  int
+}
+
+func newMemWalletDao() *memWalletDao {
+	return &memWalletDao{wallets: make(map[string]po.WalletGroupPO)}
+}
+
+func (d *memWalletDao) ListWalletGroups(includeRetired bool) ([]po.WalletGroupPO, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var wallets []po.WalletGroupPO
+	for _, w := range d.wallets {
+		if includeRetired || w.Status == po.WalletStatusActive {
+			wallets = append(wallets, w)
+		}
+	}
+	return wallets, nil
+}
+
+func (d *memWalletDao) GetByAddress(address string) (*po.WalletGroupPO, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	w, ok := d.wallets[address]
+	if !ok {
+		return nil, dao.ErrWalletNotFound
+	}
+	return &w, nil
+}
+
+func (d *memWalletDao) CreateWalletGroup(wallet *po.WalletGroupPO) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if wallet.GroupID == 0 {
+		d.nextID++
+		wallet.GroupID = 100 + d.nextID
+	}
+	d.wallets[wallet.ReceiveAddr] = *wallet
+	return nil
+}
+
+func (d *memWalletDao) UpdateWalletStatus(address, status string, retiredAt *time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	w, ok := d.wallets[address]
+	if !ok {
+		return dao.ErrWalletNotFound
+	}
+	w.Status, w.RetiredAt = status, retiredAt
+	d.wallets[address] = w
+	return nil
+}
+
+// testAddress 生成合法的 Dogecoin 主网地址
+func testAddress(t *testing.T, seed byte) string {
+	t.Helper()
+	hash := make([]byte, 20)
+	hash[0] = seed
+	addr, err := btcutil.NewAddressPubKeyHash(hash, &dogechain.DogeMainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return addr.EncodeAddress()
+}
+
+// 测试配置钱包组只在数据库中不存在时写入，不合法的地址被跳过，已停用的不会被重新启用
+func TestWalletRegistrySeed(t *testing.T) {
+	addr1, addr2, addr3 := testAddress(t, 1), testAddress(t, 2), testAddress(t, 3)
+	walletDao := newMemWalletDao()
+	walletDao.wallets[addr2] = po.WalletGroupPO{GroupID: 2, ReceiveAddr: addr2, Status: po.WalletStatusRetired}
+	walletDao.wallets[addr3] = po.WalletGroupPO{GroupID: 3, ReceiveAddr: addr3, Status: po.WalletStatusActive}
+
+	registry := NewWalletRegistry(walletDao, time.Minute)
+	err := registry.Seed([]po.WalletGroupPO{
+		{GroupID: 1, ReceiveAddr: addr1},
+		{GroupID: 2, ReceiveAddr: addr2},
+		{GroupID: 9, ReceiveAddr: "DAddress1"},
+	})
+	if err != nil {
+		t.Fatalf("seed failed: %v", err)
+	}
+
+	if !registry.Contains(addr1) || !registry.Contains(addr3) {
+		t.Errorf("Expected seeded and stored active wallets to be watched, got %v", registry.Addresses())
+	}
+	if registry.Contains(addr2) || registry.Contains("DAddress1") {
+		t.Errorf("Expected retired and invalid wallets to be skipped, got %v", registry.Addresses())
+	}
+	if len(walletDao.wallets) != 3 {
+		t.Errorf("Expected 3 stored wallets, got %d", len(walletDao.wallets))
+	}
+}
+
+// 测试运行时增删地址立即生效，停用的地址可以重新加入
+func TestWalletRegistryAddRetire(t *testing.T) {
+	addr := testAddress(t, 1)
+	registry := NewWalletRegistry(newMemWalletDao(), time.Minute)
+
+	if _, err := registry.Add(0, "not-an-address"); !errors.Is(err, ErrInvalidAddress) {
+		t.Errorf("Expected ErrInvalidAddress, got %v", err)
+	}
+
+	wallet, err := registry.Add(0, addr)
+	if err != nil {
+		t.Fatalf("add failed: %v", err)
+	}
+	if wallet.GroupID == 0 || wallet.Status != po.WalletStatusActive || !registry.Contains(addr) {
+		t.Errorf("Unexpected wallet %+v", wallet)
+	}
+	if _, err := registry.Add(0, addr); !errors.Is(err, ErrWalletExists) {
+		t.Errorf("Expected ErrWalletExists, got %v", err)
+	}
+
+	if err := registry.Retire(addr); err != nil {
+		t.Fatalf("retire failed: %v", err)
+	}
+	if registry.Contains(addr) {
+		t.Error("Expected retired address to be unwatched")
+	}
+	if err := registry.Retire(testAddress(t, 2)); !errors.Is(err, dao.ErrWalletNotFound) {
+		t.Errorf("Expected ErrWalletNotFound, got %v", err)
+	}
+	all, _ := registry.List(true)
+	if len(all) != 1 || all[0].RetiredAt == nil {
+		t.Errorf("Expected retired wallet to be kept, got %+v", all)
+	}
+
+	wallet, err = registry.Add(0, addr)
+	if err != nil || wallet.RetiredAt != nil || !registry.Contains(addr) {
+		t.Errorf("Expected retired wallet to be reactivated, got %+v %v", wallet, err)
+	}
+}
+
+// 测试交易监控无需重启即可识别运行时加入的地址
+func TestTxMonitorWatchesAddedWallet(t *testing.T) {
+	bus := mq.NewMemoryBus(10 * time.Millisecond)
+	defer bus.Close()
+
+	addr := testAddress(t, 1)
+	registry := NewWalletRegistry(newMemWalletDao(), time.Minute)
+	monitor := NewTxMonitor(nil, &MonitorConfig{}, registry, nil, bus)
+
+	tx := &dogechain.TxDetail{Hash: "txhash1"}
+	tx.Vin = append(tx.Vin, dogechain.TxInput{Addresses: []string{"DSender"}})
+	tx.Vout = append(tx.Vout, dogechain.TxOutput{Value: money.Amount(100000000)})
+	tx.Vout[0].ScriptPubKey.Addresses = []string{addr}
+
+	if err := monitor.handlePayment(tx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registry.Add(0, addr); err != nil {
+		t.Fatal(err)
+	}
+	if err := monitor.handlePayment(tx); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	var got int
+	_ = bus.Subscribe(ctx, "test", []string{PaymentTopic}, func(ctx context.Context, msg *mq.Message) error {
+		got++
+		return nil
+	})
+	if got != 1 {
+		t.Errorf("Expected 1 payment event after the address was added, got %d", got)
+	}
+}
@@ -1,7 +1,10 @@
 package api
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -12,17 +15,24 @@ import (
 // ClaimHandler 处理富豪奖励相关请求
 type ClaimHandler struct {
 	claimService *service.ClaimService
+	outboxRelay  *service.OutboxRelay
+	deadLetters  *service.DeadLetterService
+	accrual      *service.RewardAccrual
 }
 
 // NewClaimHandler 创建ClaimHandler实例
-func NewClaimHandler(claimService *service.ClaimService) *ClaimHandler {
+func NewClaimHandler(claimService *service.ClaimService, outboxRelay *service.OutboxRelay,
+	deadLetters *service.DeadLetterService, accrual *service.RewardAccrual) *ClaimHandler {
 	return &ClaimHandler{
 		claimService: claimService,
+		outboxRelay:  outboxRelay,
+		deadLetters:  deadLetters,
+		accrual:      accrual,
 	}
 }
 
 // SetupRouter 配置路由
-func (h *ClaimHandler) SetupRouter(r *gin.Engine) {
+func (h *ClaimHandler) SetupRouter(r gin.IRouter) {
 	// POST /rich/claim 处理领取请求
 	r.POST("/rich/claim", func(c *gin.Context) {
 		var req model.ClaimRequest
@@ -73,4 +83,146 @@ func (h *ClaimHandler) SetupRouter(r *gin.Engine) {
 			"message": "查询成功",
 		})
 	})
+	// GET /rich/outbox/stats 查询外发消息积压情况
+	r.GET("/rich/outbox/stats", func(c *gin.Context) {
+		stats, err := h.outboxRelay.Stats()
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"code":    500,
+				"message": "查询失败，请稍后再试",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"code":    200,
+			"data":    stats,
+			"message": "查询成功",
+		})
+	})
+}
+
+// SetupAdminRouter 配置死信管理路由，调用方负责在路由组上挂载鉴权中间件
+func (h *ClaimHandler) SetupAdminRouter(r gin.IRouter) {
+	// GET /rich/admin/dlq 分页查询死信
+	r.GET("/rich/admin/dlq", func(c *gin.Context) {
+		query := service.DeadLetterQuery{ErrorClass: c.Query("errorClass")}
+		if v := c.Query("status"); v != "" {
+			status, err := strconv.Atoi(v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "status参数错误"})
+				return
+			}
+			query.Status = &status
+		}
+		query.Cursor, _ = strconv.ParseUint(c.Query("cursor"), 10, 64)
+		query.Limit, _ = strconv.Atoi(c.Query("limit"))
+
+		letters, err := h.deadLetters.List(query)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{"code": 500, "message": "查询失败，请稍后再试"})
+			return
+		}
+
+		var nextCursor uint64
+		if len(letters) > 0 {
+			nextCursor = letters[len(letters)-1].ID
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"code": 200,
+			"data": gin.H{
+				"list":       letters,
+				"nextCursor": nextCursor,
+			},
+			"message": "查询成功",
+		})
+	})
+
+	// GET /rich/admin/dlq/:id 查看单条死信
+	r.GET("/rich/admin/dlq/:id", func(c *gin.Context) {
+		id, ok := deadLetterID(c)
+		if !ok {
+			return
+		}
+		letter, err := h.deadLetters.Get(id)
+		if err != nil {
+			writeDeadLetterError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"code": 200, "data": letter, "message": "查询成功"})
+	})
+
+	// POST /rich/admin/dlq/:id/redrive 将死信重新投递到领取主题
+	r.POST("/rich/admin/dlq/:id/redrive", func(c *gin.Context) {
+		id, ok := deadLetterID(c)
+		if !ok {
+			return
+		}
+		if err := h.deadLetters.Redrive(c, id); err != nil {
+			writeDeadLetterError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"code": 200, "message": "已重新投递"})
+	})
+
+	// POST /rich/admin/dlq/:id/discard 丢弃死信
+	r.POST("/rich/admin/dlq/:id/discard", func(c *gin.Context) {
+		id, ok := deadLetterID(c)
+		if !ok {
+			return
+		}
+		if err := h.deadLetters.Discard(id); err != nil {
+			writeDeadLetterError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"code": 200, "message": "已丢弃"})
+	})
+}
+
+// SetupAccrualRouter 配置收益累加管理路由，调用方负责在路由组上挂载鉴权中间件
+func (h *ClaimHandler) SetupAccrualRouter(r gin.IRouter) {
+	// POST /rich/admin/accrual/backfill?from=2024-05-01&to=2024-05-20 补算区间内未完成的领取日
+	r.POST("/rich/admin/accrual/backfill", func(c *gin.Context) {
+		from, errFrom := time.Parse("2006-01-02", c.Query("from"))
+		to, errTo := time.Parse("2006-01-02", c.Query("to"))
+		if errFrom != nil || errTo != nil || to.Before(from) {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "from/to参数错误，格式为2006-01-02"})
+			return
+		}
+
+		runs, err := h.accrual.Backfill(from, to)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"code":    500,
+				"data":    runs,
+				"message": "补算失败：" + err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"code": 200, "data": runs, "message": "补算完成"})
+	})
+}
+
+// deadLetterID 解析路径中的死信ID
+func deadLetterID(c *gin.Context) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "死信ID错误"})
+		return 0, false
+	}
+	return id, true
+}
+
+// writeDeadLetterError 将死信服务的错误转换为响应
+func writeDeadLetterError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrDeadLetterNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "死信不存在"})
+	case errors.Is(err, service.ErrDeadLetterHandled):
+		c.JSON(http.StatusConflict, gin.H{"code": 409, "message": "死信已处理"})
+	case errors.Is(err, service.ErrBusDisabled):
+		c.JSON(http.StatusOK, gin.H{"code": 503, "message": "消息总线未启用"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"code": 500, "message": "操作失败，请稍后再试"})
+	}
 }
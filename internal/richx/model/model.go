@@ -1,17 +1,20 @@
 package model
 
 import (
-	"math/big"
 	"time"
+
+	"claimask/pkg/money"
 )
 
 // RichRewardLog 对应Java中的RichRewardLogDo
 type RichRewardLog struct {
-	ID           uint       `gorm:"primary_key"`
-	Address      string     `gorm:"column:address;type:varchar(64)"`
-	TotalReward  *big.Float `gorm:"column:total_reward;type:decimal(65,18)"`
-	UpdateReward time.Time  `gorm:"column:update_reward"`
-	Latest       *time.Time `gorm:"column:latest"` // 最后领取时间
+	ID           uint         `gorm:"primaryKey"`
+	Address      string       `gorm:"column:address;type:varchar(64);uniqueIndex:uk_address"`
+	TotalReward  money.Amount `gorm:"column:total_reward;type:decimal(9,1)"`
+	UpdateReward *time.Time   `gorm:"column:update_reward"` // 累计收益最新日期（领取日）
+	Latest       *time.Time   `gorm:"column:latest"`        // 最后领取日期（领取日）
+	LatestDetail *time.Time   `gorm:"column:latest_detail"` // 最后领取时间
+	FenceToken   int64        `gorm:"column:fence_token"`   // 最近一次写入时持有的领取锁栅栏令牌
 }
 
 // TableName 设置RichRewardLog表名
@@ -19,15 +22,85 @@ func (RichRewardLog) TableName() string {
 	return "rich_reward_log"
 }
 
+// NFTSnapshot 每日NFT持有快照，由快照任务在领取日结束时写入，每个NFT每天一条
+type NFTSnapshot struct {
+	ID           uint64    `gorm:"primaryKey"`
+	SnapshotDate time.Time `gorm:"column:snapshot_date;type:date"` // 快照所属领取日
+	Address      string    `gorm:"column:address;type:varchar(64)"`
+	NFTID        string    `gorm:"column:nft_id;type:varchar(64)"`
+	Rarity       string    `gorm:"column:rarity;type:varchar(32)"` // 稀有度，对应收益配置中的键
+}
+
+// TableName 设置NFTSnapshot表名
+func (NFTSnapshot) TableName() string {
+	return "rich_nft_snapshot"
+}
+
+// 收益累加任务状态
+const (
+	AccrualStatusRunning = 0 // 进行中
+	AccrualStatusDone    = 1 // 已完成
+)
+
+// RewardAccrualRun 每个领取日的收益累加任务
+type RewardAccrualRun struct {
+	ID          uint64       `gorm:"primaryKey" json:"id"`
+	AccrualDate time.Time    `gorm:"column:accrual_date;type:date;uniqueIndex:uk_accrual_date" json:"accrualDate"`
+	Status      int          `gorm:"column:status" json:"status"`   // 0:进行中 1:已完成
+	Holders     int          `gorm:"column:holders" json:"holders"` // 累加的地址数
+	Skipped     int          `gorm:"column:skipped" json:"skipped"` // 跳过的地址数
+	TotalAmount money.Amount `gorm:"column:total_amount;type:decimal(20,1)" json:"totalAmount"`
+	StartedAt   time.Time    `gorm:"column:started_at" json:"startedAt"`
+	FinishedAt  *time.Time   `gorm:"column:finished_at" json:"finishedAt"`
+}
+
+// TableName 设置RewardAccrualRun表名
+func (RewardAccrualRun) TableName() string {
+	return "rich_reward_accrual_run"
+}
+
+// 收益累加明细状态
+const (
+	AccrualLogAccrued = 0 // 已累加
+	AccrualLogSkipped = 1 // 已跳过，收益未累加
+)
+
+// RewardAccrualLog 每个地址每个领取日的收益累加明细，唯一键保证每天只累加一次
+type RewardAccrualLog struct {
+	ID          uint64       `gorm:"primaryKey"`
+	AccrualDate time.Time    `gorm:"column:accrual_date;type:date;uniqueIndex:uk_date_address"`
+	Address     string       `gorm:"column:address;type:varchar(64);uniqueIndex:uk_date_address"`
+	NFTCount    int          `gorm:"column:nft_count"`
+	Amount      money.Amount `gorm:"column:amount;type:decimal(9,1)"`
+	Status      int          `gorm:"column:status"`      // 0:已累加 1:已跳过
+	SkipReason  string       `gorm:"column:skip_reason"` // 跳过原因
+	CreatedAt   time.Time    `gorm:"column:created_at"`
+}
+
+// TableName 设置RewardAccrualLog表名
+func (RewardAccrualLog) TableName() string {
+	return "rich_reward_accrual_log"
+}
+
+// 领取订单状态
+const (
+	ClaimStatusCreated    = 1 // 创建
+	ClaimStatusProcessing = 2 // 处理中
+	ClaimStatusConfirmed  = 3 // 已确认
+	ClaimStatusFailed     = 4 // 链上发放失败，金额已退回收益表
+)
+
 // ClaimOrder 对应Java中的OrderDo
 type ClaimOrder struct {
-	ID        uint       `gorm:"primary_key"`
-	OrderID   int64      `gorm:"column:order_id;unique"`
-	Address   string     `gorm:"column:address;type:varchar(64)"`
-	Amount    *big.Float `gorm:"column:amount;type:decimal(65,18)"`
-	Status    int        `gorm:"column:status"` // 1:创建 2:处理中 3:已确认
-	CreatedAt time.Time  `gorm:"column:created_at"`
-	UpdatedAt time.Time  `gorm:"column:updated_at"`
+	ID         uint         `gorm:"primaryKey"`
+	OrderID    int64        `gorm:"column:order_id;unique"`
+	Address    string       `gorm:"column:address;type:varchar(64)"`
+	Amount     money.Amount `gorm:"column:amount;type:decimal(65,18)"`
+	Status     int          `gorm:"column:status"` // 1:创建 2:处理中 3:已确认 4:失败
+	TxID       string       `gorm:"column:txid;type:varchar(128)"`
+	FailReason string       `gorm:"column:fail_reason;type:varchar(255)"`
+	CreatedAt  time.Time    `gorm:"column:created_at"`
+	UpdatedAt  time.Time    `gorm:"column:updated_at"`
 }
 
 // TableName 设置ClaimOrder表名
@@ -35,6 +108,60 @@ func (ClaimOrder) TableName() string {
 	return "order"
 }
 
+// 外发消息状态
+const (
+	OutboxStatusPending = 0 // 待发送
+	OutboxStatusSent    = 1 // 已发送
+)
+
+// OutboxEvent 与业务数据在同一事务中写入的外发消息
+type OutboxEvent struct {
+	ID          uint64     `gorm:"primaryKey"`
+	Topic       string     `gorm:"column:topic;type:varchar(128)"`
+	MsgKey      string     `gorm:"column:msg_key;type:varchar(128)"`
+	Payload     string     `gorm:"column:payload;type:text"`
+	Status      int        `gorm:"column:status"` // 0:待发送 1:已发送
+	Attempts    int        `gorm:"column:attempts"`
+	LastError   string     `gorm:"column:last_error;type:varchar(512)"`
+	NextRetryAt time.Time  `gorm:"column:next_retry_at"`
+	SentAt      *time.Time `gorm:"column:sent_at"`
+	CreatedAt   time.Time  `gorm:"column:created_at"`
+}
+
+// TableName 设置OutboxEvent表名
+func (OutboxEvent) TableName() string {
+	return "claim_outbox"
+}
+
+// 死信状态
+const (
+	DeadLetterStatusPending   = 0 // 待处理
+	DeadLetterStatusRedriven  = 1 // 已重新投递
+	DeadLetterStatusDiscarded = 2 // 已丢弃
+)
+
+// DeadLetter 进入死信主题的消息存档，供管理接口查看和重新投递
+type DeadLetter struct {
+	ID            uint64     `gorm:"primaryKey" json:"id"`
+	Topic         string     `gorm:"column:topic;type:varchar(128);uniqueIndex:uk_topic_msg_id" json:"topic"`
+	MsgID         string     `gorm:"column:msg_id;type:varchar(64);uniqueIndex:uk_topic_msg_id" json:"msgId"`
+	MsgKey        string     `gorm:"column:msg_key;type:varchar(128)" json:"key"`
+	Payload       string     `gorm:"column:payload;type:text" json:"payload"`
+	OriginalTopic string     `gorm:"column:original_topic;type:varchar(128)" json:"originalTopic"`
+	ErrorClass    string     `gorm:"column:error_class;type:varchar(32)" json:"errorClass"`
+	ErrorMessage  string     `gorm:"column:error_message;type:varchar(1024)" json:"errorMessage"`
+	RetryCount    int        `gorm:"column:retry_count" json:"retryCount"`
+	FailedAt      *time.Time `gorm:"column:failed_at" json:"failedAt"`
+	Status        int        `gorm:"column:status" json:"status"` // 0:待处理 1:已重新投递 2:已丢弃
+	RedrivenAt    *time.Time `gorm:"column:redriven_at" json:"redrivenAt"`
+	CreatedAt     time.Time  `gorm:"column:created_at" json:"createdAt"`
+}
+
+// TableName 设置DeadLetter表名
+func (DeadLetter) TableName() string {
+	return "claim_dead_letter"
+}
+
 // ClaimRequest 领取请求
 type ClaimRequest struct {
 	Address string `json:"address" binding:"required"`
@@ -42,19 +169,21 @@ type ClaimRequest struct {
 
 // KafkaMessage Kafka消息结构
 type KafkaMessage struct {
-	OrderID   string     `json:"orderId"`
-	Address   string     `json:"address"`
-	Amount    *big.Float `json:"amount"`
-	Timestamp time.Time  `json:"timestamp"`
-	Sha       string     `json:"sha"`
+	OrderID   string       `json:"orderId"`
+	Address   string       `json:"address"`
+	Amount    money.Amount `json:"amount"`
+	Timestamp time.Time    `json:"timestamp"`
+	Sha       string       `json:"sha"`
 }
 
-// ChainClaimRequest 链上请求结构
+// ChainClaimRequest 链上请求结构，Signature 为 claimsig 规范序列化的 HMAC-SHA256 签名
 type ChainClaimRequest struct {
-	Address string     `json:"address"`
-	Amount  *big.Float `json:"amount"`
-	OrderID string     `json:"orderId"`
-	Sha     string     `json:"sha"`
+	Address   string       `json:"address"`
+	Amount    money.Amount `json:"amount"`
+	OrderID   string       `json:"orderId"`
+	Timestamp int64        `json:"timestamp"` // 签名时间，Unix秒
+	KeyID     string       `json:"keyId"`
+	Signature string       `json:"signature"`
 }
 
 // Response 通用响应结构
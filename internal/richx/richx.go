@@ -0,0 +1,184 @@
+// Package richx 富豪奖励模块
+// 包含领取接口、领取消息消费、死信管理、每日收益累加和处理中订单对账，
+// 复用主程序的数据库连接池、Redis 连接和消息总线，路由挂载在主程序的 gin 引擎上
+package richx
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"claimask/comm/initialize"
+	"claimask/comm/middleware"
+	"claimask/conf"
+	"claimask/internal/richx/api"
+	"claimask/internal/richx/service"
+	"claimask/pkg/claimsig"
+	"claimask/pkg/mq"
+)
+
+// Config 模块配置，对应配置文件的 richx 节
+type Config struct {
+	Timezone  string // 领取日所在的业务时区
+	ResetHour int    // 每日领取重置时刻
+	SignKey   string // 链上领取请求签名使用的密钥ID
+	SignKeys  map[string]string
+	Chain     service.ChainClientConfig
+	Reconcile service.ReconcileConfig
+	Accrual   service.AccrualConfig
+	Outbox    service.OutboxConfig
+	Consumer  service.ConsumerConfig
+}
+
+// NewConfig 由配置文件的 richx 节创建模块配置
+func NewConfig(c conf.RichxConfig) Config {
+	signKeys := make(map[string]string, len(c.ChainSign.Keys))
+	for id, secret := range c.ChainSign.Keys {
+		signKeys[id] = secret.Reveal()
+	}
+	return Config{
+		Timezone:  c.Timezone,
+		ResetHour: c.ResetHour,
+		SignKey:   c.ChainSign.ActiveKey,
+		SignKeys:  signKeys,
+		Chain: service.ChainClientConfig{
+			URL:         c.Chain.URL,
+			Timeout:     c.Chain.Timeout,
+			MaxAttempts: c.Chain.MaxAttempts,
+		},
+		Reconcile: service.ReconcileConfig{
+			Interval:   c.Reconcile.Interval,
+			StaleAfter: c.Reconcile.StaleAfter,
+		},
+		Accrual: service.AccrualConfig{
+			Rates:     c.Accrual.Rates,
+			StartDate: c.Accrual.StartDate,
+			Interval:  c.Accrual.Interval,
+		},
+		Outbox: service.OutboxConfig{
+			Interval:  c.Outbox.Interval,
+			BatchSize: c.Outbox.BatchSize,
+			Retention: c.Outbox.Retention,
+		},
+		Consumer: service.ConsumerConfig{
+			GroupID: c.Consumer.GroupID,
+		},
+	}
+}
+
+// Deps 模块依赖的共享资源，由 initialize.Server 创建和关闭
+type Deps struct {
+	DB         *gorm.DB
+	Redis      *redis.Client
+	Publisher  mq.Publisher
+	Subscriber mq.Subscriber
+	Logger     *log.Logger
+}
+
+// Module richx 模块
+type Module struct {
+	handler    *api.ClaimHandler
+	relay      *service.OutboxRelay
+	consumer   *service.ClaimConsumer
+	archiver   *service.DeadLetterArchiver
+	accrual    *service.RewardAccrual
+	reconciler *service.ClaimReconciler
+}
+
+// NewModule 创建富豪奖励模块
+func NewModule() *Module {
+	return &Module{}
+}
+
+// Name 模块名称
+func (m *Module) Name() string {
+	return "richx"
+}
+
+// Init 读取 richx 节的配置，使用共享的连接池和消息总线创建服务并注册路由
+func (m *Module) Init(s *initialize.Server) error {
+	if err := m.setup(NewConfig(s.Config().Richx), Deps{
+		DB:         s.DB(),
+		Redis:      s.Redis(),
+		Publisher:  s.Publisher(),
+		Subscriber: s.Subscriber(),
+		Logger:     zap.NewStdLog(zap.L().Named("richx")),
+	}); err != nil {
+		return err
+	}
+	m.RegisterRoutes(s.Engine, middleware.AdminAuth(s.Config().Admin.Token.Reveal()))
+	return nil
+}
+
+// setup 按配置创建各组件，配置错误时返回错误，不启动任何后台任务
+func (m *Module) setup(cfg Config, deps Deps) error {
+	if cfg.Chain.URL == "" {
+		return fmt.Errorf("richx: chain url not configured")
+	}
+	if cfg.Consumer.GroupID == "" {
+		cfg.Consumer.GroupID = "richx"
+	}
+
+	calendar, err := service.NewClaimCalendar(cfg.Timezone, cfg.ResetHour)
+	if err != nil {
+		return fmt.Errorf("richx: %w", err)
+	}
+	keyring, err := claimsig.NewKeyring(cfg.SignKey, cfg.SignKeys)
+	if err != nil {
+		return fmt.Errorf("richx: %w", err)
+	}
+
+	db := &service.DB{DB: deps.DB}
+	accrual, err := service.NewRewardAccrual(db, calendar, deps.Logger, cfg.Accrual)
+	if err != nil {
+		return fmt.Errorf("richx: %w", err)
+	}
+
+	chainClaimer := service.NewHTTPChainClaimer(cfg.Chain, keyring)
+	claimService := service.NewClaimService(db, service.NewRedisClient(deps.Redis), calendar, chainClaimer, deps.Logger)
+	relay := service.NewOutboxRelay(db, deps.Publisher, deps.Logger, cfg.Outbox)
+	deadLetters := service.NewDeadLetterService(db, deps.Publisher, cfg.Consumer.Topic)
+
+	m.handler = api.NewClaimHandler(claimService, relay, deadLetters, accrual)
+	m.relay = relay
+	m.consumer = service.NewClaimConsumer(cfg.Consumer, deps.Subscriber, deps.Publisher, claimService, deps.Logger)
+	m.archiver = service.NewDeadLetterArchiver(cfg.Consumer, deps.Subscriber, db, deps.Logger)
+	m.accrual = accrual
+	m.reconciler = service.NewClaimReconciler(claimService, deps.Logger, cfg.Reconcile)
+	return nil
+}
+
+// RegisterRoutes 注册领取接口和管理接口，管理接口使用 adminAuth 鉴权
+func (m *Module) RegisterRoutes(r gin.IRouter, adminAuth gin.HandlerFunc) {
+	m.handler.SetupRouter(r)
+
+	admin := r.Group("", adminAuth)
+	m.handler.SetupAdminRouter(admin)
+	m.handler.SetupAccrualRouter(admin)
+}
+
+// Start 启动外发消息投递、消息消费、死信存档、收益累加和订单对账
+func (m *Module) Start() error {
+	m.relay.Start()
+	m.consumer.Start()
+	m.archiver.Start()
+	m.accrual.Start()
+	m.reconciler.Start()
+	return nil
+}
+
+// Stop 停止所有后台任务，先停止产生新工作的任务，再等待消费者处理完当前消息
+func (m *Module) Stop(ctx context.Context) error {
+	return initialize.StopFunc(ctx, func() {
+		m.reconciler.Stop()
+		m.accrual.Stop()
+		m.relay.Stop()
+		m.consumer.Stop()
+		m.archiver.Stop()
+	})
+}
@@ -0,0 +1,114 @@
+package richx
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"claimask/conf"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testConfig = `
+richx:
+  enabled: true
+  timezone: "Asia/Shanghai"
+  resetHour: 4
+  chainSign:
+    activeKey: "k1"
+    keys:
+      k1: "0123456789abcdef-key-a"
+  chain:
+    url: "http://localhost:7777/claim"
+    timeout: 3s
+  reconcile:
+    staleAfter: 20m
+  accrual:
+    rates:
+      common: "0.1"
+`
+
+// loadTestConfig 从YAML字符串加载配置
+func loadTestConfig(t *testing.T, data string) Config {
+	t.Helper()
+	cfg, err := conf.Parse(data)
+	if err != nil {
+		t.Fatalf("read config failed: %v", err)
+	}
+	return NewConfig(cfg.Richx)
+}
+
+// 测试从配置文件读取模块配置
+func TestLoadConfig(t *testing.T) {
+	cfg := loadTestConfig(t, testConfig)
+
+	if cfg.Timezone != "Asia/Shanghai" || cfg.ResetHour != 4 {
+		t.Errorf("Unexpected calendar config %s %d", cfg.Timezone, cfg.ResetHour)
+	}
+	if cfg.SignKey != "k1" || cfg.SignKeys["k1"] != "0123456789abcdef-key-a" {
+		t.Errorf("Unexpected sign keys %s %v", cfg.SignKey, cfg.SignKeys)
+	}
+	if cfg.Chain.URL != "http://localhost:7777/claim" || cfg.Chain.Timeout != 3*time.Second {
+		t.Errorf("Unexpected chain config %+v", cfg.Chain)
+	}
+	if cfg.Reconcile.StaleAfter != 20*time.Minute {
+		t.Errorf("Unexpected reconcile config %+v", cfg.Reconcile)
+	}
+	if cfg.Accrual.Rates["common"] != "0.1" {
+		t.Errorf("Unexpected accrual rates %v", cfg.Accrual.Rates)
+	}
+}
+
+// 测试配置错误时模块创建失败
+func TestNewRejectsBadConfig(t *testing.T) {
+	cases := map[string]string{
+		"missing chain url": strings.Replace(testConfig, `url: "http://localhost:7777/claim"`, `url: ""`, 1),
+		"bad timezone":      strings.Replace(testConfig, "Asia/Shanghai", "Mars/Olympus", 1),
+		"unknown sign key":  strings.Replace(testConfig, `activeKey: "k1"`, `activeKey: "k2"`, 1),
+		"no accrual rates":  strings.Replace(testConfig, `common: "0.1"`, "", 1),
+	}
+	for name, data := range cases {
+		if err := NewModule().setup(loadTestConfig(t, data), Deps{}); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+// 测试领取接口和管理接口注册到主路由，管理接口经过鉴权中间件
+func TestRegisterRoutes(t *testing.T) {
+	module := NewModule()
+	if err := module.setup(loadTestConfig(t, testConfig), Deps{}); err != nil {
+		t.Fatalf("new module failed: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	var authCalls int
+	module.RegisterRoutes(router, func(c *gin.Context) {
+		authCalls++
+		c.AbortWithStatus(401)
+	})
+
+	routes := make(map[string]bool)
+	for _, r := range router.Routes() {
+		routes[r.Method+" "+r.Path] = true
+	}
+	for _, want := range []string{
+		"POST /rich/claim",
+		"GET /rich/claim",
+		"GET /rich/admin/dlq",
+		"POST /rich/admin/accrual/backfill",
+	} {
+		if !routes[want] {
+			t.Errorf("Expected route %s", want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/rich/admin/dlq", nil))
+	if w.Code != 401 || authCalls != 1 {
+		t.Errorf("Expected admin route to be guarded, got status %d with %d auth calls", w.Code, authCalls)
+	}
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"claimask/internal/richx/model"
+	"claimask/pkg/claimsig"
+)
+
+// 链上领取状态，由查询接口返回
+const (
+	ChainClaimPending   = "pending"   // 已受理，尚未上链
+	ChainClaimConfirmed = "confirmed" // 已发放
+	ChainClaimFailed    = "failed"    // 发放失败，不会再发放
+	ChainClaimNotFound  = "not_found" // 链上服务没有收到该订单
+)
+
+var (
+	// ErrChainRejected 链上服务拒绝了请求（4xx），原样重试不会成功
+	ErrChainRejected = errors.New("chain claim rejected")
+	// ErrChainUnavailable 重试次数用完后链上服务仍不可用
+	ErrChainUnavailable = errors.New("chain claim service unavailable")
+
+	// errChainNotFound 链上服务返回404
+	errChainNotFound = errors.New("chain claim not found")
+)
+
+// ChainClaimer 链上领取服务，发放成功后返回交易ID
+type ChainClaimer interface {
+	Claim(ctx context.Context, req model.ChainClaimRequest) (string, error)
+	Query(ctx context.Context, orderID string) (*ChainClaimResult, error)
+}
+
+// ChainClaimResult 链上领取查询结果
+type ChainClaimResult struct {
+	Status string `json:"status"`
+	TxID   string `json:"txid"`
+	Reason string `json:"reason"`
+}
+
+// ChainClientConfig 链上领取服务客户端配置
+type ChainClientConfig struct {
+	URL            string        // 领取接口地址，查询接口为 URL/{orderId}
+	Timeout        time.Duration // 单次请求超时
+	MaxAttempts    int           // 单次调用的最大尝试次数
+	RetryBaseDelay time.Duration // 重试退避的初始间隔，每次翻倍
+	RetryMaxDelay  time.Duration // 重试退避的最大间隔
+}
+
+// HTTPChainClaimer 通过HTTP调用链上领取服务，请求使用密钥环中的当前密钥签名
+// 领取请求以订单号作为 Idempotency-Key，链上服务对同一订单只发放一次，因此超时等结果未知的失败可以安全重试
+type HTTPChainClaimer struct {
+	cfg        ChainClientConfig
+	keyring    *claimsig.Keyring
+	httpClient *http.Client
+}
+
+// NewHTTPChainClaimer 创建链上领取服务客户端
+func NewHTTPChainClaimer(cfg ChainClientConfig, keyring *claimsig.Keyring) *HTTPChainClaimer {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 15 * time.Second
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 3
+	}
+	if cfg.RetryBaseDelay <= 0 {
+		cfg.RetryBaseDelay = 500 * time.Millisecond
+	}
+	if cfg.RetryMaxDelay <= 0 {
+		cfg.RetryMaxDelay = 10 * time.Second
+	}
+	return &HTTPChainClaimer{
+		cfg:        cfg,
+		keyring:    keyring,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// chainResponse 链上服务的通用响应
+type chainResponse struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Claim 签名并提交链上领取请求，每次尝试重新签名，时间戳随之更新
+func (c *HTTPChainClaimer) Claim(ctx context.Context, req model.ChainClaimRequest) (string, error) {
+	var txid string
+	err := c.retry(ctx, func() error {
+		if err := SignChainClaim(c.keyring, &req, time.Now()); err != nil {
+			return fmt.Errorf("%w: sign failed: %v", ErrChainRejected, err)
+		}
+		body, err := json.Marshal(req)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrChainRejected, err)
+		}
+
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.URL, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrChainRejected, err)
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("Idempotency-Key", req.OrderID)
+
+		var data struct {
+			TxID string `json:"txid"`
+		}
+		if err := c.do(httpReq, &data); err != nil {
+			return err
+		}
+		if data.TxID == "" {
+			return errors.New("chain response missing txid")
+		}
+		txid = data.TxID
+		return nil
+	})
+	return txid, err
+}
+
+// Query 查询订单在链上服务的发放状态
+func (c *HTTPChainClaimer) Query(ctx context.Context, orderID string) (*ChainClaimResult, error) {
+	var result ChainClaimResult
+	err := c.retry(ctx, func() error {
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet,
+			strings.TrimRight(c.cfg.URL, "/")+"/"+url.PathEscape(orderID), nil)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrChainRejected, err)
+		}
+
+		err = c.do(httpReq, &result)
+		if errors.Is(err, errChainNotFound) {
+			result = ChainClaimResult{Status: ChainClaimNotFound}
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	switch result.Status {
+	case ChainClaimPending, ChainClaimConfirmed, ChainClaimFailed, ChainClaimNotFound:
+		return &result, nil
+	default:
+		return nil, fmt.Errorf("unknown chain claim status %q for order %s", result.Status, orderID)
+	}
+}
+
+// do 发送请求并解析响应数据，4xx 包装为 ErrChainRejected，404 返回 errChainNotFound
+func (c *HTTPChainClaimer) do(httpReq *http.Request, data interface{}) error {
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(httpResp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	var resp chainResponse
+	decodeErr := json.Unmarshal(raw, &resp)
+
+	switch {
+	case httpResp.StatusCode == http.StatusNotFound:
+		return errChainNotFound
+	case httpResp.StatusCode == http.StatusTooManyRequests || httpResp.StatusCode >= 500:
+		return fmt.Errorf("chain service status %d: %s", httpResp.StatusCode, resp.Message)
+	case httpResp.StatusCode >= 400:
+		return fmt.Errorf("%w: status %d, code %d, %s", ErrChainRejected, httpResp.StatusCode, resp.Code, resp.Message)
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("decode chain response failed: %w", decodeErr)
+	}
+	if resp.Code != 200 {
+		return fmt.Errorf("%w: code %d, %s", ErrChainRejected, resp.Code, resp.Message)
+	}
+	if err := json.Unmarshal(resp.Data, data); err != nil {
+		return fmt.Errorf("decode chain response data failed: %w", err)
+	}
+	return nil
+}
+
+// retry 按指数退避重试，ErrChainRejected 和上下文取消不重试
+func (c *HTTPChainClaimer) retry(ctx context.Context, fn func() error) error {
+	delay := c.cfg.RetryBaseDelay
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || errors.Is(err, ErrChainRejected) || ctx.Err() != nil {
+			return err
+		}
+		if attempt >= c.cfg.MaxAttempts {
+			return fmt.Errorf("%w after %d attempts: %v", ErrChainUnavailable, attempt, err)
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if delay *= 2; delay > c.cfg.RetryMaxDelay {
+			delay = c.cfg.RetryMaxDelay
+		}
+	}
+}
+
+// SignChainClaim 以now为时间戳签名链上领取请求
+func SignChainClaim(keyring *claimsig.Keyring, req *model.ChainClaimRequest, now time.Time) error {
+	req.Timestamp = now.Unix()
+	keyID, signature, err := keyring.Sign(chainClaim(req))
+	if err != nil {
+		return err
+	}
+	req.KeyID, req.Signature = keyID, signature
+	return nil
+}
+
+// VerifyChainClaim 校验链上领取请求的签名，供链上领取服务使用
+func VerifyChainClaim(keyring *claimsig.Keyring, req *model.ChainClaimRequest, now time.Time, maxSkew time.Duration) error {
+	return keyring.Verify(chainClaim(req), req.KeyID, req.Signature, now, maxSkew)
+}
+
+// chainClaim 取出参与签名的字段
+func chainClaim(req *model.ChainClaimRequest) claimsig.Claim {
+	return claimsig.Claim{
+		Address:   req.Address,
+		Amount:    req.Amount,
+		OrderID:   req.OrderID,
+		Timestamp: req.Timestamp,
+	}
+}
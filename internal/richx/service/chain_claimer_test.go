@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"claimask/internal/richx/model"
+	"claimask/pkg/claimsig"
+	"claimask/pkg/money"
+)
+
+// 测试链上领取请求携带可被接收方校验的签名
+func TestHTTPChainClaimerSignsRequest(t *testing.T) {
+	keys := map[string]string{"k1": "0123456789abcdef-key-a"}
+	signer, _ := claimsig.NewKeyring("k1", keys)
+	verifier, _ := claimsig.NewKeyring("", keys)
+
+	var verifyErr error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req model.ChainClaimRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			verifyErr = err
+		} else {
+			verifyErr = VerifyChainClaim(verifier, &req, time.Now(), time.Minute)
+		}
+		w.Write([]byte(`{"code":200,"data":{"txid":"tx1"}}`))
+	}))
+	defer server.Close()
+
+	claimer := NewHTTPChainClaimer(ChainClientConfig{URL: server.URL}, signer)
+	txid, err := claimer.Claim(context.Background(), model.ChainClaimRequest{
+		Address: "DAddr",
+		Amount:  money.MustParseDoge("1.5"),
+		OrderID: "42",
+	})
+	if err != nil || txid != "tx1" {
+		t.Fatalf("claim failed: %s %v", txid, err)
+	}
+	if verifyErr != nil {
+		t.Errorf("receiver verify failed: %v", verifyErr)
+	}
+}
+
+// 测试金额被篡改后校验失败
+func TestVerifyChainClaimTampered(t *testing.T) {
+	keyring, _ := claimsig.NewKeyring("k1", map[string]string{"k1": "0123456789abcdef-key-a"})
+	now := time.Now()
+
+	req := model.ChainClaimRequest{Address: "DAddr", Amount: money.MustParseDoge("1.5"), OrderID: "42"}
+	if err := SignChainClaim(keyring, &req, now); err != nil {
+		t.Fatal(err)
+	}
+	req.Amount = money.MustParseDoge("150")
+	if err := VerifyChainClaim(keyring, &req, now, time.Minute); !errors.Is(err, claimsig.ErrBadSignature) {
+		t.Errorf("Expected ErrBadSignature, got %v", err)
+	}
+}
+
+// newTestChainClaimer 创建指向测试服务的客户端，重试间隔缩短
+func newTestChainClaimer(t *testing.T, handler http.HandlerFunc) *HTTPChainClaimer {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	keyring, _ := claimsig.NewKeyring("k1", map[string]string{"k1": "0123456789abcdef-key-a"})
+	return NewHTTPChainClaimer(ChainClientConfig{
+		URL:            server.URL + "/claim",
+		MaxAttempts:    3,
+		RetryBaseDelay: time.Millisecond,
+	}, keyring)
+}
+
+// 测试暂时性失败按订单号幂等重试，最终成功
+func TestChainClaimRetriesWithIdempotencyKey(t *testing.T) {
+	var attempts int32
+	claimer := newTestChainClaimer(t, func(w http.ResponseWriter, r *http.Request) {
+		if key := r.Header.Get("Idempotency-Key"); key != "42" {
+			t.Errorf("Expected Idempotency-Key 42, got %q", key)
+		}
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"code":200,"data":{"txid":"tx1"}}`))
+	})
+
+	txid, err := claimer.Claim(context.Background(), model.ChainClaimRequest{Address: "DAddr", Amount: 1, OrderID: "42"})
+	if err != nil || txid != "tx1" {
+		t.Fatalf("claim failed: %s %v", txid, err)
+	}
+	if atomic.LoadInt32(&attempts) != 3 {
+		t.Errorf("Expected 3 attempts, got %d", atomic.LoadInt32(&attempts))
+	}
+}
+
+// 测试4xx拒绝不重试，重试用完后返回 ErrChainUnavailable
+func TestChainClaimErrors(t *testing.T) {
+	var attempts int32
+	rejecting := newTestChainClaimer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"code":401,"message":"bad signature"}`))
+	})
+	if _, err := rejecting.Claim(context.Background(), model.ChainClaimRequest{OrderID: "1"}); !errors.Is(err, ErrChainRejected) {
+		t.Errorf("Expected ErrChainRejected, got %v", err)
+	}
+	if atomic.LoadInt32(&attempts) != 1 {
+		t.Errorf("Expected no retry on rejection, got %d attempts", atomic.LoadInt32(&attempts))
+	}
+
+	failing := newTestChainClaimer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	if _, err := failing.Claim(context.Background(), model.ChainClaimRequest{OrderID: "1"}); !errors.Is(err, ErrChainUnavailable) {
+		t.Errorf("Expected ErrChainUnavailable, got %v", err)
+	}
+
+	missingTxID := newTestChainClaimer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":200,"data":{}}`))
+	})
+	if _, err := missingTxID.Claim(context.Background(), model.ChainClaimRequest{OrderID: "1"}); err == nil {
+		t.Error("Expected error for response without txid")
+	}
+}
+
+// 测试查询订单发放状态，404视为链上服务未收到
+func TestChainClaimQuery(t *testing.T) {
+	claimer := newTestChainClaimer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Expected GET, got %s", r.Method)
+		}
+		switch r.URL.Path {
+		case "/claim/1":
+			w.Write([]byte(`{"code":200,"data":{"status":"confirmed","txid":"tx1"}}`))
+		case "/claim/2":
+			w.Write([]byte(`{"code":200,"data":{"status":"failed","reason":"insufficient funds"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	cases := map[string]ChainClaimResult{
+		"1": {Status: ChainClaimConfirmed, TxID: "tx1"},
+		"2": {Status: ChainClaimFailed, Reason: "insufficient funds"},
+		"3": {Status: ChainClaimNotFound},
+	}
+	for orderID, want := range cases {
+		got, err := claimer.Query(context.Background(), orderID)
+		if err != nil || *got != want {
+			t.Errorf("order %s: expected %+v, got %+v (%v)", orderID, want, got, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"fmt"
+	"time"
+	_ "time/tzdata" // 内置时区数据，容器中没有 zoneinfo 时也能加载业务时区
+)
+
+// dateLayout date 列的读写格式
+const dateLayout = "2006-01-02"
+
+// ClaimCalendar 领取日历，按业务时区和每日重置时刻划分领取日
+// 例如时区为 Asia/Shanghai、重置时刻为4时，则北京时间每天4点到次日4点属于同一个领取日
+//
+// latest、update_reward 等 date 列只保存日期，写入时使用 DateString 生成的字符串，
+// 读出时使用 FromDate 只取年月日，避免数据库连接时区（loc=Local）参与换算
+type ClaimCalendar struct {
+	loc       *time.Location
+	resetHour int
+}
+
+// NewClaimCalendar 创建领取日历，timezone为空时使用UTC，resetHour取值0-23
+func NewClaimCalendar(timezone string, resetHour int) (*ClaimCalendar, error) {
+	if resetHour < 0 || resetHour > 23 {
+		return nil, fmt.Errorf("invalid claim reset hour %d", resetHour)
+	}
+	loc := time.UTC
+	if timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(timezone); err != nil {
+			return nil, fmt.Errorf("load claim timezone %q failed: %w", timezone, err)
+		}
+	}
+	return &ClaimCalendar{loc: loc, resetHour: resetHour}, nil
+}
+
+// Location 业务时区
+func (c *ClaimCalendar) Location() *time.Location {
+	return c.loc
+}
+
+// Day 返回t所在的领取日，以业务时区当天0点表示
+func (c *ClaimCalendar) Day(t time.Time) time.Time {
+	local := t.In(c.loc)
+	y, m, d := local.Date()
+	if local.Hour() < c.resetHour {
+		d--
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, c.loc)
+}
+
+// DateString 返回t所在领取日的 date 列取值
+func (c *ClaimCalendar) DateString(t time.Time) string {
+	return c.Day(t).Format(dateLayout)
+}
+
+// FromDate 将 date 列读出的值解释为领取日，只使用其年月日
+func (c *ClaimCalendar) FromDate(d time.Time) time.Time {
+	y, m, day := d.Date()
+	return time.Date(y, m, day, 0, 0, 0, 0, c.loc)
+}
+
+// SameDay 判断两个时刻是否属于同一个领取日
+func (c *ClaimCalendar) SameDay(a, b time.Time) bool {
+	return c.Day(a).Equal(c.Day(b))
+}
+
+// NextReset 返回t之后的下一个重置时刻
+func (c *ClaimCalendar) NextReset(t time.Time) time.Time {
+	y, m, d := c.Day(t).Date()
+	return time.Date(y, m, d+1, c.resetHour, 0, 0, 0, c.loc)
+}
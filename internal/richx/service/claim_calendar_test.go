@@ -0,0 +1,97 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+// 测试不同时区和重置时刻下的领取日划分
+func TestClaimCalendarDay(t *testing.T) {
+	cases := []struct {
+		name      string
+		timezone  string
+		resetHour int
+		at        time.Time
+		want      string
+	}{
+		{"utc midnight", "", 0, time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC), "2024-05-20"},
+		{"utc before midnight", "UTC", 0, time.Date(2024, 5, 20, 23, 59, 59, 0, time.UTC), "2024-05-20"},
+		// 北京时间比UTC早8小时，UTC 16:00 已是北京时间次日0点
+		{"shanghai after local midnight", "Asia/Shanghai", 0, time.Date(2024, 5, 20, 16, 0, 0, 0, time.UTC), "2024-05-21"},
+		{"shanghai before local midnight", "Asia/Shanghai", 0, time.Date(2024, 5, 20, 15, 59, 59, 0, time.UTC), "2024-05-20"},
+		// 重置时刻为4点，北京时间3:59仍属于前一个领取日
+		{"shanghai before reset hour", "Asia/Shanghai", 4, time.Date(2024, 5, 20, 19, 59, 0, 0, time.UTC), "2024-05-20"},
+		{"shanghai at reset hour", "Asia/Shanghai", 4, time.Date(2024, 5, 20, 20, 0, 0, 0, time.UTC), "2024-05-21"},
+		// 西五区（无夏令时），UTC 04:59 仍是前一天
+		{"fixed negative offset", "Etc/GMT+5", 0, time.Date(2024, 5, 20, 4, 59, 0, 0, time.UTC), "2024-05-19"},
+		{"fixed negative offset after midnight", "Etc/GMT+5", 0, time.Date(2024, 5, 20, 5, 0, 0, 0, time.UTC), "2024-05-20"},
+		// 跨月、跨年
+		{"reset hour crosses year", "Asia/Shanghai", 6, time.Date(2024, 12, 31, 21, 0, 0, 0, time.UTC), "2024-12-31"},
+		{"offset crosses month", "Asia/Tokyo", 0, time.Date(2024, 2, 29, 15, 0, 0, 0, time.UTC), "2024-03-01"},
+	}
+
+	for _, c := range cases {
+		calendar, err := NewClaimCalendar(c.timezone, c.resetHour)
+		if err != nil {
+			t.Fatalf("%s: create calendar failed: %v", c.name, err)
+		}
+		if got := calendar.DateString(c.at); got != c.want {
+			t.Errorf("%s: expected %s, got %s", c.name, c.want, got)
+		}
+	}
+}
+
+// 测试 date 列读出值的解释与数据库连接时区无关
+func TestClaimCalendarFromDate(t *testing.T) {
+	calendar, err := NewClaimCalendar("Asia/Shanghai", 0)
+	if err != nil {
+		t.Fatalf("create calendar failed: %v", err)
+	}
+
+	now := time.Date(2024, 5, 20, 16, 30, 0, 0, time.UTC) // 北京时间 05-21 00:30
+	for _, loc := range []*time.Location{time.UTC, time.FixedZone("minus10", -10*3600), time.FixedZone("plus14", 14*3600)} {
+		stored := time.Date(2024, 5, 21, 0, 0, 0, 0, loc)
+		if !calendar.FromDate(stored).Equal(calendar.Day(now)) {
+			t.Errorf("loc %s: expected stored date to be the current claim day", loc)
+		}
+	}
+}
+
+// 测试同一领取日判断和下一个重置时刻
+func TestClaimCalendarSameDayAndNextReset(t *testing.T) {
+	calendar, err := NewClaimCalendar("Asia/Shanghai", 4)
+	if err != nil {
+		t.Fatalf("create calendar failed: %v", err)
+	}
+	shanghai := calendar.Location()
+
+	a := time.Date(2024, 5, 20, 5, 0, 0, 0, shanghai)
+	b := time.Date(2024, 5, 21, 3, 59, 0, 0, shanghai)
+	c := time.Date(2024, 5, 21, 4, 0, 0, 0, shanghai)
+	if !calendar.SameDay(a, b) {
+		t.Error("Expected 05-20 05:00 and 05-21 03:59 to be the same claim day")
+	}
+	if calendar.SameDay(b, c) {
+		t.Error("Expected 05-21 04:00 to start a new claim day")
+	}
+
+	if got := calendar.NextReset(a); !got.Equal(c) {
+		t.Errorf("Expected next reset %s, got %s", c, got)
+	}
+	if got := calendar.NextReset(c); !got.Equal(c.Add(24 * time.Hour)) {
+		t.Errorf("Expected next reset %s, got %s", c.Add(24*time.Hour), got)
+	}
+}
+
+// 测试非法配置
+func TestNewClaimCalendarInvalid(t *testing.T) {
+	if _, err := NewClaimCalendar("Mars/Olympus", 0); err == nil {
+		t.Error("Expected error for unknown timezone")
+	}
+	if _, err := NewClaimCalendar("UTC", 24); err == nil {
+		t.Error("Expected error for reset hour 24")
+	}
+	if _, err := NewClaimCalendar("UTC", -1); err == nil {
+		t.Error("Expected error for negative reset hour")
+	}
+}
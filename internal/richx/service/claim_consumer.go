@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"claimask/internal/richx/model"
+	"claimask/pkg/mq"
+)
+
+// 失败消息携带的消息头
+const (
+	HeaderRetryCount    = "x-retry-count"    // 已重试次数
+	HeaderOriginalTopic = "x-original-topic" // 最初投递的主题
+	HeaderErrorClass    = "x-error-class"    // 错误分类
+	HeaderError         = "x-error"          // 错误信息
+	HeaderFailedAt      = "x-failed-at"      // 失败时间（RFC3339）
+	HeaderNotBefore     = "x-not-before"     // 最早可重试时间（Unix毫秒）
+	HeaderRedrivenFrom  = "x-redriven-from"  // 从死信重新投递时的死信ID
+)
+
+// 错误分类
+const (
+	ErrorClassInvalid   = "invalid"   // 消息内容非法
+	ErrorClassNotFound  = "not_found" // 订单不存在
+	ErrorClassRejected  = "rejected"  // 链上服务拒绝了请求，如签名校验失败
+	ErrorClassTransient = "transient" // 暂时性错误，如链上服务不可用
+)
+
+// 发布失败时的原地重试退避
+const (
+	publishRetryBaseDelay = time.Second
+	publishRetryMaxDelay  = time.Minute
+	deadLetterSuffix      = "-dlq"
+)
+
+// retryStages 重试阶梯，暂时性失败的消息依次进入各阶梯主题，全部失败后进入死信主题
+var retryStages = []struct {
+	suffix string
+	delay  time.Duration
+}{
+	{"-retry-1m", time.Minute},
+	{"-retry-10m", 10 * time.Minute},
+	{"-retry-1h", time.Hour},
+}
+
+// RetryTopics 返回主题对应的重试阶梯主题
+func RetryTopics(topic string) []string {
+	topics := make([]string, len(retryStages))
+	for i, stage := range retryStages {
+		topics[i] = topic + stage.suffix
+	}
+	return topics
+}
+
+// DeadLetterTopic 返回主题对应的死信主题
+func DeadLetterTopic(topic string) string {
+	return topic + deadLetterSuffix
+}
+
+// subscription 一个消费者组订阅，负责在后台运行订阅循环以及停止
+type subscription struct {
+	subscriber mq.Subscriber
+	group      string
+	topics     []string
+	handler    mq.Handler
+	ctx        context.Context
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+// newSubscription 创建订阅，处理函数返回nil后消息才会被确认
+func newSubscription(subscriber mq.Subscriber, group string, topics []string, handler mq.Handler) *subscription {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &subscription{
+		subscriber: subscriber,
+		group:      group,
+		topics:     topics,
+		handler:    handler,
+		ctx:        ctx,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+}
+
+// start 在后台运行订阅循环
+func (s *subscription) start(logger *log.Logger) {
+	go func() {
+		defer close(s.done)
+		if err := s.subscriber.Subscribe(s.ctx, s.group, s.topics, s.handler); err != nil {
+			logger.Printf("订阅 %v 失败: %v", s.topics, err)
+		}
+	}()
+}
+
+// stop 停止订阅并等待正在处理的消息完成
+func (s *subscription) stop() {
+	s.cancel()
+	<-s.done
+}
+
+// ClaimConsumer 领取消息消费者
+// 同时消费主主题和各重试阶梯主题，只有在订单状态写入数据库或消息转入下一阶梯后才确认消息
+type ClaimConsumer struct {
+	*subscription
+	topic     string
+	service   *ClaimService
+	publisher mq.Publisher
+	logger    *log.Logger
+}
+
+// NewClaimConsumer 创建领取消息消费者
+func NewClaimConsumer(cfg ConsumerConfig, subscriber mq.Subscriber, publisher mq.Publisher, service *ClaimService, logger *log.Logger) *ClaimConsumer {
+	topic := cfg.Topic
+	if topic == "" {
+		topic = claimTopic
+	}
+
+	c := &ClaimConsumer{
+		topic:     topic,
+		service:   service,
+		publisher: publisher,
+		logger:    logger,
+	}
+	c.subscription = newSubscription(subscriber, cfg.GroupID, append([]string{topic}, RetryTopics(topic)...), c.handle)
+	return c
+}
+
+// Start 启动消费
+func (c *ClaimConsumer) Start() {
+	c.start(c.logger)
+}
+
+// Stop 停止消费
+func (c *ClaimConsumer) Stop() {
+	c.stop()
+}
+
+// handle 处理一条消息，返回错误表示消息未处理完成，不确认
+// 非法消息和订单不存在直接进入死信主题，暂时性错误进入下一重试阶梯
+func (c *ClaimConsumer) handle(ctx context.Context, msg *mq.Message) error {
+	// 重试阶梯中的消息需等到最早可重试时间，等待期间消息不确认，Redis 驱动定期刷新其空闲时长，不会被其他实例接管
+	if notBefore, err := strconv.ParseInt(msg.Headers[HeaderNotBefore], 10, 64); err == nil {
+		if wait := time.Until(time.UnixMilli(notBefore)); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+
+	var message model.KafkaMessage
+	if err := json.Unmarshal(msg.Value, &message); err != nil {
+		return c.forward(ctx, msg, DeadLetterTopic(c.topic), ErrorClassInvalid, err, 0)
+	}
+
+	err := c.service.ProcessClaim(ctx, message)
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	switch {
+	case errors.Is(err, ErrInvalidMessage):
+		return c.forward(ctx, msg, DeadLetterTopic(c.topic), ErrorClassInvalid, err, 0)
+	case errors.Is(err, ErrClaimOrderNotFound):
+		return c.forward(ctx, msg, DeadLetterTopic(c.topic), ErrorClassNotFound, err, 0)
+	case errors.Is(err, ErrChainRejected):
+		return c.forward(ctx, msg, DeadLetterTopic(c.topic), ErrorClassRejected, err, 0)
+	}
+
+	retryCount, _ := strconv.Atoi(msg.Headers[HeaderRetryCount])
+	if retryCount >= len(retryStages) {
+		return c.forward(ctx, msg, DeadLetterTopic(c.topic), ErrorClassTransient, err, 0)
+	}
+	stage := retryStages[retryCount]
+	c.logger.Printf("处理订单 %s 失败，%s 后重试: %v", message.OrderID, stage.delay, err)
+	return c.forward(ctx, msg, c.topic+stage.suffix, ErrorClassTransient, err, stage.delay)
+}
+
+// forward 将失败消息连同错误信息转发到重试或死信主题
+// 转发失败时原地退避重试，直到成功或订阅停止，保证未转发成功的消息不会被确认
+func (c *ClaimConsumer) forward(ctx context.Context, msg *mq.Message, topic, errorClass string, cause error, delay time.Duration) error {
+	retryCount, _ := strconv.Atoi(msg.Headers[HeaderRetryCount])
+	originalTopic := msg.Headers[HeaderOriginalTopic]
+	if originalTopic == "" {
+		originalTopic = msg.Topic
+	}
+
+	now := time.Now()
+	out := map[string]string{
+		HeaderRetryCount:    strconv.Itoa(retryCount + 1),
+		HeaderOriginalTopic: originalTopic,
+		HeaderErrorClass:    errorClass,
+		HeaderError:         truncate(cause.Error(), 1024),
+		HeaderFailedAt:      now.Format(time.RFC3339),
+	}
+	if delay > 0 {
+		out[HeaderNotBefore] = strconv.FormatInt(now.Add(delay).UnixMilli(), 10)
+	}
+
+	if topic == DeadLetterTopic(c.topic) {
+		c.logger.Printf("消息进入死信主题 topic=%s id=%s class=%s: %v", msg.Topic, msg.ID, errorClass, cause)
+	}
+
+	wait := publishRetryBaseDelay
+	for {
+		err := c.publisher.Publish(ctx, &mq.Message{Topic: topic, Key: msg.Key, Value: msg.Value, Headers: out})
+		if err == nil {
+			return nil
+		}
+		c.logger.Printf("转发消息到 %s 失败，%s 后重试: %v", topic, wait, err)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if wait *= 2; wait > publishRetryMaxDelay {
+			wait = publishRetryMaxDelay
+		}
+	}
+}
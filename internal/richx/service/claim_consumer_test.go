@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"claimask/internal/richx/model"
+	"claimask/pkg/money"
+	"claimask/pkg/mq"
+)
+
+// fakeChainClaimer 链上领取服务，claimErr 不为nil时领取报错，results 和 queryErrs 为按订单号查询的结果
+type fakeChainClaimer struct {
+	claims    []model.ChainClaimRequest
+	claimErr  error
+	results   map[string]*ChainClaimResult
+	queryErrs map[string]error
+}
+
+func (c *fakeChainClaimer) Claim(ctx context.Context, req model.ChainClaimRequest) (string, error) {
+	c.claims = append(c.claims, req)
+	if c.claimErr != nil {
+		return "", c.claimErr
+	}
+	return "tx-" + req.OrderID, nil
+}
+
+func (c *fakeChainClaimer) Query(ctx context.Context, orderID string) (*ChainClaimResult, error) {
+	if err := c.queryErrs[orderID]; err != nil {
+		return nil, err
+	}
+	if result, ok := c.results[orderID]; ok {
+		return result, nil
+	}
+	return &ChainClaimResult{Status: ChainClaimNotFound}, nil
+}
+
+// newTestClaimService 基于内存 sqlite 和 chain 的领取服务，不连接Redis
+func newTestClaimService(t *testing.T, chain ChainClaimer) (*ClaimService, *DB) {
+	t.Helper()
+	db := newTestDB(t)
+	return NewClaimService(db, NewRedisClient(nil), nil, chain, testLogger()), db
+}
+
+// newTestClaim 创建状态为 status 的领取订单，返回对应的领取消息
+func newTestClaim(t *testing.T, db *DB, orderID int64, status int) model.KafkaMessage {
+	t.Helper()
+	now := time.Now()
+	amount := money.MustParseDoge("1.5")
+	order := model.ClaimOrder{OrderID: orderID, Address: "DAddr", Amount: amount, Status: status, CreatedAt: now, UpdatedAt: now}
+	if err := db.Create(&order).Error; err != nil {
+		t.Fatal(err)
+	}
+	return model.KafkaMessage{
+		OrderID:   strconv.FormatInt(orderID, 10),
+		Address:   order.Address,
+		Amount:    amount,
+		Timestamp: now,
+		Sha:       CalculateSha256(order.Address + amount.String() + strconv.FormatInt(now.Unix(), 10)),
+	}
+}
+
+// receive 读取主题中的第一条消息
+func receive(t *testing.T, bus *mq.MemoryBus, topic string) *mq.Message {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	got := make(chan *mq.Message, 1)
+	go bus.Subscribe(ctx, "test-"+topic, []string{topic}, func(ctx context.Context, msg *mq.Message) error {
+		select {
+		case got <- msg:
+		default:
+		}
+		return nil
+	})
+	select {
+	case msg := <-got:
+		return msg
+	case <-ctx.Done():
+		t.Fatalf("No message on %s", topic)
+		return nil
+	}
+}
+
+// 测试处理失败的消息按错误分类进入重试阶梯或死信主题
+func TestClaimConsumerRouting(t *testing.T) {
+	transient := fmt.Errorf("%w after 3 attempts: timeout", ErrChainUnavailable)
+	cases := []struct {
+		name       string
+		status     int    // 订单状态，0为不创建订单
+		tamper     bool   // 篡改消息签名
+		raw        string // 不为空时作为消息内容
+		retryCount int
+		claimErr   error
+		topic      string // 转发到的主题，为空表示处理成功不转发
+		class      string
+	}{
+		{"confirmed", model.ClaimStatusCreated, false, "", 0, nil, "", ""},
+		{"bad json", 0, false, "{", 0, nil, claimTopic + "-dlq", ErrorClassInvalid},
+		{"bad sha", model.ClaimStatusCreated, true, "", 0, nil, claimTopic + "-dlq", ErrorClassInvalid},
+		{"order not found", 0, false, "", 0, nil, claimTopic + "-dlq", ErrorClassNotFound},
+		{"chain rejected", model.ClaimStatusCreated, false, "", 0, fmt.Errorf("%w: bad signature", ErrChainRejected), claimTopic + "-dlq", ErrorClassRejected},
+		{"first transient failure", model.ClaimStatusCreated, false, "", 0, transient, claimTopic + "-retry-1m", ErrorClassTransient},
+		{"second transient failure", model.ClaimStatusProcessing, false, "", 1, transient, claimTopic + "-retry-10m", ErrorClassTransient},
+		{"retries exhausted", model.ClaimStatusProcessing, false, "", 3, transient, claimTopic + "-dlq", ErrorClassTransient},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			chain := &fakeChainClaimer{claimErr: c.claimErr}
+			service, db := newTestClaimService(t, chain)
+			bus := mq.NewMemoryBus(0)
+			defer bus.Close()
+			consumer := NewClaimConsumer(ConsumerConfig{GroupID: "richx"}, bus, bus, service, testLogger())
+
+			message := model.KafkaMessage{OrderID: "1", Address: "DAddr", Amount: money.MustParseDoge("1.5"), Timestamp: time.Now()}
+			message.Sha = CalculateSha256(message.Address + message.Amount.String() + strconv.FormatInt(message.Timestamp.Unix(), 10))
+			if c.status != 0 {
+				message = newTestClaim(t, db, 1, c.status)
+			}
+			if c.tamper {
+				message.Sha = CalculateSha256("tampered")
+			}
+			value, _ := json.Marshal(message)
+			if c.raw != "" {
+				value = []byte(c.raw)
+			}
+			msg := &mq.Message{ID: "m1", Topic: claimTopic, Key: "DAddr", Value: value, Headers: map[string]string{}}
+			if c.retryCount > 0 {
+				msg.Topic = RetryTopics(claimTopic)[c.retryCount-1]
+				msg.Headers[HeaderRetryCount] = strconv.Itoa(c.retryCount)
+				msg.Headers[HeaderOriginalTopic] = claimTopic
+			}
+
+			before := time.Now()
+			if err := consumer.handle(context.Background(), msg); err != nil {
+				t.Fatalf("Expected message handled, got %v", err)
+			}
+
+			if c.topic == "" {
+				var order model.ClaimOrder
+				db.Where("order_id = ?", 1).First(&order)
+				if order.Status != model.ClaimStatusConfirmed || order.TxID != "tx-1" {
+					t.Errorf("Expected order confirmed, got status %d txid %q", order.Status, order.TxID)
+				}
+				return
+			}
+			out := receive(t, bus, c.topic)
+			if out.Headers[HeaderErrorClass] != c.class || out.Headers[HeaderOriginalTopic] != claimTopic {
+				t.Errorf("Unexpected headers %v", out.Headers)
+			}
+			if out.Headers[HeaderRetryCount] != strconv.Itoa(c.retryCount+1) || string(out.Value) != string(value) || out.Key != "DAddr" {
+				t.Errorf("Expected message forwarded with retry count %d, got %+v", c.retryCount+1, out)
+			}
+			notBefore, err := strconv.ParseInt(out.Headers[HeaderNotBefore], 10, 64)
+			if c.topic == claimTopic+"-dlq" {
+				if err == nil {
+					t.Errorf("Expected no retry time on dead letter, got %d", notBefore)
+				}
+				return
+			}
+			delay := retryStages[c.retryCount].delay
+			if at := time.UnixMilli(notBefore); at.Before(before.Add(delay).Truncate(time.Millisecond)) || at.After(time.Now().Add(delay)) {
+				t.Errorf("Expected retry after %s, got %s", delay, at)
+			}
+		})
+	}
+}
+
+// 测试重试阶梯中的消息等到最早可重试时间后才处理，订阅停止时不确认
+func TestClaimConsumerNotBefore(t *testing.T) {
+	chain := &fakeChainClaimer{}
+	service, db := newTestClaimService(t, chain)
+	bus := mq.NewMemoryBus(0)
+	defer bus.Close()
+	consumer := NewClaimConsumer(ConsumerConfig{GroupID: "richx"}, bus, bus, service, testLogger())
+
+	value, _ := json.Marshal(newTestClaim(t, db, 1, model.ClaimStatusProcessing))
+	wait := 200 * time.Millisecond
+	msg := &mq.Message{Topic: RetryTopics(claimTopic)[0], Key: "DAddr", Value: value, Headers: map[string]string{
+		HeaderRetryCount: "1",
+		HeaderNotBefore:  strconv.FormatInt(time.Now().Add(wait).UnixMilli(), 10),
+	}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), wait/4)
+	defer cancel()
+	if err := consumer.handle(ctx, msg); err == nil {
+		t.Fatal("Expected handling to stop with the subscription before the retry time")
+	}
+	if len(chain.claims) != 0 {
+		t.Fatalf("Expected no chain claim before the retry time, got %d", len(chain.claims))
+	}
+
+	start := time.Now()
+	if err := consumer.handle(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < wait/2 {
+		t.Errorf("Expected handling to wait for the retry time, took %s", elapsed)
+	}
+	if len(chain.claims) != 1 {
+		t.Errorf("Expected one chain claim, got %d", len(chain.claims))
+	}
+}
+
+// 测试消费者订阅主主题和重试阶梯主题，处理成功后确认消息
+func TestClaimConsumerSubscribe(t *testing.T) {
+	chain := &fakeChainClaimer{}
+	service, db := newTestClaimService(t, chain)
+	bus := mq.NewMemoryBus(10 * time.Millisecond)
+	defer bus.Close()
+	consumer := NewClaimConsumer(ConsumerConfig{GroupID: "richx"}, bus, bus, service, testLogger())
+	consumer.Start()
+	defer consumer.Stop()
+
+	topics := append([]string{claimTopic}, RetryTopics(claimTopic)...)
+	for i, topic := range topics {
+		value, _ := json.Marshal(newTestClaim(t, db, int64(i+1), model.ClaimStatusCreated))
+		if err := bus.Publish(context.Background(), &mq.Message{Topic: topic, Key: "DAddr", Value: value}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for _, topic := range topics {
+		for bus.Offset("richx", topic) != 1 {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected message on %s acknowledged", topic)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	var confirmed int64
+	db.Model(&model.ClaimOrder{}).Where("status = ?", model.ClaimStatusConfirmed).Count(&confirmed)
+	if confirmed != int64(len(topics)) {
+		t.Errorf("Expected %d orders confirmed, got %d", len(topics), confirmed)
+	}
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"claimask/internal/richx/model"
+)
+
+// ReconcileConfig 处理中订单对账配置
+type ReconcileConfig struct {
+	Interval   time.Duration // 对账间隔
+	StaleAfter time.Duration // 处理中超过该时长的订单才对账，避免与正在处理的消费者竞争
+	BatchSize  int           // 单次查询订单数
+}
+
+// ReconcileStats 一轮对账的结果
+type ReconcileStats struct {
+	Checked   int `json:"checked"`
+	Confirmed int `json:"confirmed"` // 链上已发放，订单补记为已确认
+	Failed    int `json:"failed"`    // 链上发放失败，订单标记失败并退回金额
+	Resubmit  int `json:"resubmit"`  // 链上服务没有收到，按订单号幂等重新提交后确认
+	Pending   int `json:"pending"`   // 链上仍在处理，下一轮再查
+	Errors    int `json:"errors"`
+}
+
+// ClaimReconciler 对长时间停留在处理中的订单向链上服务查询结果并补齐订单状态
+// 消费者在提交链上请求后、更新订单前退出，或链上请求超时结果未知时，订单会停留在处理中
+type ClaimReconciler struct {
+	service *ClaimService
+	logger  *log.Logger
+	cfg     ReconcileConfig
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewClaimReconciler 创建对账任务
+func NewClaimReconciler(service *ClaimService, logger *log.Logger, cfg ReconcileConfig) *ClaimReconciler {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Minute
+	}
+	if cfg.StaleAfter <= 0 {
+		cfg.StaleAfter = 10 * time.Minute
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ClaimReconciler{
+		service: service,
+		logger:  logger,
+		cfg:     cfg,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Start 启动对账任务
+func (r *ClaimReconciler) Start() {
+	r.done = make(chan struct{})
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(r.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				stats, err := r.RunOnce()
+				if err != nil {
+					r.logger.Printf("订单对账失败: %v", err)
+				} else if stats.Checked > 0 {
+					r.logger.Printf("订单对账完成: %+v", stats)
+				}
+			case <-r.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止对账任务，等待正在进行的一轮对账完成
+func (r *ClaimReconciler) Stop() {
+	r.cancel()
+	if r.done != nil {
+		<-r.done
+	}
+}
+
+// RunOnce 对所有超时的处理中订单对账一次，单个订单失败不影响其他订单
+func (r *ClaimReconciler) RunOnce() (ReconcileStats, error) {
+	var stats ReconcileStats
+	before := time.Now().Add(-r.cfg.StaleAfter)
+
+	var lastID uint
+	for {
+		var orders []model.ClaimOrder
+		if err := r.service.db.
+			Where("status = ? AND updated_at < ? AND id > ?", model.ClaimStatusProcessing, before, lastID).
+			Order("id ASC").
+			Limit(r.cfg.BatchSize).
+			Find(&orders).Error; err != nil {
+			return stats, fmt.Errorf("load processing orders failed: %w", err)
+		}
+
+		for i := range orders {
+			if r.ctx.Err() != nil {
+				return stats, r.ctx.Err()
+			}
+			stats.Checked++
+			if err := r.reconcile(&orders[i], &stats); err != nil {
+				stats.Errors++
+				r.logger.Printf("订单 %d 对账失败: %v", orders[i].OrderID, err)
+			}
+		}
+
+		if len(orders) < r.cfg.BatchSize {
+			return stats, nil
+		}
+		lastID = orders[len(orders)-1].ID
+	}
+}
+
+// reconcile 按链上服务的结果处理一个订单
+func (r *ClaimReconciler) reconcile(order *model.ClaimOrder, stats *ReconcileStats) error {
+	orderID := strconv.FormatInt(order.OrderID, 10)
+	result, err := r.service.chainClaimer.Query(r.ctx, orderID)
+	if err != nil {
+		return fmt.Errorf("query chain claim failed: %w", err)
+	}
+
+	switch result.Status {
+	case ChainClaimConfirmed:
+		if err := r.service.confirmOrder(order.OrderID, result.TxID); err != nil {
+			return err
+		}
+		stats.Confirmed++
+		r.logger.Printf("订单 %d 对账：链上已发放，txid: %s", order.OrderID, result.TxID)
+	case ChainClaimFailed:
+		if err := r.service.failOrder(order, result.Reason); err != nil {
+			return err
+		}
+		stats.Failed++
+		r.logger.Printf("订单 %d 对账：链上发放失败，已退回 %s: %s", order.OrderID, order.Amount, result.Reason)
+	case ChainClaimNotFound:
+		txid, err := r.service.chainClaimer.Claim(r.ctx, model.ChainClaimRequest{
+			Address: order.Address,
+			Amount:  order.Amount,
+			OrderID: orderID,
+		})
+		if err != nil {
+			return fmt.Errorf("resubmit chain claim failed: %w", err)
+		}
+		if err := r.service.confirmOrder(order.OrderID, txid); err != nil {
+			return err
+		}
+		stats.Resubmit++
+		r.logger.Printf("订单 %d 对账：链上服务未收到请求，重新提交后发放，txid: %s", order.OrderID, txid)
+	default:
+		stats.Pending++
+	}
+	return nil
+}
+
+// confirmOrder 将处理中的订单标记为已确认
+func (s *ClaimService) confirmOrder(orderID int64, txid string) error {
+	return s.advanceStatus(orderID, model.ClaimStatusProcessing, model.ClaimStatusConfirmed, map[string]interface{}{
+		"txid": txid,
+	})
+}
+
+// failOrder 在一个事务中将处理中的订单标记为失败，并把金额退回收益表，用户可在下一个领取日重新领取
+func (s *ClaimService) failOrder(order *model.ClaimOrder, reason string) error {
+	if r := []rune(reason); len(r) > 255 {
+		reason = string(r[:255])
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.ClaimOrder{}).
+			Where("order_id = ? AND status = ?", order.OrderID, model.ClaimStatusProcessing).
+			Updates(map[string]interface{}{
+				"status":      model.ClaimStatusFailed,
+				"fail_reason": reason,
+				"updated_at":  time.Now(),
+			})
+		if result.Error != nil {
+			return fmt.Errorf("update order status failed: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("order %d is no longer in status %d", order.OrderID, model.ClaimStatusProcessing)
+		}
+
+		if err := tx.Model(&model.RichRewardLog{}).
+			Where("address = ?", order.Address).
+			Update("total_reward", gorm.Expr("total_reward + ?", order.Amount)).Error; err != nil {
+			return fmt.Errorf("refund reward failed: %w", err)
+		}
+		return nil
+	})
+}
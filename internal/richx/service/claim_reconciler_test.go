@@ -0,0 +1,84 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"claimask/internal/richx/model"
+	"claimask/pkg/money"
+)
+
+// 测试对账按链上结果补记确认、失败退款和重新提交，未超时和查询失败的订单保持处理中
+func TestClaimReconcilerRunOnce(t *testing.T) {
+	chain := &fakeChainClaimer{
+		results: map[string]*ChainClaimResult{
+			"1": {Status: ChainClaimConfirmed, TxID: "chain-tx-1"},
+			"2": {Status: ChainClaimFailed, Reason: "insufficient balance"},
+			"4": {Status: "processing"},
+		},
+		queryErrs: map[string]error{"5": errors.New("timeout")},
+	}
+	service, db := newTestClaimService(t, chain)
+	reconciler := NewClaimReconciler(service, testLogger(), ReconcileConfig{StaleAfter: time.Minute, BatchSize: 2})
+
+	stale := time.Now().Add(-time.Hour)
+	amount := money.MustParseDoge("1.5")
+	for id := int64(1); id <= 6; id++ {
+		order := model.ClaimOrder{OrderID: id, Address: "DAddr", Amount: amount, Status: model.ClaimStatusProcessing, CreatedAt: stale, UpdatedAt: stale}
+		if id == 6 {
+			order.UpdatedAt = time.Now() // 消费者可能正在处理
+		}
+		if err := db.Create(&order).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Create(&model.RichRewardLog{Address: "DAddr", TotalReward: money.MustParseDoge("2")}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := reconciler.RunOnce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ReconcileStats{Checked: 5, Confirmed: 1, Failed: 1, Resubmit: 1, Pending: 1, Errors: 1}
+	if stats != want {
+		t.Errorf("Expected stats %+v, got %+v", want, stats)
+	}
+
+	expected := map[int64]struct {
+		status int
+		txid   string
+	}{
+		1: {model.ClaimStatusConfirmed, "chain-tx-1"},
+		2: {model.ClaimStatusFailed, ""},
+		3: {model.ClaimStatusConfirmed, "tx-3"},
+		4: {model.ClaimStatusProcessing, ""},
+		5: {model.ClaimStatusProcessing, ""},
+		6: {model.ClaimStatusProcessing, ""},
+	}
+	for id, e := range expected {
+		var order model.ClaimOrder
+		db.Where("order_id = ?", id).First(&order)
+		if order.Status != e.status || order.TxID != e.txid {
+			t.Errorf("Order %d: expected status %d txid %q, got %d %q", id, e.status, e.txid, order.Status, order.TxID)
+		}
+	}
+	var failed model.ClaimOrder
+	db.Where("order_id = ?", 2).First(&failed)
+	if failed.FailReason != "insufficient balance" {
+		t.Errorf("Expected fail reason recorded, got %q", failed.FailReason)
+	}
+
+	// 失败订单的金额退回收益表
+	var rewardLog model.RichRewardLog
+	db.Where("address = ?", "DAddr").First(&rewardLog)
+	if rewardLog.TotalReward != money.MustParseDoge("3.5") {
+		t.Errorf("Expected refund credited back to 3.5, got %s", rewardLog.TotalReward)
+	}
+
+	// 只有链上服务未收到的订单重新提交
+	if len(chain.claims) != 1 || chain.claims[0].OrderID != "3" || chain.claims[0].Amount != amount {
+		t.Errorf("Expected only order 3 resubmitted, got %+v", chain.claims)
+	}
+}
@@ -2,23 +2,45 @@ package service
 
 import (
 	"context"
+	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"math/big"
 	"strconv"
 	"time"
 
-	"github.com/IBM/sarama"
 	"github.com/go-redis/redis"
-	"github.com/jinzhu/gorm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"claimask/internal/richx/model"
+	"claimask/pkg/money"
+	"claimask/pkg/redislock"
 )
 
-const redisLockKey = "richx_test:claim:lock:%s"
+const (
+	redisLockKey = "richx_test:claim:lock:%s"
+	redisLockTTL = 10 * time.Second // 领取锁过期时间，持锁期间后台自动续期
+	claimTopic   = "rich-claim"
+)
+
+var (
+	// ErrInvalidMessage 消息内容不合法，重试也无法成功
+	ErrInvalidMessage = errors.New("invalid claim message")
+	// ErrClaimOrderNotFound 消息对应的订单不存在
+	ErrClaimOrderNotFound = errors.New("claim order not found")
+	// ErrBusDisabled 未配置消息总线
+	ErrBusDisabled = errors.New("message bus disabled")
+	// ErrStaleFence 领取锁已被更新的持有者获取，本次写入被拒绝
+	ErrStaleFence = errors.New("stale claim lock fence token")
+	// ErrNothingToClaim 没有可领取的收益
+	ErrNothingToClaim = errors.New("nothing to claim")
+	// ErrAlreadyClaimed 今天已经领取过
+	ErrAlreadyClaimed = errors.New("already claimed today")
+)
 
 // DB是数据库服务
 type DB struct {
@@ -30,154 +52,173 @@ type RedisClient struct {
 	client *redis.Client
 }
 
-// KafkaProducer KafkaProducer是Kafka生产者
-type KafkaProducer struct {
-	producer sarama.SyncProducer
-	enabled  bool
+// ConsumerConfig 领取消息消费配置
+type ConsumerConfig struct {
+	Topic   string // 领取消息主题
+	GroupID string // 消费者组
 }
 
 // ClaimService 定义领取服务接口
 type ClaimService struct {
-	db            *DB
-	redisClient   *RedisClient
-	kafkaProducer *KafkaProducer
-	logger        *log.Logger
+	db           *DB
+	redisClient  *RedisClient
+	locker       *redislock.Locker
+	calendar     *ClaimCalendar
+	chainClaimer ChainClaimer
+	logger       *log.Logger
 }
 
 // NewClaimService 创建领取服务实例
-func NewClaimService(db *DB, redisClient *RedisClient, kafkaProducer *KafkaProducer, logger *log.Logger) *ClaimService {
+func NewClaimService(db *DB, redisClient *RedisClient, calendar *ClaimCalendar, chainClaimer ChainClaimer, logger *log.Logger) *ClaimService {
 	return &ClaimService{
-		db:            db,
-		redisClient:   redisClient,
-		kafkaProducer: kafkaProducer,
-		logger:        logger,
-	}
-}
-
-// InitDB 初始化数据库连接
-func InitDB() (*DB, error) {
-	db, err := gorm.Open("mysql", "root:123@jiaru@tcp(localhost:3306)/richx_test?charset=utf8mb4&parseTime=True&loc=Local")
-	if err != nil {
-		return nil, err
+		db:           db,
+		redisClient:  redisClient,
+		locker:       redislock.New(redisClient.client),
+		calendar:     calendar,
+		chainClaimer: chainClaimer,
+		logger:       logger,
 	}
-	db.LogMode(true)
-	db.DB().SetMaxIdleConns(10)
-	db.DB().SetMaxOpenConns(100)
-	db.DB().SetConnMaxLifetime(time.Hour)
-	return &DB{db}, nil
-}
-
-// InitRedis 初始化Redis连接
-func InitRedis() (*RedisClient, error) {
-	client := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
-	})
-	_, err := client.Ping().Result()
-	if err != nil {
-		return nil, err
-	}
-	return &RedisClient{client: client}, nil
-}
-
-// InitKafka 初始化Kafka连接
-func InitKafka() (*KafkaProducer, error) {
-	// 由于Kafka未安装，返回一个Mock的Kafka生产者
-	return &KafkaProducer{
-		producer: nil,
-		enabled:  false,
-	}, nil
-}
-
-// AcquireLock 获取分布式锁
-func (r *RedisClient) AcquireLock(ctx context.Context, address string, expiration time.Duration) (bool, error) {
-	lockKey := fmt.Sprintf(redisLockKey, address)
-	return r.client.SetNX(lockKey, "1", expiration).Result()
 }
 
-// ReleaseLock 释放分布式锁
-func (r *RedisClient) ReleaseLock(ctx context.Context, address string) error {
-	lockKey := fmt.Sprintf(redisLockKey, address)
-	return r.client.Del(lockKey).Err()
-}
-
-// Close 关闭Redis连接
-func (r *RedisClient) Close() error {
-	return r.client.Close()
+// NewRedisClient 包装主程序共享的Redis连接
+func NewRedisClient(client *redis.Client) *RedisClient {
+	return &RedisClient{client: client}
 }
 
 // CanClaim 判断用户是否可以领取收益
-func (s *ClaimService) CanClaim(address string) (bool, *big.Float, error) {
+func (s *ClaimService) CanClaim(address string) (bool, money.Amount, error) {
 	var rewardLog model.RichRewardLog
 	if err := s.db.Where("address = ?", address).First(&rewardLog).Error; err != nil {
-		if gorm.IsRecordNotFoundError(err) {
-			return false, big.NewFloat(0), nil
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, 0, nil
 		}
-		return false, nil, err
+		return false, 0, err
 	}
 
-	// 如果总收益为0，则无法领取
-	zero := big.NewFloat(0)
-	if rewardLog.TotalReward.Cmp(zero) == 0 {
-		return false, zero, nil
+	if err := claimable(s.calendar, &rewardLog, time.Now()); err != nil {
+		return false, 0, nil
 	}
+	return true, rewardLog.TotalReward, nil
+}
 
-	today := time.Now().Truncate(24 * time.Hour)
-
-	// 如果从未领取过，可以领取
-	if rewardLog.Latest == nil {
-		return true, rewardLog.TotalReward, nil
+// claimable 判断领取记录在now时是否可领取
+// 累计收益为0时返回 ErrNothingToClaim，当前领取日已领取时返回 ErrAlreadyClaimed
+func claimable(calendar *ClaimCalendar, rewardLog *model.RichRewardLog, now time.Time) error {
+	if rewardLog.TotalReward.Sign() <= 0 {
+		return ErrNothingToClaim
 	}
-
-	// 如果最近一次领取是今天，不能领取
-	latestDate := rewardLog.Latest.Truncate(24 * time.Hour)
-	if latestDate.Equal(today) {
-		return false, zero, nil
+	if rewardLog.Latest != nil && !calendar.FromDate(*rewardLog.Latest).Before(calendar.Day(now)) {
+		return ErrAlreadyClaimed
 	}
-
-	// 可以领取
-	return true, rewardLog.TotalReward, nil
+	return nil
 }
 
 // CreateOrder 创建领取订单
-func (s *ClaimService) CreateOrder(address string, amount *big.Float) (int64, error) {
+func (s *ClaimService) CreateOrder(address string, amount money.Amount) (int64, error) {
 	orderID := time.Now().UnixNano()
+	if err := createOrder(s.db.DB, orderID, address, amount); err != nil {
+		return 0, err
+	}
+	return orderID, nil
+}
+
+// createOrder 在给定的数据库会话中创建领取订单
+func createOrder(db *gorm.DB, orderID int64, address string, amount money.Amount) error {
 	order := model.ClaimOrder{
 		OrderID:   orderID,
 		Address:   address,
 		Amount:    amount,
-		Status:    1, // 创建状态
+		Status:    model.ClaimStatusCreated,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
+	return db.Create(&order).Error
+}
 
-	if err := s.db.Create(&order).Error; err != nil {
-		return 0, err
-	}
+// createClaim 在同一事务中完成领取：锁定并校验领取记录、创建订单、清零累计收益、更新领取日期并写入外发消息
+// 领取记录以可领取状态和栅栏令牌为条件更新，其他请求抢先领取或锁过期后被他人获取时，本次领取整体回滚
+func (s *ClaimService) createClaim(address string, now time.Time, fence int64) (int64, *model.KafkaMessage, error) {
+	orderID := now.UnixNano()
+	var message *model.KafkaMessage
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var rewardLog model.RichRewardLog
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("address = ?", address).
+			First(&rewardLog).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNothingToClaim
+			}
+			return fmt.Errorf("load reward log failed: %w", err)
+		}
+		if rewardLog.FenceToken >= fence {
+			return ErrStaleFence
+		}
+		if err := claimable(s.calendar, &rewardLog, now); err != nil {
+			return err
+		}
 
-	return orderID, nil
-}
+		amount := rewardLog.TotalReward
+		message = &model.KafkaMessage{
+			OrderID:   strconv.FormatInt(orderID, 10),
+			Address:   address,
+			Amount:    amount,
+			Timestamp: now,
+			Sha:       CalculateSha256(address + amount.String() + strconv.FormatInt(now.Unix(), 10)),
+		}
+		payload, err := json.Marshal(message)
+		if err != nil {
+			return err
+		}
 
-// SendToKafka 发送消息到Kafka
-func (s *KafkaProducer) SendToKafka(message model.KafkaMessage) error {
-	if !s.enabled {
-		return nil // Mock实现，直接返回成功
-	}
+		if err := createOrder(tx, orderID, address, amount); err != nil {
+			return fmt.Errorf("create order failed: %w", err)
+		}
+
+		result := tx.Model(&model.RichRewardLog{}).
+			Where("address = ? AND total_reward > 0 AND (latest IS NULL OR latest < ?) AND fence_token < ?",
+				address, s.calendar.DateString(now), fence).
+			Updates(map[string]interface{}{
+				"total_reward":  0,
+				"latest":        s.calendar.DateString(now),
+				"latest_detail": now,
+				"fence_token":   fence,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("update reward log failed: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrAlreadyClaimed
+		}
 
-	msgBytes, err := json.Marshal(message)
+		event := model.OutboxEvent{
+			Topic:       claimTopic,
+			MsgKey:      address, // 使用地址作为Key确保同一用户的消息顺序处理
+			Payload:     string(payload),
+			Status:      model.OutboxStatusPending,
+			NextRetryAt: now,
+			CreatedAt:   now,
+		}
+		if err := tx.Create(&event).Error; err != nil {
+			return fmt.Errorf("create outbox event failed: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return err
+		return 0, nil, err
 	}
+	return orderID, message, nil
+}
 
-	msg := &sarama.ProducerMessage{
-		Topic: "rich-claim",
-		Value: sarama.StringEncoder(msgBytes),
-		Key:   sarama.StringEncoder(message.Address), // 使用地址作为Key确保同一用户的消息顺序处理
+// fenceFloor 查询领取记录上已写入的栅栏令牌，没有记录时为0
+func (s *ClaimService) fenceFloor(address string) (int64, error) {
+	var fences []int64
+	err := s.db.Model(&model.RichRewardLog{}).
+		Where("address = ?", address).
+		Pluck("fence_token", &fences).Error
+	if err != nil || len(fences) == 0 {
+		return 0, err
 	}
-
-	_, _, err = s.producer.SendMessage(msg)
-	return err
+	return fences[0], nil
 }
 
 // CalculateSha256 计算SHA256哈希
@@ -190,51 +231,56 @@ func CalculateSha256(data string) string {
 func (s *ClaimService) Claim(ctx context.Context, address string) (*model.Response, error) {
 	s.logger.Printf("收到来自 %s 的领取请求", address)
 
-	// 使用Redis分布式锁防止并发请求
-	acquired, err := s.redisClient.AcquireLock(ctx, address, 10*time.Second)
+	// Redis 中的栅栏令牌计数器可能随清空或故障切换丢失，以库中已写入的令牌为下限
+	floor, err := s.fenceFloor(address)
 	if err != nil {
-		s.logger.Printf("获取锁失败: %v", err)
+		s.logger.Printf("查询栅栏令牌失败: %v", err)
 		return &model.Response{
 			Code:    500,
 			Message: "系统错误，请稍后再试",
 		}, err
 	}
 
-	if !acquired {
+	// 使用Redis分布式锁防止并发请求，持锁期间自动续期
+	lock, err := s.locker.AcquireAbove(ctx, fmt.Sprintf(redisLockKey, address), redisLockTTL, floor)
+	if errors.Is(err, redislock.ErrNotAcquired) {
 		s.logger.Printf("用户 %s 操作太频繁", address)
 		return &model.Response{
 			Code:    429,
 			Message: "操作太频繁，请稍后再试",
 		}, nil
 	}
-
-	defer func() {
-		if err := s.redisClient.ReleaseLock(ctx, address); err != nil {
-			s.logger.Printf("释放锁失败: %v", err)
-		}
-	}()
-
-	// 检查是否可以领取
-	canClaim, amount, err := s.CanClaim(address)
 	if err != nil {
-		s.logger.Printf("检查是否可领取失败: %v", err)
+		s.logger.Printf("获取锁失败: %v", err)
 		return &model.Response{
 			Code:    500,
 			Message: "系统错误，请稍后再试",
 		}, err
 	}
 
-	if !canClaim {
-		s.logger.Printf("用户 %s 当天已领取或无可领取收益", address)
-		return &model.Response{
-			Code:    400,
-			Message: "您今天已领取或没有可领取的收益",
-		}, nil
-	}
+	defer func() {
+		if err := lock.Release(); err != nil {
+			s.logger.Printf("释放锁失败: %v", err)
+		}
+	}()
 
-	// 创建订单
-	orderID, err := s.CreateOrder(address, amount)
+	// 校验、创建订单、清零收益和写入外发消息在同一事务中完成，由OutboxRelay投递到消息总线
+	orderID, message, err := s.createClaim(address, time.Now(), lock.Fence())
 	if err != nil {
+		switch {
+		case errors.Is(err, ErrNothingToClaim), errors.Is(err, ErrAlreadyClaimed):
+			s.logger.Printf("用户 %s 当天已领取或无可领取收益", address)
+			return &model.Response{
+				Code:    400,
+				Message: "您今天已领取或没有可领取的收益",
+			}, nil
+		case errors.Is(err, ErrStaleFence):
+			s.logger.Printf("用户 %s 的领取锁已失效，放弃本次写入", address)
+			return &model.Response{
+				Code:    409,
+				Message: "领取请求已失效，请稍后再试",
+			}, nil
+		}
 		s.logger.Printf("创建订单失败: %v", err)
 		return &model.Response{
 			Code:    500,
@@ -242,82 +288,97 @@ func (s *ClaimService) Claim(ctx context.Context, address string) (*model.Respon
 		}, err
 	}
 
-	// 更新领取记录
-	now := time.Now()
-	if err := s.db.Model(&model.RichRewardLog{}).
-		Where("address = ?", address).
-		Update("latest", now).Error; err != nil {
-		s.logger.Printf("更新领取记录失败: %v", err)
-		// 继续处理，不影响用户体验
-	}
-
-	// 构造Kafka消息
-	message := model.KafkaMessage{
-		OrderID:   strconv.FormatInt(orderID, 10),
-		Address:   address,
-		Amount:    amount,
-		Timestamp: time.Now(),
-		Sha:       CalculateSha256(address + amount.String() + strconv.FormatInt(time.Now().Unix(), 10)),
-	}
-
-	// 发送到Kafka
-	if err := s.kafkaProducer.SendToKafka(message); err != nil {
-		s.logger.Printf("发送到Kafka失败: %v", err)
-		// 继续处理，假设有重试机制
-	}
-
-	// 异步处理（模拟）
-	go s.ProcessClaimAsync(message)
-
 	return &model.Response{
 		Code: 200,
 		Data: map[string]interface{}{
 			"orderID": orderID,
-			"amount":  amount.String(),
+			"amount":  message.Amount.String(),
 		},
 		Message: "领取成功",
 	}, nil
 }
 
-// ProcessClaimAsync 异步处理领取请求
-func (s *ClaimService) ProcessClaimAsync(message model.KafkaMessage) error {
-	s.logger.Printf("异步处理订单 %s", message.OrderID)
-
-	// 模拟处理延迟
-	time.Sleep(2 * time.Second)
+// ProcessClaim 处理一条领取消息，由ClaimConsumer调用
+// 订单状态按 1:创建 → 2:处理中 → 3:已确认 推进，重复投递的消息按订单当前状态幂等处理
+func (s *ClaimService) ProcessClaim(ctx context.Context, message model.KafkaMessage) error {
+	if !VerifyMessage(message) {
+		return fmt.Errorf("%w: sha mismatch for order %s", ErrInvalidMessage, message.OrderID)
+	}
 
-	// 解析订单ID
 	orderID, err := strconv.ParseInt(message.OrderID, 10, 64)
 	if err != nil {
-		s.logger.Printf("解析订单ID失败: %v", err)
-		return err
+		return fmt.Errorf("%w: bad order id %q", ErrInvalidMessage, message.OrderID)
 	}
 
-	// 更新订单状态为处理中
-	if err := s.db.Model(&model.ClaimOrder{}).
-		Where("order_id = ?", orderID).
-		Updates(map[string]interface{}{
-			"status":     2,
-			"updated_at": time.Now(),
-		}).Error; err != nil {
-		s.logger.Printf("更新订单状态失败: %v", err)
+	var order model.ClaimOrder
+	if err := s.db.Where("order_id = ?", orderID).First(&order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: %d", ErrClaimOrderNotFound, orderID)
+		}
 		return err
 	}
 
-	// 模拟链上处理
-	time.Sleep(3 * time.Second)
+	switch order.Status {
+	case model.ClaimStatusConfirmed:
+		s.logger.Printf("订单 %s 已确认，忽略重复消息", message.OrderID)
+		return nil
+	case model.ClaimStatusCreated:
+		// 更新订单状态为处理中
+		if err := s.advanceStatus(orderID, model.ClaimStatusCreated, model.ClaimStatusProcessing, nil); err != nil {
+			return err
+		}
+	case model.ClaimStatusProcessing:
+		// 上次处理中断，链上服务按订单号（Idempotency-Key）幂等，直接重新提交
+		s.logger.Printf("订单 %s 处于处理中，重新提交链上请求", message.OrderID)
+	default:
+		return fmt.Errorf("%w: order %d in status %d", ErrInvalidMessage, orderID, order.Status)
+	}
+
+	txid, err := s.chainClaimer.Claim(ctx, model.ChainClaimRequest{
+		Address: message.Address,
+		Amount:  message.Amount,
+		OrderID: message.OrderID,
+	})
+	if err != nil {
+		return fmt.Errorf("chain claim failed: %w", err)
+	}
 
 	// 更新订单状态为已确认
-	if err := s.db.Model(&model.ClaimOrder{}).
-		Where("order_id = ?", orderID).
-		Updates(map[string]interface{}{
-			"status":     3,
-			"updated_at": time.Now(),
-		}).Error; err != nil {
-		s.logger.Printf("更新订单状态失败: %v", err)
+	if err := s.confirmOrder(orderID, txid); err != nil {
 		return err
 	}
 
-	s.logger.Printf("订单 %s 处理完成", message.OrderID)
+	s.logger.Printf("订单 %s 处理完成，txid: %s", message.OrderID, txid)
+	return nil
+}
+
+// advanceStatus 以当前状态为条件推进订单状态
+func (s *ClaimService) advanceStatus(orderID int64, from, to int, extra map[string]interface{}) error {
+	updates := map[string]interface{}{
+		"status":     to,
+		"updated_at": time.Now(),
+	}
+	for k, v := range extra {
+		updates[k] = v
+	}
+
+	result := s.db.Model(&model.ClaimOrder{}).
+		Where("order_id = ? AND status = ?", orderID, from).
+		Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("update order status failed: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("order %d is no longer in status %d", orderID, from)
+	}
 	return nil
 }
+
+// VerifyMessage 校验消息签名
+func VerifyMessage(message model.KafkaMessage) bool {
+	if message.Amount.Sign() <= 0 {
+		return false
+	}
+	expected := CalculateSha256(message.Address + message.Amount.String() + strconv.FormatInt(message.Timestamp.Unix(), 10))
+	return hmac.Equal([]byte(expected), []byte(message.Sha))
+}
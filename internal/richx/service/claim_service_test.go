@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"claimask/internal/richx/model"
+	"claimask/pkg/money"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"gorm.io/gorm"
+)
+
+// 测试每日领取资格判断
+func TestClaimable(t *testing.T) {
+	calendar, err := NewClaimCalendar("Asia/Shanghai", 0)
+	if err != nil {
+		t.Fatalf("create calendar failed: %v", err)
+	}
+	// 北京时间 2024-05-20 23:00，UTC 仍是 15:00
+	now := time.Date(2024, 5, 20, 15, 0, 0, 0, time.UTC)
+	// date 列读出的值为连接时区的0点，只取年月日
+	yesterday := time.Date(2024, 5, 19, 0, 0, 0, 0, time.Local)
+	today := time.Date(2024, 5, 20, 0, 0, 0, 0, time.Local)
+
+	cases := []struct {
+		name   string
+		reward money.Amount
+		latest *time.Time
+		want   error
+	}{
+		{"never claimed", money.MustParseDoge("1.5"), nil, nil},
+		{"claimed yesterday", money.MustParseDoge("1.5"), &yesterday, nil},
+		{"claimed today", money.MustParseDoge("1.5"), &today, ErrAlreadyClaimed},
+		{"zero reward", 0, nil, ErrNothingToClaim},
+		{"negative reward", money.MustParseDoge("-0.1"), nil, ErrNothingToClaim},
+	}
+	for _, c := range cases {
+		err := claimable(calendar, &model.RichRewardLog{TotalReward: c.reward, Latest: c.latest}, now)
+		if !errors.Is(err, c.want) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, err)
+		}
+	}
+}
+
+// newTestClaimer 基于内存 sqlite 和内存 Redis 的领取服务，address 的领取记录已写入栅栏令牌 fence
+func newTestClaimer(t *testing.T, address string, fence int64) (*ClaimService, *DB, *miniredis.Miniredis) {
+	t.Helper()
+	calendar, err := NewClaimCalendar("Asia/Shanghai", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	db := newTestDB(t)
+	rewardLog := model.RichRewardLog{Address: address, TotalReward: money.MustParseDoge("1.5"), FenceToken: fence}
+	if err := db.Create(&rewardLog).Error; err != nil {
+		t.Fatal(err)
+	}
+	return NewClaimService(db, NewRedisClient(client), calendar, nil, testLogger()), db, server
+}
+
+// 测试领取写入以栅栏令牌和可领取状态为条件，被拒绝的写入整体回滚
+func TestCreateClaimFence(t *testing.T) {
+	const address = "DAddr"
+	svc, db, _ := newTestClaimer(t, address, 5)
+	now := time.Now()
+
+	// 令牌不大于已写入的令牌，锁已被更新的持有者获取
+	if _, _, err := svc.createClaim(address, now, 5); !errors.Is(err, ErrStaleFence) {
+		t.Fatalf("Expected ErrStaleFence, got %v", err)
+	}
+
+	// 读取后、更新前另一持有者抢先领取，条件更新不命中
+	race := func(tx *gorm.DB) {
+		if tx.Statement.Table == "rich_reward_log" {
+			tx.Session(&gorm.Session{NewDB: true}).Exec("UPDATE rich_reward_log SET fence_token = 8")
+		}
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("test:race", race); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := svc.createClaim(address, now, 6); !errors.Is(err, ErrAlreadyClaimed) {
+		t.Fatalf("Expected ErrAlreadyClaimed when the conditional update misses, got %v", err)
+	}
+	if err := db.Callback().Update().Remove("test:race"); err != nil {
+		t.Fatal(err)
+	}
+	var orders, events int64
+	db.Model(&model.ClaimOrder{}).Count(&orders)
+	db.Model(&model.OutboxEvent{}).Count(&events)
+	if orders != 0 || events != 0 {
+		t.Fatalf("Expected rejected claims rolled back, got %d orders and %d events", orders, events)
+	}
+
+	orderID, message, err := svc.createClaim(address, now, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.Amount != money.MustParseDoge("1.5") || message.OrderID != fmt.Sprint(orderID) {
+		t.Errorf("Unexpected claim message %+v", message)
+	}
+	var rewardLog model.RichRewardLog
+	db.Where("address = ?", address).First(&rewardLog)
+	if rewardLog.TotalReward != 0 || rewardLog.FenceToken != 6 || rewardLog.Latest == nil || rewardLog.LatestDetail == nil {
+		t.Errorf("Expected reward cleared with fence 6, got %+v", rewardLog)
+	}
+	db.Model(&model.ClaimOrder{}).Count(&orders)
+	db.Model(&model.OutboxEvent{}).Count(&events)
+	if orders != 1 || events != 1 {
+		t.Errorf("Expected one order and one outbox event, got %d and %d", orders, events)
+	}
+
+	// 同一领取日又累计了收益，仍不能再次领取
+	if err := db.Model(&model.RichRewardLog{}).Where("address = ?", address).Update("total_reward", money.MustParseDoge("1")).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := svc.createClaim(address, now, 7); !errors.Is(err, ErrAlreadyClaimed) {
+		t.Errorf("Expected ErrAlreadyClaimed, got %v", err)
+	}
+}
+
+// 测试Redis清空后栅栏令牌计数器重新开始，领取仍以库中的令牌为下限成功
+func TestClaimAfterRedisFlush(t *testing.T) {
+	const address = "DAddr"
+	svc, db, server := newTestClaimer(t, address, 5)
+	server.FlushAll()
+
+	resp, err := svc.Claim(context.Background(), address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != 200 {
+		t.Fatalf("Expected claim to succeed after Redis flush, got %d %s", resp.Code, resp.Message)
+	}
+	var rewardLog model.RichRewardLog
+	db.Where("address = ?", address).First(&rewardLog)
+	if rewardLog.FenceToken <= 5 || rewardLog.TotalReward != 0 {
+		t.Errorf("Expected fence above 5 and reward cleared, got %+v", rewardLog)
+	}
+
+	resp, err = svc.Claim(context.Background(), address)
+	if err != nil || resp.Code != 400 {
+		t.Errorf("Expected second claim of the day rejected, got %+v %v", resp, err)
+	}
+}
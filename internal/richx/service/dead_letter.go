@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"claimask/internal/richx/model"
+	"claimask/pkg/mq"
+)
+
+// 死信列表分页参数
+const (
+	defaultDeadLetterPageSize = 20
+	maxDeadLetterPageSize     = 100
+)
+
+var (
+	// ErrDeadLetterNotFound 死信不存在
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+	// ErrDeadLetterHandled 死信已被处理
+	ErrDeadLetterHandled = errors.New("dead letter already handled")
+)
+
+// DeadLetterArchiver 消费死信主题，将消息及错误信息存档到 claim_dead_letter 表
+type DeadLetterArchiver struct {
+	*subscription
+	db     *DB
+	logger *log.Logger
+}
+
+// NewDeadLetterArchiver 创建死信存档消费者，使用独立的消费者组
+func NewDeadLetterArchiver(cfg ConsumerConfig, subscriber mq.Subscriber, db *DB, logger *log.Logger) *DeadLetterArchiver {
+	topic := cfg.Topic
+	if topic == "" {
+		topic = claimTopic
+	}
+
+	a := &DeadLetterArchiver{db: db, logger: logger}
+	a.subscription = newSubscription(subscriber, cfg.GroupID+deadLetterSuffix, []string{DeadLetterTopic(topic)}, a.archive)
+	return a
+}
+
+// Start 启动消费
+func (a *DeadLetterArchiver) Start() {
+	a.start(a.logger)
+}
+
+// Stop 停止消费
+func (a *DeadLetterArchiver) Stop() {
+	a.stop()
+}
+
+// archive 存档一条死信，按主题和消息ID去重，存档成功后才确认消息
+func (a *DeadLetterArchiver) archive(ctx context.Context, msg *mq.Message) error {
+	retryCount, _ := strconv.Atoi(msg.Headers[HeaderRetryCount])
+
+	letter := model.DeadLetter{
+		Topic:         msg.Topic,
+		MsgID:         msg.ID,
+		MsgKey:        msg.Key,
+		Payload:       string(msg.Value),
+		OriginalTopic: msg.Headers[HeaderOriginalTopic],
+		ErrorClass:    msg.Headers[HeaderErrorClass],
+		ErrorMessage:  msg.Headers[HeaderError],
+		RetryCount:    retryCount,
+		Status:        model.DeadLetterStatusPending,
+		CreatedAt:     time.Now(),
+	}
+	if failedAt, err := time.Parse(time.RFC3339, msg.Headers[HeaderFailedAt]); err == nil {
+		letter.FailedAt = &failedAt
+	}
+
+	if err := a.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&letter).Error; err != nil {
+		a.logger.Printf("死信存档失败 topic=%s id=%s: %v", msg.Topic, msg.ID, err)
+		return err
+	}
+	return nil
+}
+
+// DeadLetterQuery 死信列表查询条件
+type DeadLetterQuery struct {
+	Status     *int
+	ErrorClass string
+	Cursor     uint64 // 上一页最后一条记录的ID
+	Limit      int
+}
+
+// DeadLetterService 死信管理服务
+type DeadLetterService struct {
+	db        *DB
+	publisher mq.Publisher
+	topic     string
+}
+
+// NewDeadLetterService 创建死信管理服务
+func NewDeadLetterService(db *DB, publisher mq.Publisher, topic string) *DeadLetterService {
+	if topic == "" {
+		topic = claimTopic
+	}
+	return &DeadLetterService{db: db, publisher: publisher, topic: topic}
+}
+
+// List 按ID倒序分页查询死信
+func (s *DeadLetterService) List(query DeadLetterQuery) ([]model.DeadLetter, error) {
+	if query.Limit <= 0 {
+		query.Limit = defaultDeadLetterPageSize
+	}
+	if query.Limit > maxDeadLetterPageSize {
+		query.Limit = maxDeadLetterPageSize
+	}
+
+	db := s.db.DB
+	if query.Status != nil {
+		db = db.Where("status = ?", *query.Status)
+	}
+	if query.ErrorClass != "" {
+		db = db.Where("error_class = ?", query.ErrorClass)
+	}
+	if query.Cursor > 0 {
+		db = db.Where("id < ?", query.Cursor)
+	}
+
+	var letters []model.DeadLetter
+	if err := db.Order("id DESC").Limit(query.Limit).Find(&letters).Error; err != nil {
+		return nil, err
+	}
+	return letters, nil
+}
+
+// Get 查询单条死信
+func (s *DeadLetterService) Get(id uint64) (*model.DeadLetter, error) {
+	var letter model.DeadLetter
+	if err := s.db.Where("id = ?", id).First(&letter).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDeadLetterNotFound
+		}
+		return nil, err
+	}
+	return &letter, nil
+}
+
+// Redrive 将死信重新投递到领取主题，重试计数清零
+func (s *DeadLetterService) Redrive(ctx context.Context, id uint64) error {
+	letter, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	if letter.Status != model.DeadLetterStatusPending {
+		return ErrDeadLetterHandled
+	}
+
+	if s.publisher == nil {
+		return ErrBusDisabled
+	}
+
+	msg := &mq.Message{
+		Topic:   s.topic,
+		Key:     letter.MsgKey,
+		Value:   []byte(letter.Payload),
+		Headers: map[string]string{HeaderRedrivenFrom: strconv.FormatUint(id, 10)},
+	}
+	if err := s.publisher.Publish(ctx, msg); err != nil {
+		return fmt.Errorf("redrive dead letter failed: %w", err)
+	}
+	return s.markHandled(id, model.DeadLetterStatusRedriven)
+}
+
+// Discard 将死信标记为已丢弃
+func (s *DeadLetterService) Discard(id uint64) error {
+	if _, err := s.Get(id); err != nil {
+		return err
+	}
+	return s.markHandled(id, model.DeadLetterStatusDiscarded)
+}
+
+// markHandled 以待处理状态为条件更新死信状态
+func (s *DeadLetterService) markHandled(id uint64, status int) error {
+	now := time.Now()
+	result := s.db.Model(&model.DeadLetter{}).
+		Where("id = ? AND status = ?", id, model.DeadLetterStatusPending).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"redriven_at": &now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDeadLetterHandled
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"claimask/internal/richx/model"
+	"claimask/pkg/mq"
+)
+
+// deadLetterHeaders 消费者转发到死信主题时携带的消息头
+func deadLetterHeaders(class string) map[string]string {
+	return map[string]string{
+		HeaderRetryCount:    "4",
+		HeaderOriginalTopic: claimTopic,
+		HeaderErrorClass:    class,
+		HeaderError:         "chain claim service unavailable",
+		HeaderFailedAt:      "2024-05-20T15:00:00Z",
+	}
+}
+
+// waitArchived 等待死信表达到 n 条
+func waitArchived(t *testing.T, db *DB, n int64) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		var count int64
+		db.Model(&model.DeadLetter{}).Count(&count)
+		if count == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d dead letters, got %d", n, count)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// 测试死信按主题和消息ID去重存档，重启后的新消息ID不会与已存档的死信冲突
+func TestDeadLetterArchive(t *testing.T) {
+	db := newTestDB(t)
+	dlq := DeadLetterTopic(claimTopic)
+	msg := &mq.Message{Topic: dlq, Key: "DAddr", Value: []byte(`{"orderId":"1"}`), Headers: deadLetterHeaders(ErrorClassTransient)}
+
+	bus := mq.NewMemoryBus(10 * time.Millisecond)
+	archiver := NewDeadLetterArchiver(ConsumerConfig{GroupID: "richx"}, bus, db, testLogger())
+	archiver.Start()
+	if err := bus.Publish(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	waitArchived(t, db, 1)
+
+	var letter model.DeadLetter
+	db.First(&letter)
+	if letter.MsgID == "" || letter.MsgKey != "DAddr" || letter.OriginalTopic != claimTopic || letter.RetryCount != 4 ||
+		letter.ErrorClass != ErrorClassTransient || letter.FailedAt == nil || letter.Status != model.DeadLetterStatusPending {
+		t.Errorf("Unexpected dead letter %+v", letter)
+	}
+
+	// 确认前进程退出，同一条死信重新投递
+	redelivered := *msg
+	redelivered.ID = letter.MsgID
+	if err := archiver.archive(context.Background(), &redelivered); err != nil {
+		t.Fatal(err)
+	}
+	waitArchived(t, db, 1)
+	archiver.Stop()
+	bus.Close()
+
+	// 进程重启后新的消息总线重新发布死信，消息ID与已存档的死信不同
+	restarted := mq.NewMemoryBus(10 * time.Millisecond)
+	defer restarted.Close()
+	archiver = NewDeadLetterArchiver(ConsumerConfig{GroupID: "richx"}, restarted, db, testLogger())
+	archiver.Start()
+	defer archiver.Stop()
+	if err := restarted.Publish(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	waitArchived(t, db, 2)
+}
+
+// 测试死信列表过滤和翻页，重新投递和丢弃只能执行一次
+func TestDeadLetterService(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now()
+	letters := []model.DeadLetter{
+		{Topic: DeadLetterTopic(claimTopic), MsgID: "1", MsgKey: "a", Payload: "p1", ErrorClass: ErrorClassInvalid, CreatedAt: now},
+		{Topic: DeadLetterTopic(claimTopic), MsgID: "2", MsgKey: "b", Payload: "p2", ErrorClass: ErrorClassTransient, CreatedAt: now},
+		{Topic: DeadLetterTopic(claimTopic), MsgID: "3", MsgKey: "c", Payload: "p3", ErrorClass: ErrorClassTransient, CreatedAt: now},
+	}
+	if err := db.Create(&letters).Error; err != nil {
+		t.Fatal(err)
+	}
+	bus := mq.NewMemoryBus(0)
+	defer bus.Close()
+	service := NewDeadLetterService(db, bus, "")
+
+	page, err := service.List(DeadLetterQuery{ErrorClass: ErrorClassTransient, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 1 || page[0].MsgID != "3" {
+		t.Fatalf("Expected newest transient letter first, got %+v", page)
+	}
+	page, _ = service.List(DeadLetterQuery{ErrorClass: ErrorClassTransient, Cursor: page[0].ID})
+	if len(page) != 1 || page[0].MsgID != "2" {
+		t.Fatalf("Expected second page with letter 2, got %+v", page)
+	}
+
+	if err := service.Redrive(context.Background(), letters[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	out := receive(t, bus, claimTopic)
+	if out.Key != "b" || string(out.Value) != "p2" || out.Headers[HeaderRedrivenFrom] != "2" || out.Headers[HeaderRetryCount] != "" {
+		t.Errorf("Unexpected redriven message %+v", out)
+	}
+	if err := service.Redrive(context.Background(), letters[1].ID); !errors.Is(err, ErrDeadLetterHandled) {
+		t.Errorf("Expected ErrDeadLetterHandled, got %v", err)
+	}
+	if err := service.Discard(letters[2].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Discard(letters[2].ID); !errors.Is(err, ErrDeadLetterHandled) {
+		t.Errorf("Expected ErrDeadLetterHandled, got %v", err)
+	}
+	if err := service.Redrive(context.Background(), 99); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Errorf("Expected ErrDeadLetterNotFound, got %v", err)
+	}
+	if bus.Len(claimTopic) != 1 {
+		t.Errorf("Expected one redriven message, got %d", bus.Len(claimTopic))
+	}
+
+	pending := model.DeadLetterStatusPending
+	page, _ = service.List(DeadLetterQuery{Status: &pending})
+	if len(page) != 1 || page[0].MsgID != "1" {
+		t.Errorf("Expected only letter 1 pending, got %+v", page)
+	}
+	if err := NewDeadLetterService(db, nil, "").Redrive(context.Background(), letters[0].ID); !errors.Is(err, ErrBusDisabled) {
+		t.Errorf("Expected ErrBusDisabled, got %v", err)
+	}
+}
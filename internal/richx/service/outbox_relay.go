@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"claimask/internal/richx/model"
+	"claimask/pkg/mq"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OutboxConfig 外发消息投递配置
+type OutboxConfig struct {
+	Interval      time.Duration // 轮询间隔
+	BatchSize     int           // 单次投递条数
+	Retention     time.Duration // 已发送消息保留时长
+	MaxRetryDelay time.Duration // 失败重试的最大退避时长
+	LagWarning    time.Duration // 积压告警阈值
+}
+
+// OutboxStats 外发消息积压情况
+type OutboxStats struct {
+	Pending       int64         `json:"pending"`       // 待发送条数
+	Failing       int64         `json:"failing"`       // 发送失败过的待发送条数
+	OldestPending *time.Time    `json:"oldestPending"` // 最早一条待发送消息的创建时间
+	Lag           time.Duration `json:"-"`             // 最早待发送消息的等待时长
+	LagSeconds    float64       `json:"lagSeconds"`
+}
+
+// OutboxRelay 将claim_outbox中的消息投递到消息总线
+// 先发送再标记，进程在两步之间退出会导致重复投递，因此语义为至少一次，消费端需按orderId幂等
+type OutboxRelay struct {
+	db        *DB
+	publisher mq.Publisher
+	logger    *log.Logger
+	cfg       OutboxConfig
+	ctx       context.Context
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// NewOutboxRelay 创建外发消息投递器，publisher为nil时消息保留在表中不投递
+func NewOutboxRelay(db *DB, publisher mq.Publisher, logger *log.Logger, cfg OutboxConfig) *OutboxRelay {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = 7 * 24 * time.Hour
+	}
+	if cfg.MaxRetryDelay <= 0 {
+		cfg.MaxRetryDelay = 5 * time.Minute
+	}
+	if cfg.LagWarning <= 0 {
+		cfg.LagWarning = time.Minute
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &OutboxRelay{
+		db:        db,
+		publisher: publisher,
+		logger:    logger,
+		cfg:       cfg,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Start 启动投递、清理和积压检查
+func (r *OutboxRelay) Start() {
+	r.done = make(chan struct{})
+	go func() {
+		defer close(r.done)
+		relayTicker := time.NewTicker(r.cfg.Interval)
+		cleanupTicker := time.NewTicker(time.Hour)
+		lagTicker := time.NewTicker(time.Minute)
+		defer relayTicker.Stop()
+		defer cleanupTicker.Stop()
+		defer lagTicker.Stop()
+
+		for {
+			select {
+			case <-relayTicker.C:
+				if _, err := r.RelayOnce(); err != nil {
+					r.logger.Printf("投递外发消息失败: %v", err)
+				}
+			case <-cleanupTicker.C:
+				if n, err := r.Cleanup(); err != nil {
+					r.logger.Printf("清理外发消息失败: %v", err)
+				} else if n > 0 {
+					r.logger.Printf("已清理 %d 条已发送的外发消息", n)
+				}
+			case <-lagTicker.C:
+				r.checkLag()
+			case <-r.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止投递，等待正在进行的一轮投递或清理完成
+func (r *OutboxRelay) Stop() {
+	r.cancel()
+	if r.done != nil {
+		<-r.done
+	}
+}
+
+// RelayOnce 投递一批到期的待发送消息，返回成功条数
+// 使用 FOR UPDATE SKIP LOCKED 锁定本批消息，多实例部署时互不重复
+func (r *OutboxRelay) RelayOnce() (int, error) {
+	if r.publisher == nil {
+		return 0, nil // 消息总线未启用，消息保留在表中等待后续投递
+	}
+
+	sent := 0
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var events []model.OutboxEvent
+		if err := claimOutbox(tx, time.Now(), r.cfg.BatchSize).Find(&events).Error; err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			now := time.Now()
+			var updates map[string]interface{}
+			msg := &mq.Message{Topic: event.Topic, Key: event.MsgKey, Value: []byte(event.Payload)}
+			if err := r.publisher.Publish(r.ctx, msg); err != nil {
+				updates = map[string]interface{}{
+					"attempts":      event.Attempts + 1,
+					"last_error":    truncate(err.Error(), 512),
+					"next_retry_at": now.Add(r.retryDelay(event.Attempts + 1)),
+				}
+			} else {
+				sent++
+				updates = map[string]interface{}{
+					"status":  model.OutboxStatusSent,
+					"sent_at": now,
+				}
+			}
+			if err := tx.Model(&model.OutboxEvent{}).Where("id = ?", event.ID).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return sent, err
+}
+
+// claimOutbox 按ID顺序锁定最多limit条到期的待发送消息，已被其他实例锁定的行直接跳过
+func claimOutbox(tx *gorm.DB, now time.Time, limit int) *gorm.DB {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND next_retry_at <= ?", model.OutboxStatusPending, now).
+		Order("id ASC").
+		Limit(limit)
+}
+
+// Cleanup 删除超过保留时长的已发送消息
+func (r *OutboxRelay) Cleanup() (int64, error) {
+	result := r.db.Where("status = ? AND sent_at < ?", model.OutboxStatusSent, time.Now().Add(-r.cfg.Retention)).
+		Delete(&model.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
+
+// Stats 统计待发送消息的积压情况
+func (r *OutboxRelay) Stats() (*OutboxStats, error) {
+	stats := &OutboxStats{}
+	pending := r.db.Model(&model.OutboxEvent{}).Where("status = ?", model.OutboxStatusPending).Session(&gorm.Session{})
+	if err := pending.Count(&stats.Pending).Error; err != nil {
+		return nil, err
+	}
+	if stats.Pending == 0 {
+		return stats, nil
+	}
+	if err := pending.Where("attempts > 0").Count(&stats.Failing).Error; err != nil {
+		return nil, err
+	}
+
+	var oldest model.OutboxEvent
+	if err := r.db.Where("status = ?", model.OutboxStatusPending).
+		Order("id ASC").
+		First(&oldest).Error; err != nil {
+		return nil, err
+	}
+	stats.OldestPending = &oldest.CreatedAt
+	stats.Lag = time.Since(oldest.CreatedAt)
+	stats.LagSeconds = stats.Lag.Seconds()
+	return stats, nil
+}
+
+// checkLag 积压超过阈值时输出告警日志
+func (r *OutboxRelay) checkLag() {
+	stats, err := r.Stats()
+	if err != nil {
+		r.logger.Printf("统计外发消息积压失败: %v", err)
+		return
+	}
+	if stats.Lag > r.cfg.LagWarning {
+		r.logger.Printf("外发消息积压告警: pending=%d failing=%d lag=%s", stats.Pending, stats.Failing, stats.Lag)
+	}
+}
+
+// retryDelay 计算第attempts次失败后的退避时长
+func (r *OutboxRelay) retryDelay(attempts int) time.Duration {
+	if attempts > 16 {
+		return r.cfg.MaxRetryDelay
+	}
+	delay := time.Second * time.Duration(1<<uint(attempts))
+	if delay > r.cfg.MaxRetryDelay {
+		return r.cfg.MaxRetryDelay
+	}
+	return delay
+}
+
+// truncate 截断过长的字符串
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
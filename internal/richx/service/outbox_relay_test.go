@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"claimask/internal/richx/model"
+	"claimask/pkg/mq"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// newTestDB 基于内存 sqlite 的数据库，建好领取相关的表
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内存数据库每个连接独立，只保留一个连接
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&model.RichRewardLog{}, &model.ClaimOrder{}, &model.OutboxEvent{}, &model.DeadLetter{}); err != nil {
+		t.Fatal(err)
+	}
+	return &DB{db}
+}
+
+// testLogger 丢弃输出的日志
+func testLogger() *log.Logger {
+	return log.New(io.Discard, "", 0)
+}
+
+// flakyPublisher 发布 key 为 failKey 的消息时报错，其余交给内层发布者
+type flakyPublisher struct {
+	mq.Publisher
+	failKey string
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, msg *mq.Message) error {
+	if msg.Key == p.failKey {
+		return errors.New("broker unavailable")
+	}
+	return p.Publisher.Publish(ctx, msg)
+}
+
+// 测试锁定待发送消息的语句带 SKIP LOCKED，多实例不会锁定同一批消息
+func TestClaimOutboxSkipLocked(t *testing.T) {
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "user@tcp(127.0.0.1:3306)/richx", SkipInitializeWithVersion: true}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []model.OutboxEvent
+	sql := claimOutbox(db, time.Now(), 10).Find(&events).Statement.SQL.String()
+	for _, want := range []string{"status = ? AND next_retry_at <= ?", "ORDER BY id ASC", "LIMIT ?", "FOR UPDATE SKIP LOCKED"} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("Expected %q in %s", want, sql)
+		}
+	}
+}
+
+// 测试投递到期消息，发送失败的消息保留为待发送并退避，未到期的消息不投递
+func TestOutboxRelayOnce(t *testing.T) {
+	db := newTestDB(t)
+	bus := mq.NewMemoryBus(0)
+	defer bus.Close()
+	relay := NewOutboxRelay(db, &flakyPublisher{Publisher: bus, failKey: "bad"}, testLogger(), OutboxConfig{})
+
+	now := time.Now()
+	events := []model.OutboxEvent{
+		{Topic: claimTopic, MsgKey: "a", Payload: "1", NextRetryAt: now.Add(-time.Second), CreatedAt: now},
+		{Topic: claimTopic, MsgKey: "bad", Payload: "2", NextRetryAt: now.Add(-time.Second), CreatedAt: now},
+		{Topic: claimTopic, MsgKey: "c", Payload: "3", NextRetryAt: now.Add(time.Hour), CreatedAt: now},
+	}
+	if err := db.Create(&events).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	sent, err := relay.RelayOnce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent != 1 || bus.Len(claimTopic) != 1 {
+		t.Fatalf("Expected one message published, got %d (bus %d)", sent, bus.Len(claimTopic))
+	}
+
+	var got []model.OutboxEvent
+	db.Order("id ASC").Find(&got)
+	if got[0].Status != model.OutboxStatusSent || got[0].SentAt == nil {
+		t.Errorf("Expected event a sent, got %+v", got[0])
+	}
+	failed := got[1]
+	if failed.Status != model.OutboxStatusPending || failed.Attempts != 1 || failed.LastError != "broker unavailable" {
+		t.Errorf("Expected event bad kept pending with error, got %+v", failed)
+	}
+	if !failed.NextRetryAt.After(now) {
+		t.Errorf("Expected retry to back off, got %s", failed.NextRetryAt)
+	}
+	if got[2].Status != model.OutboxStatusPending || got[2].Attempts != 0 {
+		t.Errorf("Expected future event untouched, got %+v", got[2])
+	}
+
+	// 再次投递时失败的消息未到重试时间，不重复发送已发送的消息
+	if sent, err := relay.RelayOnce(); err != nil || sent != 0 {
+		t.Errorf("Expected nothing due, got %d, %v", sent, err)
+	}
+	stats, err := relay.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Pending != 2 || stats.Failing != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+// blockingPublisher 发布时通知 started 并阻塞到 release 关闭，模拟正在进行的投递
+type blockingPublisher struct {
+	mq.Publisher
+	started chan struct{}
+	release chan struct{}
+}
+
+func (p *blockingPublisher) Publish(ctx context.Context, msg *mq.Message) error {
+	close(p.started)
+	<-p.release
+	return p.Publisher.Publish(ctx, msg)
+}
+
+// 测试停止投递时等待正在进行的一轮投递写回结果
+func TestOutboxRelayStopWaits(t *testing.T) {
+	db := newTestDB(t)
+	bus := mq.NewMemoryBus(0)
+	defer bus.Close()
+	publisher := &blockingPublisher{Publisher: bus, started: make(chan struct{}), release: make(chan struct{})}
+	relay := NewOutboxRelay(db, publisher, testLogger(), OutboxConfig{Interval: 10 * time.Millisecond})
+	now := time.Now()
+	if err := db.Create(&model.OutboxEvent{Topic: claimTopic, MsgKey: "a", Payload: "1", NextRetryAt: now.Add(-time.Second), CreatedAt: now}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	relay.Start()
+	<-publisher.started
+	stopped := make(chan struct{})
+	go func() {
+		relay.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatal("Expected Stop to wait for the in-flight relay")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(publisher.release)
+	<-stopped
+
+	var event model.OutboxEvent
+	if err := db.First(&event).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 停止时投递被取消，本轮仍在 Stop 返回前写回了结果
+	if event.Status != model.OutboxStatusPending || event.Attempts != 1 || event.LastError == "" {
+		t.Errorf("Expected the relay result written before Stop returned, got %+v", event)
+	}
+}
+
+// 测试只清理超过保留时长的已发送消息
+func TestOutboxRelayCleanup(t *testing.T) {
+	db := newTestDB(t)
+	relay := NewOutboxRelay(db, nil, testLogger(), OutboxConfig{Retention: time.Hour})
+
+	now := time.Now()
+	old, recent := now.Add(-2*time.Hour), now.Add(-time.Minute)
+	events := []model.OutboxEvent{
+		{Topic: claimTopic, MsgKey: "old", Status: model.OutboxStatusSent, SentAt: &old, CreatedAt: old},
+		{Topic: claimTopic, MsgKey: "recent", Status: model.OutboxStatusSent, SentAt: &recent, CreatedAt: recent},
+		{Topic: claimTopic, MsgKey: "pending", CreatedAt: old, NextRetryAt: old},
+	}
+	if err := db.Create(&events).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := relay.Cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("Expected one event removed, got %d", n)
+	}
+	var keys []string
+	db.Model(&model.OutboxEvent{}).Order("id ASC").Pluck("msg_key", &keys)
+	if strings.Join(keys, ",") != "recent,pending" {
+		t.Errorf("Unexpected remaining events %v", keys)
+	}
+
+	// 未启用消息总线时不投递
+	if sent, err := relay.RelayOnce(); err != nil || sent != 0 {
+		t.Errorf("Expected no relay without publisher, got %d, %v", sent, err)
+	}
+}
@@ -0,0 +1,430 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"claimask/internal/richx/model"
+	"claimask/pkg/money"
+)
+
+const (
+	// rewardUnit 收益的最小单位（0.1 DOGE），与 rich_reward_log.total_reward 的 decimal(9,1) 一致
+	rewardUnit = money.ElonPerDoge / 10
+	// maxTotalReward decimal(9,1) 能保存的最大收益 99999999.9 DOGE
+	maxTotalReward = money.Amount(999999999 * rewardUnit)
+)
+
+var (
+	// ErrNoSnapshot 领取日没有NFT快照
+	ErrNoSnapshot = errors.New("no nft snapshot for accrual date")
+	// ErrRewardOverflow 累加后的收益超出 total_reward 列的范围
+	ErrRewardOverflow = errors.New("accrued reward exceeds decimal(9,1)")
+)
+
+// AccrualConfig 收益累加任务配置
+type AccrualConfig struct {
+	Rates     map[string]string // 稀有度 -> 每个NFT每日收益（DOGE），十进制字符串，最小单位 0.1；稀有度不区分大小写
+	StartDate string            // 最早累加的领取日（2006-01-02），为空时从最早的快照开始
+	Interval  time.Duration     // 检查待累加领取日的间隔
+}
+
+// holderReward 一个地址在一个领取日的收益
+type holderReward struct {
+	Address    string
+	NFTCount   int
+	Amount     money.Amount
+	SkipReason string // 不为空时该地址当天不累加，只记录跳过原因
+}
+
+// snapshotCount 按地址和稀有度聚合的快照
+type snapshotCount struct {
+	Address string `gorm:"column:address"`
+	Rarity  string `gorm:"column:rarity"`
+	Count   int    `gorm:"column:count"`
+}
+
+// RewardAccrual 每日收益累加任务
+// 领取日结束后按当日NFT快照计算每个持有人的收益，累加到 rich_reward_log.total_reward 并更新 update_reward；
+// 每个地址每天的累加记录在 rich_reward_accrual_log 中，唯一键保证重复执行不会重复累加；
+// 稀有度未配置收益或累加后超出 total_reward 范围的地址记为跳过并告警，不阻塞其他地址和之后的领取日；
+// 每轮检查从起始日期到昨天所有未完成的领取日，因此停机错过的日期会自动补算
+type RewardAccrual struct {
+	db       *DB
+	calendar *ClaimCalendar
+	rates    map[string]money.Amount
+	start    *time.Time
+	cfg      AccrualConfig
+	logger   *log.Logger
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewRewardAccrual 创建收益累加任务
+func NewRewardAccrual(db *DB, calendar *ClaimCalendar, logger *log.Logger, cfg AccrualConfig) (*RewardAccrual, error) {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Minute
+	}
+
+	rates, err := parseRates(cfg.Rates)
+	if err != nil {
+		return nil, err
+	}
+
+	var start *time.Time
+	if cfg.StartDate != "" {
+		d, err := time.ParseInLocation(dateLayout, cfg.StartDate, calendar.Location())
+		if err != nil {
+			return nil, fmt.Errorf("invalid accrual start date %q: %w", cfg.StartDate, err)
+		}
+		start = &d
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &RewardAccrual{
+		db:       db,
+		calendar: calendar,
+		rates:    rates,
+		start:    start,
+		cfg:      cfg,
+		logger:   logger,
+		ctx:      ctx,
+		cancel:   cancel,
+	}, nil
+}
+
+// Start 启动收益累加任务，启动时立即检查一次
+func (a *RewardAccrual) Start() {
+	a.done = make(chan struct{})
+	go func() {
+		defer close(a.done)
+		ticker := time.NewTicker(a.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			if err := a.RunOnce(); err != nil {
+				a.logger.Printf("收益累加失败: %v", err)
+			}
+			select {
+			case <-ticker.C:
+			case <-a.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止收益累加任务，等待正在累加的地址写完
+func (a *RewardAccrual) Stop() {
+	a.cancel()
+	if a.done != nil {
+		<-a.done
+	}
+}
+
+// RunOnce 累加从起始日期到昨天所有未完成的领取日
+func (a *RewardAccrual) RunOnce() error {
+	from, err := a.firstDay()
+	if err != nil || from == nil {
+		return err
+	}
+	yesterday := a.calendar.Day(time.Now()).AddDate(0, 0, -1)
+	_, err = a.Backfill(*from, yesterday)
+	return err
+}
+
+// Backfill 按日期顺序累加 [from, to] 中未完成的领取日，返回本次完成的任务
+// 没有快照的领取日跳过，待快照补齐后下一轮再累加
+func (a *RewardAccrual) Backfill(from, to time.Time) ([]model.RewardAccrualRun, error) {
+	from, to = a.calendar.FromDate(from), a.calendar.FromDate(to)
+	if to.Before(from) {
+		return nil, nil
+	}
+
+	done, err := a.doneDays(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []model.RewardAccrualRun
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if a.ctx.Err() != nil {
+			return runs, a.ctx.Err()
+		}
+		if done[day.Format(dateLayout)] {
+			continue
+		}
+		run, err := a.AccrueDay(day)
+		if errors.Is(err, ErrNoSnapshot) {
+			a.logger.Printf("领取日 %s 没有NFT快照，跳过收益累加", day.Format(dateLayout))
+			continue
+		}
+		if err != nil {
+			return runs, fmt.Errorf("accrue %s failed: %w", day.Format(dateLayout), err)
+		}
+		runs = append(runs, *run)
+	}
+	return runs, nil
+}
+
+// AccrueDay 累加一个领取日的收益，已完成的领取日直接返回已有任务
+// 中途失败后重新执行时，已累加的地址由明细表唯一键跳过
+func (a *RewardAccrual) AccrueDay(day time.Time) (*model.RewardAccrualRun, error) {
+	date := day.Format(dateLayout)
+
+	var run model.RewardAccrualRun
+	err := a.db.Where("accrual_date = ?", date).First(&run).Error
+	switch {
+	case err == nil && run.Status == model.AccrualStatusDone:
+		return &run, nil
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	rewards, err := a.loadRewards(date)
+	if err != nil {
+		return nil, err
+	}
+	if len(rewards) == 0 {
+		return nil, ErrNoSnapshot
+	}
+
+	// 日期按字符串写入和查询，与 date 列的比较不受连接时区影响
+	if err := a.db.Model(&model.RewardAccrualRun{}).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(map[string]interface{}{
+			"accrual_date": date,
+			"status":       model.AccrualStatusRunning,
+			"started_at":   time.Now(),
+		}).Error; err != nil {
+		return nil, fmt.Errorf("create accrual run failed: %w", err)
+	}
+
+	for _, reward := range rewards {
+		skipped, err := a.accrueHolder(date, reward)
+		if err != nil {
+			return nil, fmt.Errorf("accrue %s failed: %w", reward.Address, err)
+		}
+		if skipped != "" {
+			a.logger.Printf("[告警] 领取日 %s 地址 %s 的收益 %s 未累加: %s", date, reward.Address, reward.Amount, skipped)
+		}
+	}
+
+	// 统计以明细表为准，包含中途失败前已累加和已跳过的地址
+	var logs []model.RewardAccrualLog
+	if err := a.db.Where("accrual_date = ?", date).Find(&logs).Error; err != nil {
+		return nil, fmt.Errorf("load accrual log failed: %w", err)
+	}
+	var holders, skipped int
+	var total money.Amount
+	for _, l := range logs {
+		if l.Status == model.AccrualLogSkipped {
+			skipped++
+			continue
+		}
+		holders++
+		total = total.Add(l.Amount)
+	}
+
+	if err := a.db.Model(&model.RewardAccrualRun{}).
+		Where("accrual_date = ?", date).
+		Updates(map[string]interface{}{
+			"status":       model.AccrualStatusDone,
+			"holders":      holders,
+			"skipped":      skipped,
+			"total_amount": total,
+			"finished_at":  time.Now(),
+		}).Error; err != nil {
+		return nil, fmt.Errorf("finish accrual run failed: %w", err)
+	}
+	if err := a.db.Where("accrual_date = ?", date).First(&run).Error; err != nil {
+		return nil, err
+	}
+	a.logger.Printf("领取日 %s 收益累加完成，地址数 %d，跳过 %d，总额 %s", date, run.Holders, run.Skipped, run.TotalAmount)
+	return &run, nil
+}
+
+// accrueHolder 在一个事务中写入累加明细并累加收益，明细已存在时跳过
+// 地址带有跳过原因或累加后超出 total_reward 列的范围时只写入跳过明细，不修改收益，避免数据库截断或报错后少记；
+// 返回本次新记为跳过的原因，运维处理后删除该明细并将领取日任务改回进行中即可重新累加
+func (a *RewardAccrual) accrueHolder(date string, reward holderReward) (string, error) {
+	var skipped string
+	err := a.db.Transaction(func(tx *gorm.DB) error {
+		reason := reward.SkipReason
+		if reason == "" {
+			var current []money.Amount
+			if err := tx.Model(&model.RichRewardLog{}).
+				Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("address = ?", reward.Address).
+				Pluck("total_reward", &current).Error; err != nil {
+				return err
+			}
+			var total money.Amount
+			if len(current) > 0 {
+				total = current[0]
+			}
+			if err := checkRewardRange(total, reward.Amount); err != nil {
+				reason = err.Error()
+			}
+		}
+
+		status := model.AccrualLogAccrued
+		if reason != "" {
+			status = model.AccrualLogSkipped
+		}
+		result := tx.Model(&model.RewardAccrualLog{}).
+			Clauses(clause.OnConflict{DoNothing: true}).
+			Create(map[string]interface{}{
+				"accrual_date": date,
+				"address":      reward.Address,
+				"nft_count":    reward.NFTCount,
+				"amount":       reward.Amount,
+				"status":       status,
+				"skip_reason":  reason,
+				"created_at":   time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil // 该地址当天已累加或已跳过
+		}
+		if reason != "" {
+			skipped = reason
+			return nil
+		}
+
+		// update_reward 只前进不后退，补算较早的领取日时保持最新日期
+		return tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "address"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"total_reward":  gorm.Expr("total_reward + ?", reward.Amount),
+				"update_reward": gorm.Expr("CASE WHEN update_reward IS NULL OR update_reward < ? THEN ? ELSE update_reward END", date, date),
+			}),
+		}).Model(&model.RichRewardLog{}).Create(map[string]interface{}{
+			"address":       reward.Address,
+			"total_reward":  reward.Amount,
+			"update_reward": date,
+		}).Error
+	})
+	return skipped, err
+}
+
+// loadRewards 读取领取日的快照并计算每个地址的收益
+func (a *RewardAccrual) loadRewards(date string) ([]holderReward, error) {
+	var counts []snapshotCount
+	if err := a.db.Model(&model.NFTSnapshot{}).
+		Select("address, rarity, COUNT(*) AS count").
+		Where("snapshot_date = ?", date).
+		Group("address, rarity").
+		Scan(&counts).Error; err != nil {
+		return nil, fmt.Errorf("load nft snapshot failed: %w", err)
+	}
+	return computeRewards(counts, a.rates), nil
+}
+
+// firstDay 返回最早需要累加的领取日，没有配置起始日期且没有快照时返回nil
+func (a *RewardAccrual) firstDay() (*time.Time, error) {
+	if a.start != nil {
+		return a.start, nil
+	}
+
+	var snapshot model.NFTSnapshot
+	if err := a.db.Order("snapshot_date ASC").First(&snapshot).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	day := a.calendar.FromDate(snapshot.SnapshotDate)
+	return &day, nil
+}
+
+// doneDays 返回区间内已完成的领取日
+func (a *RewardAccrual) doneDays(from, to time.Time) (map[string]bool, error) {
+	var runs []model.RewardAccrualRun
+	if err := a.db.Where("accrual_date BETWEEN ? AND ? AND status = ?",
+		from.Format(dateLayout), to.Format(dateLayout), model.AccrualStatusDone).
+		Find(&runs).Error; err != nil {
+		return nil, err
+	}
+
+	done := make(map[string]bool, len(runs))
+	for _, run := range runs {
+		done[a.calendar.FromDate(run.AccrualDate).Format(dateLayout)] = true
+	}
+	return done, nil
+}
+
+// checkRewardRange 判断已有收益加上 amount 后是否仍在 total_reward 列的范围内
+func checkRewardRange(total, amount money.Amount) error {
+	if amount > maxTotalReward || total > maxTotalReward-amount {
+		return fmt.Errorf("%w: %s + %s", ErrRewardOverflow, total, amount)
+	}
+	return nil
+}
+
+// computeRewards 按稀有度收益计算每个地址的收益，结果按地址排序
+// 稀有度按小写匹配收益配置，持有未配置稀有度的地址带上跳过原因，避免静默少发
+func computeRewards(counts []snapshotCount, rates map[string]money.Amount) []holderReward {
+	byAddress := make(map[string]*holderReward)
+	for _, c := range counts {
+		reward, ok := byAddress[c.Address]
+		if !ok {
+			reward = &holderReward{Address: c.Address}
+			byAddress[c.Address] = reward
+		}
+		reward.NFTCount += c.Count
+		rate, ok := rates[strings.ToLower(c.Rarity)]
+		if !ok {
+			if reward.SkipReason == "" {
+				reward.SkipReason = fmt.Sprintf("no reward rate for rarity %q", c.Rarity)
+			}
+			continue
+		}
+		reward.Amount = reward.Amount.Add(rate.Mul(int64(c.Count)))
+	}
+
+	rewards := make([]holderReward, 0, len(byAddress))
+	for _, reward := range byAddress {
+		if reward.Amount.Sign() > 0 || reward.SkipReason != "" {
+			rewards = append(rewards, *reward)
+		}
+	}
+	sort.Slice(rewards, func(i, j int) bool { return rewards[i].Address < rewards[j].Address })
+	return rewards
+}
+
+// parseRates 解析稀有度收益配置，收益必须非负且是 rewardUnit 的整数倍
+// 配置经 viper 读取后键已是小写，这里统一转为小写，与快照中的稀有度按小写匹配
+func parseRates(cfg map[string]string) (map[string]money.Amount, error) {
+	if len(cfg) == 0 {
+		return nil, errors.New("no reward rates configured")
+	}
+
+	rates := make(map[string]money.Amount, len(cfg))
+	for rarity, v := range cfg {
+		rate, err := money.ParseDoge(v)
+		if err != nil || rate.Sign() < 0 {
+			return nil, fmt.Errorf("invalid reward rate %q for rarity %q", v, rarity)
+		}
+		if rate.Elon()%rewardUnit != 0 {
+			return nil, fmt.Errorf("reward rate %q for rarity %q is finer than %s DOGE", v, rarity, money.FromElon(rewardUnit))
+		}
+		key := strings.ToLower(rarity)
+		if _, ok := rates[key]; ok {
+			return nil, fmt.Errorf("duplicate reward rate for rarity %q", key)
+		}
+		rates[key] = rate
+	}
+	return rates, nil
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"claimask/internal/richx/model"
+	"claimask/pkg/money"
+)
+
+// 测试按稀有度计算每个地址的收益
+func TestComputeRewards(t *testing.T) {
+	rates, err := parseRates(map[string]string{"common": "0.1", "Rare": "0.5", "none": "0"})
+	if err != nil {
+		t.Fatalf("parse rates failed: %v", err)
+	}
+
+	rewards := computeRewards([]snapshotCount{
+		{Address: "DB", Rarity: "common", Count: 3},
+		{Address: "DA", Rarity: "common", Count: 7},
+		{Address: "DA", Rarity: "RARE", Count: 1},
+		{Address: "DA", Rarity: "rare", Count: 1},
+		{Address: "DC", Rarity: "none", Count: 5},
+		{Address: "DD", Rarity: "common", Count: 1},
+		{Address: "DD", Rarity: "mythic", Count: 1},
+	}, rates)
+
+	// 稀有度不区分大小写；0.1 不能被二进制浮点精确表示，7*0.1 + 2*0.5 需精确得到 1.7
+	// 持有未配置稀有度的地址带上跳过原因，不影响其他地址
+	want := []struct {
+		address string
+		count   int
+		amount  string
+		skip    bool
+	}{
+		{"DA", 9, "1.7", false},
+		{"DB", 3, "0.3", false},
+		{"DD", 2, "0.1", true},
+	}
+	if len(rewards) != len(want) {
+		t.Fatalf("Expected %d rewards, got %d", len(want), len(rewards))
+	}
+	for i, w := range want {
+		if rewards[i].Address != w.address || rewards[i].NFTCount != w.count || rewards[i].Amount.String() != w.amount ||
+			(rewards[i].SkipReason != "") != w.skip {
+			t.Errorf("Reward %d: expected %s/%d/%s/%v, got %+v", i, w.address, w.count, w.amount, w.skip, rewards[i])
+		}
+	}
+}
+
+// 测试收益配置校验
+func TestParseRates(t *testing.T) {
+	valid := []map[string]string{
+		{"common": "0.1"},
+		{"common": "12", "rare": "3.5"},
+	}
+	for _, cfg := range valid {
+		if _, err := parseRates(cfg); err != nil {
+			t.Errorf("%v: unexpected error %v", cfg, err)
+		}
+	}
+
+	invalid := []map[string]string{
+		nil,
+		{"common": "abc"},
+		{"common": "-1"},
+		{"common": "0.05"}, // 超过1位小数
+		{"common": "1/3"},
+		{"common": "0.1", "Common": "0.2"},
+	}
+	for _, cfg := range invalid {
+		if _, err := parseRates(cfg); err == nil {
+			t.Errorf("%v: expected error", cfg)
+		}
+	}
+}
+
+// 测试累加后的收益不超出 decimal(9,1)
+func TestCheckRewardRange(t *testing.T) {
+	cases := []struct {
+		total, amount string
+		ok            bool
+	}{
+		{"0", "99999999.9", true},
+		{"99999999.8", "0.1", true},
+		{"99999999.8", "0.2", false},
+		{"0", "100000000", false},
+	}
+	for _, c := range cases {
+		err := checkRewardRange(money.MustParseDoge(c.total), money.MustParseDoge(c.amount))
+		if c.ok && err != nil || !c.ok && !errors.Is(err, ErrRewardOverflow) {
+			t.Errorf("%s + %s: unexpected result %v", c.total, c.amount, err)
+		}
+	}
+}
+
+// newTestAccrual 基于内存 sqlite 的收益累加任务，稀有度 common 每日 0.1、rare 每日 0.5
+func newTestAccrual(t *testing.T) (*RewardAccrual, *DB) {
+	t.Helper()
+	db := newTestDB(t)
+	if err := db.AutoMigrate(&model.NFTSnapshot{}, &model.RewardAccrualRun{}, &model.RewardAccrualLog{}); err != nil {
+		t.Fatal(err)
+	}
+	calendar, err := NewClaimCalendar("Asia/Shanghai", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accrual, err := NewRewardAccrual(db, calendar, testLogger(), AccrualConfig{Rates: map[string]string{"common": "0.1", "rare": "0.5"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return accrual, db
+}
+
+// addSnapshot 写入领取日 date 的快照，rarities 依次为每个NFT的稀有度
+func addSnapshot(t *testing.T, db *DB, date, address string, rarities ...string) {
+	t.Helper()
+	for _, rarity := range rarities {
+		if err := db.Model(&model.NFTSnapshot{}).Create(map[string]interface{}{
+			"snapshot_date": date,
+			"address":       address,
+			"nft_id":        address + "-" + rarity,
+			"rarity":        rarity,
+		}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// totalReward 读取地址的累计收益和最新累加日期，没有记录时返回空
+func totalReward(t *testing.T, db *DB, address string) (string, string) {
+	t.Helper()
+	var rows []struct {
+		TotalReward  money.Amount
+		UpdateReward string
+	}
+	if err := db.Model(&model.RichRewardLog{}).Select("total_reward, update_reward").
+		Where("address = ?", address).Scan(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) == 0 {
+		return "", ""
+	}
+	return rows[0].TotalReward.String(), rows[0].UpdateReward
+}
+
+// 测试重复执行和中途失败后重新执行都不会重复累加，异常地址跳过后不阻塞其他地址
+func TestAccrueDay(t *testing.T) {
+	accrual, db := newTestAccrual(t)
+	day := time.Date(2026, 5, 1, 0, 0, 0, 0, accrual.calendar.Location())
+	date := day.Format(dateLayout)
+	addSnapshot(t, db, date, "DA", "common", "rare")
+	addSnapshot(t, db, date, "DB", "rare")
+	addSnapshot(t, db, date, "DC", "mythic")
+	addSnapshot(t, db, date, "DD", "common")
+	// DD 再累加就超出 decimal(9,1)
+	if err := db.Create(&model.RichRewardLog{Address: "DD", TotalReward: maxTotalReward}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟上次执行在累加 DA 之后中断
+	if _, err := accrual.accrueHolder(date, holderReward{Address: "DA", NFTCount: 2, Amount: money.MustParseDoge("0.6")}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		run, err := accrual.AccrueDay(day)
+		if err != nil {
+			t.Fatalf("Run %d: accrue day failed: %v", i, err)
+		}
+		if run.Status != model.AccrualStatusDone || run.Holders != 2 || run.Skipped != 2 || run.TotalAmount.String() != "1.1" {
+			t.Errorf("Run %d: expected done with 2 holders, 2 skipped and 1.1 total, got %+v", i, run)
+		}
+	}
+
+	want := map[string]string{"DA": "0.6", "DB": "0.5", "DC": "", "DD": maxTotalReward.String()}
+	for address, reward := range want {
+		if got, _ := totalReward(t, db, address); got != reward {
+			t.Errorf("%s: expected total reward %q, got %q", address, reward, got)
+		}
+	}
+
+	var skipped []model.RewardAccrualLog
+	if err := db.Where("status = ?", model.AccrualLogSkipped).Order("address").Find(&skipped).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 2 || skipped[0].Address != "DC" || skipped[1].Address != "DD" || skipped[1].SkipReason == "" {
+		t.Errorf("Expected DC and DD skipped with reason, got %+v", skipped)
+	}
+}
+
+// 测试补算跳过没有快照的领取日，快照补齐后再补算，update_reward 不后退
+func TestBackfill(t *testing.T) {
+	accrual, db := newTestAccrual(t)
+	loc := accrual.calendar.Location()
+	from := time.Date(2026, 5, 1, 0, 0, 0, 0, loc)
+	to := from.AddDate(0, 0, 2)
+	addSnapshot(t, db, "2026-05-01", "DA", "common")
+	addSnapshot(t, db, "2026-05-03", "DA", "rare")
+
+	runs, err := accrual.Backfill(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("Expected 2 runs, got %d", len(runs))
+	}
+	if total, updated := totalReward(t, db, "DA"); total != "0.6" || updated[:10] != "2026-05-03" {
+		t.Errorf("Expected 0.6 accrued up to 2026-05-03, got %s at %s", total, updated)
+	}
+
+	addSnapshot(t, db, "2026-05-02", "DA", "common")
+	runs, err = accrual.Backfill(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].Holders != 1 {
+		t.Fatalf("Expected only the gap day accrued, got %+v", runs)
+	}
+	if total, updated := totalReward(t, db, "DA"); total != "0.7" || updated[:10] != "2026-05-03" {
+		t.Errorf("Expected 0.7 accrued up to 2026-05-03, got %s at %s", total, updated)
+	}
+}
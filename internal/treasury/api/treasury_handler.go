@@ -0,0 +1,36 @@
+package api
+
+import (
+	"claimask/comm/response"
+	"claimask/internal/treasury/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TreasuryAPI 资金监控
+type TreasuryAPI struct {
+	Treasury *service.Treasury
+}
+
+// NewTreasuryAPI 创建TreasuryAPI实例
+func NewTreasuryAPI(treasury *service.Treasury) *TreasuryAPI {
+	return &TreasuryAPI{Treasury: treasury}
+}
+
+// GetTreasury 查询最近一次资金统计，refresh=true 或尚未统计时立即统计
+func (api *TreasuryAPI) GetTreasury(ctx *gin.Context) {
+	snapshot := api.Treasury.Last()
+	if snapshot == nil || ctx.Query("refresh") == "true" {
+		var err error
+		if snapshot, err = api.Treasury.Check(); err != nil {
+			response.FailWithMessage(ctx, response.ERROR, "资金统计失败: "+err.Error())
+			return
+		}
+	}
+	response.OkWithData(ctx, snapshot)
+}
+
+// RegisterTreasuryRoutes 设置资金监控路由，均为运营接口
+func RegisterTreasuryRoutes(r *gin.RouterGroup, api *TreasuryAPI, adminAuth gin.HandlerFunc) {
+	r.GET("/admin/treasury", adminAuth, api.GetTreasury)
+}
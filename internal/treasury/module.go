@@ -0,0 +1,75 @@
+// Package treasury 资金监控模块：定时统计热钱包和各钱包组余额，与待打款订单金额比较并告警
+package treasury
+
+import (
+	"context"
+
+	"claimask/comm/initialize"
+	"claimask/comm/middleware"
+	claimdao "claimask/internal/claimask/dao"
+	monitordao "claimask/internal/monitor/dao"
+	monitorservice "claimask/internal/monitor/service"
+	"claimask/internal/treasury/api"
+	"claimask/internal/treasury/service"
+
+	"go.uber.org/zap"
+)
+
+// Module 资金监控模块
+type Module struct {
+	treasury *service.Treasury
+}
+
+// NewModule 创建资金监控模块
+func NewModule() *Module {
+	return &Module{}
+}
+
+// Name 模块名称
+func (m *Module) Name() string {
+	return "treasury"
+}
+
+// Init 创建资金监控并注册 /api/admin/treasury 路由
+func (m *Module) Init(s *initialize.Server) error {
+	cfg := s.Config()
+	alerter := service.MultiAlerter{service.LogAlerter{}}
+	if cfg.Treasury.Alert.Webhook != "" {
+		alerter = append(alerter, service.NewWebhookAlerter(cfg.Treasury.Alert.Webhook, cfg.Treasury.Alert.Timeout))
+	}
+	m.treasury = service.NewTreasury(utxoSource(s), monitordao.NewWalletGroupDao(s.DB()), claimdao.NewOrderDAO(s.DB()), alerter, service.Config{
+		HotWallet:        cfg.Payout.Address,
+		Interval:         cfg.Treasury.Interval,
+		MinConfirmations: cfg.Treasury.MinConfirmations,
+		MinCoverage:      cfg.Treasury.MinCoverage,
+		AlertRepeat:      cfg.Treasury.Alert.Repeat,
+	})
+
+	adminAuth := middleware.AdminAuth(cfg.Admin.Token.Reveal())
+	api.RegisterTreasuryRoutes(s.Engine.Group("/api"), api.NewTreasuryAPI(m.treasury), adminAuth)
+	return nil
+}
+
+// utxoSource 余额统计的UTXO来源
+// 钱包组地址不在节点钱包中，listunspent 查不到其UTXO，开启UTXO索引时从索引读取；索引由监控模块同步，这里只读
+func utxoSource(s *initialize.Server) service.Chain {
+	cfg := s.Config()
+	if !cfg.Monitor.Indexer.Enabled {
+		zap.L().Warn("未开启UTXO索引，钱包组余额依赖节点钱包的 listunspent，不在节点钱包中的地址余额为0")
+		return s.RPC()
+	}
+	return monitorservice.NewUTXOIndexer(monitordao.NewUTXODao(s.DB()), s.RPC(), monitorservice.IndexerConfig{
+		ReorgDepth: cfg.Monitor.Indexer.ReorgDepth,
+	})
+}
+
+// Start 启动定时统计
+func (m *Module) Start() error {
+	m.treasury.Start()
+	return nil
+}
+
+// Stop 停止定时统计
+func (m *Module) Stop(ctx context.Context) error {
+	return initialize.StopFunc(ctx, m.treasury.Stop)
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 告警事件
+const (
+	AlertLowCoverage = "treasury.low_coverage" // 覆盖率低于下限
+	AlertRecovered   = "treasury.recovered"    // 覆盖率恢复
+)
+
+// Alert 资金告警
+type Alert struct {
+	Event    string    `json:"event"`
+	Text     string    `json:"text"` // 可直接发到聊天群的摘要
+	Snapshot *Snapshot `json:"snapshot"`
+}
+
+// NewAlert 由统计结果生成告警
+func NewAlert(event string, snapshot *Snapshot) Alert {
+	coverage := "n/a"
+	if snapshot.Coverage != nil {
+		coverage = fmt.Sprintf("%.2f", *snapshot.Coverage)
+	}
+	var text string
+	if event == AlertRecovered {
+		text = fmt.Sprintf("资金覆盖率已恢复：%s（下限 %.2f）", coverage, snapshot.MinCoverage)
+	} else {
+		text = fmt.Sprintf("资金覆盖率过低：%s（下限 %.2f），已确认余额 %d，待打款 %d（%d 笔）",
+			coverage, snapshot.MinCoverage, snapshot.Confirmed, snapshot.Outstanding, snapshot.OutstandingOrders)
+	}
+	return Alert{Event: event, Text: text, Snapshot: snapshot}
+}
+
+// Alerter 告警通道
+type Alerter interface {
+	Alert(ctx context.Context, alert Alert) error
+}
+
+// LogAlerter 将告警写入日志
+type LogAlerter struct{}
+
+// Alert 写入告警日志
+func (LogAlerter) Alert(ctx context.Context, alert Alert) error {
+	if alert.Event == AlertRecovered {
+		zap.L().Info(alert.Text, zap.String("event", alert.Event))
+	} else {
+		zap.L().Error(alert.Text, zap.String("event", alert.Event))
+	}
+	return nil
+}
+
+// WebhookAlerter 以 JSON POST 到 webhook 地址
+type WebhookAlerter struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookAlerter 创建 webhook 告警
+func NewWebhookAlerter(url string, timeout time.Duration) *WebhookAlerter {
+	return &WebhookAlerter{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// Alert 发送告警，非2xx响应视为失败
+func (w *WebhookAlerter) Alert(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// MultiAlerter 依次发送到多个告警通道，某个通道失败不影响其他通道
+type MultiAlerter []Alerter
+
+// Alert 发送告警，返回所有通道的错误
+func (m MultiAlerter) Alert(ctx context.Context, alert Alert) error {
+	var errs []error
+	for _, alerter := range m {
+		if err := alerter.Alert(ctx, alert); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
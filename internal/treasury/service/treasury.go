@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	claimdao "claimask/internal/claimask/dao"
+	claimpo "claimask/internal/claimask/model/po"
+	"claimask/internal/monitor/model/po"
+	"claimask/pkg/dogechain"
+
+	"go.uber.org/zap"
+)
+
+// OutstandingStatuses 尚未打款的订单状态，失败的订单在退款前仍可能重新排队打款
+var OutstandingStatuses = []claimpo.OrderStatus{
+	claimpo.OrderStatusCreated,
+	claimpo.OrderStatusQueued,
+	claimpo.OrderStatusFailed,
+}
+
+// Chain 资金统计依赖的链上接口
+type Chain interface {
+	GetAddressUTXOs(address string) ([]dogechain.UTXO, error)
+}
+
+// WalletSource 监控中的钱包组
+type WalletSource interface {
+	ListWalletGroups(includeRetired bool) ([]po.WalletGroupPO, error)
+}
+
+// Liabilities 待打款订单
+type Liabilities interface {
+	SumAmountByStatus(statuses ...claimpo.OrderStatus) (claimdao.OrderSum, error)
+}
+
+// Config 资金监控配置
+type Config struct {
+	HotWallet        string        // 打款热钱包地址，为空时只统计钱包组
+	Interval         time.Duration // 统计间隔
+	MinConfirmations int64         // 计入已确认余额所需的确认数
+	MinCoverage      float64       // 已确认余额与待打款金额之比的下限
+	AlertRepeat      time.Duration // 持续低于下限时重复告警的间隔
+}
+
+// WalletBalance 一个钱包的余额，金额单位为 ELON
+type WalletBalance struct {
+	Group       int    `json:"group"` // 打款热钱包为0
+	Address     string `json:"address"`
+	Confirmed   int64  `json:"confirmed"`
+	Unconfirmed int64  `json:"unconfirmed"`
+	UTXOs       int    `json:"utxos"`
+	Error       string `json:"error,omitempty"` // 查询UTXO失败的原因，失败的钱包不计入合计
+}
+
+// Snapshot 一次资金统计结果，金额单位为 ELON
+type Snapshot struct {
+	Wallets           []WalletBalance `json:"wallets"`
+	Confirmed         int64           `json:"confirmed"`
+	Unconfirmed       int64           `json:"unconfirmed"`
+	Outstanding       int64           `json:"outstanding"`       // 待打款金额
+	OutstandingOrders int64           `json:"outstandingOrders"` // 待打款订单数
+	Coverage          *float64        `json:"coverage"`          // 已确认余额/待打款金额，没有待打款订单时为null
+	MinCoverage       float64         `json:"minCoverage"`
+	Healthy           bool            `json:"healthy"`
+	Partial           bool            `json:"partial"` // 部分钱包查询失败
+	CheckedAt         time.Time       `json:"checkedAt"`
+}
+
+// Treasury 资金监控
+// 周期性统计热钱包和各钱包组的UTXO余额，与待打款订单金额比较，覆盖率低于下限时告警
+type Treasury struct {
+	chain       Chain
+	wallets     WalletSource
+	liabilities Liabilities
+	alerter     Alerter
+	cfg         Config
+
+	mu        sync.Mutex
+	last      *Snapshot
+	low       bool      // 上一次统计是否低于下限
+	alertedAt time.Time // 上一次低覆盖率告警的时间
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewTreasury 创建资金监控
+func NewTreasury(chain Chain, wallets WalletSource, liabilities Liabilities, alerter Alerter, cfg Config) *Treasury {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Minute
+	}
+	if cfg.AlertRepeat <= 0 {
+		cfg.AlertRepeat = time.Hour
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Treasury{
+		chain:       chain,
+		wallets:     wallets,
+		liabilities: liabilities,
+		alerter:     alerter,
+		cfg:         cfg,
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Check 统计一次资金并按需告警，返回统计结果
+func (t *Treasury) Check() (*Snapshot, error) {
+	groups, err := t.wallets.ListWalletGroups(false)
+	if err != nil {
+		return nil, err
+	}
+	outstanding, err := t.liabilities.SumAmountByStatus(OutstandingStatuses...)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{
+		Outstanding:       outstanding.Amount,
+		OutstandingOrders: outstanding.Count,
+		MinCoverage:       t.cfg.MinCoverage,
+		CheckedAt:         time.Now(),
+	}
+	if t.cfg.HotWallet != "" {
+		snapshot.Wallets = append(snapshot.Wallets, t.balance(0, t.cfg.HotWallet))
+	}
+	for _, group := range groups {
+		if group.ReceiveAddr == t.cfg.HotWallet {
+			continue
+		}
+		snapshot.Wallets = append(snapshot.Wallets, t.balance(group.GroupID, group.ReceiveAddr))
+	}
+	for _, wallet := range snapshot.Wallets {
+		if wallet.Error != "" {
+			snapshot.Partial = true
+			continue
+		}
+		snapshot.Confirmed += wallet.Confirmed
+		snapshot.Unconfirmed += wallet.Unconfirmed
+	}
+
+	snapshot.Healthy = true
+	if snapshot.Outstanding > 0 {
+		coverage := float64(snapshot.Confirmed) / float64(snapshot.Outstanding)
+		snapshot.Coverage = &coverage
+		snapshot.Healthy = coverage >= t.cfg.MinCoverage
+	}
+
+	t.mu.Lock()
+	t.last = snapshot
+	t.mu.Unlock()
+	t.alert(snapshot)
+	return snapshot, nil
+}
+
+// Last 返回最近一次统计结果，尚未统计时为nil
+func (t *Treasury) Last() *Snapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.last
+}
+
+// balance 按确认数统计一个钱包的余额
+func (t *Treasury) balance(group int, address string) WalletBalance {
+	balance := WalletBalance{Group: group, Address: address}
+	utxos, err := t.chain.GetAddressUTXOs(address)
+	if err != nil {
+		zap.L().Warn("查询钱包UTXO失败", zap.String("address", address), zap.Error(err))
+		balance.Error = err.Error()
+		return balance
+	}
+	balance.UTXOs = len(utxos)
+	for _, utxo := range utxos {
+		if utxo.Confirmations >= t.cfg.MinConfirmations {
+			balance.Confirmed += utxo.Value
+		} else {
+			balance.Unconfirmed += utxo.Value
+		}
+	}
+	return balance
+}
+
+// alert 覆盖率低于下限时告警，持续低于下限时按 AlertRepeat 重复告警，恢复时通知一次
+// 部分钱包查询失败时余额偏低，不据此告警
+func (t *Treasury) alert(snapshot *Snapshot) {
+	if snapshot.Partial {
+		return
+	}
+
+	t.mu.Lock()
+	var event string
+	switch {
+	case !snapshot.Healthy && (!t.low || snapshot.CheckedAt.Sub(t.alertedAt) >= t.cfg.AlertRepeat):
+		event = AlertLowCoverage
+		t.alertedAt = snapshot.CheckedAt
+	case snapshot.Healthy && t.low:
+		event = AlertRecovered
+	}
+	t.low = !snapshot.Healthy
+	t.mu.Unlock()
+
+	if event == "" {
+		return
+	}
+	if err := t.alerter.Alert(t.ctx, NewAlert(event, snapshot)); err != nil {
+		zap.L().Error("发送资金告警失败", zap.String("event", event), zap.Error(err))
+	}
+}
+
+// Start 启动定时统计
+func (t *Treasury) Start() {
+	t.done = make(chan struct{})
+	go func() {
+		defer close(t.done)
+		ticker := time.NewTicker(t.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := t.Check(); err != nil {
+				zap.L().Warn("资金统计失败", zap.Error(err))
+			}
+			select {
+			case <-ticker.C:
+			case <-t.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止定时统计，等待正在进行的统计完成
+func (t *Treasury) Stop() {
+	t.cancel()
+	if t.done != nil {
+		<-t.done
+	}
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	claimdao "claimask/internal/claimask/dao"
+	claimpo "claimask/internal/claimask/model/po"
+	"claimask/internal/monitor/model/po"
+	"claimask/pkg/dogechain"
+)
+
+// fakeChain 按地址返回固定的UTXO
+type fakeChain map[string][]dogechain.UTXO
+
+func (c fakeChain) GetAddressUTXOs(address string) ([]dogechain.UTXO, error) {
+	utxos, ok := c[address]
+	if !ok {
+		return nil, errors.New("rpc unavailable")
+	}
+	return utxos, nil
+}
+
+type fakeWallets []po.WalletGroupPO
+
+func (w fakeWallets) ListWalletGroups(includeRetired bool) ([]po.WalletGroupPO, error) {
+	return w, nil
+}
+
+// fakeLiabilities 待打款金额可在测试中修改
+type fakeLiabilities struct {
+	sum      claimdao.OrderSum
+	statuses []claimpo.OrderStatus
+}
+
+func (l *fakeLiabilities) SumAmountByStatus(statuses ...claimpo.OrderStatus) (claimdao.OrderSum, error) {
+	l.statuses = statuses
+	return l.sum, nil
+}
+
+// recordAlerter 记录收到的告警事件
+type recordAlerter struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recordAlerter) Alert(ctx context.Context, alert Alert) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, alert.Event)
+	return nil
+}
+
+// 测试按确认数区分余额，热钱包和钱包组合计，覆盖率按已确认余额计算
+func TestTreasuryCheck(t *testing.T) {
+	chain := fakeChain{
+		"DHot":    {{Value: 600, Confirmations: 3}, {Value: 50, Confirmations: 0}},
+		"DGroup1": {{Value: 400, Confirmations: 1}},
+	}
+	wallets := fakeWallets{{GroupID: 1, ReceiveAddr: "DGroup1"}, {GroupID: 2, ReceiveAddr: "DHot"}}
+	liabilities := &fakeLiabilities{sum: claimdao.OrderSum{Count: 4, Amount: 800}}
+	treasury := NewTreasury(chain, wallets, liabilities, &recordAlerter{}, Config{HotWallet: "DHot", MinConfirmations: 1, MinCoverage: 1.2})
+
+	snapshot, err := treasury.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot.Wallets) != 2 {
+		t.Fatalf("Expected the hot wallet to be counted once, got %+v", snapshot.Wallets)
+	}
+	if snapshot.Confirmed != 1000 || snapshot.Unconfirmed != 50 || snapshot.Outstanding != 800 || snapshot.OutstandingOrders != 4 {
+		t.Errorf("Unexpected totals %+v", snapshot)
+	}
+	if snapshot.Coverage == nil || *snapshot.Coverage != 1.25 || !snapshot.Healthy || snapshot.Partial {
+		t.Errorf("Expected healthy coverage 1.25, got %+v", snapshot)
+	}
+	if len(liabilities.statuses) != len(OutstandingStatuses) {
+		t.Errorf("Expected outstanding statuses, got %v", liabilities.statuses)
+	}
+	if treasury.Last() != snapshot {
+		t.Error("Expected Last to return the latest snapshot")
+	}
+
+	liabilities.sum = claimdao.OrderSum{}
+	if snapshot, _ = treasury.Check(); snapshot.Coverage != nil || !snapshot.Healthy {
+		t.Errorf("Expected no coverage without outstanding orders, got %+v", snapshot)
+	}
+}
+
+// 测试低于下限时告警一次、持续低于下限时按间隔重复、恢复时通知，部分钱包查询失败时不告警
+func TestTreasuryAlerts(t *testing.T) {
+	chain := fakeChain{"DHot": {{Value: 1000, Confirmations: 6}}}
+	liabilities := &fakeLiabilities{sum: claimdao.OrderSum{Count: 1, Amount: 1000}}
+	alerter := &recordAlerter{}
+	treasury := NewTreasury(chain, fakeWallets{}, liabilities, alerter, Config{HotWallet: "DHot", MinCoverage: 1.2, AlertRepeat: time.Hour})
+
+	check := func() {
+		t.Helper()
+		if _, err := treasury.Check(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check()
+	check()
+	if len(alerter.events) != 1 || alerter.events[0] != AlertLowCoverage {
+		t.Fatalf("Expected a single low coverage alert, got %v", alerter.events)
+	}
+
+	treasury.alertedAt = treasury.alertedAt.Add(-time.Hour)
+	check()
+	if len(alerter.events) != 2 {
+		t.Fatalf("Expected the alert to repeat, got %v", alerter.events)
+	}
+
+	// 钱包组查询失败时余额不完整，不据此告警或恢复
+	treasury.wallets = fakeWallets{{GroupID: 1, ReceiveAddr: "DBroken"}}
+	liabilities.sum.Amount = 100
+	snapshot, _ := treasury.Check()
+	if !snapshot.Partial || len(alerter.events) != 2 {
+		t.Fatalf("Expected partial snapshot without alerts, got %+v %v", snapshot, alerter.events)
+	}
+
+	treasury.wallets = fakeWallets{}
+	check()
+	check()
+	if len(alerter.events) != 3 || alerter.events[2] != AlertRecovered {
+		t.Errorf("Expected a single recovery alert, got %v", alerter.events)
+	}
+}
+
+// 测试 webhook 告警的请求体和失败响应
+func TestWebhookAlerter(t *testing.T) {
+	var got Alert
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	coverage := 0.5
+	alert := NewAlert(AlertLowCoverage, &Snapshot{Confirmed: 500, Outstanding: 1000, OutstandingOrders: 2, Coverage: &coverage, MinCoverage: 1.2})
+	alerter := NewWebhookAlerter(server.URL, time.Second)
+	if err := alerter.Alert(context.Background(), alert); err != nil {
+		t.Fatal(err)
+	}
+	if got.Event != AlertLowCoverage || got.Text == "" || got.Snapshot == nil || got.Snapshot.Outstanding != 1000 {
+		t.Errorf("Unexpected webhook body %+v", got)
+	}
+
+	status = http.StatusInternalServerError
+	if err := alerter.Alert(context.Background(), alert); err == nil {
+		t.Error("Expected error on non-2xx response")
+	}
+}
@@ -1,73 +1,65 @@
 package main
 
 import (
-	"claimask/comm/initialize"
-	"claimask/comm/utils"
-	claimaskAPI "claimask/internal/claimask/api"
-	claimaskDao "claimask/internal/claimask/dao"
-	claimaskService "claimask/internal/claimask/service"
-	monitorAPI "claimask/internal/monitor/api"
+	"errors"
 	"fmt"
+	"os"
+
+	"claimask/comm/initialize"
+	"claimask/conf"
+	"claimask/internal/claimask"
+	"claimask/internal/monitor"
+	"claimask/internal/richx"
+	"claimask/internal/treasury"
 
-	"github.com/gin-gonic/gin"
-	"github.com/jinzhu/gorm"
-	"github.com/spf13/viper"
+	"github.com/spf13/pflag"
 	"go.uber.org/zap"
 )
 
 func main() {
-	// 初始化配置
-	viper.SetConfigFile("./conf/config.yaml")
-	if err := viper.ReadInConfig(); err != nil {
-		panic(fmt.Sprintf("配置文件读取失败: %v", err))
+	// 按 默认值 < 配置文件 < 环境变量 < 命令行参数 加载并校验配置
+	cfg, args, err := conf.Load(os.Args[1:])
+	if errors.Is(err, pflag.ErrHelp) {
+		return
 	}
-
-	// 初始化日志
-	utils.InitLogger()
-
-	// 初始化RPC连接
-	rpcClient := initialize.InitDogecoinRPC(
-		viper.GetString("rpc.ip"),
-		viper.GetInt("rpc.port"),
-		viper.GetString("rpc.user"),
-		viper.GetString("rpc.password"),
-	)
-
-	// 初始化Redis
-	redisClient := initialize.InitRedis(
-		viper.GetString("redis.addr"),
-		viper.GetString("redis.password"),
-		viper.GetInt("redis.db"),
-	)
-
-	// 初始化数据库连接
-	var db *gorm.DB
-	// 这里假设我们有DB初始化函数，根据实际情况使用正确的函数
-	var err error
-	if db, err = initialize.InitDB(); err != nil {
-		zap.L().Fatal("数据库初始化失败", zap.Error(err))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
-	defer db.Close()
 
-	// 创建Gin引擎
-	router := gin.Default()
+	// claimask migrate <command>：只执行表结构迁移，不启动服务
+	if len(args) > 0 && args[0] == "migrate" {
+		if err := initialize.Migrate(cfg, args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	// 注册监控服务路由 - Monitor服务
-	monitorAPI.RegisterRoutes(router, rpcClient, redisClient)
+	// claimask keys <command>：生成主密钥、加密私钥，不启动服务
+	if len(args) > 0 && args[0] == "keys" {
+		if err := initialize.Keys(cfg, args[1:], os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	// 初始化ClaimMask相关服务
-	orderDAO := claimaskDao.NewOrderDAO(db)
-	claimService := claimaskService.NewClaimService(orderDAO, redisClient)
-	claimAPI := claimaskAPI.NewClaimAPI(claimService)
+	// 初始化日志、数据库、Redis、RPC和消息总线
+	server := initialize.NewServer(cfg)
 
-	// 注册ClaimMask路由
-	apiGroup := router.Group("/api")
-	claimaskAPI.RegisterClaimRoutes(apiGroup, claimAPI)
+	// 注册模块，按注册顺序初始化和启动，退出时按逆序停止
+	server.Register(monitor.NewModule(), claimask.NewModule())
+	if cfg.Richx.Enabled {
+		server.Register(richx.NewModule())
+	}
+	if cfg.Treasury.Enabled {
+		server.Register(treasury.NewModule())
+	}
 
-	// 启动服务
-	port := viper.GetString("server.port")
-	zap.L().Info("服务启动成功", zap.String("port", port))
-	if err := router.Run(":" + port); err != nil {
-		zap.L().Fatal("服务启动失败", zap.Error(err))
+	// 启动服务，收到退出信号后优雅关闭，可热更新的配置项在运行期间随配置文件生效
+	if err := server.Run(); err != nil {
+		zap.L().Fatal("服务异常退出", zap.Error(err))
 	}
+	zap.L().Info("服务已关闭")
 }
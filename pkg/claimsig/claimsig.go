@@ -0,0 +1,148 @@
+// Package claimsig 链上领取请求的 HMAC-SHA256 签名
+// 发送方与链上领取服务共享一组按ID区分的密钥，使用当前密钥对请求的规范序列化签名，
+// 接收方按请求中的 keyId 选取密钥校验。轮换密钥时先在接收方加入新密钥，再切换发送方的当前密钥，
+// 旧密钥在所有在途请求过期后移除
+package claimsig
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"claimask/pkg/money"
+)
+
+// version 规范序列化的版本前缀，字段或格式变化时递增
+const version = "claimask-chain-claim/v1"
+
+// minSecretLen 密钥最小长度（字节）
+const minSecretLen = 16
+
+var (
+	// ErrUnknownKey 请求中的 keyId 不在密钥环中
+	ErrUnknownKey = errors.New("claimsig: unknown key id")
+	// ErrBadSignature 签名不匹配
+	ErrBadSignature = errors.New("claimsig: bad signature")
+	// ErrExpired 请求时间戳超出允许的时钟偏差
+	ErrExpired = errors.New("claimsig: timestamp out of range")
+)
+
+// Claim 参与签名的领取请求字段
+type Claim struct {
+	Address   string
+	Amount    money.Amount
+	OrderID   string
+	Timestamp int64 // Unix秒
+}
+
+// Canonical 返回请求的规范序列化：版本前缀和各字段按固定顺序以换行分隔，金额固定8位小数
+// 地址和订单号中出现换行会导致不同请求序列化相同，此时返回错误
+func (c Claim) Canonical() ([]byte, error) {
+	if strings.ContainsAny(c.Address, "\n\r") || strings.ContainsAny(c.OrderID, "\n\r") {
+		return nil, fmt.Errorf("claimsig: field contains newline")
+	}
+	return []byte(strings.Join([]string{
+		version,
+		c.Address,
+		c.Amount.Fixed(),
+		c.OrderID,
+		strconv.FormatInt(c.Timestamp, 10),
+	}, "\n")), nil
+}
+
+// Keyring 签名密钥环
+type Keyring struct {
+	active string
+	keys   map[string][]byte
+}
+
+// NewKeyring 创建密钥环，keys 为 keyId -> 密钥，active 为签名使用的密钥ID
+// 只用于校验的接收方可以将 active 留空
+func NewKeyring(active string, keys map[string]string) (*Keyring, error) {
+	k := &Keyring{active: active, keys: make(map[string][]byte, len(keys))}
+	for id, secret := range keys {
+		if id == "" {
+			return nil, errors.New("claimsig: empty key id")
+		}
+		if len(secret) < minSecretLen {
+			return nil, fmt.Errorf("claimsig: key %q shorter than %d bytes", id, minSecretLen)
+		}
+		k.keys[id] = []byte(secret)
+	}
+	if active != "" {
+		if _, ok := k.keys[active]; !ok {
+			return nil, fmt.Errorf("%w: active key %q", ErrUnknownKey, active)
+		}
+	}
+	return k, nil
+}
+
+// ParseKeys 解析 "id1:secret1,id2:secret2" 格式的密钥列表，用于环境变量配置
+func ParseKeys(s string) (map[string]string, error) {
+	keys := make(map[string]string)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		id, secret, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("claimsig: invalid key entry %q", id)
+		}
+		keys[strings.TrimSpace(id)] = strings.TrimSpace(secret)
+	}
+	return keys, nil
+}
+
+// ActiveKeyID 返回签名使用的密钥ID
+func (k *Keyring) ActiveKeyID() string {
+	return k.active
+}
+
+// Sign 使用当前密钥签名，返回密钥ID和十六进制签名
+func (k *Keyring) Sign(c Claim) (keyID, signature string, err error) {
+	secret, ok := k.keys[k.active]
+	if !ok {
+		return "", "", fmt.Errorf("%w: no active key", ErrUnknownKey)
+	}
+	payload, err := c.Canonical()
+	if err != nil {
+		return "", "", err
+	}
+	return k.active, hex.EncodeToString(mac(secret, payload)), nil
+}
+
+// Verify 校验签名，并要求请求时间戳与now相差不超过maxSkew；maxSkew为0时不校验时间戳
+func (k *Keyring) Verify(c Claim, keyID, signature string, now time.Time, maxSkew time.Duration) error {
+	secret, ok := k.keys[keyID]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+	payload, err := c.Canonical()
+	if err != nil {
+		return err
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(got, mac(secret, payload)) {
+		return ErrBadSignature
+	}
+	if maxSkew > 0 {
+		skew := now.Sub(time.Unix(c.Timestamp, 0))
+		if skew > maxSkew || skew < -maxSkew {
+			return ErrExpired
+		}
+	}
+	return nil
+}
+
+// mac 计算 HMAC-SHA256
+func mac(secret, payload []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write(payload)
+	return h.Sum(nil)
+}
@@ -0,0 +1,95 @@
+package claimsig
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"claimask/pkg/money"
+)
+
+const (
+	secretA = "0123456789abcdef-key-a"
+	secretB = "0123456789abcdef-key-b"
+)
+
+// 测试签名校验以及篡改字段后校验失败
+func TestSignVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	signer, err := NewKeyring("k1", map[string]string{"k1": secretA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, _ := NewKeyring("", map[string]string{"k1": secretA})
+
+	claim := Claim{Address: "DAddr", Amount: money.MustParseDoge("1.5"), OrderID: "42", Timestamp: now.Unix()}
+	keyID, sig, err := signer.Sign(claim)
+	if err != nil || keyID != "k1" {
+		t.Fatalf("sign failed: %s %v", keyID, err)
+	}
+	if err := verifier.Verify(claim, keyID, sig, now, time.Minute); err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+
+	tampered := []Claim{
+		{Address: "DOther", Amount: claim.Amount, OrderID: "42", Timestamp: claim.Timestamp},
+		{Address: "DAddr", Amount: money.MustParseDoge("15"), OrderID: "42", Timestamp: claim.Timestamp},
+		{Address: "DAddr", Amount: claim.Amount, OrderID: "43", Timestamp: claim.Timestamp},
+		{Address: "DAddr", Amount: claim.Amount, OrderID: "42", Timestamp: claim.Timestamp + 1},
+	}
+	for _, c := range tampered {
+		if err := verifier.Verify(c, keyID, sig, now, time.Minute); !errors.Is(err, ErrBadSignature) {
+			t.Errorf("%+v: expected ErrBadSignature, got %v", c, err)
+		}
+	}
+	if err := verifier.Verify(claim, keyID, "zz", now, time.Minute); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("Expected ErrBadSignature for non-hex signature, got %v", err)
+	}
+	if err := verifier.Verify(claim, keyID, sig, now.Add(2*time.Minute), time.Minute); !errors.Is(err, ErrExpired) {
+		t.Errorf("Expected ErrExpired, got %v", err)
+	}
+}
+
+// 测试密钥轮换：接收方同时持有新旧密钥时两种签名都能通过
+func TestKeyRotation(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	claim := Claim{Address: "DAddr", Amount: 1, OrderID: "1", Timestamp: now.Unix()}
+
+	oldSigner, _ := NewKeyring("k1", map[string]string{"k1": secretA})
+	newSigner, _ := NewKeyring("k2", map[string]string{"k1": secretA, "k2": secretB})
+	verifier, _ := NewKeyring("", map[string]string{"k1": secretA, "k2": secretB})
+
+	for _, signer := range []*Keyring{oldSigner, newSigner} {
+		keyID, sig, err := signer.Sign(claim)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := verifier.Verify(claim, keyID, sig, now, 0); err != nil {
+			t.Errorf("key %s: verify failed: %v", keyID, err)
+		}
+	}
+
+	retired, _ := NewKeyring("", map[string]string{"k2": secretB})
+	keyID, sig, _ := oldSigner.Sign(claim)
+	if err := retired.Verify(claim, keyID, sig, now, 0); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected ErrUnknownKey after retiring k1, got %v", err)
+	}
+}
+
+// 测试密钥配置校验
+func TestNewKeyring(t *testing.T) {
+	if _, err := NewKeyring("k1", map[string]string{"k1": "short"}); err == nil {
+		t.Error("Expected error for short secret")
+	}
+	if _, err := NewKeyring("k9", map[string]string{"k1": secretA}); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected ErrUnknownKey for missing active key, got %v", err)
+	}
+
+	keys, err := ParseKeys(" k1:" + secretA + ", k2:" + secretB + ",")
+	if err != nil || keys["k1"] != secretA || keys["k2"] != secretB {
+		t.Errorf("Unexpected keys %v (%v)", keys, err)
+	}
+	if _, err := ParseKeys("k1"); err == nil {
+		t.Error("Expected error for entry without secret")
+	}
+}
@@ -0,0 +1,61 @@
+package dogechain
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+)
+
+// BIP44 路径 m/44'/3'/account'/change/index 中的链
+const (
+	ExternalChain uint32 = 0 // 收款地址
+	InternalChain uint32 = 1 // 找零地址
+)
+
+// HDAccount BIP44 账户扩展公钥（m/44'/3'/account'），只能派生地址，不能签名
+type HDAccount struct {
+	account  uint32
+	external *hdkeychain.ExtendedKey
+}
+
+// NewHDAccount 解析账户层的扩展公钥，接受 dgub 和 xpub 两种版本号
+// 拒绝扩展私钥，私钥不应出现在派生地址的服务中
+func NewHDAccount(xpub string) (*HDAccount, error) {
+	key, err := hdkeychain.NewKeyFromString(xpub)
+	if err != nil {
+		return nil, fmt.Errorf("invalid xpub: %w", err)
+	}
+	if key.IsPrivate() {
+		return nil, errors.New("invalid xpub: extended private key is not allowed, export the account xpub instead")
+	}
+	if key.Depth() != 3 || key.ChildIndex() < hdkeychain.HardenedKeyStart {
+		return nil, fmt.Errorf("invalid xpub: want an account level key m/44'/3'/account', got depth %d", key.Depth())
+	}
+	external, err := key.Derive(ExternalChain)
+	if err != nil {
+		return nil, err
+	}
+	return &HDAccount{account: key.ChildIndex() - hdkeychain.HardenedKeyStart, external: external}, nil
+}
+
+// Address 派生第 index 个收款地址
+func (a *HDAccount) Address(index uint32) (string, error) {
+	if index >= hdkeychain.HardenedKeyStart {
+		return "", fmt.Errorf("address index %d out of range", index)
+	}
+	child, err := a.external.Derive(index)
+	if err != nil {
+		return "", err
+	}
+	addr, err := child.Address(&DogeMainNetParams)
+	if err != nil {
+		return "", err
+	}
+	return addr.EncodeAddress(), nil
+}
+
+// Path 第 index 个收款地址的派生路径
+func (a *HDAccount) Path(index uint32) string {
+	return fmt.Sprintf("m/44'/%d'/%d'/%d/%d", DogeMainNetParams.HDCoinType, a.account, ExternalChain, index)
+}
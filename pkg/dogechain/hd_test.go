@@ -0,0 +1,89 @@
+package dogechain
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+// registerHDKeyID Neuter 需要按私钥版本号查找公钥版本号，测试中注册一次 dgpv/dgub
+var registerHDKeyID sync.Once
+
+// newTestAccount 从固定种子派生 m/44'/3'/0' 账户，返回账户扩展私钥和扩展公钥
+func newTestAccount(t *testing.T) (*hdkeychain.ExtendedKey, string) {
+	t.Helper()
+	registerHDKeyID.Do(func() {
+		if err := chaincfg.RegisterHDKeyID(DogeMainNetParams.HDPublicKeyID[:], DogeMainNetParams.HDPrivateKeyID[:]); err != nil {
+			t.Fatal(err)
+		}
+	})
+	master, err := hdkeychain.NewMaster([]byte(strings.Repeat("claimask", 4)), &DogeMainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := master
+	for _, i := range []uint32{44, 3, 0} {
+		if key, err = key.Derive(hdkeychain.HardenedKeyStart + i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pub, err := key.Neuter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, pub.String()
+}
+
+// 测试由扩展公钥派生的地址与由私钥派生的地址一致
+func TestHDAccountAddress(t *testing.T) {
+	priv, xpub := newTestAccount(t)
+	if !strings.HasPrefix(xpub, "dgub") {
+		t.Fatalf("Expected dgub prefix, got %s", xpub)
+	}
+	account, err := NewHDAccount(xpub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, index := range []uint32{0, 1, 19} {
+		got, err := account.Address(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		external, _ := priv.Derive(ExternalChain)
+		child, _ := external.Derive(index)
+		want, _ := child.Address(&DogeMainNetParams)
+		if got != want.EncodeAddress() || got[0] != 'D' {
+			t.Errorf("index %d: got %s, want %s", index, got, want.EncodeAddress())
+		}
+		if err := ValidateAddress(got); err != nil {
+			t.Error(err)
+		}
+	}
+	if account.Path(7) != "m/44'/3'/0'/0/7" {
+		t.Errorf("Unexpected path %s", account.Path(7))
+	}
+	if _, err := account.Address(hdkeychain.HardenedKeyStart); err == nil {
+		t.Error("Expected hardened index to be rejected")
+	}
+}
+
+// 测试拒绝扩展私钥和非账户层的扩展公钥
+func TestNewHDAccountRejects(t *testing.T) {
+	priv, _ := newTestAccount(t)
+	if _, err := NewHDAccount(priv.String()); err == nil {
+		t.Error("Expected extended private key to be rejected")
+	}
+
+	external, _ := priv.Derive(ExternalChain)
+	pub, _ := external.Neuter()
+	if _, err := NewHDAccount(pub.String()); err == nil {
+		t.Error("Expected non account level key to be rejected")
+	}
+	if _, err := NewHDAccount("dgub-not-a-key"); err == nil {
+		t.Error("Expected malformed key to be rejected")
+	}
+}
@@ -0,0 +1,41 @@
+package dogechain
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+// DogeMainNetParams Dogecoin主网参数
+// btcd 只内置比特币网络参数，这里基于比特币主网参数替换 Dogecoin 的地址与密钥版本号
+var DogeMainNetParams = func() chaincfg.Params {
+	params := chaincfg.MainNetParams
+	params.Name = "dogecoin-mainnet"
+	params.Net = 0xc0c0c0c0
+	params.DefaultPort = "22556"
+	params.DNSSeeds = nil
+	params.Checkpoints = nil
+	params.Bech32HRPSegwit = ""
+
+	params.PubKeyHashAddrID = 0x1e // 地址以 D 开头
+	params.ScriptHashAddrID = 0x16 // 地址以 9 或 A 开头
+	params.PrivateKeyID = 0x9e     // WIF 以 Q 或 6 开头
+
+	params.HDPrivateKeyID = [4]byte{0x02, 0xfa, 0xc3, 0x98} // dgpv
+	params.HDPublicKeyID = [4]byte{0x02, 0xfa, 0xca, 0xfd}  // dgub
+	params.HDCoinType = 3
+	return params
+}()
+
+// ValidateAddress 校验 Dogecoin 主网地址（P2PKH 或 P2SH）
+func ValidateAddress(address string) error {
+	addr, err := btcutil.DecodeAddress(address, &DogeMainNetParams)
+	if err != nil {
+		return fmt.Errorf("invalid dogecoin address %q: %w", address, err)
+	}
+	if !addr.IsForNet(&DogeMainNetParams) {
+		return fmt.Errorf("invalid dogecoin address %q: not a mainnet address", address)
+	}
+	return nil
+}
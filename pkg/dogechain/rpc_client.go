@@ -4,12 +4,55 @@ package dogechain
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"sort"
 	"time"
+
+	"claimask/pkg/money"
+)
+
+// 节点返回的RPC错误码
+const (
+	RPCInvalidAddressOrKey  = -5  // 交易或地址不存在
+	RPCDeserializationErr   = -22 // 交易解码失败
+	RPCVerifyError          = -25 // 交易校验失败，如输入不存在
+	RPCVerifyRejected       = -26 // 交易被内存池拒绝
+	RPCVerifyAlreadyInChain = -27 // 交易已在链上
 )
 
+// ErrTxNotFound 节点的内存池和区块中都没有该交易
+var ErrTxNotFound = errors.New("transaction not found")
+
+// RPCError 节点返回的RPC错误
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error 实现 error 接口
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("RPC error %d: %s", e.Code, e.Message)
+}
+
+// IsTxRejected 判断广播错误是否为节点明确拒绝交易，交易不会进入内存池
+// 超时、连接失败、交易已存在等其他错误无法确定交易是否已在网络中
+func IsTxRejected(err error) bool {
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	return rpcErr.Code == RPCDeserializationErr || rpcErr.Code == RPCVerifyRejected
+}
+
+// IsMissingInputs 判断广播错误是否为交易输入不存在或已被花费（-25）
+// 输入可能被其他交易花费，也可能是父交易尚未被节点看到，需查询输入状态后再处理
+func IsMissingInputs(err error) bool {
+	var rpcErr *RPCError
+	return errors.As(err, &rpcErr) && rpcErr.Code == RPCVerifyError
+}
+
 // RPCClient Dogecoin RPC客户端
 type RPCClient struct {
 	endpoint   string
@@ -37,12 +80,12 @@ type TxDetail struct {
 
 // TxInput 交易输入
 type TxInput struct {
-	Txid      string     `json:"txid"`
-	Vout      uint32     `json:"vout"`
-	ScriptSig *ScriptSig `json:"scriptSig"`
-	Sequence  uint32     `json:"sequence"`
-	Addresses []string   `json:"addresses,omitempty"`
-	Value     float64    `json:"value,omitempty"`
+	Txid      string       `json:"txid"`
+	Vout      uint32       `json:"vout"`
+	ScriptSig *ScriptSig   `json:"scriptSig"`
+	Sequence  uint32       `json:"sequence"`
+	Addresses []string     `json:"addresses,omitempty"`
+	Value     money.Amount `json:"value,omitempty"`
 }
 
 // ScriptSig 签名脚本
@@ -53,7 +96,7 @@ type ScriptSig struct {
 
 // TxOutput 交易输出
 type TxOutput struct {
-	Value        float64      `json:"value"`
+	Value        money.Amount `json:"value"`
 	N            uint32       `json:"n"`
 	ScriptPubKey ScriptPubKey `json:"scriptPubKey"`
 }
@@ -67,6 +110,14 @@ type ScriptPubKey struct {
 	Addresses []string `json:"addresses,omitempty"`
 }
 
+// Block 区块及其中的交易详情
+type Block struct {
+	Hash              string     `json:"hash"`
+	Height            int64      `json:"height"`
+	PreviousBlockHash string     `json:"previousblockhash"`
+	Tx                []TxDetail `json:"tx"`
+}
+
 // NewRPCClient 创建RPC客户端
 func NewRPCClient(endpoint, user, password string) *RPCClient {
 	return &RPCClient{
@@ -90,9 +141,10 @@ func (c *RPCClient) GetAddressUTXOs(address string) ([]UTXO, error) {
 
 	var resp struct {
 		Result []struct {
-			TxID   string  `json:"txid"`
-			Vout   uint32  `json:"vout"`
-			Amount float64 `json:"amount"`
+			TxID          string       `json:"txid"`
+			Vout          uint32       `json:"vout"`
+			Amount        money.Amount `json:"amount"`
+			Confirmations int64        `json:"confirmations"`
 		} `json:"result"`
 		Error interface{} `json:"error"`
 	}
@@ -109,9 +161,10 @@ func (c *RPCClient) GetAddressUTXOs(address string) ([]UTXO, error) {
 	utxos := make([]UTXO, len(resp.Result))
 	for i, u := range resp.Result {
 		utxos[i] = UTXO{
-			TxHash: u.TxID,
-			Index:  u.Vout,
-			Value:  int64(u.Amount * 100000000), // 转换为ELON单位
+			TxHash:        u.TxID,
+			Index:         u.Vout,
+			Value:         u.Amount.Elon(),
+			Confirmations: u.Confirmations,
 		}
 	}
 
@@ -122,7 +175,7 @@ func (c *RPCClient) GetAddressUTXOs(address string) ([]UTXO, error) {
 	return utxos, nil
 }
 
-// GetTransaction 获取交易详情
+// GetTransaction 获取交易详情，内存池和区块中都没有时返回 ErrTxNotFound
 func (c *RPCClient) GetTransaction(txid string) (*TxDetail, error) {
 	req := map[string]interface{}{
 		"jsonrpc": "1.0",
@@ -132,8 +185,8 @@ func (c *RPCClient) GetTransaction(txid string) (*TxDetail, error) {
 	}
 
 	var resp struct {
-		Result TxDetail    `json:"result"`
-		Error  interface{} `json:"error"`
+		Result TxDetail  `json:"result"`
+		Error  *RPCError `json:"error"`
 	}
 
 	if err := c.rpcCall(req, &resp); err != nil {
@@ -142,12 +195,105 @@ func (c *RPCClient) GetTransaction(txid string) (*TxDetail, error) {
 
 	// 检查是否有错误
 	if resp.Error != nil {
-		return nil, fmt.Errorf("RPC error: %v", resp.Error)
+		if resp.Error.Code == RPCInvalidAddressOrKey {
+			return nil, fmt.Errorf("%w: %s", ErrTxNotFound, txid)
+		}
+		return nil, resp.Error
 	}
 
 	return &resp.Result, nil
 }
 
+// GetTxOut 查询未花费的交易输出，包含内存池中交易的花费；输出已被花费或不存在时返回nil
+func (c *RPCClient) GetTxOut(txid string, vout uint32) (*UTXO, error) {
+	var out *struct {
+		Value         money.Amount `json:"value"`
+		Confirmations int64        `json:"confirmations"`
+	}
+	if err := c.call("gettxout", []interface{}{txid, vout, true}, &out); err != nil {
+		return nil, err
+	}
+	if out == nil {
+		return nil, nil
+	}
+	return &UTXO{TxHash: txid, Index: vout, Value: out.Value.Elon(), Confirmations: out.Confirmations}, nil
+}
+
+// GetBlockCount 获取最长链的区块高度
+func (c *RPCClient) GetBlockCount() (int64, error) {
+	var height int64
+	err := c.call("getblockcount", []interface{}{}, &height)
+	return height, err
+}
+
+// GetBlockHash 获取最长链上指定高度的区块哈希
+func (c *RPCClient) GetBlockHash(height int64) (string, error) {
+	var hash string
+	err := c.call("getblockhash", []interface{}{height}, &hash)
+	return hash, err
+}
+
+// GetBlock 获取区块及其中的交易详情（getblock verbosity 2），一次调用取回整个区块，不依赖 txindex
+func (c *RPCClient) GetBlock(hash string) (*Block, error) {
+	var block Block
+	if err := c.call("getblock", []interface{}{hash, 2}, &block); err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// SendRawTransaction 广播已签名的交易，返回交易ID
+func (c *RPCClient) SendRawTransaction(txHex string) (string, error) {
+	req := map[string]interface{}{
+		"jsonrpc": "1.0",
+		"id":      "claimask",
+		"method":  "sendrawtransaction",
+		"params":  []interface{}{txHex},
+	}
+
+	var resp struct {
+		Result string    `json:"result"`
+		Error  *RPCError `json:"error"`
+	}
+
+	if err := c.rpcCall(req, &resp); err != nil {
+		return "", err
+	}
+
+	// 检查是否有错误
+	if resp.Error != nil {
+		return "", resp.Error
+	}
+
+	return resp.Result, nil
+}
+
+// call 执行RPC调用并将结果解码到 result
+func (c *RPCClient) call(method string, params []interface{}, result interface{}) error {
+	req := map[string]interface{}{
+		"jsonrpc": "1.0",
+		"id":      "claimask",
+		"method":  method,
+		"params":  params,
+	}
+
+	var resp struct {
+		Result json.RawMessage `json:"result"`
+		Error  interface{}     `json:"error"`
+	}
+
+	if err := c.rpcCall(req, &resp); err != nil {
+		return err
+	}
+
+	// 检查是否有错误
+	if resp.Error != nil {
+		return fmt.Errorf("RPC error: %v", resp.Error)
+	}
+
+	return json.Unmarshal(resp.Result, result)
+}
+
 // rpcCall 执行RPC调用
 func (c *RPCClient) rpcCall(req interface{}, resp interface{}) error {
 	body, _ := json.Marshal(req)
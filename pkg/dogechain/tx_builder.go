@@ -3,16 +3,27 @@ package dogechain
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
 
 	"github.com/btcsuite/btcd/btcutil"
-	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 )
 
-func TransferElonUseUtxo(sender, privKey, receiver string, utxos []UTXO) (string, error) {
+// 交易大小估算参数（P2PKH）
+const (
+	txOverheadSize = 10
+	txInputSize    = 148
+	txOutputSize   = 34
+)
+
+// ErrInsufficientFunds UTXO余额不足以支付输出和手续费
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// TransferElonUseUtxo 构建将 utxos 全部转给 receiver 的交易，返回未签名的十六进制交易，由 Signer 签名
+func TransferElonUseUtxo(receiver string, utxos []UTXO) (string, error) {
 	tx := wire.NewMsgTx(wire.TxVersion)
 
 	// 添加UTXO输入
@@ -27,7 +38,7 @@ func TransferElonUseUtxo(sender, privKey, receiver string, utxos []UTXO) (string
 	}
 
 	// 构建输出
-	receiverAddr, err := btcutil.DecodeAddress(receiver, &chaincfg.MainNetParams)
+	receiverAddr, err := btcutil.DecodeAddress(receiver, &DogeMainNetParams)
 	if err != nil {
 		return "", fmt.Errorf("invalid receiver address: %w", err)
 	}
@@ -37,18 +48,163 @@ func TransferElonUseUtxo(sender, privKey, receiver string, utxos []UTXO) (string
 	}
 	tx.AddTxOut(wire.NewTxOut(utxos[0].Value, pkScript))
 
-	// 签名
-	// 简化实现，仅仅返回序列化的交易
-	// 注意：实际项目中应该实现完整的签名逻辑
+	return EncodeTx(tx)
+}
+
+// PayOutput 打款输出
+type PayOutput struct {
+	Address string
+	Value   int64 // 单位：ELON
+}
+
+// Prevout 交易输入所花费的输出，签名时需要
+type Prevout struct {
+	Value    int64  // 单位：ELON
+	PkScript []byte // 锁定脚本
+}
+
+// PayoutTx 未签名的打款交易，由 Signer 签名后广播
+type PayoutTx struct {
+	Tx       *wire.MsgTx
+	Prevouts []Prevout // 与 Tx 的输入一一对应
+	Fee      int64     // 手续费（ELON）
+	Change   int64     // 找零（ELON）
+	Inputs   []UTXO    // 实际使用的UTXO
+}
+
+// BuildPayoutTx 构建一笔未签名的多输出打款交易
+// 从utxos中按顺序选取输入直到覆盖输出总额和手续费，找零返回发送方地址，低于粉尘阈值的找零并入手续费
+// sender 为P2PKH地址，utxos 均属于 sender，feeRate 单位为 ELON/byte
+func BuildPayoutTx(sender string, utxos []UTXO, outputs []PayOutput, feeRate, dustLimit int64) (*PayoutTx, error) {
+	if len(outputs) == 0 {
+		return nil, errors.New("no outputs")
+	}
+
+	senderScript, err := PayToAddrScript(sender)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address: %w", err)
+	}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	var total int64
+	for _, out := range outputs {
+		if out.Value <= 0 {
+			return nil, fmt.Errorf("invalid output value for %s", out.Address)
+		}
+		pkScript, err := PayToAddrScript(out.Address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid receiver address %s: %w", out.Address, err)
+		}
+		tx.AddTxOut(wire.NewTxOut(out.Value, pkScript))
+		total += out.Value
+	}
+
+	// 选取输入：预留一个找零输出计算手续费
+	var selected []UTXO
+	var prevouts []Prevout
+	var inputSum, fee int64
+	for _, utxo := range utxos {
+		hash, err := chainhash.NewHashFromStr(utxo.TxHash)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hash: %w", err)
+		}
+		tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(hash, utxo.Index), nil, nil))
+		selected = append(selected, utxo)
+		prevouts = append(prevouts, Prevout{Value: utxo.Value, PkScript: senderScript})
+		inputSum += utxo.Value
+
+		fee = estimateSize(len(tx.TxIn), len(tx.TxOut)+1) * feeRate
+		if inputSum >= total+fee {
+			break
+		}
+	}
+	if inputSum < total+fee {
+		return nil, fmt.Errorf("%w: need %d, have %d", ErrInsufficientFunds, total+fee, inputSum)
+	}
+
+	change := inputSum - total - fee
+	if change >= dustLimit {
+		tx.AddTxOut(wire.NewTxOut(change, senderScript))
+	} else {
+		fee += change
+		change = 0
+	}
+
+	return &PayoutTx{
+		Tx:       tx,
+		Prevouts: prevouts,
+		Fee:      fee,
+		Change:   change,
+		Inputs:   selected,
+	}, nil
+}
 
-	// 返回十六进制格式交易
+// SignP2PKH 用 wif 私钥签名 tx 的所有输入，所有 prevouts 必须是该私钥对应地址的P2PKH输出
+func SignP2PKH(tx *wire.MsgTx, prevouts []Prevout, wif *btcutil.WIF) error {
+	if len(prevouts) != len(tx.TxIn) {
+		return fmt.Errorf("got %d prevouts for %d inputs", len(prevouts), len(tx.TxIn))
+	}
+	keyAddr, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(wif.SerializePubKey()), &DogeMainNetParams)
+	if err != nil {
+		return err
+	}
+	keyScript, err := txscript.PayToAddrScript(keyAddr)
+	if err != nil {
+		return err
+	}
+
+	for i := range tx.TxIn {
+		if !bytes.Equal(prevouts[i].PkScript, keyScript) {
+			return fmt.Errorf("input %d is not spendable by key of %s", i, keyAddr.EncodeAddress())
+		}
+		sigScript, err := txscript.SignatureScript(tx, i, keyScript, txscript.SigHashAll, wif.PrivKey, wif.CompressPubKey)
+		if err != nil {
+			return fmt.Errorf("sign input %d failed: %w", i, err)
+		}
+		tx.TxIn[i].SignatureScript = sigScript
+	}
+	return nil
+}
+
+// PayToAddrScript 地址对应的锁定脚本
+func PayToAddrScript(address string) ([]byte, error) {
+	addr, err := btcutil.DecodeAddress(address, &DogeMainNetParams)
+	if err != nil {
+		return nil, err
+	}
+	return txscript.PayToAddrScript(addr)
+}
+
+// EncodeTx 将交易序列化为十六进制
+func EncodeTx(tx *wire.MsgTx) (string, error) {
 	buf := bytes.NewBuffer(make([]byte, 0, tx.SerializeSize()))
-	tx.Serialize(buf)
+	if err := tx.Serialize(buf); err != nil {
+		return "", err
+	}
 	return hex.EncodeToString(buf.Bytes()), nil
 }
 
+// DecodeTx 从十六进制反序列化交易
+func DecodeTx(txHex string) (*wire.MsgTx, error) {
+	raw, err := hex.DecodeString(txHex)
+	if err != nil {
+		return nil, err
+	}
+	var tx wire.MsgTx
+	if err := tx.Deserialize(bytes.NewReader(raw)); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+// estimateSize 估算P2PKH交易大小（字节）
+func estimateSize(inputs, outputs int) int64 {
+	return int64(txOverheadSize + inputs*txInputSize + outputs*txOutputSize)
+}
+
 type UTXO struct {
-	TxHash string
-	Index  uint32
-	Value  int64
+	TxHash        string
+	Index         uint32
+	Value         int64
+	Confirmations int64 // 确认数，0为未确认
 }
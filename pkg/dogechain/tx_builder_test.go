@@ -0,0 +1,114 @@
+package dogechain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/txscript"
+)
+
+// newTestWallet 生成测试用的Dogecoin地址和WIF私钥
+func newTestWallet(t *testing.T) (string, *btcutil.WIF) {
+	t.Helper()
+	key, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatalf("generate key failed: %v", err)
+	}
+	wif, err := btcutil.NewWIF(key, &DogeMainNetParams, true)
+	if err != nil {
+		t.Fatalf("create wif failed: %v", err)
+	}
+	addr, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(wif.SerializePubKey()), &DogeMainNetParams)
+	if err != nil {
+		t.Fatalf("create address failed: %v", err)
+	}
+	return addr.EncodeAddress(), wif
+}
+
+// 测试多输出交易的构建、找零与签名校验
+func TestBuildPayoutTx(t *testing.T) {
+	sender, privKey := newTestWallet(t)
+	receiverA, _ := newTestWallet(t)
+	receiverB, _ := newTestWallet(t)
+	if sender[0] != 'D' {
+		t.Fatalf("Expected Dogecoin address, got %s", sender)
+	}
+
+	utxos := []UTXO{
+		{TxHash: "a1075db55d416d3ca199f55b6084e2115b9345e16c5cf302fc80e9d5fbf5d48d", Index: 0, Value: 500000000},
+		{TxHash: "b1075db55d416d3ca199f55b6084e2115b9345e16c5cf302fc80e9d5fbf5d48d", Index: 1, Value: 300000000},
+	}
+	outputs := []PayOutput{
+		{Address: receiverA, Value: 200000000},
+		{Address: receiverB, Value: 100000000},
+	}
+
+	payout, err := BuildPayoutTx(sender, utxos, outputs, 1000, 100000)
+	if err != nil {
+		t.Fatalf("BuildPayoutTx failed: %v", err)
+	}
+	if err := SignP2PKH(payout.Tx, payout.Prevouts, privKey); err != nil {
+		t.Fatalf("SignP2PKH failed: %v", err)
+	}
+	if len(payout.Inputs) != 1 {
+		t.Errorf("Expected 1 input selected, got %d", len(payout.Inputs))
+	}
+	if payout.Fee+payout.Change+300000000 != 500000000 {
+		t.Errorf("Unbalanced tx: fee %d change %d", payout.Fee, payout.Change)
+	}
+
+	txHex, err := EncodeTx(payout.Tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := DecodeTx(txHex)
+	if err != nil {
+		t.Fatalf("DecodeTx failed: %v", err)
+	}
+	if len(tx.TxOut) != 3 {
+		t.Errorf("Expected 2 outputs plus change, got %d", len(tx.TxOut))
+	}
+	if tx.TxHash() != payout.Tx.TxHash() {
+		t.Errorf("TxID mismatch")
+	}
+
+	// 使用脚本引擎校验签名
+	senderAddr, _ := btcutil.DecodeAddress(sender, &DogeMainNetParams)
+	senderScript, _ := txscript.PayToAddrScript(senderAddr)
+	fetcher := txscript.NewCannedPrevOutputFetcher(senderScript, utxos[0].Value)
+	vm, err := txscript.NewEngine(senderScript, tx, 0, txscript.StandardVerifyFlags, nil,
+		txscript.NewTxSigHashes(tx, fetcher), utxos[0].Value, fetcher)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	if err := vm.Execute(); err != nil {
+		t.Errorf("Signature verification failed: %v", err)
+	}
+}
+
+// 测试余额不足与私钥不匹配
+func TestBuildPayoutTxErrors(t *testing.T) {
+	sender, privKey := newTestWallet(t)
+	other, otherKey := newTestWallet(t)
+
+	utxos := []UTXO{{TxHash: "a1075db55d416d3ca199f55b6084e2115b9345e16c5cf302fc80e9d5fbf5d48d", Value: 100000000}}
+	outputs := []PayOutput{{Address: other, Value: 100000000}}
+
+	if _, err := BuildPayoutTx(sender, utxos, outputs, 1000, 100000); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("Expected ErrInsufficientFunds, got %v", err)
+	}
+
+	outputs[0].Value = 50000000
+	payout, err := BuildPayoutTx(sender, utxos, outputs, 1000, 100000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SignP2PKH(payout.Tx, payout.Prevouts, otherKey); err == nil {
+		t.Error("Expected key mismatch error, got nil")
+	}
+	if err := SignP2PKH(payout.Tx, payout.Prevouts[:0], privKey); err == nil {
+		t.Error("Expected prevout count mismatch error, got nil")
+	}
+}
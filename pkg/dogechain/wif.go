@@ -0,0 +1,99 @@
+package dogechain
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// ErrMalformedWIF WIF私钥格式错误
+var ErrMalformedWIF = errors.New("malformed wif")
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// base58Index 字符到 base58 数值的映射，非法字符为 -1
+var base58Index = func() [256]int8 {
+	var index [256]int8
+	for i := range index {
+		index[i] = -1
+	}
+	for i := 0; i < len(base58Alphabet); i++ {
+		index[base58Alphabet[i]] = int8(i)
+	}
+	return index
+}()
+
+// DecodeWIF 从字节切片解析WIF私钥
+// 与 btcutil.DecodeWIF 不同，不需要把私钥转成字符串，中间结果在返回前清零，调用方负责清零 key 和返回私钥
+func DecodeWIF(key []byte) (*btcutil.WIF, error) {
+	decoded, err := decodeBase58(key)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(decoded)
+
+	// 版本(1) + 私钥(32) [+ 压缩标志(1)] + 校验和(4)
+	var compress bool
+	switch len(decoded) {
+	case 1 + btcec.PrivKeyBytesLen + 1 + 4:
+		if decoded[1+btcec.PrivKeyBytesLen] != 0x01 {
+			return nil, ErrMalformedWIF
+		}
+		compress = true
+	case 1 + btcec.PrivKeyBytesLen + 4:
+	default:
+		return nil, ErrMalformedWIF
+	}
+	payload, checksum := decoded[:len(decoded)-4], decoded[len(decoded)-4:]
+	hash := chainhash.DoubleHashB(payload)
+	defer zero(hash)
+	if !bytes.Equal(hash[:4], checksum) {
+		return nil, ErrMalformedWIF
+	}
+
+	privKey, _ := btcec.PrivKeyFromBytes(payload[1 : 1+btcec.PrivKeyBytesLen])
+	return &btcutil.WIF{PrivKey: privKey, CompressPubKey: compress}, nil
+}
+
+// decodeBase58 base58 解码，返回新分配的切片，调用方负责清零
+func decodeBase58(src []byte) ([]byte, error) {
+	// base58 每个字符约 log(58)/log(256) ≈ 0.733 字节
+	buf := make([]byte, len(src)*733/1000+1)
+	for _, c := range src {
+		digit := base58Index[c]
+		if digit < 0 {
+			zero(buf)
+			return nil, ErrMalformedWIF
+		}
+		carry := int(digit)
+		for j := len(buf) - 1; j >= 0; j-- {
+			carry += 58 * int(buf[j])
+			buf[j] = byte(carry)
+			carry >>= 8
+		}
+	}
+	defer zero(buf)
+
+	// 开头的 '1' 对应值为0的字节
+	leading := 0
+	for leading < len(src) && src[leading] == base58Alphabet[0] {
+		leading++
+	}
+	start := 0
+	for start < len(buf) && buf[start] == 0 {
+		start++
+	}
+	out := make([]byte, leading+len(buf)-start)
+	copy(out[leading:], buf[start:])
+	return out, nil
+}
+
+// zero 清零中间结果
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,44 @@
+package dogechain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+)
+
+// 测试按字节解析WIF与 btcutil 结果一致，校验和错误或非法字符时报错
+func TestDecodeWIF(t *testing.T) {
+	for _, compress := range []bool{true, false} {
+		key, err := btcec.NewPrivateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		wif, err := btcutil.NewWIF(key, &DogeMainNetParams, compress)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded := wif.String()
+
+		got, err := DecodeWIF([]byte(encoded))
+		if err != nil {
+			t.Fatalf("compress=%v: %v", compress, err)
+		}
+		if !got.PrivKey.Key.Equals(&key.Key) || got.CompressPubKey != compress {
+			t.Errorf("compress=%v: decoded key mismatch", compress)
+		}
+
+		tampered := []byte(encoded)
+		tampered[len(tampered)-1] ^= 'a' ^ 'b'
+		if _, err := DecodeWIF(tampered); !errors.Is(err, ErrMalformedWIF) {
+			t.Errorf("compress=%v: expected ErrMalformedWIF for bad checksum, got %v", compress, err)
+		}
+	}
+
+	for _, bad := range []string{"", "0OIl", "QWifPrivateKey"} {
+		if _, err := DecodeWIF([]byte(bad)); !errors.Is(err, ErrMalformedWIF) {
+			t.Errorf("%q: expected ErrMalformedWIF, got %v", bad, err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package keystore
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// GormBackend 将密文保存在数据库表的一列中，按 id 列查找
+type GormBackend struct {
+	db       *gorm.DB
+	table    string
+	idColumn string
+	column   string
+}
+
+// NewGormBackend 创建数据库密文存储，密文列为空的行视为没有私钥
+func NewGormBackend(db *gorm.DB, table, idColumn, column string) *GormBackend {
+	return &GormBackend{db: db, table: table, idColumn: idColumn, column: column}
+}
+
+// LoadSealed 读取密文
+func (g *GormBackend) LoadSealed(id string) (string, error) {
+	var sealed []string
+	err := g.db.Table(g.table).
+		Where(fmt.Sprintf("%s = ? AND %s <> ''", g.idColumn, g.column), id).
+		Limit(1).
+		Pluck(g.column, &sealed).Error
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) == 0 {
+		return "", fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+	}
+	return sealed[0], nil
+}
+
+// SaveSealed 更新已有行的密文
+func (g *GormBackend) SaveSealed(id, sealed string) error {
+	result := g.db.Table(g.table).
+		Where(fmt.Sprintf("%s = ?", g.idColumn), id).
+		Update(g.column, sealed)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+	}
+	return nil
+}
+
+// ListSealed 列出所有非空密文
+func (g *GormBackend) ListSealed() (map[string]string, error) {
+	rows, err := g.db.Table(g.table).
+		Select(g.idColumn, g.column).
+		Where(fmt.Sprintf("%s <> ''", g.column)).
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	all := make(map[string]string)
+	for rows.Next() {
+		var id, sealed string
+		if err := rows.Scan(&id, &sealed); err != nil {
+			return nil, err
+		}
+		all[id] = sealed
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
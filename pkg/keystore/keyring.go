@@ -0,0 +1,271 @@
+// Package keystore 私钥的信封加密存储
+// 每个私钥用随机生成的数据密钥（AES-256-GCM）加密，数据密钥再用主密钥加密后与密文一起保存，
+// 主密钥只从环境变量或文件读取，不落库也不写入配置文件。
+// 更换主密钥时只需用新主密钥重新加密数据密钥（Rewrap），私钥密文不变；
+// 旧主密钥在所有密文轮换完成前需保留在主密钥列表中
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// SealedPrefix 信封加密密文的前缀
+const SealedPrefix = "enc:v1:"
+
+// keySize 主密钥和数据密钥长度（AES-256）
+const keySize = 32
+
+var (
+	// ErrNoMasterKey 未配置主密钥
+	ErrNoMasterKey = errors.New("keystore: no master key configured")
+	// ErrUnknownMasterKey 密文使用的主密钥不在主密钥列表中
+	ErrUnknownMasterKey = errors.New("keystore: unknown master key")
+	// ErrMalformed 密文格式错误或被篡改
+	ErrMalformed = errors.New("keystore: malformed sealed value")
+)
+
+// Keyring 主密钥列表，新密文使用 active 主密钥加密，旧主密钥只用于解密
+type Keyring struct {
+	active string
+	keys   map[string][]byte
+}
+
+// IsSealed 判断值是否为信封加密密文
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, SealedPrefix)
+}
+
+// GenerateMasterKey 生成一个主密钥，返回 id:base64 格式，可直接写入主密钥环境变量或文件
+func GenerateMasterKey(id string) (string, error) {
+	if err := validID(id); err != nil {
+		return "", err
+	}
+	key := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+	defer Zero(key)
+	return id + ":" + base64.StdEncoding.EncodeToString(key), nil
+}
+
+// LoadKeyring 读取主密钥：环境变量 env 非空时优先，否则读取文件 file
+// active 为空且只有一个主密钥时使用该主密钥
+func LoadKeyring(env, file, active string) (*Keyring, error) {
+	data := ""
+	if env != "" {
+		data = os.Getenv(env)
+	}
+	if data == "" && file != "" {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("keystore: read master key file failed: %w", err)
+		}
+		data = string(content)
+		Zero(content)
+	}
+	if strings.TrimSpace(data) == "" {
+		return nil, ErrNoMasterKey
+	}
+	return ParseKeyring(data, active)
+}
+
+// ParseKeyring 解析主密钥列表，每项格式为 id:base64(32字节)，以换行或逗号分隔
+func ParseKeyring(data, active string) (*Keyring, error) {
+	k := &Keyring{active: active, keys: make(map[string][]byte)}
+	for _, entry := range strings.FieldsFunc(data, func(r rune) bool { return r == '\n' || r == ',' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, errors.New("keystore: master key must be id:base64")
+		}
+		if err := validID(id); err != nil {
+			return nil, err
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != keySize {
+			return nil, fmt.Errorf("keystore: master key %q must be %d bytes base64", id, keySize)
+		}
+		if _, ok := k.keys[id]; ok {
+			return nil, fmt.Errorf("keystore: duplicate master key %q", id)
+		}
+		k.keys[id] = key
+	}
+
+	if len(k.keys) == 0 {
+		return nil, ErrNoMasterKey
+	}
+	if k.active == "" {
+		if len(k.keys) > 1 {
+			return nil, errors.New("keystore: active master key must be set when several are configured")
+		}
+		for id := range k.keys {
+			k.active = id
+		}
+	}
+	if _, ok := k.keys[k.active]; !ok {
+		return nil, fmt.Errorf("%w: active %q", ErrUnknownMasterKey, k.active)
+	}
+	return k, nil
+}
+
+// Active 当前用于加密的主密钥ID
+func (k *Keyring) Active() string {
+	return k.active
+}
+
+// Seal 用新的数据密钥加密明文，数据密钥用当前主密钥加密
+func (k *Keyring) Seal(plaintext []byte) (string, error) {
+	dek := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, dek); err != nil {
+		return "", err
+	}
+	defer Zero(dek)
+
+	wrapped, err := k.wrap(k.active, dek)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := encrypt(dek, plaintext, nil)
+	if err != nil {
+		return "", err
+	}
+	return SealedPrefix + k.active + ":" + encode(wrapped) + ":" + encode(ciphertext), nil
+}
+
+// Open 解密密文，调用方用完后应调用 Zero 清零返回的明文
+func (k *Keyring) Open(sealed string) ([]byte, error) {
+	id, wrapped, ciphertext, err := parse(sealed)
+	if err != nil {
+		return nil, err
+	}
+	dek, err := k.unwrap(id, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	defer Zero(dek)
+
+	plaintext, err := decrypt(dek, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	return plaintext, nil
+}
+
+// Rewrap 用当前主密钥重新加密数据密钥，私钥密文不变
+// 已使用当前主密钥的密文原样返回，changed 为 false
+func (k *Keyring) Rewrap(sealed string) (string, bool, error) {
+	id, wrapped, ciphertext, err := parse(sealed)
+	if err != nil {
+		return "", false, err
+	}
+	if id == k.active {
+		return sealed, false, nil
+	}
+	dek, err := k.unwrap(id, wrapped)
+	if err != nil {
+		return "", false, err
+	}
+	defer Zero(dek)
+
+	rewrapped, err := k.wrap(k.active, dek)
+	if err != nil {
+		return "", false, err
+	}
+	return SealedPrefix + k.active + ":" + encode(rewrapped) + ":" + encode(ciphertext), true, nil
+}
+
+// wrap 用主密钥加密数据密钥，主密钥ID作为附加数据防止替换
+func (k *Keyring) wrap(id string, dek []byte) ([]byte, error) {
+	return encrypt(k.keys[id], dek, []byte(id))
+}
+
+// unwrap 用主密钥解密数据密钥
+func (k *Keyring) unwrap(id string, wrapped []byte) ([]byte, error) {
+	master, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownMasterKey, id)
+	}
+	dek, err := decrypt(master, wrapped, []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("%w: unwrap data key: %v", ErrMalformed, err)
+	}
+	return dek, nil
+}
+
+// parse 拆分密文：enc:v1:主密钥ID:加密的数据密钥:私钥密文
+func parse(sealed string) (id string, wrapped, ciphertext []byte, err error) {
+	if !IsSealed(sealed) {
+		return "", nil, nil, ErrMalformed
+	}
+	parts := strings.Split(strings.TrimPrefix(sealed, SealedPrefix), ":")
+	if len(parts) != 3 || parts[0] == "" {
+		return "", nil, nil, ErrMalformed
+	}
+	if wrapped, err = decode(parts[1]); err != nil {
+		return "", nil, nil, ErrMalformed
+	}
+	if ciphertext, err = decode(parts[2]); err != nil {
+		return "", nil, nil, ErrMalformed
+	}
+	return parts[0], wrapped, ciphertext, nil
+}
+
+// encrypt AES-GCM 加密，随机 nonce 放在密文前
+func encrypt(key, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+// decrypt AES-GCM 解密
+func decrypt(key, data, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], aad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+// validID 主密钥ID不能为空，不能包含分隔符
+func validID(id string) error {
+	if id == "" || strings.ContainsAny(id, ":,\n \t") {
+		return fmt.Errorf("keystore: bad master key id %q", id)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package keystore
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrKeyNotFound 私钥不存在
+var ErrKeyNotFound = errors.New("keystore: key not found")
+
+// Keystore 签名方获取私钥的接口
+// 私钥只在 fn 执行期间以明文存在，fn 返回后立即清零，fn 不能保留 key 的引用
+type Keystore interface {
+	Use(id string, fn func(key []byte) error) error
+}
+
+// Backend 密文的持久化，id 通常为私钥对应的地址
+type Backend interface {
+	LoadSealed(id string) (string, error) // 不存在时返回 ErrKeyNotFound
+	SaveSealed(id, sealed string) error
+	ListSealed() (map[string]string, error)
+}
+
+// Store 基于信封加密的 Keystore 实现
+type Store struct {
+	keyring *Keyring
+	backend Backend
+}
+
+// New 创建私钥存储
+func New(keyring *Keyring, backend Backend) *Store {
+	return &Store{keyring: keyring, backend: backend}
+}
+
+// Use 解密私钥交给 fn 使用，fn 返回后清零明文
+func (s *Store) Use(id string, fn func(key []byte) error) error {
+	sealed, err := s.backend.LoadSealed(id)
+	if err != nil {
+		return err
+	}
+	key, err := s.keyring.Open(sealed)
+	if err != nil {
+		return fmt.Errorf("open key %s failed: %w", id, err)
+	}
+	defer Zero(key)
+	return fn(key)
+}
+
+// Put 加密并保存私钥，调用方负责清零传入的明文
+func (s *Store) Put(id string, key []byte) error {
+	sealed, err := s.keyring.Seal(key)
+	if err != nil {
+		return err
+	}
+	return s.backend.SaveSealed(id, sealed)
+}
+
+// Rotate 用当前主密钥重新加密所有未使用当前主密钥的数据密钥，返回更新的私钥ID
+// 启用加密前写入的明文私钥在这里一次性加密，之后才能通过 Use 使用
+// 中途失败时已更新的私钥保持更新，可重复执行
+func (s *Store) Rotate() ([]string, error) {
+	all, err := s.backend.ListSealed()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(all))
+	for id := range all {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var rotated []string
+	for _, id := range ids {
+		if !IsSealed(all[id]) {
+			plaintext := []byte(all[id])
+			err := s.Put(id, plaintext)
+			Zero(plaintext)
+			if err != nil {
+				return rotated, fmt.Errorf("seal plaintext key %s failed: %w", id, err)
+			}
+			rotated = append(rotated, id)
+			continue
+		}
+		sealed, changed, err := s.keyring.Rewrap(all[id])
+		if err != nil {
+			return rotated, fmt.Errorf("rewrap key %s failed: %w", id, err)
+		}
+		if !changed {
+			continue
+		}
+		if err := s.backend.SaveSealed(id, sealed); err != nil {
+			return rotated, fmt.Errorf("save key %s failed: %w", id, err)
+		}
+		rotated = append(rotated, id)
+	}
+	return rotated, nil
+}
+
+// MemoryBackend 内存中的密文，用于配置文件中的私钥
+type MemoryBackend struct {
+	mu     sync.RWMutex
+	sealed map[string]string
+}
+
+// NewMemoryBackend 创建内存密文存储，sealed 为 id 到密文的映射
+func NewMemoryBackend(sealed map[string]string) *MemoryBackend {
+	m := &MemoryBackend{sealed: make(map[string]string, len(sealed))}
+	for id, value := range sealed {
+		m.sealed[id] = value
+	}
+	return m
+}
+
+// LoadSealed 读取密文
+func (m *MemoryBackend) LoadSealed(id string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	sealed, ok := m.sealed[id]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+	}
+	return sealed, nil
+}
+
+// SaveSealed 保存密文
+func (m *MemoryBackend) SaveSealed(id, sealed string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sealed[id] = sealed
+	return nil
+}
+
+// ListSealed 列出所有密文
+func (m *MemoryBackend) ListSealed() (map[string]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	all := make(map[string]string, len(m.sealed))
+	for id, sealed := range m.sealed {
+		all[id] = sealed
+	}
+	return all, nil
+}
+
+// Zero 清零内存中的密钥
+func Zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,214 @@
+package keystore
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestKeyring 生成包含指定主密钥的列表，第一个为当前主密钥
+func newTestKeyring(t *testing.T, ids ...string) (*Keyring, string) {
+	t.Helper()
+	var entries []string
+	for _, id := range ids {
+		entry, err := GenerateMasterKey(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	data := strings.Join(entries, "\n")
+	k, err := ParseKeyring(data, ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k, data
+}
+
+// 测试加密后的密文可以解密，不泄露明文，篡改或替换主密钥ID后解密失败
+func TestSealOpen(t *testing.T) {
+	k, _ := newTestKeyring(t, "k1")
+	sealed, err := k.Seal([]byte("QWifPrivateKey"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsSealed(sealed) || strings.Contains(sealed, "QWifPrivateKey") {
+		t.Fatalf("Unexpected sealed value %q", sealed)
+	}
+	other, _ := k.Seal([]byte("QWifPrivateKey"))
+	if other == sealed {
+		t.Error("Expected a fresh data key and nonce for every seal")
+	}
+
+	plain, err := k.Open(sealed)
+	if err != nil || string(plain) != "QWifPrivateKey" {
+		t.Fatalf("Open got %q, %v", plain, err)
+	}
+
+	parts := strings.Split(sealed, ":")
+	tampered := strings.Join(append(parts[:4:4], parts[4][:len(parts[4])-2]+"AA"), ":")
+	if _, err := k.Open(tampered); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Expected ErrMalformed for tampered ciphertext, got %v", err)
+	}
+	if _, err := k.Open("QWifPrivateKey"); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Expected ErrMalformed for plaintext, got %v", err)
+	}
+
+	k2, _ := newTestKeyring(t, "k2")
+	renamed := strings.Replace(sealed, SealedPrefix+"k1:", SealedPrefix+"k2:", 1)
+	if _, err := k2.Open(renamed); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Expected data key bound to master key id, got %v", err)
+	}
+	if _, err := k2.Open(sealed); !errors.Is(err, ErrUnknownMasterKey) {
+		t.Errorf("Expected ErrUnknownMasterKey, got %v", err)
+	}
+}
+
+// 测试主密钥的读取和校验
+func TestLoadKeyring(t *testing.T) {
+	_, data := newTestKeyring(t, "old", "new")
+	path := filepath.Join(t.TempDir(), "master.key")
+	if err := os.WriteFile(path, []byte("# 主密钥\n"+data+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadKeyring("TEST_MASTER_KEY", path, ""); err == nil {
+		t.Error("Expected active key to be required with several master keys")
+	}
+	k, err := LoadKeyring("TEST_MASTER_KEY", path, "new")
+	if err != nil || k.Active() != "new" {
+		t.Fatalf("load from file got %v, %v", k, err)
+	}
+
+	single, _ := GenerateMasterKey("env")
+	t.Setenv("TEST_MASTER_KEY", single)
+	k, err = LoadKeyring("TEST_MASTER_KEY", path, "")
+	if err != nil || k.Active() != "env" {
+		t.Fatalf("Expected env to take precedence, got %v, %v", k, err)
+	}
+
+	t.Setenv("TEST_MASTER_KEY", "")
+	if _, err := LoadKeyring("TEST_MASTER_KEY", "", ""); !errors.Is(err, ErrNoMasterKey) {
+		t.Errorf("Expected ErrNoMasterKey, got %v", err)
+	}
+	for _, bad := range []string{"k1", "k1:short", ":" + strings.Repeat("A", 44)} {
+		if _, err := ParseKeyring(bad, ""); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+// 测试私钥只在回调期间以明文存在，回调返回后被清零
+func TestStoreUseZeroesKey(t *testing.T) {
+	k, _ := newTestKeyring(t, "k1")
+	store := New(k, NewMemoryBackend(nil))
+	if err := store.Put("DAddr", []byte("QWifPrivateKey")); err != nil {
+		t.Fatal(err)
+	}
+
+	var leaked []byte
+	err := store.Use("DAddr", func(key []byte) error {
+		if string(key) != "QWifPrivateKey" {
+			t.Errorf("Unexpected key %q", key)
+		}
+		leaked = key
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(leaked) != string(make([]byte, len("QWifPrivateKey"))) {
+		t.Errorf("Expected key to be zeroed after use, got %q", leaked)
+	}
+
+	if err := store.Use("DOther", func([]byte) error { return nil }); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+// 测试轮换主密钥后数据密钥用新主密钥重新加密，私钥密文不变，旧主密钥可以移除
+func TestStoreRotate(t *testing.T) {
+	oldRing, oldData := newTestKeyring(t, "old")
+	backend := NewMemoryBackend(nil)
+	if err := New(oldRing, backend).Put("DAddr1", []byte("key1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := New(oldRing, backend).Put("DAddr2", []byte("key2")); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := backend.ListSealed()
+
+	newEntry, _ := GenerateMasterKey("new")
+	ring, err := ParseKeyring(oldData+","+newEntry, "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := New(ring, backend).Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rotated, []string{"DAddr1", "DAddr2"}) {
+		t.Errorf("Unexpected rotated ids %v", rotated)
+	}
+	if again, _ := New(ring, backend).Rotate(); len(again) != 0 {
+		t.Errorf("Expected rotation to be idempotent, got %v", again)
+	}
+
+	after, _ := backend.ListSealed()
+	for id, sealed := range after {
+		if !strings.HasPrefix(sealed, SealedPrefix+"new:") {
+			t.Errorf("Expected %s to use the new master key, got %q", id, sealed)
+		}
+		oldParts, newParts := strings.Split(before[id], ":"), strings.Split(sealed, ":")
+		if oldParts[4] != newParts[4] {
+			t.Errorf("Expected ciphertext of %s to be unchanged", id)
+		}
+	}
+
+	onlyNew, _ := ParseKeyring(newEntry, "")
+	err = New(onlyNew, backend).Use("DAddr2", func(key []byte) error {
+		if string(key) != "key2" {
+			t.Errorf("Unexpected key %q", key)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 测试启用加密前写入的明文私钥在轮换时加密，之后可以正常使用
+func TestStoreRotateSealsPlaintext(t *testing.T) {
+	k, _ := newTestKeyring(t, "k1")
+	backend := NewMemoryBackend(map[string]string{"DAddr": "QWifPrivateKey"})
+	store := New(k, backend)
+	if err := store.Use("DAddr", func([]byte) error { return nil }); !errors.Is(err, ErrMalformed) {
+		t.Fatalf("Expected plaintext key to be unusable before sealing, got %v", err)
+	}
+
+	rotated, err := store.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rotated, []string{"DAddr"}) {
+		t.Errorf("Unexpected rotated ids %v", rotated)
+	}
+	if sealed, _ := backend.LoadSealed("DAddr"); !IsSealed(sealed) {
+		t.Fatalf("Expected key to be sealed, got %q", sealed)
+	}
+	err = store.Use("DAddr", func(key []byte) error {
+		if string(key) != "QWifPrivateKey" {
+			t.Errorf("Unexpected key %q", key)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := store.Rotate(); len(again) != 0 {
+		t.Errorf("Expected sealing to be idempotent, got %v", again)
+	}
+}
@@ -0,0 +1,371 @@
+// Package migrate 版本化的数据库表结构迁移
+// 迁移文件成对出现：NNNN_name.up.sql 升级，NNNN_name.down.sql 回滚，版本号为文件名前缀的数字，
+// 按版本号升序执行，已执行的版本记录在 schema_migrations 表中。
+// MySQL 的 DDL 会隐式提交，迁移失败时无法整体回滚：执行前先将版本标记为 dirty，全部语句成功后清除，
+// 存在 dirty 版本时拒绝继续迁移，需人工修复表结构后用 Force 重置该版本的状态
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// versionTable 记录已执行版本的表名
+const versionTable = "schema_migrations"
+
+var (
+	// ErrDirty 存在执行失败的迁移，需人工修复后 Force
+	ErrDirty = errors.New("migrate: database is dirty")
+	// ErrUnknownVersion 版本不在迁移文件中
+	ErrUnknownVersion = errors.New("migrate: unknown version")
+)
+
+// fileName 迁移文件名格式：版本号_名称.up|down.sql
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration 一个版本的升级和回滚语句
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status 一个版本的执行状态
+type Status struct {
+	Version   uint64     `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	Dirty     bool       `json:"dirty"`
+	Missing   bool       `json:"missing"` // 数据库中已执行但迁移文件中不存在
+	AppliedAt *time.Time `json:"appliedAt"`
+}
+
+// schemaMigration schema_migrations 表的一行
+type schemaMigration struct {
+	Version   uint64    `gorm:"column:version;primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"column:name"`
+	Dirty     bool      `gorm:"column:dirty"`
+	AppliedAt time.Time `gorm:"column:applied_at"`
+}
+
+// TableName 设置schemaMigration表名
+func (schemaMigration) TableName() string {
+	return versionTable
+}
+
+// Load 读取目录下的迁移文件，按版本号升序返回
+// 每个版本必须同时有 up 和 down 文件，版本号不能重复
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migrate: bad migration file name %q", entry.Name())
+		}
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("migrate: bad version in %q", entry.Name())
+		}
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migrate: version %d used by both %q and %q", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if len(splitStatements(m.Up)) == 0 || len(splitStatements(m.Down)) == 0 {
+			return nil, fmt.Errorf("migrate: version %d (%s) needs non-empty up and down files", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Migrator 在数据库上执行迁移
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+	logger     *log.Logger
+}
+
+// New 读取迁移文件并创建迁移器
+func New(db *gorm.DB, fsys fs.FS, logger *log.Logger) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Migrator{db: db, migrations: migrations, logger: logger}, nil
+}
+
+// Up 按版本号升序执行未执行的迁移，target 为0时执行全部，否则执行到 target（含）为止
+func (m *Migrator) Up(target uint64) ([]Migration, error) {
+	if target != 0 && m.find(target) == nil {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, target)
+	}
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range m.migrations {
+		if target != 0 && migration.Version > target {
+			break
+		}
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		if err := m.run(migration, true); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down 按版本号降序回滚最近执行的 steps 个迁移
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if err := m.run(migration, false); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Status 返回所有迁移文件及数据库中已执行版本的状态，按版本号升序
+func (m *Migrator) Status() ([]Status, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+	var rows []schemaMigration
+	if err := m.db.Order("version ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	recorded := make(map[uint64]schemaMigration, len(rows))
+	for _, row := range rows {
+		recorded[row.Version] = row
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if row, ok := recorded[migration.Version]; ok {
+			status.Applied = true
+			status.Dirty = row.Dirty
+			appliedAt := row.AppliedAt
+			status.AppliedAt = &appliedAt
+			delete(recorded, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, row := range recorded {
+		appliedAt := row.AppliedAt
+		statuses = append(statuses, Status{
+			Version:   row.Version,
+			Name:      row.Name,
+			Applied:   true,
+			Dirty:     row.Dirty,
+			Missing:   true,
+			AppliedAt: &appliedAt,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// Force 人工修复表结构后重置版本状态：applied 为 true 时记为已执行，否则删除执行记录
+func (m *Migrator) Force(version uint64, applied bool) error {
+	migration := m.find(version)
+	if migration == nil {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+	if err := m.ensureTable(); err != nil {
+		return err
+	}
+	if !applied {
+		return m.db.Where("version = ?", version).Delete(&schemaMigration{}).Error
+	}
+	return m.db.Save(&schemaMigration{
+		Version:   version,
+		Name:      migration.Name,
+		AppliedAt: time.Now(),
+	}).Error
+}
+
+// run 执行一个迁移的升级或回滚语句，执行期间版本标记为 dirty
+func (m *Migrator) run(migration Migration, up bool) error {
+	script, direction := migration.Up, "up"
+	if !up {
+		script, direction = migration.Down, "down"
+	}
+	m.logger.Printf("[迁移] %s %d_%s", direction, migration.Version, migration.Name)
+
+	if err := m.db.Save(&schemaMigration{
+		Version:   migration.Version,
+		Name:      migration.Name,
+		Dirty:     true,
+		AppliedAt: time.Now(),
+	}).Error; err != nil {
+		return fmt.Errorf("mark version %d dirty failed: %w", migration.Version, err)
+	}
+
+	for i, stmt := range splitStatements(script) {
+		if err := m.db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("migrate %s %d_%s statement %d failed: %w",
+				direction, migration.Version, migration.Name, i+1, err)
+		}
+	}
+
+	if !up {
+		return m.db.Where("version = ?", migration.Version).Delete(&schemaMigration{}).Error
+	}
+	return m.db.Model(&schemaMigration{}).
+		Where("version = ?", migration.Version).
+		Updates(map[string]interface{}{"dirty": false, "applied_at": time.Now()}).Error
+}
+
+// applied 返回已执行的版本，存在 dirty 版本时返回 ErrDirty
+func (m *Migrator) applied() (map[uint64]schemaMigration, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+	var rows []schemaMigration
+	if err := m.db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[uint64]schemaMigration, len(rows))
+	for _, row := range rows {
+		if row.Dirty {
+			return nil, fmt.Errorf("%w: version %d (%s) failed halfway, fix the schema and force it",
+				ErrDirty, row.Version, row.Name)
+		}
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// ensureTable 创建版本记录表
+func (m *Migrator) ensureTable() error {
+	return m.db.Exec("CREATE TABLE IF NOT EXISTS " + versionTable + ` (
+    version    bigint unsigned                     not null primary key,
+    name       varchar(255)                        not null,
+    dirty      tinyint(1) default 0                not null,
+    applied_at timestamp  default CURRENT_TIMESTAMP not null
+) comment '表结构迁移版本'`).Error
+}
+
+// find 按版本号查找迁移
+func (m *Migrator) find(version uint64) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// splitStatements 按分号拆分SQL脚本，忽略引号内的分号以及 -- 和 /* */ 注释，去掉空语句
+func splitStatements(script string) []string {
+	var (
+		statements []string
+		current    strings.Builder
+		quote      rune
+	)
+	flush := func() {
+		if stmt := strings.TrimSpace(current.String()); stmt != "" {
+			statements = append(statements, stmt)
+		}
+		current.Reset()
+	}
+
+	runes := []rune(script)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if quote != 0 {
+			current.WriteRune(r)
+			switch {
+			case r == '\\' && quote != '`' && i+1 < len(runes):
+				i++
+				current.WriteRune(runes[i])
+			case r == quote:
+				quote = 0
+			}
+			continue
+		}
+
+		switch {
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+			current.WriteRune(r)
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			current.WriteRune('\n')
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			for i += 2; i+1 < len(runes) && (runes[i] != '*' || runes[i+1] != '/'); i++ {
+			}
+			i++ // 跳过结尾的 '/'
+			current.WriteRune(' ')
+		case r == ';':
+			flush()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+	return statements
+}
@@ -0,0 +1,98 @@
+package migrate
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"claimask/sql/migrations"
+)
+
+// 测试迁移文件按版本号排序并配对
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_index.up.sql":   {Data: []byte("create index idx_a on t (a);")},
+		"0002_add_index.down.sql": {Data: []byte("drop index idx_a on t;")},
+		"0001_create_t.up.sql":    {Data: []byte("create table t (a int);")},
+		"0001_create_t.down.sql":  {Data: []byte("drop table t;")},
+		"README.md":               {Data: []byte("ignored")},
+	}
+	got, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(got) != 2 || got[0].Version != 1 || got[1].Version != 2 {
+		t.Fatalf("Unexpected migrations %+v", got)
+	}
+	if got[0].Name != "create_t" || got[0].Down != "drop table t;" {
+		t.Errorf("Unexpected migration %+v", got[0])
+	}
+}
+
+// 测试不合法的迁移文件被拒绝
+func TestLoadRejectsBadFiles(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"missing down": {
+			"0001_create_t.up.sql": {Data: []byte("create table t (a int);")},
+		},
+		"duplicate version": {
+			"0001_a.up.sql":   {Data: []byte("select 1;")},
+			"0001_a.down.sql": {Data: []byte("select 1;")},
+			"0001_b.up.sql":   {Data: []byte("select 1;")},
+			"0001_b.down.sql": {Data: []byte("select 1;")},
+		},
+		"bad name": {
+			"create_t.up.sql": {Data: []byte("create table t (a int);")},
+		},
+		"zero version": {
+			"0000_a.up.sql":   {Data: []byte("select 1;")},
+			"0000_a.down.sql": {Data: []byte("select 1;")},
+		},
+		"only comments": {
+			"0001_a.up.sql":   {Data: []byte("select 1;")},
+			"0001_a.down.sql": {Data: []byte("-- nothing to undo\n")},
+		},
+	}
+	for name, fsys := range cases {
+		if _, err := Load(fsys); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+// 测试按分号拆分语句时跳过注释和引号内的分号
+func TestSplitStatements(t *testing.T) {
+	script := `-- 建表; 注释里的分号
+create table t
+(
+    a varchar(8) default ';' not null comment 'it''s; fine', /* 块注释; */
+    ` + "`b;`" + ` int
+);
+
+create index idx_a on t (a);
+`
+	got := splitStatements(script)
+	want := []string{
+		"create table t\n(\n    a varchar(8) default ';' not null comment 'it''s; fine',  \n    `b;` int\n)",
+		"create index idx_a on t (a)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected statements\n got %q\nwant %q", got, want)
+	}
+}
+
+// 测试仓库内的迁移文件可以加载，版本号从1开始连续
+func TestRepositoryMigrations(t *testing.T) {
+	got, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatalf("load repository migrations failed: %v", err)
+	}
+	if len(got) == 0 {
+		t.Fatal("Expected repository migrations")
+	}
+	for i, m := range got {
+		if m.Version != uint64(i+1) {
+			t.Errorf("Expected version %d, got %d (%s)", i+1, m.Version, m.Name)
+		}
+	}
+}
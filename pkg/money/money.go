@@ -0,0 +1,202 @@
+// Package money 定点金额类型
+// Amount 以 ELON（1 DOGE = 10^8 ELON）为单位保存为 int64，所有转换都是精确的十进制运算，
+// 数据库和JSON中以 DOGE 十进制字符串表示
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Decimals DOGE 的小数位数
+	Decimals = 8
+	// ElonPerDoge 1 DOGE 对应的 ELON 数
+	ElonPerDoge = 100000000
+)
+
+var (
+	// ErrInvalidAmount 金额格式非法
+	ErrInvalidAmount = errors.New("money: invalid amount")
+	// ErrPrecision 金额超过8位小数
+	ErrPrecision = errors.New("money: more than 8 decimal places")
+	// ErrOverflow 金额超出 int64 ELON 的范围
+	ErrOverflow = errors.New("money: amount overflows int64 elon")
+)
+
+var elonPerDogeRat = big.NewRat(ElonPerDoge, 1)
+
+// Amount 金额，单位 ELON
+type Amount int64
+
+// FromElon 由 ELON 数创建金额
+func FromElon(elon int64) Amount {
+	return Amount(elon)
+}
+
+// ParseDoge 精确解析 DOGE 十进制字符串，如 "1.5"、"0.00000001"、"1e-3"
+// 超过8位小数的非零部分返回 ErrPrecision，不做舍入
+func ParseDoge(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.ContainsAny(s, "/") {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	r.Mul(r, elonPerDogeRat)
+	if !r.IsInt() {
+		return 0, fmt.Errorf("%w: %q", ErrPrecision, s)
+	}
+	n := r.Num()
+	if !n.IsInt64() {
+		return 0, fmt.Errorf("%w: %q", ErrOverflow, s)
+	}
+	return Amount(n.Int64()), nil
+}
+
+// MustParseDoge 解析 DOGE 十进制字符串，失败时panic，用于常量初始化
+func MustParseDoge(s string) Amount {
+	a, err := ParseDoge(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// FromDogeFloat 将浮点 DOGE 数四舍五入到最近的 ELON
+// 仅用于兼容只能提供浮点数的外部接口，直接乘以 10^8 再截断会因二进制误差少算 1 ELON
+func FromDogeFloat(doge float64) Amount {
+	return Amount(math.Round(doge * ElonPerDoge))
+}
+
+// Elon 返回 ELON 数
+func (a Amount) Elon() int64 {
+	return int64(a)
+}
+
+// DogeFloat 返回浮点 DOGE 数，仅用于展示
+func (a Amount) DogeFloat() float64 {
+	return float64(a) / ElonPerDoge
+}
+
+// Add 加法
+func (a Amount) Add(b Amount) Amount {
+	return a + b
+}
+
+// Sub 减法
+func (a Amount) Sub(b Amount) Amount {
+	return a - b
+}
+
+// Mul 乘以整数
+func (a Amount) Mul(n int64) Amount {
+	return a * Amount(n)
+}
+
+// Sign 返回 -1、0 或 1
+func (a Amount) Sign() int {
+	switch {
+	case a < 0:
+		return -1
+	case a > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// IsZero 是否为0
+func (a Amount) IsZero() bool {
+	return a == 0
+}
+
+// String 返回去掉末尾0的 DOGE 十进制字符串，如 "1.5"
+func (a Amount) String() string {
+	s := a.Fixed()
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+// Fixed 返回固定8位小数的 DOGE 十进制字符串，如 "1.50000000"
+func (a Amount) Fixed() string {
+	v := int64(a)
+	sign := ""
+	u := uint64(v)
+	if v < 0 {
+		sign = "-"
+		u = uint64(-v) // math.MinInt64 取反后按无符号解释仍然正确
+	}
+	frac := strconv.FormatUint(u%ElonPerDoge, 10)
+	return sign + strconv.FormatUint(u/ElonPerDoge, 10) + "." + strings.Repeat("0", Decimals-len(frac)) + frac
+}
+
+// Value 实现 driver.Valuer，以 DOGE 十进制字符串写入 decimal 列
+func (a Amount) Value() (driver.Value, error) {
+	return a.Fixed(), nil
+}
+
+// Scan 实现 sql.Scanner，从 decimal 列读取 DOGE 十进制数
+func (a *Amount) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*a = 0
+		return nil
+	case []byte:
+		return a.scanString(string(v))
+	case string:
+		return a.scanString(v)
+	case int64:
+		if v > math.MaxInt64/ElonPerDoge || v < math.MinInt64/ElonPerDoge {
+			return fmt.Errorf("%w: %d", ErrOverflow, v)
+		}
+		*a = Amount(v * ElonPerDoge)
+		return nil
+	case float64:
+		*a = FromDogeFloat(v)
+		return nil
+	default:
+		return fmt.Errorf("%w: unsupported scan type %T", ErrInvalidAmount, src)
+	}
+}
+
+// scanString 解析数据库返回的十进制字符串
+func (a *Amount) scanString(s string) error {
+	v, err := ParseDoge(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// MarshalJSON 以 DOGE 十进制字符串输出，避免前端按浮点数解析丢失精度
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(a.String())), nil
+}
+
+// UnmarshalJSON 接受 DOGE 十进制字符串或数字（如节点RPC返回的 0.001），均按十进制精确解析
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	v, err := ParseDoge(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
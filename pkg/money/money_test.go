@@ -0,0 +1,151 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+// 测试 DOGE 十进制字符串的精确解析
+func TestParseDoge(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Amount
+		err  error
+	}{
+		{"1", 100000000, nil},
+		{"1.5", 150000000, nil},
+		{"0.00000001", 1, nil},
+		{"0.29", 29000000, nil},
+		{"-2.5", -250000000, nil},
+		{"1e-3", 100000, nil},
+		{"1.500000000000000000", 150000000, nil}, // decimal(65,18) 读出的值
+		{"0.000000001", 0, ErrPrecision},
+		{"92233720368.54775808", 0, ErrOverflow},
+		{"", 0, ErrInvalidAmount},
+		{"abc", 0, ErrInvalidAmount},
+		{"1/3", 0, ErrInvalidAmount},
+	}
+	for _, c := range cases {
+		got, err := ParseDoge(c.in)
+		if c.err != nil {
+			if !errors.Is(err, c.err) {
+				t.Errorf("%q: expected %v, got %v", c.in, c.err, err)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("%q: expected %d, got %d (%v)", c.in, c.want, got, err)
+		}
+	}
+}
+
+// 测试浮点 DOGE 转换四舍五入到最近的 ELON
+func TestFromDogeFloat(t *testing.T) {
+	// 0.29 * 1e8 在二进制浮点下为 28999999.999999996，截断会少 1 ELON
+	if got := FromDogeFloat(0.29); got != 29000000 {
+		t.Errorf("Expected 29000000, got %d", got)
+	}
+	if got := FromDogeFloat(0.001); got != 100000 {
+		t.Errorf("Expected 100000, got %d", got)
+	}
+}
+
+// 测试格式化输出
+func TestFormat(t *testing.T) {
+	cases := []struct {
+		in     Amount
+		str    string
+		fixed  string
+		doge64 float64
+	}{
+		{0, "0", "0.00000000", 0},
+		{150000000, "1.5", "1.50000000", 1.5},
+		{1, "0.00000001", "0.00000001", 0.00000001},
+		{-250000000, "-2.5", "-2.50000000", -2.5},
+		{100000000, "1", "1.00000000", 1},
+	}
+	for _, c := range cases {
+		if c.in.String() != c.str || c.in.Fixed() != c.fixed || c.in.DogeFloat() != c.doge64 {
+			t.Errorf("%d: got %s / %s / %v", c.in, c.in.String(), c.in.Fixed(), c.in.DogeFloat())
+		}
+	}
+	if got := Amount(math.MinInt64).Fixed(); got != "-92233720368.54775808" {
+		t.Errorf("Unexpected min value format %s", got)
+	}
+}
+
+// 测试数据库读写
+func TestScanValue(t *testing.T) {
+	v, err := Amount(150000000).Value()
+	if err != nil || v != "1.50000000" {
+		t.Fatalf("Unexpected value %v (%v)", v, err)
+	}
+
+	cases := []struct {
+		src  interface{}
+		want Amount
+	}{
+		{[]byte("1.500000000000000000"), 150000000},
+		{"0.1", 10000000},
+		{int64(3), 300000000},
+		{0.29, 29000000},
+		{nil, 0},
+	}
+	for _, c := range cases {
+		a := Amount(42)
+		if err := a.Scan(c.src); err != nil || a != c.want {
+			t.Errorf("%v: expected %d, got %d (%v)", c.src, c.want, a, err)
+		}
+	}
+
+	var a Amount
+	if err := a.Scan([]byte("0.1234567891")); !errors.Is(err, ErrPrecision) {
+		t.Errorf("Expected ErrPrecision, got %v", err)
+	}
+	if err := a.Scan(true); err == nil {
+		t.Error("Expected error for bool source")
+	}
+}
+
+// 测试JSON编解码
+func TestJSON(t *testing.T) {
+	type payload struct {
+		Amount Amount `json:"amount"`
+	}
+
+	data, err := json.Marshal(payload{Amount: 150000000})
+	if err != nil || string(data) != `{"amount":"1.5"}` {
+		t.Fatalf("Unexpected json %s (%v)", data, err)
+	}
+
+	for _, in := range []string{`{"amount":"1.5"}`, `{"amount":1.5}`, `{"amount":1.50000000}`} {
+		var p payload
+		if err := json.Unmarshal([]byte(in), &p); err != nil || p.Amount != 150000000 {
+			t.Errorf("%s: got %d (%v)", in, p.Amount, err)
+		}
+	}
+
+	var p payload
+	if err := json.Unmarshal([]byte(`{"amount":null}`), &p); err != nil || p.Amount != 0 {
+		t.Errorf("null: got %d (%v)", p.Amount, err)
+	}
+	if err := json.Unmarshal([]byte(`{"amount":"1.123456789"}`), &p); err == nil {
+		t.Error("Expected precision error")
+	}
+}
+
+// 测试算术运算
+func TestArithmetic(t *testing.T) {
+	a := MustParseDoge("0.1")
+	if got := a.Mul(3).Add(MustParseDoge("0.2")).Sub(MustParseDoge("0.5")); !got.IsZero() {
+		t.Errorf("Expected 0.1*3+0.2-0.5 to be exactly zero, got %s", got)
+	}
+	if a.Sign() != 1 || Amount(-1).Sign() != -1 || Amount(0).Sign() != 0 {
+		t.Error("Unexpected sign")
+	}
+	if FromElon(7).Elon() != 7 {
+		t.Error("Unexpected elon round trip")
+	}
+}
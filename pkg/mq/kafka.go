@@ -0,0 +1,172 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// KafkaPublisher 基于sarama同步生产者的发布者
+type KafkaPublisher struct {
+	producer sarama.SyncProducer
+}
+
+// NewKafkaPublisher 创建Kafka发布者，要求所有副本确认写入
+func NewKafkaPublisher(brokers []string) (*KafkaPublisher, error) {
+	config := sarama.NewConfig()
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Retry.Max = 3
+	config.Producer.Return.Successes = true
+
+	producer, err := sarama.NewSyncProducer(brokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("create kafka producer failed: %w", err)
+	}
+	return &KafkaPublisher{producer: producer}, nil
+}
+
+// Publish 实现 Publisher
+func (p *KafkaPublisher) Publish(ctx context.Context, msg *Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	pm := &sarama.ProducerMessage{
+		Topic: msg.Topic,
+		Key:   sarama.StringEncoder(msg.Key),
+		Value: sarama.ByteEncoder(msg.Value),
+	}
+	_, headers := withMessageID(msg.Headers)
+	for k, v := range headers {
+		pm.Headers = append(pm.Headers, sarama.RecordHeader{Key: []byte(k), Value: []byte(v)})
+	}
+
+	_, _, err := p.producer.SendMessage(pm)
+	return err
+}
+
+// Close 实现 Publisher
+func (p *KafkaPublisher) Close() error {
+	return p.producer.Close()
+}
+
+// KafkaSubscriber 基于sarama消费者组的订阅者
+// 关闭自动提交，处理函数返回nil后才提交位点；处理失败时结束本次会话，重新加入后从未提交的位点继续消费
+type KafkaSubscriber struct {
+	brokers    []string
+	retryDelay time.Duration
+
+	mu     sync.Mutex
+	groups []sarama.ConsumerGroup
+}
+
+// NewKafkaSubscriber 创建Kafka订阅者
+func NewKafkaSubscriber(brokers []string, retryDelay time.Duration) *KafkaSubscriber {
+	return &KafkaSubscriber{brokers: brokers, retryDelay: retryDelay}
+}
+
+// Subscribe 实现 Subscriber
+func (s *KafkaSubscriber) Subscribe(ctx context.Context, group string, topics []string, handler Handler) error {
+	config := sarama.NewConfig()
+	config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	config.Consumer.Offsets.AutoCommit.Enable = false
+	config.Consumer.Return.Errors = true
+
+	cg, err := sarama.NewConsumerGroup(s.brokers, group, config)
+	if err != nil {
+		return fmt.Errorf("create consumer group failed: %w", err)
+	}
+	s.mu.Lock()
+	s.groups = append(s.groups, cg)
+	s.mu.Unlock()
+	defer cg.Close()
+
+	go func() {
+		for err := range cg.Errors() {
+			log.Printf("[mq] 消费者组 %s 错误: %v", group, err)
+		}
+	}()
+
+	h := &kafkaGroupHandler{handler: handler}
+	for {
+		if err := cg.Consume(ctx, topics, h); err != nil {
+			if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+				return nil
+			}
+			log.Printf("[mq] 消费者组 %s 消费失败: %v", group, err)
+			if !sleep(ctx, s.retryDelay) {
+				return nil
+			}
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// Close 实现 Subscriber，关闭所有消费者组
+func (s *KafkaSubscriber) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var firstErr error
+	for _, cg := range s.groups {
+		if err := cg.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	s.groups = nil
+	return firstErr
+}
+
+// kafkaGroupHandler 将 Handler 适配为 sarama.ConsumerGroupHandler
+type kafkaGroupHandler struct {
+	handler Handler
+}
+
+// Setup 实现 sarama.ConsumerGroupHandler
+func (h *kafkaGroupHandler) Setup(sarama.ConsumerGroupSession) error { return nil }
+
+// Cleanup 实现 sarama.ConsumerGroupHandler
+func (h *kafkaGroupHandler) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+// ConsumeClaim 实现 sarama.ConsumerGroupHandler，逐条处理分区消息
+func (h *kafkaGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for {
+		select {
+		case cm, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			msg := &Message{
+				ID:      fmt.Sprintf("%d-%d", cm.Partition, cm.Offset),
+				Topic:   cm.Topic,
+				Key:     string(cm.Key),
+				Value:   cm.Value,
+				Headers: make(map[string]string, len(cm.Headers)),
+			}
+			for _, header := range cm.Headers {
+				if header != nil {
+					msg.Headers[string(header.Key)] = string(header.Value)
+				}
+			}
+			msg.ID = messageID(msg.Headers, msg.ID)
+
+			if err := h.handler(session.Context(), msg); err != nil {
+				if session.Context().Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("handle %s/%s failed: %w", cm.Topic, msg.ID, err)
+			}
+			session.MarkMessage(cm, "")
+			session.Commit()
+		case <-session.Context().Done():
+			return nil
+		}
+	}
+}
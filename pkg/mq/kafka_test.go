@@ -0,0 +1,112 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+)
+
+// fakeSession 记录已确认位点的消费者组会话
+type fakeSession struct {
+	ctx     context.Context
+	marked  []int64
+	commits int
+}
+
+func (s *fakeSession) Claims() map[string][]int32               { return nil }
+func (s *fakeSession) MemberID() string                         { return "member" }
+func (s *fakeSession) GenerationID() int32                      { return 1 }
+func (s *fakeSession) MarkOffset(string, int32, int64, string)  {}
+func (s *fakeSession) ResetOffset(string, int32, int64, string) {}
+func (s *fakeSession) Commit()                                  { s.commits++ }
+func (s *fakeSession) Context() context.Context                 { return s.ctx }
+func (s *fakeSession) MarkMessage(msg *sarama.ConsumerMessage, _ string) {
+	s.marked = append(s.marked, msg.Offset)
+}
+
+// fakeClaim 从通道读取分区消息
+type fakeClaim struct {
+	messages chan *sarama.ConsumerMessage
+}
+
+func (c *fakeClaim) Topic() string                            { return "t" }
+func (c *fakeClaim) Partition() int32                         { return 0 }
+func (c *fakeClaim) InitialOffset() int64                     { return 0 }
+func (c *fakeClaim) HighWaterMarkOffset() int64               { return int64(len(c.messages)) }
+func (c *fakeClaim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
+
+// 测试发布时写入消息头和全局唯一的消息ID，转发的消息获得新ID
+func TestKafkaPublisher(t *testing.T) {
+	producer := mocks.NewSyncProducer(t, nil)
+	publisher := &KafkaPublisher{producer: producer}
+	var ids []string
+	check := func(msg *sarama.ProducerMessage) error {
+		headers := make(map[string]string)
+		for _, h := range msg.Headers {
+			headers[string(h.Key)] = string(h.Value)
+		}
+		if headers["h"] != "v" || headers[HeaderMessageID] == "" {
+			return errors.New("missing headers")
+		}
+		ids = append(ids, headers[HeaderMessageID])
+		return nil
+	}
+	producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(check)
+	producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(check)
+
+	msg := &Message{Topic: "t", Key: "k", Value: []byte("v"), Headers: map[string]string{"h": "v", HeaderMessageID: "old"}}
+	for i := 0; i < 2; i++ {
+		if err := publisher.Publish(context.Background(), msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(ids) != 2 || ids[0] == ids[1] || ids[0] == "old" {
+		t.Errorf("Expected a new unique id per publish, got %v", ids)
+	}
+	if msg.Headers[HeaderMessageID] != "old" {
+		t.Error("Expected caller headers to be left untouched")
+	}
+	if err := publisher.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 测试处理成功后才确认位点，失败时结束会话且不确认，消息ID取自消息头
+func TestKafkaGroupHandler(t *testing.T) {
+	claim := &fakeClaim{messages: make(chan *sarama.ConsumerMessage, 3)}
+	header := func(id string) []*sarama.RecordHeader {
+		return []*sarama.RecordHeader{{Key: []byte(HeaderMessageID), Value: []byte(id)}}
+	}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "t", Offset: 0, Value: []byte("a"), Headers: header("id-a")}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "t", Offset: 1, Value: []byte("b")}
+	claim.messages <- &sarama.ConsumerMessage{Topic: "t", Offset: 2, Value: []byte("c"), Headers: header("id-c")}
+
+	var ids []string
+	handler := &kafkaGroupHandler{handler: func(ctx context.Context, msg *Message) error {
+		ids = append(ids, msg.ID)
+		if string(msg.Value) == "c" {
+			return errors.New("boom")
+		}
+		return nil
+	}}
+	session := &fakeSession{ctx: context.Background()}
+	if err := handler.ConsumeClaim(session, claim); err == nil {
+		t.Fatal("Expected handler error to end the session")
+	}
+	if len(ids) != 3 || ids[0] != "id-a" || ids[1] != "0-1" || ids[2] != "id-c" {
+		t.Errorf("Unexpected message ids %v", ids)
+	}
+	if len(session.marked) != 2 || session.marked[1] != 1 || session.commits != 2 {
+		t.Errorf("Expected only handled messages to be committed, got %v commits=%d", session.marked, session.commits)
+	}
+
+	// 会话结束时正常退出
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := handler.ConsumeClaim(&fakeSession{ctx: ctx}, &fakeClaim{messages: make(chan *sarama.ConsumerMessage)}); err != nil {
+		t.Errorf("Expected nil on session end, got %v", err)
+	}
+}
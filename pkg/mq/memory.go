@@ -0,0 +1,166 @@
+package mq
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryBus 进程内消息总线，同时实现 Publisher 和 Subscriber
+// 每个主题保存完整的消息日志，每个消费者组在每个主题上维护一个位点，
+// 同组的订阅者串行处理，语义与Kafka单分区一致；消息只保存在内存中，进程退出即丢失，仅用于测试和本地开发
+type MemoryBus struct {
+	retryDelay time.Duration
+
+	mu      sync.Mutex
+	topics  map[string][]*Message
+	cursors map[string]*memoryCursor // 消费者组+主题 -> 位点
+	notify  chan struct{}            // 有新消息时关闭并替换
+	closed  bool
+	done    chan struct{}
+}
+
+// memoryCursor 消费者组在一个主题上的位点
+type memoryCursor struct {
+	mu     sync.Mutex // 处理期间持有，保证同组串行
+	offset int
+}
+
+// NewMemoryBus 创建进程内消息总线
+func NewMemoryBus(retryDelay time.Duration) *MemoryBus {
+	if retryDelay <= 0 {
+		retryDelay = defaultRetryDelay
+	}
+	return &MemoryBus{
+		retryDelay: retryDelay,
+		topics:     make(map[string][]*Message),
+		cursors:    make(map[string]*memoryCursor),
+		notify:     make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Publish 实现 Publisher
+func (b *MemoryBus) Publish(ctx context.Context, msg *Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+
+	id, headers := withMessageID(msg.Headers)
+	b.topics[msg.Topic] = append(b.topics[msg.Topic], &Message{
+		ID:      id,
+		Topic:   msg.Topic,
+		Key:     msg.Key,
+		Value:   append([]byte(nil), msg.Value...),
+		Headers: headers,
+	})
+	close(b.notify)
+	b.notify = make(chan struct{})
+	return nil
+}
+
+// Subscribe 实现 Subscriber，每个主题一个处理协程
+func (b *MemoryBus) Subscribe(ctx context.Context, group string, topics []string, handler Handler) error {
+	var wg sync.WaitGroup
+	for _, topic := range topics {
+		wg.Add(1)
+		go func(topic string) {
+			defer wg.Done()
+			b.consume(ctx, b.cursor(group, topic), topic, handler)
+		}(topic)
+	}
+	wg.Wait()
+	return nil
+}
+
+// Close 实现 Publisher 和 Subscriber，关闭后发布返回 ErrClosed，订阅者退出
+func (b *MemoryBus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed {
+		b.closed = true
+		close(b.done)
+	}
+	return nil
+}
+
+// Len 返回主题中已发布的消息数
+func (b *MemoryBus) Len(topic string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.topics[topic])
+}
+
+// Offset 返回消费者组在主题上已确认的消息数
+func (b *MemoryBus) Offset(group, topic string) int {
+	cur := b.cursor(group, topic)
+	cur.mu.Lock()
+	defer cur.mu.Unlock()
+	return cur.offset
+}
+
+// cursor 获取或创建消费者组在主题上的位点
+func (b *MemoryBus) cursor(group, topic string) *memoryCursor {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	key := group + "\x00" + topic
+	cur, ok := b.cursors[key]
+	if !ok {
+		cur = &memoryCursor{}
+		b.cursors[key] = cur
+	}
+	return cur
+}
+
+// next 返回位点处的消息；没有新消息时返回等待通道
+func (b *MemoryBus) next(topic string, offset int) (*Message, <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if messages := b.topics[topic]; offset < len(messages) {
+		return messages[offset], nil
+	}
+	return nil, b.notify
+}
+
+// consume 按位点顺序处理主题消息，处理失败时等待后重试同一条消息
+func (b *MemoryBus) consume(ctx context.Context, cur *memoryCursor, topic string, handler Handler) {
+	for ctx.Err() == nil {
+		select {
+		case <-b.done:
+			return
+		default:
+		}
+
+		cur.mu.Lock()
+		msg, wait := b.next(topic, cur.offset)
+		if msg == nil {
+			cur.mu.Unlock()
+			select {
+			case <-wait:
+			case <-b.done:
+				return
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+
+		delivered := *msg
+		delivered.Headers = copyHeaders(msg.Headers)
+		err := handler(ctx, &delivered)
+		if err == nil {
+			cur.offset++
+		}
+		cur.mu.Unlock()
+
+		if err != nil && !sleep(ctx, b.retryDelay) {
+			return
+		}
+	}
+}
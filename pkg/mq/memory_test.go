@@ -0,0 +1,167 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collect 订阅主题并收集处理成功的消息，直到收到want条后取消订阅
+func collect(t *testing.T, bus *MemoryBus, group string, topics []string, want int, fail func(*Message) error) []*Message {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var mu sync.Mutex
+	var got []*Message
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = bus.Subscribe(ctx, group, topics, func(ctx context.Context, msg *Message) error {
+			if fail != nil {
+				if err := fail(msg); err != nil {
+					return err
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, msg)
+			if len(got) == want {
+				cancel()
+			}
+			return nil
+		})
+	}()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != want {
+		t.Fatalf("Expected %d messages, got %d", want, len(got))
+	}
+	return got
+}
+
+// 测试消息按发布顺序投递，且消息头被完整保留
+func TestMemoryBusPublishSubscribe(t *testing.T) {
+	bus := NewMemoryBus(10 * time.Millisecond)
+	defer bus.Close()
+
+	ctx := context.Background()
+	for _, v := range []string{"a", "b", "c"} {
+		if err := bus.Publish(ctx, &Message{Topic: "t", Key: "k", Value: []byte(v), Headers: map[string]string{"h": v}}); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+	}
+
+	got := collect(t, bus, "g", []string{"t"}, 3, nil)
+	for i, v := range []string{"a", "b", "c"} {
+		if string(got[i].Value) != v || got[i].Headers["h"] != v || got[i].Key != "k" {
+			t.Errorf("Message %d: got value=%q headers=%v key=%q", i, got[i].Value, got[i].Headers, got[i].Key)
+		}
+	}
+	if bus.Offset("g", "t") != 3 {
+		t.Errorf("Expected offset 3, got %d", bus.Offset("g", "t"))
+	}
+
+	// 重启后的新总线不会复用已用过的消息ID
+	restarted := NewMemoryBus(10 * time.Millisecond)
+	defer restarted.Close()
+	if err := restarted.Publish(ctx, &Message{Topic: "t", Value: []byte("d")}); err != nil {
+		t.Fatal(err)
+	}
+	ids := map[string]bool{}
+	for _, msg := range append(got, collect(t, restarted, "g", []string{"t"}, 1, nil)...) {
+		if msg.ID == "" || ids[msg.ID] {
+			t.Errorf("Expected unique message ids, got duplicate %q", msg.ID)
+		}
+		ids[msg.ID] = true
+	}
+}
+
+// 测试不同消费者组各自维护位点，都能收到全部消息
+func TestMemoryBusConsumerGroups(t *testing.T) {
+	bus := NewMemoryBus(10 * time.Millisecond)
+	defer bus.Close()
+
+	ctx := context.Background()
+	_ = bus.Publish(ctx, &Message{Topic: "t", Value: []byte("1")})
+	_ = bus.Publish(ctx, &Message{Topic: "t", Value: []byte("2")})
+
+	collect(t, bus, "g1", []string{"t"}, 2, nil)
+	collect(t, bus, "g2", []string{"t"}, 2, nil)
+
+	// 已确认的消息不会再次投递给同一个组
+	_ = bus.Publish(ctx, &Message{Topic: "t", Value: []byte("3")})
+	got := collect(t, bus, "g1", []string{"t"}, 1, nil)
+	if string(got[0].Value) != "3" {
+		t.Errorf("Expected message 3, got %q", got[0].Value)
+	}
+}
+
+// 测试处理失败的消息会重新投递，且不会越过它处理后续消息
+func TestMemoryBusRedeliverOnError(t *testing.T) {
+	bus := NewMemoryBus(10 * time.Millisecond)
+	defer bus.Close()
+
+	ctx := context.Background()
+	_ = bus.Publish(ctx, &Message{Topic: "t", Value: []byte("poison")})
+	_ = bus.Publish(ctx, &Message{Topic: "t", Value: []byte("next")})
+
+	attempts := 0
+	got := collect(t, bus, "g", []string{"t"}, 2, func(msg *Message) error {
+		if string(msg.Value) == "poison" {
+			attempts++
+			if attempts < 3 {
+				return errors.New("transient")
+			}
+		}
+		return nil
+	})
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+	if string(got[0].Value) != "poison" || string(got[1].Value) != "next" {
+		t.Errorf("Unexpected order: %q, %q", got[0].Value, got[1].Value)
+	}
+}
+
+// 测试订阅多个主题以及关闭后的发布
+func TestMemoryBusMultiTopicAndClose(t *testing.T) {
+	bus := NewMemoryBus(10 * time.Millisecond)
+
+	ctx := context.Background()
+	_ = bus.Publish(ctx, &Message{Topic: "a", Value: []byte("1")})
+	_ = bus.Publish(ctx, &Message{Topic: "b", Value: []byte("2")})
+	collect(t, bus, "g", []string{"a", "b"}, 2, nil)
+
+	if err := bus.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if err := bus.Publish(ctx, &Message{Topic: "a"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
+
+// 测试按配置选择驱动
+func TestOpen(t *testing.T) {
+	pub, sub, err := Open(Config{Driver: DriverMemory})
+	if err != nil {
+		t.Fatalf("open memory failed: %v", err)
+	}
+	if pub != sub.(Publisher) {
+		t.Error("Expected memory publisher and subscriber to share one bus")
+	}
+
+	if _, _, err := Open(Config{Driver: "nats"}); !errors.Is(err, ErrUnknownDriver) {
+		t.Errorf("Expected ErrUnknownDriver, got %v", err)
+	}
+	if _, _, err := Open(Config{Driver: DriverKafka}); err == nil {
+		t.Error("Expected error for kafka without brokers")
+	}
+	if _, _, err := Open(Config{Driver: DriverRedis}); err == nil {
+		t.Error("Expected error for redis without client")
+	}
+}
@@ -0,0 +1,144 @@
+// Package mq 消息总线抽象，提供Kafka、Redis Streams和进程内三种实现，按配置选择
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/google/uuid"
+)
+
+// 消息总线驱动
+const (
+	DriverKafka  = "kafka"  // Kafka（sarama）
+	DriverRedis  = "redis"  // Redis Streams
+	DriverMemory = "memory" // 进程内通道，消息不持久化，只用于测试和本地开发
+)
+
+// HeaderMessageID 发布时生成的全局唯一消息ID，订阅端据此填充 Message.ID
+const HeaderMessageID = "mq-id"
+
+// 订阅端处理失败后的默认重试间隔
+const defaultRetryDelay = time.Second
+
+var (
+	// ErrClosed 消息总线已关闭
+	ErrClosed = errors.New("mq: closed")
+	// ErrUnknownDriver 未知的驱动
+	ErrUnknownDriver = errors.New("mq: unknown driver")
+)
+
+// Message 一条消息
+type Message struct {
+	ID      string            // 全局唯一的消息ID，由发布时生成、订阅端填充，同一消息重新投递时不变；发布时忽略
+	Topic   string            // 主题，Redis驱动下为Stream名
+	Key     string            // 分区Key，同一Key的消息按发布顺序处理
+	Value   []byte            // 消息内容
+	Headers map[string]string // 消息头
+}
+
+// Publisher 消息发布者
+type Publisher interface {
+	// Publish 同步发布一条消息，返回nil表示消息已被队列确认写入
+	Publish(ctx context.Context, msg *Message) error
+	// Close 关闭发布者
+	Close() error
+}
+
+// Handler 消息处理函数
+// 返回nil后消息才会被确认；返回错误时消息不确认，稍后重新投递给同组的订阅者
+type Handler func(ctx context.Context, msg *Message) error
+
+// Subscriber 消息订阅者
+type Subscriber interface {
+	// Subscribe 以消费者组身份订阅主题并处理消息，阻塞直到ctx取消
+	// 同一消费者组内每条消息只会被确认一次，语义为至少一次，处理函数需幂等
+	Subscribe(ctx context.Context, group string, topics []string, handler Handler) error
+	// Close 关闭订阅者
+	Close() error
+}
+
+// Config 消息总线配置
+type Config struct {
+	Driver     string        // kafka / redis / memory
+	Brokers    []string      // Kafka Broker地址
+	Redis      *redis.Client // Redis驱动使用的连接，由调用方创建和关闭
+	MaxLen     int64         // Redis Stream 保留的最大消息数（近似），0表示不限制
+	RetryDelay time.Duration // 处理失败后的重试间隔
+}
+
+// Open 按配置创建发布者和订阅者
+// 进程内驱动返回的发布者和订阅者共享同一个总线
+func Open(cfg Config) (Publisher, Subscriber, error) {
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = defaultRetryDelay
+	}
+
+	switch cfg.Driver {
+	case DriverKafka:
+		if len(cfg.Brokers) == 0 {
+			return nil, nil, errors.New("mq: kafka driver requires brokers")
+		}
+		publisher, err := NewKafkaPublisher(cfg.Brokers)
+		if err != nil {
+			return nil, nil, err
+		}
+		return publisher, NewKafkaSubscriber(cfg.Brokers, cfg.RetryDelay), nil
+	case DriverRedis:
+		if cfg.Redis == nil {
+			return nil, nil, errors.New("mq: redis driver requires a client")
+		}
+		return NewRedisPublisher(cfg.Redis, cfg.MaxLen), NewRedisSubscriber(cfg.Redis, cfg.RetryDelay), nil
+	case DriverMemory:
+		bus := NewMemoryBus(cfg.RetryDelay)
+		return bus, bus, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: %q", ErrUnknownDriver, cfg.Driver)
+	}
+}
+
+// withMessageID 复制消息头并写入新生成的消息ID，转发的消息也获得新ID
+func withMessageID(headers map[string]string) (string, map[string]string) {
+	id := uuid.NewString()
+	out := make(map[string]string, len(headers)+1)
+	for k, v := range headers {
+		out[k] = v
+	}
+	out[HeaderMessageID] = id
+	return id, out
+}
+
+// messageID 返回消息头中的消息ID，没有该消息头的旧消息使用队列内的位置
+func messageID(headers map[string]string, fallback string) string {
+	if id := headers[HeaderMessageID]; id != "" {
+		return id
+	}
+	return fallback
+}
+
+// copyHeaders 复制消息头，避免发布方后续修改影响已发布的消息
+func copyHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(headers))
+	for k, v := range headers {
+		out[k] = v
+	}
+	return out
+}
+
+// sleep 等待指定时长，ctx取消时返回false
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,264 @@
+package mq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// Redis Stream 中消息的字段名
+const (
+	redisFieldKey     = "key"
+	redisFieldValue   = "value"
+	redisFieldHeaders = "headers"
+)
+
+const (
+	redisReadBlock     = time.Second      // 读取新消息的阻塞时长，需小于连接的读超时
+	redisClaimInterval = 30 * time.Second // 检查其他消费者遗留消息的间隔
+	redisClaimMinIdle  = time.Minute      // 遗留消息空闲超过该时长后被接管
+	redisKeepAlive     = 20 * time.Second // 处理中的消息刷新空闲时长的间隔，需小于 redisClaimMinIdle
+)
+
+// RedisPublisher 基于 Redis Streams 的发布者，每个主题对应一个Stream
+type RedisPublisher struct {
+	client *redis.Client
+	maxLen int64
+}
+
+// NewRedisPublisher 创建Redis发布者，maxLen大于0时按近似长度裁剪Stream
+func NewRedisPublisher(client *redis.Client, maxLen int64) *RedisPublisher {
+	return &RedisPublisher{client: client, maxLen: maxLen}
+}
+
+// Publish 实现 Publisher
+func (p *RedisPublisher) Publish(ctx context.Context, msg *Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	_, headers := withMessageID(msg.Headers)
+	encoded, err := json.Marshal(headers)
+	if err != nil {
+		return err
+	}
+	values := map[string]interface{}{
+		redisFieldKey:     msg.Key,
+		redisFieldValue:   msg.Value,
+		redisFieldHeaders: encoded,
+	}
+
+	return p.client.XAdd(&redis.XAddArgs{
+		Stream:       msg.Topic,
+		MaxLenApprox: p.maxLen,
+		Values:       values,
+	}).Err()
+}
+
+// Close 实现 Publisher，连接由调用方关闭
+func (p *RedisPublisher) Close() error {
+	return nil
+}
+
+// RedisSubscriber 基于 Redis Streams 消费者组的订阅者
+// 处理成功后XACK；处理失败的消息留在待确认列表中，稍后由同一消费者重新读取，
+// 空闲过久的其他消费者遗留消息会被接管；处理中的消息定期刷新空闲时长，处理耗时较长（如等待重试时间）时不会被接管
+type RedisSubscriber struct {
+	client     *redis.Client
+	retryDelay time.Duration
+	keepAlive  time.Duration
+	consumer   string
+}
+
+// NewRedisSubscriber 创建Redis订阅者
+func NewRedisSubscriber(client *redis.Client, retryDelay time.Duration) *RedisSubscriber {
+	host, _ := os.Hostname()
+	return &RedisSubscriber{
+		client:     client,
+		retryDelay: retryDelay,
+		keepAlive:  redisKeepAlive,
+		consumer:   fmt.Sprintf("%s-%d-%d", host, os.Getpid(), time.Now().UnixNano()),
+	}
+}
+
+// Subscribe 实现 Subscriber，每个主题一个读取协程
+func (s *RedisSubscriber) Subscribe(ctx context.Context, group string, topics []string, handler Handler) error {
+	for _, topic := range topics {
+		err := s.client.XGroupCreateMkStream(topic, group, "0").Err()
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return fmt.Errorf("create stream group %s/%s failed: %w", topic, group, err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, topic := range topics {
+		wg.Add(1)
+		go func(topic string) {
+			defer wg.Done()
+			s.consume(ctx, group, topic, handler)
+		}(topic)
+	}
+	wg.Wait()
+	return nil
+}
+
+// Close 实现 Subscriber，连接由调用方关闭
+func (s *RedisSubscriber) Close() error {
+	return nil
+}
+
+// consume 消费单个Stream
+// 每轮先读取本消费者未确认的消息，没有时再读取新消息，保证失败的消息按顺序重试
+func (s *RedisSubscriber) consume(ctx context.Context, group, topic string, handler Handler) {
+	lastClaim := time.Time{}
+	for ctx.Err() == nil {
+		if time.Since(lastClaim) > redisClaimInterval {
+			s.claimStale(group, topic)
+			lastClaim = time.Now()
+		}
+
+		xm, err := s.read(group, topic, "0")
+		if err == nil && xm == nil {
+			xm, err = s.read(group, topic, ">")
+		}
+		if err != nil {
+			log.Printf("[mq] 读取Stream %s 失败: %v", topic, err)
+			sleep(ctx, s.retryDelay)
+			continue
+		}
+		if xm == nil {
+			continue
+		}
+
+		msg := redisMessage(topic, xm)
+		stop := s.keepPending(group, topic, xm.ID)
+		err = handler(ctx, msg)
+		stop()
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("[mq] 处理消息 %s/%s 失败: %v", topic, msg.ID, err)
+				sleep(ctx, s.retryDelay)
+			}
+			continue
+		}
+		if err := s.client.XAck(topic, group, xm.ID).Err(); err != nil {
+			log.Printf("[mq] 确认消息 %s/%s 失败: %v", topic, msg.ID, err)
+		}
+	}
+}
+
+// read 读取一条消息，id为"0"时读取本消费者未确认的消息，为">"时读取新消息
+func (s *RedisSubscriber) read(group, topic, id string) (*redis.XMessage, error) {
+	args := &redis.XReadGroupArgs{
+		Group:    group,
+		Consumer: s.consumer,
+		Streams:  []string{topic, id},
+		Count:    1,
+	}
+	if id == ">" {
+		args.Block = redisReadBlock
+	}
+
+	streams, err := s.client.XReadGroup(args).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, stream := range streams {
+		if len(stream.Messages) > 0 {
+			return &stream.Messages[0], nil
+		}
+	}
+	return nil, nil
+}
+
+// keepPending 处理期间定期将消息重新认领给本消费者，刷新其在待确认列表中的空闲时长，返回的函数停止刷新
+// 本实例宕机后不再刷新，消息空闲超过 redisClaimMinIdle 后仍会被其他消费者接管
+func (s *RedisSubscriber) keepPending(group, topic, id string) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(s.keepAlive)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.client.XClaimJustID(&redis.XClaimArgs{
+					Stream:   topic,
+					Group:    group,
+					Consumer: s.consumer,
+					Messages: []string{id},
+				}).Err(); err != nil {
+					log.Printf("[mq] 刷新消息 %s/%s 的空闲时长失败: %v", topic, id, err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// claimStale 接管其他消费者空闲过久的未确认消息，例如实例宕机后遗留的消息
+func (s *RedisSubscriber) claimStale(group, topic string) {
+	pending, err := s.client.XPendingExt(&redis.XPendingExtArgs{
+		Stream: topic,
+		Group:  group,
+		Start:  "-",
+		End:    "+",
+		Count:  100,
+	}).Result()
+	if err != nil {
+		return
+	}
+
+	var ids []string
+	for _, p := range pending {
+		if p.Consumer != s.consumer && p.Idle >= redisClaimMinIdle {
+			ids = append(ids, p.Id)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	if err := s.client.XClaim(&redis.XClaimArgs{
+		Stream:   topic,
+		Group:    group,
+		Consumer: s.consumer,
+		MinIdle:  redisClaimMinIdle,
+		Messages: ids,
+	}).Err(); err != nil {
+		log.Printf("[mq] 接管Stream %s 遗留消息失败: %v", topic, err)
+	}
+}
+
+// redisMessage 将Stream消息转换为Message
+func redisMessage(topic string, xm *redis.XMessage) *Message {
+	msg := &Message{ID: xm.ID, Topic: topic}
+	if v, ok := xm.Values[redisFieldKey].(string); ok {
+		msg.Key = v
+	}
+	if v, ok := xm.Values[redisFieldValue].(string); ok {
+		msg.Value = []byte(v)
+	}
+	if v, ok := xm.Values[redisFieldHeaders].(string); ok && v != "" {
+		if err := json.Unmarshal([]byte(v), &msg.Headers); err != nil {
+			log.Printf("[mq] 解析消息 %s/%s 的消息头失败: %v", topic, xm.ID, err)
+		}
+	}
+	msg.ID = messageID(msg.Headers, xm.ID)
+	return msg
+}
@@ -0,0 +1,150 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+)
+
+// newTestRedis 启动内存中的Redis并返回连接
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return server, client
+}
+
+// 测试Redis驱动按发布顺序投递，失败的消息以同一ID重新投递，确认后从待确认列表移除
+func TestRedisPublishSubscribe(t *testing.T) {
+	_, client := newTestRedis(t)
+	publisher := NewRedisPublisher(client, 100)
+	subscriber := NewRedisSubscriber(client, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, v := range []string{"a", "b"} {
+		if err := publisher.Publish(ctx, &Message{Topic: "t", Key: "k", Value: []byte(v), Headers: map[string]string{"h": v}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var mu sync.Mutex
+	var got []*Message
+	failed := false
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = subscriber.Subscribe(ctx, "g", []string{"t"}, func(ctx context.Context, msg *Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, msg)
+			if string(msg.Value) == "a" && !failed {
+				failed = true
+				return errors.New("boom")
+			}
+			if len(got) == 3 {
+				cancel()
+			}
+			return nil
+		})
+	}()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 3 {
+		t.Fatalf("Expected 3 deliveries, got %d", len(got))
+	}
+	first, retried, second := got[0], got[1], got[2]
+	if string(first.Value) != "a" || string(retried.Value) != "a" || string(second.Value) != "b" {
+		t.Errorf("Expected a, a, b, got %q %q %q", first.Value, retried.Value, second.Value)
+	}
+	if first.ID == "" || first.ID != retried.ID || first.ID == second.ID || first.ID != first.Headers[HeaderMessageID] {
+		t.Errorf("Expected a stable unique id per message, got %q %q %q", first.ID, retried.ID, second.ID)
+	}
+	if first.Key != "k" || first.Headers["h"] != "a" {
+		t.Errorf("Unexpected message %+v", first)
+	}
+
+	pending, err := client.XPending("t", "g").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending.Count != 0 {
+		t.Errorf("Expected no pending messages, got %d", pending.Count)
+	}
+}
+
+// 测试没有消息ID消息头的旧消息使用Stream中的ID
+func TestRedisMessageFallbackID(t *testing.T) {
+	msg := redisMessage("t", &redis.XMessage{ID: "1-0", Values: map[string]interface{}{"value": "v"}})
+	if msg.ID != "1-0" || string(msg.Value) != "v" {
+		t.Errorf("Unexpected message %+v", msg)
+	}
+}
+
+// 测试接管其他消费者空闲过久的未确认消息
+func TestRedisClaimStale(t *testing.T) {
+	server, client := newTestRedis(t)
+	if err := NewRedisPublisher(client, 0).Publish(context.Background(), &Message{Topic: "t", Value: []byte("v")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.XGroupCreate("t", "g", "0").Err(); err != nil {
+		t.Fatal(err)
+	}
+	// 另一个消费者读取后宕机，消息留在它的待确认列表中
+	if err := client.XReadGroup(&redis.XReadGroupArgs{Group: "g", Consumer: "dead", Streams: []string{"t", ">"}, Count: 1}).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	subscriber := NewRedisSubscriber(client, 10*time.Millisecond)
+	server.SetTime(time.Now().Add(2 * redisClaimMinIdle))
+	subscriber.claimStale("g", "t")
+
+	xm, err := subscriber.read("g", "t", "0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if xm == nil {
+		t.Fatal("Expected stale message to be claimed")
+	}
+}
+
+// 测试处理耗时超过接管时长的消息定期刷新空闲时长，不会被其他消费者接管
+func TestRedisKeepPending(t *testing.T) {
+	server, client := newTestRedis(t)
+	if err := NewRedisPublisher(client, 0).Publish(context.Background(), &Message{Topic: "t", Value: []byte("v")}); err != nil {
+		t.Fatal(err)
+	}
+	subscriber := NewRedisSubscriber(client, 10*time.Millisecond)
+	subscriber.keepAlive = 10 * time.Millisecond
+	other := NewRedisSubscriber(client, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var delivered int
+	err := subscriber.Subscribe(ctx, "g", []string{"t"}, func(ctx context.Context, msg *Message) error {
+		delivered++
+		// 处理中时间推进到超过接管时长，刷新后其他消费者接管不到
+		server.SetTime(time.Now().Add(2 * redisClaimMinIdle))
+		time.Sleep(50 * time.Millisecond)
+		other.claimStale("g", "t")
+		if xm, err := other.read("g", "t", "0"); err != nil || xm != nil {
+			t.Errorf("Expected message kept by its consumer, got %v %v", xm, err)
+		}
+		cancel()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivered != 1 {
+		t.Errorf("Expected one delivery, got %d", delivered)
+	}
+}
@@ -12,8 +12,8 @@ import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis"
-	"github.com/jinzhu/gorm"
-	_ "github.com/jinzhu/gorm/dialects/mysql"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
 )
 
 /**
@@ -22,7 +22,7 @@ import (
 
 // 定义订单结构体
 type Order struct {
-	ID         uint      `gorm:"primary_key"`
+	ID         uint      `gorm:"primaryKey"`
 	OrderID    uint64    `gorm:"column:order_id"`
 	Address    string    `gorm:"column:address"`
 	Json1      string    `gorm:"column:json1"`
@@ -55,7 +55,7 @@ func main() {
 	 */
 	var err error
 	// 连接 MySQL 数据库
-	DB, err = gorm.Open("mysql", "root:@tcp(127.0.0.1:3306)/faker?charset=utf8mb4&parseTime=True")
+	DB, err = gorm.Open(mysql.Open("root:@tcp(127.0.0.1:3306)/faker?charset=utf8mb4&parseTime=True"), &gorm.Config{})
 	if err != nil {
 		panic("failed to connect database")
 	}
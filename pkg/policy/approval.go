@@ -0,0 +1,132 @@
+package policy
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrApprovalNotFound 审批单不存在
+	ErrApprovalNotFound = errors.New("policy: approval not found")
+	// ErrApprovalConflict 审批单状态已变化，如已被审批或已被使用
+	ErrApprovalConflict = errors.New("policy: approval status changed")
+)
+
+// ApprovalStatus 审批单状态
+type ApprovalStatus string
+
+const (
+	ApprovalPending  ApprovalStatus = "pending"  // 等待审批
+	ApprovalApproved ApprovalStatus = "approved" // 已批准，等待签名
+	ApprovalRejected ApprovalStatus = "rejected" // 已拒绝
+	ApprovalUsed     ApprovalStatus = "used"     // 已批准且已签名，不能再次使用
+)
+
+// Approval 超过审批阈值的转出的审批单，同一转出钱包的 Ref 唯一
+type Approval struct {
+	ID        uint64         `gorm:"column:id;primaryKey" json:"id"`
+	From      string         `gorm:"column:from_addr" json:"from"` // 转出钱包
+	Ref       string         `gorm:"column:ref" json:"ref"`        // 业务单号，如打款订单号
+	To        string         `gorm:"column:to_addr" json:"to"`     // 收款地址
+	Value     int64          `gorm:"column:value" json:"value"`    // 金额（ELON）
+	Status    ApprovalStatus `gorm:"column:status" json:"status"`  // pending / approved / rejected / used
+	Note      string         `gorm:"column:note" json:"note"`      // 审批备注
+	CreatedAt time.Time      `gorm:"column:created_at" json:"createdAt"`
+	DecidedAt *time.Time     `gorm:"column:decided_at" json:"decidedAt,omitempty"`
+}
+
+// TableName 设置Approval表名
+func (Approval) TableName() string {
+	return "payout_approval"
+}
+
+// ApprovalStore 审批单存储
+type ApprovalStore interface {
+	// FindApproval 按转出钱包和业务单号查询，不存在时返回 ErrApprovalNotFound
+	FindApproval(from, ref string) (*Approval, error)
+	// CreateApproval 新建审批单
+	CreateApproval(approval *Approval) error
+	// ListApprovals 按创建顺序查询，status 为空时查询全部
+	ListApprovals(status ApprovalStatus, limit int) ([]Approval, error)
+	// TransitionApproval 以当前状态为条件更新审批单，状态已变化时返回 ErrApprovalConflict
+	// 从 pending 迁移时记录审批时间 at
+	TransitionApproval(id uint64, from, to ApprovalStatus, note string, at time.Time) error
+}
+
+// MemoryStore 内存中的审批单和转出记录，用于测试，进程重启后丢失
+type MemoryStore struct {
+	mu          sync.Mutex
+	approvals   map[uint64]*Approval
+	nextID      uint64
+	spends      []Spend
+	nextSpendID uint64
+}
+
+// NewMemoryStore 创建内存存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{approvals: make(map[uint64]*Approval)}
+}
+
+// FindApproval 按转出钱包和业务单号查询
+func (s *MemoryStore) FindApproval(from, ref string) (*Approval, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range s.approvals {
+		if a.From == from && a.Ref == ref {
+			found := *a
+			return &found, nil
+		}
+	}
+	return nil, ErrApprovalNotFound
+}
+
+// CreateApproval 新建审批单
+func (s *MemoryStore) CreateApproval(approval *Approval) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	approval.ID = s.nextID
+	stored := *approval
+	s.approvals[approval.ID] = &stored
+	return nil
+}
+
+// ListApprovals 按创建顺序查询
+func (s *MemoryStore) ListApprovals(status ApprovalStatus, limit int) ([]Approval, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var approvals []Approval
+	for _, a := range s.approvals {
+		if status == "" || a.Status == status {
+			approvals = append(approvals, *a)
+		}
+	}
+	sort.Slice(approvals, func(i, j int) bool { return approvals[i].ID < approvals[j].ID })
+	if limit > 0 && len(approvals) > limit {
+		approvals = approvals[:limit]
+	}
+	return approvals, nil
+}
+
+// TransitionApproval 以当前状态为条件更新审批单
+func (s *MemoryStore) TransitionApproval(id uint64, from, to ApprovalStatus, note string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.approvals[id]
+	if !ok {
+		return ErrApprovalNotFound
+	}
+	if a.Status != from {
+		return ErrApprovalConflict
+	}
+	a.Status = to
+	if note != "" {
+		a.Note = note
+	}
+	if from == ApprovalPending {
+		a.DecidedAt = &at
+	}
+	return nil
+}
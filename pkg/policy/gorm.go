@@ -0,0 +1,184 @@
+package policy
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GormStore 将审批单保存在 payout_approval 表中，转出记录保存在 payout_spend 表中
+type GormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore 创建数据库审批单存储
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+// FindApproval 按转出钱包和业务单号查询
+func (g *GormStore) FindApproval(from, ref string) (*Approval, error) {
+	var approval Approval
+	if err := g.db.Where("from_addr = ? AND ref = ?", from, ref).First(&approval).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrApprovalNotFound
+		}
+		return nil, err
+	}
+	return &approval, nil
+}
+
+// CreateApproval 新建审批单，(from_addr, ref) 唯一索引保证多个实例不会重复创建
+func (g *GormStore) CreateApproval(approval *Approval) error {
+	return g.db.Create(approval).Error
+}
+
+// ListApprovals 按创建顺序查询
+func (g *GormStore) ListApprovals(status ApprovalStatus, limit int) ([]Approval, error) {
+	db := g.db.Order("id ASC")
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	if limit > 0 {
+		db = db.Limit(limit)
+	}
+	var approvals []Approval
+	if err := db.Find(&approvals).Error; err != nil {
+		return nil, err
+	}
+	return approvals, nil
+}
+
+// TransitionApproval 以当前状态为条件更新审批单
+func (g *GormStore) TransitionApproval(id uint64, from, to ApprovalStatus, note string, at time.Time) error {
+	updates := map[string]interface{}{"status": to}
+	if note != "" {
+		updates["note"] = note
+	}
+	if from == ApprovalPending {
+		updates["decided_at"] = at
+	}
+	result := g.db.Model(&Approval{}).Where("id = ? AND status = ?", id, from).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+	var count int64
+	if err := g.db.Model(&Approval{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrApprovalNotFound
+	}
+	return ErrApprovalConflict
+}
+
+// Reserved 判断 key 是否已有未释放的转出记录
+func (g *GormStore) Reserved(key string) (bool, error) {
+	var count int64
+	err := g.db.Model(&Spend{}).Where("spend_key = ? AND status <> ?", key, SpendReleased).Count(&count).Error
+	return count > 0, err
+}
+
+// Usage 统计已用额度
+func (g *GormStore) Usage(from string, to []string, day, window time.Time) (*Usage, error) {
+	usage := &Usage{Received: make(map[string]int64, len(to))}
+	active := g.db.Model(&Spend{}).Where("status <> ?", SpendReleased)
+
+	if err := active.Session(&gorm.Session{}).
+		Select("COALESCE(SUM(value), 0)").
+		Where("from_addr = ? AND created_at >= ?", from, day).
+		Scan(&usage.Wallet).Error; err != nil {
+		return nil, err
+	}
+
+	if len(to) > 0 {
+		var rows []struct {
+			To    string `gorm:"column:to_addr"`
+			Value int64  `gorm:"column:value"`
+		}
+		if err := active.Session(&gorm.Session{}).
+			Select("to_addr, SUM(value) AS value").
+			Where("to_addr IN ? AND created_at >= ?", to, day).
+			Group("to_addr").
+			Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			usage.Received[row.To] = row.Value
+		}
+	}
+
+	var recent int64
+	if err := active.Session(&gorm.Session{}).
+		Where("from_addr = ? AND created_at > ?", from, window).
+		Distinct("spend_key").
+		Count(&recent).Error; err != nil {
+		return nil, err
+	}
+	usage.Recent = int(recent)
+	return usage, nil
+}
+
+// Reserve 写入一笔交易的转出记录并使用审批单
+// 同一 key 已释放的旧记录先删除，(spend_key, n) 唯一索引保证多个实例不会重复写入
+func (g *GormStore) Reserve(spends []Spend) error {
+	if len(spends) == 0 {
+		return nil
+	}
+	return g.db.Transaction(func(tx *gorm.DB) error {
+		key := spends[0].Key
+		if err := tx.Where("spend_key = ? AND status = ?", key, SpendReleased).Delete(&Spend{}).Error; err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&Spend{}).Where("spend_key = ?", key).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrSpendExists
+		}
+		for _, spend := range spends {
+			if spend.ApprovalID == 0 {
+				continue
+			}
+			result := tx.Model(&Approval{}).
+				Where("id = ? AND status = ?", spend.ApprovalID, ApprovalApproved).
+				Update("status", ApprovalUsed)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrApprovalConflict
+			}
+		}
+		return tx.Create(&spends).Error
+	})
+}
+
+// Settle 回写广播结果
+func (g *GormStore) Settle(key string, status SpendStatus) error {
+	return g.db.Transaction(func(tx *gorm.DB) error {
+		if status == SpendReleased {
+			var ids []uint64
+			if err := tx.Model(&Spend{}).
+				Where("spend_key = ? AND status = ? AND approval_id <> 0", key, SpendReserved).
+				Pluck("approval_id", &ids).Error; err != nil {
+				return err
+			}
+			if len(ids) > 0 {
+				if err := tx.Model(&Approval{}).
+					Where("id IN ? AND status = ?", ids, ApprovalUsed).
+					Update("status", ApprovalApproved).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return tx.Model(&Spend{}).
+			Where("spend_key = ? AND status = ?", key, SpendReserved).
+			Update("status", status).Error
+	})
+}
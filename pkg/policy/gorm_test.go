@@ -0,0 +1,87 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// newTestGormStore 基于内存 sqlite 的存储
+func newTestGormStore(t *testing.T) *GormStore {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内存数据库每个连接独立，只保留一个连接
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&Approval{}, &Spend{}); err != nil {
+		t.Fatal(err)
+	}
+	return NewGormStore(db)
+}
+
+// 测试数据库转出记录的预留、统计和回写，以及审批单随预留和释放变化
+func TestGormStoreLedger(t *testing.T) {
+	store := newTestGormStore(t)
+	now := time.Now()
+	day := now.Add(-time.Hour)
+
+	approval := &Approval{From: "from", Ref: "1", To: "a", Value: 700, Status: ApprovalApproved, CreatedAt: now}
+	if err := store.CreateApproval(approval); err != nil {
+		t.Fatal(err)
+	}
+	spends := []Spend{
+		{Key: "k1", N: 0, From: "from", To: "a", Value: 700, ApprovalID: approval.ID, Status: SpendReserved, CreatedAt: now},
+		{Key: "k1", N: 1, From: "from", To: "b", Value: 300, Status: SpendReserved, CreatedAt: now},
+	}
+	if err := store.Reserve(spends); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Reserve(spends); !errors.Is(err, ErrSpendExists) {
+		t.Errorf("Expected ErrSpendExists, got %v", err)
+	}
+	other := []Spend{{Key: "k2", From: "from", To: "a", Value: 700, ApprovalID: approval.ID, Status: SpendReserved, CreatedAt: now}}
+	if err := store.Reserve(other); !errors.Is(err, ErrApprovalConflict) {
+		t.Errorf("Expected used approval to conflict, got %v", err)
+	}
+	if reserved, _ := store.Reserved("k2"); reserved {
+		t.Error("Expected conflicting reservation to roll back")
+	}
+
+	usage, err := store.Usage("from", []string{"a", "c"}, day, now.Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Wallet != 1000 || usage.Received["a"] != 700 || usage.Received["c"] != 0 || usage.Recent != 1 {
+		t.Errorf("Unexpected usage %+v", usage)
+	}
+
+	// 释放后不计入额度，审批单退回已批准，同一交易可重新预留
+	if err := store.Settle("k1", SpendReleased); err != nil {
+		t.Fatal(err)
+	}
+	usage, _ = store.Usage("from", []string{"a"}, day, now.Add(-time.Minute))
+	if usage.Wallet != 0 || usage.Recent != 0 {
+		t.Errorf("Expected released spend excluded, got %+v", usage)
+	}
+	if found, _ := store.FindApproval("from", "1"); found.Status != ApprovalApproved {
+		t.Errorf("Expected approval returned, got %s", found.Status)
+	}
+	if err := store.Reserve(spends); err != nil {
+		t.Fatalf("Expected released key to be reserved again, got %v", err)
+	}
+	if err := store.Settle("k1", SpendCommitted); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Settle("k1", SpendReleased); err != nil {
+		t.Fatal(err)
+	}
+	if reserved, _ := store.Reserved("k1"); !reserved {
+		t.Error("Expected committed spend to stay")
+	}
+}
@@ -0,0 +1,174 @@
+package policy
+
+import (
+	"errors"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// ErrSpendExists 同一笔交易已有未释放的转出记录
+var ErrSpendExists = errors.New("policy: spend already reserved")
+
+// SpendStatus 转出记录状态
+type SpendStatus string
+
+const (
+	SpendReserved  SpendStatus = "reserved"  // 已授权签名，等待广播结果，计入额度
+	SpendCommitted SpendStatus = "committed" // 已广播，计入额度
+	SpendReleased  SpendStatus = "released"  // 未广播，不计入额度
+)
+
+// Spend 一笔已授权转出中的一个输出，找零不记录
+type Spend struct {
+	ID         uint64      `gorm:"column:id;primaryKey" json:"id"`
+	Key        string      `gorm:"column:spend_key" json:"key"` // 交易去掉签名后的哈希，见 SpendKey
+	N          int         `gorm:"column:n" json:"n"`           // 在转出输出中的序号
+	From       string      `gorm:"column:from_addr" json:"from"`
+	To         string      `gorm:"column:to_addr" json:"to"`
+	Value      int64       `gorm:"column:value" json:"value"`
+	ApprovalID uint64      `gorm:"column:approval_id" json:"approvalId"` // 使用的审批单，0为不需要审批
+	Status     SpendStatus `gorm:"column:status" json:"status"`
+	CreatedAt  time.Time   `gorm:"column:created_at" json:"createdAt"`
+}
+
+// TableName 设置Spend表名
+func (Spend) TableName() string {
+	return "payout_spend"
+}
+
+// Usage 已用额度，已释放的转出不计入
+type Usage struct {
+	Wallet   int64            // 转出钱包当日转出合计
+	Received map[string]int64 // 各收款地址当日收款合计
+	Recent   int              // 转出钱包在频率窗口内授权的交易数
+}
+
+// Ledger 转出记录，每日额度和频率由此统计，进程重启后不丢失
+type Ledger interface {
+	// Reserved 判断 key 是否已有未释放的转出记录
+	Reserved(key string) (bool, error)
+	// Usage 统计 from 自 day 起的转出合计、to 中各地址自 day 起的收款合计、from 在 window 之后的交易数
+	Usage(from string, to []string, day, window time.Time) (*Usage, error)
+	// Reserve 在一个事务中写入一笔交易的转出记录，并将记录引用的审批单从 approved 置为 used
+	// key 已有未释放的记录时返回 ErrSpendExists，审批单已被使用时返回 ErrApprovalConflict
+	Reserve(spends []Spend) error
+	// Settle 将 key 的预留记录置为 committed 或 released，释放时将其引用的审批单退回 approved
+	Settle(key string, status SpendStatus) error
+}
+
+// Store 策略引擎的持久化存储
+type Store interface {
+	ApprovalStore
+	Ledger
+}
+
+// SpendKey 交易去掉签名脚本后的哈希，签名前后相同，用于关联授权和广播结果
+func SpendKey(tx *wire.MsgTx) string {
+	unsigned := tx.Copy()
+	for _, in := range unsigned.TxIn {
+		in.SignatureScript = nil
+		in.Witness = nil
+	}
+	return unsigned.TxHash().String()
+}
+
+// Reserved 判断 key 是否已有未释放的转出记录
+func (s *MemoryStore) Reserved(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, spend := range s.spends {
+		if spend.Key == key && spend.Status != SpendReleased {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Usage 统计已用额度
+func (s *MemoryStore) Usage(from string, to []string, day, window time.Time) (*Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	usage := &Usage{Received: make(map[string]int64, len(to))}
+	recipients := make(map[string]bool, len(to))
+	for _, addr := range to {
+		recipients[addr] = true
+	}
+	recent := make(map[string]bool)
+	for _, spend := range s.spends {
+		if spend.Status == SpendReleased {
+			continue
+		}
+		if !spend.CreatedAt.Before(day) {
+			if spend.From == from {
+				usage.Wallet += spend.Value
+			}
+			if recipients[spend.To] {
+				usage.Received[spend.To] += spend.Value
+			}
+		}
+		if spend.From == from && spend.CreatedAt.After(window) {
+			recent[spend.Key] = true
+		}
+	}
+	usage.Recent = len(recent)
+	return usage, nil
+}
+
+// Reserve 写入一笔交易的转出记录并使用审批单
+func (s *MemoryStore) Reserve(spends []Spend) error {
+	if len(spends) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := spends[0].Key
+	for _, spend := range s.spends {
+		if spend.Key == key && spend.Status != SpendReleased {
+			return ErrSpendExists
+		}
+	}
+	for _, spend := range spends {
+		if spend.ApprovalID == 0 {
+			continue
+		}
+		if a, ok := s.approvals[spend.ApprovalID]; !ok || a.Status != ApprovalApproved {
+			return ErrApprovalConflict
+		}
+	}
+	kept := s.spends[:0]
+	for _, spend := range s.spends {
+		if spend.Key != key {
+			kept = append(kept, spend)
+		}
+	}
+	for _, spend := range spends {
+		if spend.ApprovalID != 0 {
+			s.approvals[spend.ApprovalID].Status = ApprovalUsed
+		}
+		s.nextSpendID++
+		spend.ID = s.nextSpendID
+		kept = append(kept, spend)
+	}
+	s.spends = kept
+	return nil
+}
+
+// Settle 回写广播结果
+func (s *MemoryStore) Settle(key string, status SpendStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.spends {
+		spend := &s.spends[i]
+		if spend.Key != key || spend.Status != SpendReserved {
+			continue
+		}
+		spend.Status = status
+		if status == SpendReleased && spend.ApprovalID != 0 {
+			if a, ok := s.approvals[spend.ApprovalID]; ok && a.Status == ApprovalUsed {
+				a.Status = ApprovalApproved
+			}
+		}
+	}
+	return nil
+}
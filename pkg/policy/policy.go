@@ -0,0 +1,330 @@
+// Package policy 转出策略引擎
+// 所有转出在签名前经过同一套规则：收款地址黑白名单、单笔上限、每个转出钱包和每个收款地址的每日上限、
+// 转出频率上限，以及超过审批阈值的转出需运营人工批准。
+// 业务代码在转出入队前调用 Screen 逐笔预检并创建审批单，签名方在签名前调用 Authorize 对整笔交易复核并预留额度，
+// 广播后调用 Commit 确认，确定未广播时调用 Release 归还额度和审批单。
+// 每日额度和频率按持久化的转出记录统计，进程重启后不清零
+package policy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"claimask/pkg/dogechain"
+
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+var (
+	// ErrDenied 转出违反策略，重试也不会通过
+	ErrDenied = errors.New("policy: denied")
+	// ErrLimited 超过每日额度或频率上限，稍后可以重试
+	ErrLimited = errors.New("policy: limit reached")
+	// ErrPendingApproval 转出等待人工审批
+	ErrPendingApproval = errors.New("policy: pending approval")
+)
+
+// Rules 转出规则，金额单位为 ELON，值为0的限制不生效
+type Rules struct {
+	MaxTx             int64         // 单笔交易转出上限
+	DailyPerWallet    int64         // 每个转出钱包每个自然日转出上限
+	DailyPerAddress   int64         // 每个收款地址每个自然日收款上限
+	VelocityCount     int           // 每个转出钱包在 VelocityWindow 内最多签名的交易数
+	VelocityWindow    time.Duration // 频率统计窗口
+	Allow             []string      // 非空时只允许转给名单中的地址
+	Deny              []string      // 禁止转给名单中的地址
+	ApprovalThreshold int64         // 单个收款地址的金额超过该值时需人工审批
+}
+
+// Payout 一笔待转出的款项
+type Payout struct {
+	Ref   string // 业务单号，需审批时用于关联审批单
+	To    string // 收款地址
+	Value int64  // 金额（ELON）
+}
+
+// Screener 转出入队前的逐笔预检
+type Screener interface {
+	// Screen 校验从 from 转出 payout 是否允许，需审批时返回 ErrPendingApproval
+	Screen(from string, payout Payout) error
+}
+
+// Engine 转出策略引擎
+type Engine struct {
+	store Store
+
+	mu    sync.Mutex // 保护规则，不在持锁时访问存储
+	rules Rules
+	allow map[string]struct{}
+	deny  map[string]struct{}
+
+	authorize sync.Mutex // 串行执行 Authorize，统计额度和写入转出记录之间不插入其他授权
+	now       func() time.Time
+}
+
+// NewEngine 创建策略引擎，store 保存审批单和转出记录，为nil时使用内存存储，重启后额度清零，只用于测试
+func NewEngine(rules Rules, store Store) *Engine {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	e := &Engine{
+		store: store,
+		now:   time.Now,
+	}
+	e.SetRules(rules)
+	return e
+}
+
+// SetRules 替换转出规则，已统计的额度保留
+func (e *Engine) SetRules(rules Rules) {
+	allow := make(map[string]struct{}, len(rules.Allow))
+	for _, addr := range rules.Allow {
+		allow[addr] = struct{}{}
+	}
+	deny := make(map[string]struct{}, len(rules.Deny))
+	for _, addr := range rules.Deny {
+		deny[addr] = struct{}{}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rules, e.allow, e.deny = rules, allow, deny
+}
+
+// Screen 转出入队前逐笔预检：校验黑白名单和单笔上限，超过审批阈值时创建审批单
+// 返回 ErrPendingApproval 时等待审批后再次预检，审批被拒绝的返回 ErrDenied
+func (e *Engine) Screen(from string, payout Payout) error {
+	e.mu.Lock()
+	rules := e.rules
+	err := e.checkDestination(payout.To)
+	e.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if rules.MaxTx > 0 && payout.Value > rules.MaxTx {
+		return fmt.Errorf("%w: payout %d exceeds per-tx limit %d", ErrDenied, payout.Value, rules.MaxTx)
+	}
+	if rules.ApprovalThreshold <= 0 || payout.Value <= rules.ApprovalThreshold {
+		return nil
+	}
+	if payout.Ref == "" {
+		return fmt.Errorf("%w: payout %d above approval threshold needs a reference", ErrDenied, payout.Value)
+	}
+
+	approval, err := e.store.FindApproval(from, payout.Ref)
+	if errors.Is(err, ErrApprovalNotFound) {
+		approval = &Approval{
+			From:      from,
+			Ref:       payout.Ref,
+			To:        payout.To,
+			Value:     payout.Value,
+			Status:    ApprovalPending,
+			CreatedAt: e.now(),
+		}
+		if err := e.store.CreateApproval(approval); err != nil {
+			return fmt.Errorf("create approval for %s failed: %w", payout.Ref, err)
+		}
+		return fmt.Errorf("%w: approval %d created for %s", ErrPendingApproval, approval.ID, payout.Ref)
+	}
+	if err != nil {
+		return err
+	}
+	if approval.To != payout.To || approval.Value != payout.Value {
+		return fmt.Errorf("%w: payout %s differs from approval %d", ErrDenied, payout.Ref, approval.ID)
+	}
+	switch approval.Status {
+	case ApprovalPending:
+		return fmt.Errorf("%w: approval %d for %s", ErrPendingApproval, approval.ID, payout.Ref)
+	case ApprovalRejected:
+		return fmt.Errorf("%w: approval %d for %s rejected", ErrDenied, approval.ID, payout.Ref)
+	}
+	return nil
+}
+
+// Authorize 签名前复核整笔交易，通过后写入转出记录预留额度，并将用到的审批单置为已使用
+// 找零（转回 address 的输出）不计入转出金额。同一笔交易已预留时直接通过，重新签名不会重复计入
+func (e *Engine) Authorize(address string, tx *wire.MsgTx, prevouts []dogechain.Prevout) error {
+	payouts, err := destinations(address, tx)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	rules := e.rules
+	var total int64
+	for _, payout := range payouts {
+		if err = e.checkDestination(payout.To); err != nil {
+			break
+		}
+		total += payout.Value
+	}
+	e.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if rules.MaxTx > 0 && total > rules.MaxTx {
+		return fmt.Errorf("%w: transfer %d exceeds per-tx limit %d", ErrDenied, total, rules.MaxTx)
+	}
+	if len(payouts) == 0 {
+		return nil
+	}
+
+	e.authorize.Lock()
+	defer e.authorize.Unlock()
+
+	key := SpendKey(tx)
+	reserved, err := e.store.Reserved(key)
+	if err != nil {
+		return err
+	}
+	if reserved {
+		return nil
+	}
+
+	received := make(map[string]int64)
+	recipients := make([]string, 0, len(payouts))
+	for _, payout := range payouts {
+		if _, ok := received[payout.To]; !ok {
+			recipients = append(recipients, payout.To)
+		}
+		received[payout.To] += payout.Value
+	}
+	now := e.now()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	usage, err := e.store.Usage(address, recipients, day, now.Add(-rules.VelocityWindow))
+	if err != nil {
+		return fmt.Errorf("load spend usage failed: %w", err)
+	}
+	if rules.DailyPerWallet > 0 && usage.Wallet+total > rules.DailyPerWallet {
+		return fmt.Errorf("%w: transfer %d exceeds daily limit %d of %s, spent %d",
+			ErrLimited, total, rules.DailyPerWallet, address, usage.Wallet)
+	}
+	if rules.DailyPerAddress > 0 {
+		for _, to := range recipients {
+			if usage.Received[to]+received[to] > rules.DailyPerAddress {
+				return fmt.Errorf("%w: %d to %s exceeds daily per-address limit %d, received %d",
+					ErrLimited, received[to], to, rules.DailyPerAddress, usage.Received[to])
+			}
+		}
+	}
+	if rules.VelocityCount > 0 && usage.Recent >= rules.VelocityCount {
+		return fmt.Errorf("%w: %s signed %d transfers within %s", ErrLimited, address, usage.Recent, rules.VelocityWindow)
+	}
+
+	spends := make([]Spend, len(payouts))
+	for n, payout := range payouts {
+		spends[n] = Spend{Key: key, N: n, From: address, To: payout.To, Value: payout.Value, Status: SpendReserved, CreatedAt: now}
+	}
+	if err := e.matchApprovals(address, spends, rules.ApprovalThreshold); err != nil {
+		return err
+	}
+	if err := e.store.Reserve(spends); err != nil {
+		if errors.Is(err, ErrApprovalConflict) {
+			return fmt.Errorf("%w: approval used concurrently: %w", ErrPendingApproval, err)
+		}
+		return fmt.Errorf("reserve spend failed: %w", err)
+	}
+	return nil
+}
+
+// Commit 交易已广播，确认其预留的额度
+func (e *Engine) Commit(tx *wire.MsgTx) error {
+	return e.store.Settle(SpendKey(tx), SpendCommitted)
+}
+
+// Release 交易确定未广播，归还其预留的额度和审批单
+// 广播结果不明时不要调用，预留的额度保留到当日结束
+func (e *Engine) Release(tx *wire.MsgTx) error {
+	return e.store.Settle(SpendKey(tx), SpendReleased)
+}
+
+// matchApprovals 超过审批阈值的每个输出都需要一张金额和收款地址相同、已批准且未使用的审批单
+func (e *Engine) matchApprovals(address string, spends []Spend, threshold int64) error {
+	if threshold <= 0 {
+		return nil
+	}
+	var approvals []Approval
+	for n := range spends {
+		spend := &spends[n]
+		if spend.Value <= threshold {
+			continue
+		}
+		if approvals == nil {
+			all, err := e.store.ListApprovals(ApprovalApproved, 0)
+			if err != nil {
+				return err
+			}
+			approvals = make([]Approval, 0, len(all))
+			for _, a := range all {
+				if a.From == address {
+					approvals = append(approvals, a)
+				}
+			}
+		}
+		matched := -1
+		for i, a := range approvals {
+			if a.ID != 0 && a.To == spend.To && a.Value == spend.Value {
+				matched = i
+				break
+			}
+		}
+		if matched < 0 {
+			return fmt.Errorf("%w: %d to %s has no approval", ErrPendingApproval, spend.Value, spend.To)
+		}
+		spend.ApprovalID = approvals[matched].ID
+		approvals[matched].ID = 0 // 一张审批单只能用于一个输出
+	}
+	return nil
+}
+
+// checkDestination 校验收款地址黑白名单，调用方持有锁
+func (e *Engine) checkDestination(to string) error {
+	if _, ok := e.deny[to]; ok {
+		return fmt.Errorf("%w: %s is on the deny list", ErrDenied, to)
+	}
+	if len(e.allow) > 0 {
+		if _, ok := e.allow[to]; !ok {
+			return fmt.Errorf("%w: %s is not on the allow list", ErrDenied, to)
+		}
+	}
+	return nil
+}
+
+// Approvals 查询审批单，status 为空时查询全部
+func (e *Engine) Approvals(status ApprovalStatus, limit int) ([]Approval, error) {
+	return e.store.ListApprovals(status, limit)
+}
+
+// Decide 批准或拒绝等待审批的审批单
+func (e *Engine) Decide(id uint64, approve bool, note string) error {
+	to := ApprovalRejected
+	if approve {
+		to = ApprovalApproved
+	}
+	return e.store.TransitionApproval(id, ApprovalPending, to, note, e.now())
+}
+
+// destinations 解析交易中除找零外的输出
+func destinations(address string, tx *wire.MsgTx) ([]Payout, error) {
+	changeScript, err := dogechain.PayToAddrScript(address)
+	if err != nil {
+		return nil, fmt.Errorf("bad address %s: %w", address, err)
+	}
+	payouts := make([]Payout, 0, len(tx.TxOut))
+	for _, out := range tx.TxOut {
+		if bytes.Equal(out.PkScript, changeScript) {
+			continue
+		}
+		payout := Payout{Value: out.Value}
+		_, addrs, _, err := txscript.ExtractPkScriptAddrs(out.PkScript, &dogechain.DogeMainNetParams)
+		if err == nil && len(addrs) == 1 {
+			payout.To = addrs[0].EncodeAddress()
+		}
+		payouts = append(payouts, payout)
+	}
+	return payouts, nil
+}
@@ -0,0 +1,247 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"claimask/pkg/dogechain"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// testAddress 生成合法的 Dogecoin 主网地址
+func testAddress(t *testing.T, seed byte) string {
+	t.Helper()
+	hash := make([]byte, 20)
+	hash[0] = seed
+	addr, err := btcutil.NewAddressPubKeyHash(hash, &dogechain.DogeMainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return addr.EncodeAddress()
+}
+
+// newTx 构建一笔从 sender 转出到 outputs 的未签名交易，带找零
+func newTx(t *testing.T, sender string, outputs ...dogechain.PayOutput) *wire.MsgTx {
+	t.Helper()
+	payout, err := dogechain.BuildPayoutTx(sender,
+		[]dogechain.UTXO{{TxHash: "a1075db55d416d3ca199f55b6084e2115b9345e16c5cf302fc80e9d5fbf5d48d", Value: 10000000000}},
+		outputs, 1000, 100000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payout.Tx
+}
+
+// 测试黑白名单和单笔上限
+func TestEngineDestinations(t *testing.T) {
+	sender, good, bad := testAddress(t, 1), testAddress(t, 2), testAddress(t, 3)
+	engine := NewEngine(Rules{MaxTx: 1000, Deny: []string{bad}}, nil)
+
+	if err := engine.Screen(sender, Payout{To: good, Value: 1000}); err != nil {
+		t.Errorf("Expected payout to pass, got %v", err)
+	}
+	if err := engine.Screen(sender, Payout{To: good, Value: 1001}); !errors.Is(err, ErrDenied) {
+		t.Errorf("Expected ErrDenied over per-tx limit, got %v", err)
+	}
+	if err := engine.Screen(sender, Payout{To: bad, Value: 1}); !errors.Is(err, ErrDenied) {
+		t.Errorf("Expected ErrDenied for deny list, got %v", err)
+	}
+	if err := engine.Authorize(sender, newTx(t, sender, dogechain.PayOutput{Address: bad, Value: 500}), nil); !errors.Is(err, ErrDenied) {
+		t.Errorf("Expected Authorize to reject deny list, got %v", err)
+	}
+	if err := engine.Authorize(sender, newTx(t, sender,
+		dogechain.PayOutput{Address: good, Value: 600}, dogechain.PayOutput{Address: good, Value: 600}), nil); !errors.Is(err, ErrDenied) {
+		t.Errorf("Expected Authorize to reject tx total over limit, got %v", err)
+	}
+
+	engine.SetRules(Rules{Allow: []string{good}})
+	if err := engine.Screen(sender, Payout{To: bad, Value: 1}); !errors.Is(err, ErrDenied) {
+		t.Errorf("Expected ErrDenied outside allow list, got %v", err)
+	}
+	if err := engine.Authorize(sender, newTx(t, sender, dogechain.PayOutput{Address: good, Value: 5000}), nil); err != nil {
+		t.Errorf("Expected allow list payout to pass, got %v", err)
+	}
+}
+
+// 测试每日额度和频率上限，找零不计入，跨天后额度重置
+func TestEngineLimits(t *testing.T) {
+	sender, to1, to2 := testAddress(t, 1), testAddress(t, 2), testAddress(t, 3)
+	engine := NewEngine(Rules{DailyPerWallet: 1000, DailyPerAddress: 600, VelocityCount: 2, VelocityWindow: time.Minute}, nil)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.Local)
+	engine.now = func() time.Time { return now }
+
+	if err := engine.Authorize(sender, newTx(t, sender, dogechain.PayOutput{Address: to1, Value: 500}), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Authorize(sender, newTx(t, sender, dogechain.PayOutput{Address: to1, Value: 200}), nil); !errors.Is(err, ErrLimited) {
+		t.Errorf("Expected per-address limit, got %v", err)
+	}
+	if err := engine.Authorize(sender, newTx(t, sender, dogechain.PayOutput{Address: to2, Value: 600}), nil); !errors.Is(err, ErrLimited) {
+		t.Errorf("Expected per-wallet limit, got %v", err)
+	}
+	if err := engine.Authorize(sender, newTx(t, sender, dogechain.PayOutput{Address: to2, Value: 400}), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// 额度已用完，次日重置后受频率上限限制
+	now = now.Add(12 * time.Hour)
+	if err := engine.Authorize(sender, newTx(t, sender, dogechain.PayOutput{Address: to2, Value: 100}), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Authorize(sender, newTx(t, sender, dogechain.PayOutput{Address: to2, Value: 101}), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Authorize(sender, newTx(t, sender, dogechain.PayOutput{Address: to2, Value: 102}), nil); !errors.Is(err, ErrLimited) {
+		t.Errorf("Expected velocity limit, got %v", err)
+	}
+	// 已预留的同一笔交易重新签名不重复计入
+	if err := engine.Authorize(sender, newTx(t, sender, dogechain.PayOutput{Address: to2, Value: 101}), nil); err != nil {
+		t.Errorf("Expected reserved tx to pass again, got %v", err)
+	}
+	now = now.Add(time.Minute)
+	if err := engine.Authorize(sender, newTx(t, sender, dogechain.PayOutput{Address: to2, Value: 103}), nil); err != nil {
+		t.Errorf("Expected velocity window to slide, got %v", err)
+	}
+}
+
+// 测试额度按持久化的转出记录统计：重启后不清零，未广播释放后归还，已广播确认后保留
+func TestEngineLedger(t *testing.T) {
+	sender, to := testAddress(t, 1), testAddress(t, 2)
+	store := NewMemoryStore()
+	rules := Rules{DailyPerWallet: 1000}
+	engine := NewEngine(rules, store)
+
+	first := newTx(t, sender, dogechain.PayOutput{Address: to, Value: 600})
+	if err := engine.Authorize(sender, first, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重启后额度仍计入
+	engine = NewEngine(rules, store)
+	second := newTx(t, sender, dogechain.PayOutput{Address: to, Value: 500})
+	if err := engine.Authorize(sender, second, nil); !errors.Is(err, ErrLimited) {
+		t.Fatalf("Expected daily limit to survive restart, got %v", err)
+	}
+
+	// 第一笔未广播，释放后额度归还
+	if err := engine.Release(first); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Authorize(sender, second, nil); err != nil {
+		t.Fatalf("Expected released quota to be available, got %v", err)
+	}
+	if err := engine.Commit(second); err != nil {
+		t.Fatal(err)
+	}
+	// 已确认的转出不能再释放
+	if err := engine.Release(second); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Authorize(sender, first, nil); !errors.Is(err, ErrLimited) {
+		t.Errorf("Expected committed spend to keep its quota, got %v", err)
+	}
+}
+
+// lockCheckStore 访问存储时调用 SetRules，引擎持锁访问存储时会死锁
+type lockCheckStore struct {
+	*MemoryStore
+	engine *Engine
+}
+
+func (s *lockCheckStore) Usage(from string, to []string, day, window time.Time) (*Usage, error) {
+	s.engine.SetRules(Rules{DailyPerWallet: 1000})
+	return s.MemoryStore.Usage(from, to, day, window)
+}
+
+// 测试 Authorize 访问存储时不持有规则锁
+func TestEngineAuthorizeUnlocked(t *testing.T) {
+	sender, to := testAddress(t, 1), testAddress(t, 2)
+	store := &lockCheckStore{MemoryStore: NewMemoryStore()}
+	store.engine = NewEngine(Rules{DailyPerWallet: 1000}, store)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- store.engine.Authorize(sender, newTx(t, sender, dogechain.PayOutput{Address: to, Value: 100}), nil)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Authorize holds the rules lock while accessing the store")
+	}
+}
+
+// 测试超过审批阈值的转出：预检创建审批单，批准后签名前使用审批单，审批单只能使用一次
+func TestEngineApproval(t *testing.T) {
+	sender, to := testAddress(t, 1), testAddress(t, 2)
+	store := NewMemoryStore()
+	engine := NewEngine(Rules{ApprovalThreshold: 1000}, store)
+	big := Payout{Ref: "42", To: to, Value: 5000}
+
+	if err := engine.Screen(sender, Payout{To: to, Value: 1000}); err != nil {
+		t.Errorf("Expected payout at threshold to pass, got %v", err)
+	}
+	if err := engine.Screen(sender, Payout{To: to, Value: 5000}); !errors.Is(err, ErrDenied) {
+		t.Errorf("Expected payout without reference to be denied, got %v", err)
+	}
+	if err := engine.Screen(sender, big); !errors.Is(err, ErrPendingApproval) {
+		t.Fatalf("Expected ErrPendingApproval, got %v", err)
+	}
+	if err := engine.Screen(sender, big); !errors.Is(err, ErrPendingApproval) {
+		t.Fatalf("Expected ErrPendingApproval, got %v", err)
+	}
+	pending, _ := engine.Approvals(ApprovalPending, 0)
+	if len(pending) != 1 || pending[0].Ref != "42" || pending[0].Value != 5000 {
+		t.Fatalf("Expected one pending approval, got %+v", pending)
+	}
+
+	tx := newTx(t, sender, dogechain.PayOutput{Address: to, Value: 5000})
+	if err := engine.Authorize(sender, tx, nil); !errors.Is(err, ErrPendingApproval) {
+		t.Errorf("Expected unapproved tx to be held, got %v", err)
+	}
+
+	if err := engine.Decide(pending[0].ID, true, "ok"); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Decide(pending[0].ID, false, ""); !errors.Is(err, ErrApprovalConflict) {
+		t.Errorf("Expected ErrApprovalConflict on second decision, got %v", err)
+	}
+	if err := engine.Screen(sender, big); err != nil {
+		t.Errorf("Expected approved payout to pass screening, got %v", err)
+	}
+	if err := engine.Authorize(sender, tx, nil); err != nil {
+		t.Fatalf("Expected approved tx to pass, got %v", err)
+	}
+	other := newTx(t, sender, dogechain.PayOutput{Address: to, Value: 5000}, dogechain.PayOutput{Address: testAddress(t, 4), Value: 1})
+	if err := engine.Authorize(sender, other, nil); !errors.Is(err, ErrPendingApproval) {
+		t.Errorf("Expected approval to be used only once, got %v", err)
+	}
+	used, _ := engine.Approvals(ApprovalUsed, 0)
+	if len(used) != 1 || used[0].Note != "ok" || used[0].DecidedAt == nil {
+		t.Errorf("Expected approval to be used, got %+v", used)
+	}
+
+	// 交易未广播，释放后审批单可用于重新构建的交易
+	if err := engine.Release(tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Authorize(sender, other, nil); err != nil {
+		t.Errorf("Expected released approval to be reusable, got %v", err)
+	}
+
+	if err := engine.Screen(sender, Payout{Ref: "43", To: to, Value: 2000}); !errors.Is(err, ErrPendingApproval) {
+		t.Fatal(err)
+	}
+	pending, _ = engine.Approvals(ApprovalPending, 0)
+	if err := engine.Decide(pending[0].ID, false, "suspicious"); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Screen(sender, Payout{Ref: "43", To: to, Value: 2000}); !errors.Is(err, ErrDenied) {
+		t.Errorf("Expected rejected payout to be denied, got %v", err)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"sync"
 	"time"
+
+	"claimask/pkg/signer"
 )
 
 // QueueMessage 表示通用的队列消息
@@ -18,7 +20,8 @@ type QueueMessage struct {
 }
 
 // TaskProcessor 定义消息处理器函数签名
-type TaskProcessor func(sender string, senderKey string, messages []QueueMessage) error
+// 交易由 txSigner 签名，私钥不经过队列
+type TaskProcessor func(sender string, txSigner signer.Signer, messages []QueueMessage) error
 
 // RateLimitedQueue 实现了一个速率限制队列
 // 特点:
@@ -32,7 +35,7 @@ type RateLimitedQueue struct {
 	blacklist    map[string]struct{} // 黑名单集合(使用map提高查找效率)
 	processor    TaskProcessor       // 消息处理器
 	sender       string              // 发送方地址
-	senderKey    string              // 发送方密钥
+	signer       signer.Signer       // 发送方签名方
 
 	// 速率控制
 	interval     time.Duration // 处理间隔
@@ -49,13 +52,14 @@ type RateLimitedQueue struct {
 // NewRateLimitedQueue 创建新的速率限制队列
 // processor: 消息处理函数
 // sender: 发送方地址
-// senderKey: 发送方密钥
+// txSigner: 发送方签名方
 // interval: 处理间隔时间
 // batchProcess: 是否批量处理
 // valueLimit: 单笔交易值上限
 func NewRateLimitedQueue(
 	processor TaskProcessor,
-	sender, senderKey string,
+	sender string,
+	txSigner signer.Signer,
 	interval time.Duration,
 	batchProcess bool,
 	valueLimit int,
@@ -63,7 +67,7 @@ func NewRateLimitedQueue(
 	q := &RateLimitedQueue{
 		processor:    processor,
 		sender:       sender,
-		senderKey:    senderKey,
+		signer:       txSigner,
 		interval:     interval,
 		batchProcess: batchProcess,
 		valueLimit:   valueLimit,
@@ -148,7 +152,7 @@ func (q *RateLimitedQueue) processBatch() {
 
 	// 异步处理消息批次
 	go func() {
-		if err := q.processor(q.sender, q.senderKey, messages); err != nil {
+		if err := q.processor(q.sender, q.signer, messages); err != nil {
 			// 处理失败可以选择重新入队或记录日志
 		}
 	}()
@@ -175,7 +179,7 @@ func (q *RateLimitedQueue) processSequential() {
 		q.mutex.Unlock()
 
 		// 处理单条消息
-		err := q.processor(q.sender, q.senderKey, []QueueMessage{message})
+		err := q.processor(q.sender, q.signer, []QueueMessage{message})
 		if err != nil {
 			// 这里可以实现错误处理策略
 		}
@@ -6,11 +6,13 @@ import (
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"claimask/pkg/signer"
 )
 
 // 测试基本功能: 创建队列和检查初始状态
 func TestNewRateLimitedQueue(t *testing.T) {
-	processor := func(sender, senderKey string, messages []QueueMessage) error {
+	processor := func(sender string, txSigner signer.Signer, messages []QueueMessage) error {
 		return nil
 	}
 
@@ -18,7 +20,7 @@ func TestNewRateLimitedQueue(t *testing.T) {
 	batchQueue := NewRateLimitedQueue(
 		processor,
 		"testAddress",
-		"testKey",
+		nil,
 		100*time.Millisecond,
 		true, // 批量处理
 		1000,
@@ -38,7 +40,7 @@ func TestNewRateLimitedQueue(t *testing.T) {
 	seqQueue := NewRateLimitedQueue(
 		processor,
 		"testAddress",
-		"testKey",
+		nil,
 		100*time.Millisecond,
 		false, // 顺序处理
 		1000,
@@ -58,7 +60,7 @@ func TestNewRateLimitedQueue(t *testing.T) {
 // 测试消息入队功能
 func TestEnqueue(t *testing.T) {
 	var processed bool
-	processor := func(sender, senderKey string, messages []QueueMessage) error {
+	processor := func(sender string, txSigner signer.Signer, messages []QueueMessage) error {
 		processed = true
 		return nil
 	}
@@ -66,7 +68,7 @@ func TestEnqueue(t *testing.T) {
 	queue := NewRateLimitedQueue(
 		processor,
 		"testAddress",
-		"testKey",
+		nil,
 		50*time.Millisecond,
 		false, // 顺序处理模式便于测试
 		1000,
@@ -116,14 +118,14 @@ func TestEnqueue(t *testing.T) {
 
 // 测试黑名单管理功能
 func TestBlacklistManagement(t *testing.T) {
-	processor := func(sender, senderKey string, messages []QueueMessage) error {
+	processor := func(sender string, txSigner signer.Signer, messages []QueueMessage) error {
 		return nil
 	}
 
 	queue := NewRateLimitedQueue(
 		processor,
 		"testAddress",
-		"testKey",
+		nil,
 		100*time.Millisecond,
 		true,
 		1000,
@@ -158,7 +160,7 @@ func TestBatchProcessing(t *testing.T) {
 	wg := sync.WaitGroup{}
 	wg.Add(1)
 
-	processor := func(sender, senderKey string, messages []QueueMessage) error {
+	processor := func(sender string, txSigner signer.Signer, messages []QueueMessage) error {
 		atomic.AddInt32(&processedCount, int32(len(messages)))
 		if len(messages) >= 3 {
 			wg.Done() // 当至少处理3条消息时通知测试
@@ -169,7 +171,7 @@ func TestBatchProcessing(t *testing.T) {
 	queue := NewRateLimitedQueue(
 		processor,
 		"testAddress",
-		"testKey",
+		nil,
 		100*time.Millisecond, // 短间隔便于快速测试
 		true,                 // 批量处理
 		1000,
@@ -206,7 +208,7 @@ func TestSequentialProcessing(t *testing.T) {
 	wg := sync.WaitGroup{}
 	wg.Add(3) // 期望处理3条消息
 
-	processor := func(sender, senderKey string, messages []QueueMessage) error {
+	processor := func(sender string, txSigner signer.Signer, messages []QueueMessage) error {
 		if len(messages) != 1 {
 			t.Errorf("Expected single message in sequential mode, got %d", len(messages))
 		}
@@ -222,7 +224,7 @@ func TestSequentialProcessing(t *testing.T) {
 	queue := NewRateLimitedQueue(
 		processor,
 		"testAddress",
-		"testKey",
+		nil,
 		50*time.Millisecond, // 短间隔便于快速测试
 		false,               // 顺序处理
 		1000,
@@ -263,7 +265,7 @@ func TestSequentialProcessing(t *testing.T) {
 func BenchmarkBatchProcessing(b *testing.B) {
 	var count int32
 
-	processor := func(sender, senderKey string, messages []QueueMessage) error {
+	processor := func(sender string, txSigner signer.Signer, messages []QueueMessage) error {
 		atomic.AddInt32(&count, int32(len(messages)))
 		return nil
 	}
@@ -271,7 +273,7 @@ func BenchmarkBatchProcessing(b *testing.B) {
 	queue := NewRateLimitedQueue(
 		processor,
 		"benchAddress",
-		"benchKey",
+		nil,
 		10*time.Millisecond,
 		true,  // 批量处理
 		10000, // 高限制值避免拒绝
@@ -316,7 +318,7 @@ func BenchmarkSequentialLatency(b *testing.B) {
 
 	startTimes := make(map[string]time.Time)
 
-	processor := func(sender, senderKey string, messages []QueueMessage) error {
+	processor := func(sender string, txSigner signer.Signer, messages []QueueMessage) error {
 		addr := messages[0].Address
 
 		mu.Lock()
@@ -332,7 +334,7 @@ func BenchmarkSequentialLatency(b *testing.B) {
 	queue := NewRateLimitedQueue(
 		processor,
 		"benchAddress",
-		"benchKey",
+		nil,
 		5*time.Millisecond, // 更短的间隔用于基准测试
 		false,              // 顺序处理
 		10000,
@@ -4,6 +4,9 @@ import (
 	"errors"
 	"sync"
 	"time"
+
+	"claimask/pkg/policy"
+	"claimask/pkg/signer"
 )
 
 // Message 表示一个需要处理的消息，包含目标地址和交易值。
@@ -13,39 +16,51 @@ type Message struct {
 	Address string
 	// Value 表示消息的交易金额或数量
 	Value int
+	// Ref 表示业务单号，金额超过审批阈值时用于关联审批单
+	Ref string
 }
 
+// 入队被拒绝的原因
+var (
+	// ErrAddressBanned 地址在冷却期内已入队过
+	ErrAddressBanned = errors.New("address banned within cooldown")
+	// ErrAddressQueued 地址已在当前批次中
+	ErrAddressQueued = errors.New("address already in processing queue")
+)
+
 // SlowSpeedBox 实现了一个批量消息处理系统，用于周期性地处理累积的消息。
-// 它内部使用定时器定期处理队列中的消息，同时实现了防重复提交和地址黑名单功能。
+// 它内部使用定时器定期处理队列中的消息，同时实现了防重复提交和地址冷却功能。
 // 该结构适用于需要批量处理且有频率限制的场景，如批量发送交易。
 type SlowSpeedBox struct {
-	addressQueue []Message                                            // 存储待处理的消息队列
-	banAddress   []string                                             // 存储被禁止处理的地址列表
-	fun          func(address, privateKey string, messages []Message) // 消息处理函数
-	address      string                                               // 处理消息的账户地址
-	privateKey   string                                               // 处理消息的账户私钥
-	senderTicker *time.Ticker                                         // 控制消息处理频率的定时器
-	banderTicker *time.Ticker                                         // 控制黑名单清理频率的定时器
-	mutex        sync.Mutex                                           // 保证并发安全的互斥锁
+	addressQueue []Message                                                      // 存储待处理的消息队列
+	banAddress   map[string]time.Time                                           // 冷却中的地址及其入队时间
+	cooldown     time.Duration                                                  // 同一地址两次入队的最小间隔
+	fun          func(address string, signer signer.Signer, messages []Message) // 消息处理函数
+	address      string                                                         // 处理消息的账户地址
+	signer       signer.Signer                                                  // 处理消息的账户签名方
+	policy       policy.Screener                                                // 入队前的转出策略预检
+	senderTicker *time.Ticker                                                   // 控制消息处理频率的定时器，手动模式为nil
+	banderTicker *time.Ticker                                                   // 控制冷却清理频率的定时器，手动模式为nil
+	stop         chan struct{}                                                  // 停止内部定时器
+	mutex        sync.Mutex                                                     // 保证并发安全的互斥锁
 }
 
 // NewSlowSpeedBox 创建并初始化一个新的SlowSpeedBox实例。
 // 参数fun是处理消息的函数，将在定时器触发时被调用。
-// 参数address和privateKey是发送方的账户信息。
-// 返回一个已启动内部定时器的SlowSpeedBox指针。
+// 参数address是发送方地址，txSigner用于签名发送方的交易，私钥不经过队列。
+// 参数screener在入队前按转出策略预检每条消息，为nil时不预检。
+// 返回一个已启动内部定时器的SlowSpeedBox指针，同一地址24小时内只能入队一次。
 func NewSlowSpeedBox(
-	fun func(address, privateKey string, messages []Message),
-	address, privateKey string,
+	fun func(address string, signer signer.Signer, messages []Message),
+	address string,
+	txSigner signer.Signer,
+	screener policy.Screener,
 ) *SlowSpeedBox {
-	s := &SlowSpeedBox{
-		fun:        fun,
-		address:    address,
-		privateKey: privateKey,
-	}
+	s := NewManualSlowSpeedBox(fun, address, txSigner, screener, 24*time.Hour)
 
 	// 初始化定时器：每60秒处理一次消息队列
 	s.senderTicker = time.NewTicker(60 * time.Second)
-	// 初始化定时器：每24小时清理一次黑名单
+	// 初始化定时器：每24小时清理一次冷却记录
 	s.banderTicker = time.NewTicker(24 * time.Hour)
 
 	// 启动后台处理goroutine
@@ -55,7 +70,9 @@ func NewSlowSpeedBox(
 			case <-s.senderTicker.C:
 				s.processBatch() // 定期处理批次消息
 			case <-s.banderTicker.C:
-				s.clearBanned() // 定期清理黑名单
+				s.clearBanned() // 定期清理冷却记录
+			case <-s.stop:
+				return
 			}
 		}
 	}()
@@ -63,6 +80,38 @@ func NewSlowSpeedBox(
 	return s
 }
 
+// NewManualSlowSpeedBox 创建不启动内部定时器的SlowSpeedBox实例。
+// 由调用方在自己的调度循环中调用 Flush 同步处理批次，便于与任务的启停和单轮执行配合。
+// 参数cooldown是同一地址两次入队的最小间隔，0为不限制。
+func NewManualSlowSpeedBox(
+	fun func(address string, signer signer.Signer, messages []Message),
+	address string,
+	txSigner signer.Signer,
+	screener policy.Screener,
+	cooldown time.Duration,
+) *SlowSpeedBox {
+	return &SlowSpeedBox{
+		banAddress: make(map[string]time.Time),
+		cooldown:   cooldown,
+		fun:        fun,
+		address:    address,
+		signer:     txSigner,
+		policy:     screener,
+		stop:       make(chan struct{}),
+	}
+}
+
+// take 取出当前队列中的所有消息并清空队列，调用方需持有锁
+func (s *SlowSpeedBox) take() []Message {
+	if len(s.addressQueue) == 0 {
+		return nil
+	}
+	messages := make([]Message, len(s.addressQueue))
+	copy(messages, s.addressQueue)
+	s.addressQueue = s.addressQueue[:0]
+	return messages
+}
+
 // processBatch 处理当前队列中的所有消息。
 // 该方法会将消息队列中的所有消息一次性提取出来，然后异步调用处理函数。
 // 处理完成后，队列会被清空，为新的消息做准备。
@@ -71,71 +120,102 @@ func (s *SlowSpeedBox) processBatch() {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	if len(s.addressQueue) == 0 {
+	messages := s.take()
+	if len(messages) == 0 {
 		return // 队列为空，无需处理
 	}
 
-	// 复制当前队列并清空原队列
-	messages := make([]Message, len(s.addressQueue))
-	copy(messages, s.addressQueue)
-	s.addressQueue = s.addressQueue[:0]
-
 	// 异步处理复制出的消息批次
 	go func() {
-		s.fun(s.address, s.privateKey, messages)
+		s.fun(s.address, s.signer, messages)
 		// 这里可以添加日志记录或结果回调
 	}()
 }
 
-// clearBanned 清空黑名单列表，允许之前被禁止的地址再次提交消息。
+// Flush 同步处理当前队列中的所有消息，处理函数返回后才返回。
+// 处理函数在锁外执行，处理期间可以继续入队。
+func (s *SlowSpeedBox) Flush() {
+	s.mutex.Lock()
+	messages := s.take()
+	s.mutex.Unlock()
+
+	if len(messages) > 0 {
+		s.fun(s.address, s.signer, messages)
+	}
+}
+
+// Release 解除地址的冷却，用于消息未能处理完成、需要重新入队的情况。
+// 这是一个线程安全的操作。
+func (s *SlowSpeedBox) Release(address string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.banAddress, address)
+}
+
+// Stop 停止内部定时器，未处理的消息保留在队列中。手动模式下无需调用。
+func (s *SlowSpeedBox) Stop() {
+	if s.senderTicker == nil {
+		return
+	}
+	s.senderTicker.Stop()
+	s.banderTicker.Stop()
+	close(s.stop)
+}
+
+// clearBanned 清理冷却期已过的地址，允许这些地址再次提交消息。
 // 该方法通常由内部定时器自动调用，周期为24小时。
 // 这是一个线程安全的操作。
 func (s *SlowSpeedBox) clearBanned() {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	s.banAddress = s.banAddress[:0] // 清空黑名单
+	now := time.Now()
+	for address, bannedAt := range s.banAddress {
+		if now.Sub(bannedAt) >= s.cooldown {
+			delete(s.banAddress, address)
+		}
+	}
 }
 
 // Enqueue 将一个新消息添加到处理队列中。
 // 该方法会进行多项检查:
-// 1. 验证消息值是否超过单笔限额(5000)
-// 2. 检查目标地址是否在黑名单中
-// 3. 确保同一地址不会在一个批次中重复出现
-// 如果所有检查通过，则将消息添加到队列并将地址加入黑名单。
+// 1. 按转出策略预检（黑白名单、单笔上限、审批阈值）
+// 2. 检查目标地址是否在冷却期内，是则返回 ErrAddressBanned
+// 3. 确保同一地址不会在一个批次中重复出现，重复时返回 ErrAddressQueued
+// 如果所有检查通过，则将消息添加到队列并记录地址的入队时间。
 // 返回error表示添加失败的原因，nil表示添加成功。
 // 这是一个线程安全的操作。
 func (s *SlowSpeedBox) Enqueue(message Message) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	// 检查单笔交易金额是否超过限制
-	if message.Value > 5000 {
-		return errors.New("single transaction exceeds 5000 limit")
+	// 按转出策略预检，等待审批的消息需审批通过后重新入队
+	if s.policy != nil {
+		if err := s.policy.Screen(s.address, policy.Payout{Ref: message.Ref, To: message.Address, Value: int64(message.Value)}); err != nil {
+			return err
+		}
 	}
 
-	// 检查地址是否在黑名单中
-	for _, addr := range s.banAddress {
-		if addr == message.Address {
-			return errors.New("address banned within 24 hours")
-		}
+	// 检查地址是否在冷却期内
+	if bannedAt, ok := s.banAddress[message.Address]; ok && time.Since(bannedAt) < s.cooldown {
+		return ErrAddressBanned
 	}
 
 	// 检查地址是否已在当前批次中
 	for _, msg := range s.addressQueue {
 		if msg.Address == message.Address {
-			return errors.New("address already in processing queue")
+			return ErrAddressQueued
 		}
 	}
 
-	// 将地址加入黑名单并添加消息到队列
-	s.banAddress = append(s.banAddress, message.Address)
+	// 记录地址的入队时间并添加消息到队列
+	s.banAddress[message.Address] = time.Now()
 	s.addressQueue = append(s.addressQueue, message)
 	return nil
 }
 
 // TaskHandler 定义了处理单个任务的函数类型。
-// 该函数接收发送方地址、私钥和任务数据，返回处理结果。
-type TaskHandler func(address, privateKey string, data interface{}) error
+// 该函数接收发送方地址、签名方和任务数据，返回处理结果。
+type TaskHandler func(address string, signer signer.Signer, data interface{}) error
 
 // SlowSpeedQueue 实现了一个简单的顺序任务处理队列。
 // 与SlowSpeedBox不同，它不进行批处理，而是一个接一个地处理任务，
@@ -146,19 +226,19 @@ type SlowSpeedQueue struct {
 	processing bool          // 标记是否正在处理队列
 	fun        TaskHandler   // 任务处理函数
 	address    string        // 处理任务的账户地址
-	privateKey string        // 处理任务的账户私钥
+	signer     signer.Signer // 处理任务的账户签名方
 	mutex      sync.Mutex    // 保证并发安全的互斥锁
 }
 
 // NewSlowSpeedQueue 创建并初始化一个新的SlowSpeedQueue实例。
 // 参数fun是处理任务的函数。
-// 参数address和privateKey是发送方的账户信息。
+// 参数address是发送方地址，txSigner用于签名发送方的交易。
 // 返回一个初始化完成的SlowSpeedQueue指针。
-func NewSlowSpeedQueue(fun TaskHandler, address, privateKey string) *SlowSpeedQueue {
+func NewSlowSpeedQueue(fun TaskHandler, address string, txSigner signer.Signer) *SlowSpeedQueue {
 	return &SlowSpeedQueue{
-		fun:        fun,
-		address:    address,
-		privateKey: privateKey,
+		fun:     fun,
+		address: address,
+		signer:  txSigner,
 	}
 }
 
@@ -204,7 +284,7 @@ func (q *SlowSpeedQueue) processLoop() {
 		q.mutex.Unlock()
 
 		// 执行任务处理函数
-		if err := q.fun(q.address, q.privateKey, task); err != nil {
+		if err := q.fun(q.address, q.signer, task); err != nil {
 			// 这里可以添加错误处理逻辑
 		}
 
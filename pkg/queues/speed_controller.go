@@ -13,11 +13,12 @@ import (
 // 它结合了优先级队列、信号量和重试机制，确保高优先级任务优先处理，同时防止系统过载。
 // SpeedController是线程安全的，可以被多个goroutine并发访问。
 type SpeedController struct {
-	mu          sync.Mutex       // 互斥锁，保证并发安全
-	priorityQ   *PriorityQueue   // 存储待处理任务的优先级队列
-	workerSem   chan struct{}    // 并发控制信号量，限制同时执行的任务数量
-	retryPolicy RetryPolicy      // 失败任务的重试策略
-	pending     map[string]*Item // 记录正在处理的任务，用于去重和状态跟踪
+	mu            sync.Mutex       // 互斥锁，保证并发安全
+	priorityQ     *PriorityQueue   // 存储待处理任务的优先级队列
+	maxConcurrent int              // 同时执行的任务数量上限
+	running       int              // 正在执行的任务数量
+	retryPolicy   RetryPolicy      // 失败任务的重试策略
+	pending       map[string]*Item // 记录正在处理的任务，用于去重和状态跟踪
 }
 
 // RetryPolicy 定义了任务失败后的重试策略。
@@ -36,14 +37,26 @@ type RetryPolicy struct {
 // 返回一个可立即使用的SpeedController实例。
 func NewSpeedController(config Config) *SpeedController {
 	sc := &SpeedController{
-		priorityQ:   NewPriorityQueue(100),                     // 创建一个初始容量为100的优先级队列
-		workerSem:   make(chan struct{}, config.MaxConcurrent), // 创建容量为MaxConcurrent的信号量通道
-		retryPolicy: config.RetryPolicy,
-		pending:     make(map[string]*Item), // 初始化待处理任务映射
+		priorityQ:     NewPriorityQueue(100), // 创建一个初始容量为100的优先级队列
+		maxConcurrent: config.MaxConcurrent,
+		retryPolicy:   config.RetryPolicy,
+		pending:       make(map[string]*Item), // 初始化待处理任务映射
 	}
 	return sc
 }
 
+// SetConfig 运行时调整并发上限和重试策略，用于配置热更新。
+// 调大并发上限后立即调度排队中的任务；调小时正在执行的任务不受影响，新任务等待执行数降到上限以下。
+// 新的重试策略对之后开始执行的任务生效。
+func (sc *SpeedController) SetConfig(config Config) {
+	sc.mu.Lock()
+	sc.maxConcurrent = config.MaxConcurrent
+	sc.retryPolicy = config.RetryPolicy
+	sc.mu.Unlock()
+
+	go sc.processTasks()
+}
+
 // Enqueue 将一个任务添加到处理队列中。
 // 如果任务的Key已经存在于pending map中，表示相同任务正在处理中，此时会忽略新任务。
 // 任务入队后，会自动启动一个goroutine来处理队列中的任务。
@@ -63,70 +76,98 @@ func (sc *SpeedController) Enqueue(item *Item) {
 }
 
 // processTasks 是一个内部方法，用于处理队列中的任务。
-// 它会尝试获取信号量，一旦获取成功，就会从队列中取出优先级最高的任务进行处理。
-// 如果无法立即获取信号量（已达到最大并发数），此方法会立即返回。
-// 任务处理完成后会释放信号量，允许处理下一个任务。
+// 在执行数未达到并发上限时，从队列中取出优先级最高的任务进行处理。
+// 如果已达到最大并发数或队列为空，此方法会立即返回。
+// 任务处理完成后会释放执行名额，允许处理下一个任务。
 func (sc *SpeedController) processTasks() {
 	for {
+		item, policy := sc.dequeue()
+		if item == nil {
+			return
+		}
+		go sc.executeWithRetry(item, policy) // 异步执行任务，并在失败时进行重试
+	}
+}
+
+// Drain 等待队列中的任务全部执行完毕，用于关闭前刷出队列。
+// 失败后重新入队的任务不会自动触发处理，因此等待期间会周期性地重新调度。
+// ctx到期时返回ctx的错误，此时仍有任务未完成。
+func (sc *SpeedController) Drain(ctx context.Context) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if sc.idle() {
+			return nil
+		}
+		sc.processTasks()
 		select {
-		case sc.workerSem <- struct{}{}: // 尝试获取信号量
-			item := sc.dequeue()
-			if item == nil {
-				<-sc.workerSem // 如果没有任务，释放信号量并返回
-				return
-			}
-
-			go sc.executeWithRetry(item) // 异步执行任务，并在失败时进行重试
-		default:
-			return // 如果无法获取信号量，说明已达到最大并发数，直接返回
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 	}
 }
 
-// dequeue 从优先级队列中取出优先级最高的任务。
-// 如果队列为空，返回nil。
+// idle 判断队列为空且没有正在执行的任务。
+func (sc *SpeedController) idle() bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.priorityQ.Len() == 0 && sc.running == 0
+}
+
+// dequeue 占用一个执行名额并从优先级队列中取出优先级最高的任务，同时返回当前的重试策略。
+// 如果队列为空或已达到最大并发数，返回nil。
 // 任务被取出后会从pending map中移除。
 // 这是一个线程安全的操作。
-func (sc *SpeedController) dequeue() *Item {
+func (sc *SpeedController) dequeue() (*Item, RetryPolicy) {
 	sc.mu.Lock()
 	defer sc.mu.Unlock()
 
-	if sc.priorityQ.Len() == 0 {
-		return nil
+	if sc.priorityQ.Len() == 0 || sc.running >= sc.maxConcurrent {
+		return nil, sc.retryPolicy
 	}
 
 	item := heap.Pop(sc.priorityQ).(*Item)
 	delete(sc.pending, item.Key) // 从待处理映射中移除
-	return item
+	sc.running++
+	return item, sc.retryPolicy
+}
+
+// release 释放一个执行名额。
+func (sc *SpeedController) release() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.running--
 }
 
-// executeWithRetry 执行任务，并在失败时根据RetryPolicy进行重试。
-// 每次执行结束后，无论成功失败，都会释放一个信号量。
+// executeWithRetry 执行任务，并在失败时根据取出任务时的RetryPolicy进行重试。
+// 每次执行结束后，无论成功失败，都会释放执行名额。
 // 如果任务在最大重试次数内仍然失败，会调用handleFailure方法进行处理。
-func (sc *SpeedController) executeWithRetry(item *Item) {
-	defer func() { <-sc.workerSem }() // 确保在函数返回时释放信号量
+func (sc *SpeedController) executeWithRetry(item *Item, policy RetryPolicy) {
+	defer sc.release() // 确保在函数返回时释放执行名额
 
-	for attempt := 0; attempt <= sc.retryPolicy.MaxRetries; attempt++ {
+	for attempt := 0; attempt <= policy.MaxRetries; attempt++ {
 		err := item.Handler(context.Background()) // 执行任务处理器
 		if err == nil {
 			return // 处理成功，直接返回
 		}
 
-		delay := sc.calculateBackoff(attempt) // 计算退避延迟时间
-		time.Sleep(delay)                     // 等待后重试
+		delay := policy.backoff(attempt) // 计算退避延迟时间
+		time.Sleep(delay)                // 等待后重试
 	}
 
 	// 超过最大重试次数，处理失败
 	sc.handleFailure(item)
 }
 
-// calculateBackoff 根据重试次数计算退避延迟时间。
+// backoff 根据重试次数计算退避延迟时间。
 // 使用指数退避算法：delay = BaseDelay * 2^attempt，但不超过MaxDelay。
 // 这种算法可以在系统负载高时降低重试频率，减轻系统压力。
-func (sc *SpeedController) calculateBackoff(attempt int) time.Duration {
-	delay := sc.retryPolicy.BaseDelay * time.Duration(1<<uint(attempt)) // 指数增长
-	if delay > sc.retryPolicy.MaxDelay {
-		return sc.retryPolicy.MaxDelay // 不超过最大延迟时间
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay * time.Duration(1<<uint(attempt)) // 指数增长
+	if delay > p.MaxDelay {
+		return p.MaxDelay // 不超过最大延迟时间
 	}
 	return delay
 }
@@ -0,0 +1,90 @@
+package queues
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 测试Drain等待所有已入队任务执行完毕
+func TestSpeedControllerDrain(t *testing.T) {
+	sc := NewSpeedController(Config{MaxConcurrent: 2})
+
+	var done int32
+	for i := 0; i < 5; i++ {
+		sc.Enqueue(&Item{
+			Key: strconv.Itoa(i),
+			Handler: func(ctx context.Context) error {
+				time.Sleep(20 * time.Millisecond)
+				atomic.AddInt32(&done, 1)
+				return nil
+			},
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := sc.Drain(ctx); err != nil {
+		t.Fatalf("drain failed: %v", err)
+	}
+	if n := atomic.LoadInt32(&done); n != 5 {
+		t.Errorf("Expected 5 tasks done, got %d", n)
+	}
+}
+
+// 测试任务一直执行不完时Drain在ctx到期后返回
+func TestSpeedControllerDrainTimeout(t *testing.T) {
+	sc := NewSpeedController(Config{MaxConcurrent: 1})
+	release := make(chan struct{})
+	defer close(release)
+	sc.Enqueue(&Item{Key: "slow", Handler: func(ctx context.Context) error {
+		<-release
+		return nil
+	}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := sc.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected DeadlineExceeded, got %v", err)
+	}
+}
+
+// 测试运行时调大并发上限后排队中的任务立即开始执行
+func TestSpeedControllerSetConfig(t *testing.T) {
+	sc := NewSpeedController(Config{MaxConcurrent: 1})
+	release := make(chan struct{})
+	started := make(chan struct{}, 3)
+	for i := 0; i < 3; i++ {
+		sc.Enqueue(&Item{Key: strconv.Itoa(i), Handler: func(ctx context.Context) error {
+			started <- struct{}{}
+			<-release
+			return nil
+		}})
+	}
+
+	<-started
+	select {
+	case <-started:
+		t.Fatal("Expected only one task running before raising the limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	sc.SetConfig(Config{MaxConcurrent: 3})
+	for i := 0; i < 2; i++ {
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatal("Expected queued tasks to start after raising the limit")
+		}
+	}
+
+	close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := sc.Drain(ctx); err != nil {
+		t.Fatalf("drain failed: %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+// Package redislock 基于Redis的分布式锁
+// 每次加锁生成唯一令牌，释放和续期都先比较令牌；持锁期间后台自动续期；
+// 每次加锁成功返回单调递增的栅栏令牌，写库时以栅栏令牌为条件，防止锁过期后的旧持有者覆盖新数据；
+// 计数器随Redis清空或故障切换丢失后从1重新开始，加锁时可传入库中已写入的最大令牌作为下限
+package redislock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// fenceSuffix 栅栏令牌计数器的Key后缀，计数器不过期
+const fenceSuffix = ":fence"
+
+var (
+	// ErrNotAcquired 锁已被其他持有者占用
+	ErrNotAcquired = errors.New("redislock: not acquired")
+	// ErrNotHeld 锁已过期或已被其他持有者获取
+	ErrNotHeld = errors.New("redislock: lock not held")
+)
+
+// acquireScript 加锁成功后递增栅栏令牌计数器并返回，计数器不大于下限 ARGV[3] 时从下限之后继续，加锁失败返回0
+var acquireScript = redis.NewScript(`
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	local fence = redis.call("INCR", KEYS[2])
+	local floor = tonumber(ARGV[3])
+	if fence <= floor then
+		fence = floor + 1
+		redis.call("SET", KEYS[2], fence)
+	end
+	return fence
+end
+return 0
+`)
+
+// renewScript 令牌一致时续期
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript 令牌一致时删除
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// Locker 分布式锁客户端
+type Locker struct {
+	client *redis.Client
+}
+
+// New 创建分布式锁客户端
+func New(client *redis.Client) *Locker {
+	return &Locker{client: client}
+}
+
+// Lock 一把已获取的锁
+type Lock struct {
+	client *redis.Client
+	key    string
+	token  string
+	fence  int64
+	ttl    time.Duration
+
+	ctx    context.Context // 锁丢失或释放后取消
+	cancel context.CancelFunc
+	once   sync.Once
+	done   chan struct{} // 续期协程退出后关闭
+}
+
+// Acquire 尝试获取锁，锁被占用时返回 ErrNotAcquired
+// 获取成功后每隔 ttl/3 自动续期，直到调用 Release；续期失败超过ttl后认为锁已丢失，Context 随之取消
+func (l *Locker) Acquire(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	return l.AcquireAbove(ctx, key, ttl, 0)
+}
+
+// AcquireAbove 同 Acquire，返回的栅栏令牌保证大于 floor
+// floor 通常为库中以该锁写入的最大栅栏令牌，Redis 计数器丢失后新令牌仍能通过写库条件
+func (l *Locker) AcquireAbove(ctx context.Context, key string, ttl time.Duration, floor int64) (*Lock, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	fence, err := acquireScript.Run(l.client, []string{key, key + fenceSuffix}, token, ttl.Milliseconds(), floor).Int64()
+	if err != nil {
+		return nil, err
+	}
+	if fence == 0 {
+		return nil, ErrNotAcquired
+	}
+
+	lockCtx, cancel := context.WithCancel(ctx)
+	lock := &Lock{
+		client: l.client,
+		key:    key,
+		token:  token,
+		fence:  fence,
+		ttl:    ttl,
+		ctx:    lockCtx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go lock.watchdog()
+	return lock, nil
+}
+
+// Token 本次加锁的唯一令牌
+func (lk *Lock) Token() string {
+	return lk.token
+}
+
+// Fence 本次加锁的栅栏令牌，同一个Key上单调递增
+func (lk *Lock) Fence() int64 {
+	return lk.fence
+}
+
+// Context 锁丢失或释放后取消的上下文，临界区内的操作应使用它
+func (lk *Lock) Context() context.Context {
+	return lk.ctx
+}
+
+// Renew 立即续期一次
+func (lk *Lock) Renew() error {
+	ok, err := renewScript.Run(lk.client, []string{lk.key}, lk.token, lk.ttl.Milliseconds()).Int64()
+	if err != nil {
+		return err
+	}
+	if ok == 0 {
+		return ErrNotHeld
+	}
+	return nil
+}
+
+// Release 停止续期并释放锁，锁已不属于本持有者时返回 ErrNotHeld
+func (lk *Lock) Release() error {
+	lk.stop()
+
+	ok, err := releaseScript.Run(lk.client, []string{lk.key}, lk.token).Int64()
+	if err != nil {
+		return err
+	}
+	if ok == 0 {
+		return ErrNotHeld
+	}
+	return nil
+}
+
+// stop 停止续期协程并取消上下文
+func (lk *Lock) stop() {
+	lk.once.Do(lk.cancel)
+	<-lk.done
+}
+
+// watchdog 后台续期，直到锁释放或丢失
+func (lk *Lock) watchdog() {
+	defer close(lk.done)
+
+	ticker := time.NewTicker(lk.ttl / 3)
+	defer ticker.Stop()
+
+	lastRenewed := time.Now()
+	for {
+		select {
+		case <-ticker.C:
+			err := lk.Renew()
+			if err == nil {
+				lastRenewed = time.Now()
+				continue
+			}
+			if errors.Is(err, ErrNotHeld) || time.Since(lastRenewed) >= lk.ttl {
+				log.Printf("[redislock] 锁 %s 已丢失: %v", lk.key, err)
+				lk.once.Do(lk.cancel)
+				return
+			}
+			log.Printf("[redislock] 锁 %s 续期失败，稍后重试: %v", lk.key, err)
+		case <-lk.ctx.Done():
+			return
+		}
+	}
+}
+
+// newToken 生成随机令牌
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,151 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+)
+
+// newTestLocker 启动内存中的Redis并返回锁客户端
+func newTestLocker(t *testing.T) (*Locker, *redis.Client, *miniredis.Miniredis, string) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return New(client), client, server, "redislock:test:" + t.Name()
+}
+
+// 测试互斥、比较令牌释放以及栅栏令牌递增
+func TestAcquireRelease(t *testing.T) {
+	locker, client, _, key := newTestLocker(t)
+	ctx := context.Background()
+
+	first, err := locker.Acquire(ctx, key, time.Second)
+	if err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+	if _, err := locker.Acquire(ctx, key, time.Second); !errors.Is(err, ErrNotAcquired) {
+		t.Fatalf("Expected ErrNotAcquired, got %v", err)
+	}
+	if err := first.Release(); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	if first.Context().Err() == nil {
+		t.Error("Expected lock context to be canceled after release")
+	}
+
+	second, err := locker.Acquire(ctx, key, time.Second)
+	if err != nil {
+		t.Fatalf("acquire after release failed: %v", err)
+	}
+	defer second.Release()
+	if second.Fence() <= first.Fence() {
+		t.Errorf("Expected fence to increase, got %d then %d", first.Fence(), second.Fence())
+	}
+	if second.Token() == first.Token() {
+		t.Error("Expected unique tokens")
+	}
+
+	// 旧持有者再次释放不能删除新持有者的锁
+	if err := first.Release(); !errors.Is(err, ErrNotHeld) {
+		t.Errorf("Expected ErrNotHeld, got %v", err)
+	}
+	if v, _ := client.Get(key).Result(); v != second.Token() {
+		t.Errorf("Expected lock held by second holder, got %q", v)
+	}
+}
+
+// 测试持锁时间超过ttl时后台自动续期
+func TestWatchdogRenews(t *testing.T) {
+	locker, _, server, key := newTestLocker(t)
+	ctx := context.Background()
+
+	lock, err := locker.Acquire(ctx, key, 300*time.Millisecond)
+	if err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+	// 内存Redis的过期时间只随 FastForward 推进，续期后剩余时间恢复为ttl
+	server.FastForward(200 * time.Millisecond)
+	time.Sleep(250 * time.Millisecond)
+	server.FastForward(200 * time.Millisecond)
+
+	if lock.Context().Err() != nil {
+		t.Fatal("Expected lock to be kept alive by watchdog")
+	}
+	if _, err := locker.Acquire(ctx, key, time.Second); !errors.Is(err, ErrNotAcquired) {
+		t.Errorf("Expected ErrNotAcquired while renewed, got %v", err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Errorf("release failed: %v", err)
+	}
+}
+
+// 测试锁被他人夺走后上下文被取消
+func TestLockLostCancelsContext(t *testing.T) {
+	locker, client, _, key := newTestLocker(t)
+	ctx := context.Background()
+
+	lock, err := locker.Acquire(ctx, key, 300*time.Millisecond)
+	if err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+	client.Set(key, "someone-else", time.Second)
+
+	select {
+	case <-lock.Context().Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected lock context to be canceled after losing the lock")
+	}
+	if err := lock.Release(); !errors.Is(err, ErrNotHeld) {
+		t.Errorf("Expected ErrNotHeld, got %v", err)
+	}
+}
+
+// 测试Redis清空后栅栏令牌计数器从1重新开始，传入下限后新令牌仍大于已写入的令牌
+func TestFenceFloorAfterReset(t *testing.T) {
+	locker, _, server, key := newTestLocker(t)
+	ctx := context.Background()
+
+	var last int64
+	for i := 0; i < 3; i++ {
+		lock, err := locker.Acquire(ctx, key, time.Second)
+		if err != nil {
+			t.Fatalf("acquire failed: %v", err)
+		}
+		last = lock.Fence()
+		lock.Release()
+	}
+
+	server.FlushAll()
+	lock, err := locker.Acquire(ctx, key, time.Second)
+	if err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+	if lock.Fence() != 1 {
+		t.Errorf("Expected counter to restart after flush, got %d", lock.Fence())
+	}
+	lock.Release()
+
+	lock, err = locker.AcquireAbove(ctx, key, time.Second, last)
+	if err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+	if lock.Fence() != last+1 {
+		t.Errorf("Expected fence %d above floor, got %d", last+1, lock.Fence())
+	}
+	lock.Release()
+
+	// 计数器已超过下限后继续递增
+	lock, err = locker.AcquireAbove(ctx, key, time.Second, 1)
+	if err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+	defer lock.Release()
+	if lock.Fence() != last+2 {
+		t.Errorf("Expected fence %d, got %d", last+2, lock.Fence())
+	}
+}
@@ -0,0 +1,153 @@
+package signer
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"claimask/pkg/dogechain"
+	"claimask/pkg/policy"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+// signPath 签名接口路径
+const signPath = "/v1/sign"
+
+// signRequest 签名请求
+type signRequest struct {
+	Address  string          `json:"address"`
+	Tx       string          `json:"tx"` // 未签名交易（十六进制）
+	Prevouts []prevoutRecord `json:"prevouts"`
+}
+
+// prevoutRecord 输入花费的输出
+type prevoutRecord struct {
+	Value    int64  `json:"value"`
+	PkScript string `json:"pkScript"` // 十六进制
+}
+
+// 签名失败的分类，远程签名据此还原为对应的错误，调用方可区分稍后重试和永久拒绝
+const (
+	codeDenied          = "denied"           // 违反转出策略，重试也不会通过
+	codeLimited         = "limited"          // 超过每日额度或频率上限
+	codePendingApproval = "pending_approval" // 等待人工审批
+	codeUnknownKey      = "unknown_key"      // 签名进程没有该地址的私钥
+)
+
+// signResponse 签名结果，失败时只有 Error 和 Code
+type signResponse struct {
+	Tx    string `json:"tx,omitempty"`
+	Error string `json:"error,omitempty"`
+	Code  string `json:"code,omitempty"`
+}
+
+// Remote 通过HTTP调用签名进程
+type Remote struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewRemote 创建远程签名客户端，url 为签名进程地址，token 为签名进程的访问令牌
+func NewRemote(url, token string, timeout time.Duration) *Remote {
+	return &Remote{
+		url:    strings.TrimRight(url, "/"),
+		token:  token,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// SignTx 发送签名请求，签名进程没有私钥时返回 ErrUnknownKey
+// 策略拒绝时返回 ErrRejected，并按签名进程返回的分类包装 policy.ErrDenied、policy.ErrLimited 或 policy.ErrPendingApproval
+func (r *Remote) SignTx(ctx context.Context, address string, tx *wire.MsgTx, prevouts []dogechain.Prevout) (*wire.MsgTx, error) {
+	txHex, err := dogechain.EncodeTx(tx)
+	if err != nil {
+		return nil, err
+	}
+	req := signRequest{Address: address, Tx: txHex, Prevouts: make([]prevoutRecord, 0, len(prevouts))}
+	for _, p := range prevouts {
+		req.Prevouts = append(req.Prevouts, prevoutRecord{Value: p.Value, PkScript: hex.EncodeToString(p.PkScript)})
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url+signPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+r.token)
+
+	resp, err := r.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("signer: request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result signResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return nil, fmt.Errorf("signer: bad response (status %d): %w", resp.StatusCode, err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusForbidden:
+		return nil, rejection(result)
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, strings.TrimPrefix(result.Error, ErrUnknownKey.Error()+": "))
+	default:
+		return nil, fmt.Errorf("signer: status %d: %s", resp.StatusCode, result.Error)
+	}
+
+	signed, err := dogechain.DecodeTx(result.Tx)
+	if err != nil {
+		return nil, fmt.Errorf("signer: bad signed tx: %w", err)
+	}
+	if signed.TxHash() == tx.TxHash() || !sameOutputs(tx, signed) {
+		return nil, errors.New("signer: signed tx does not match the request")
+	}
+	return signed, nil
+}
+
+// rejection 将签名进程的策略拒绝还原为与进程内签名相同的错误，没有分类时按永久拒绝处理
+func rejection(result signResponse) error {
+	var cause error
+	switch result.Code {
+	case codeLimited:
+		cause = policy.ErrLimited
+	case codePendingApproval:
+		cause = policy.ErrPendingApproval
+	default:
+		cause = policy.ErrDenied
+	}
+	msg := strings.TrimPrefix(result.Error, ErrRejected.Error()+": ")
+	return fmt.Errorf("%w: %w: %s", ErrRejected, cause, strings.TrimPrefix(msg, cause.Error()+": "))
+}
+
+// sameOutputs 签名后的交易只能增加签名脚本，输入和输出不能被改动
+func sameOutputs(unsigned, signed *wire.MsgTx) bool {
+	if len(unsigned.TxIn) != len(signed.TxIn) || len(unsigned.TxOut) != len(signed.TxOut) {
+		return false
+	}
+	for i := range unsigned.TxIn {
+		if unsigned.TxIn[i].PreviousOutPoint != signed.TxIn[i].PreviousOutPoint {
+			return false
+		}
+	}
+	for i := range unsigned.TxOut {
+		if unsigned.TxOut[i].Value != signed.TxOut[i].Value ||
+			!bytes.Equal(unsigned.TxOut[i].PkScript, signed.TxOut[i].PkScript) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+package signer
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"claimask/pkg/dogechain"
+	"claimask/pkg/policy"
+
+	"go.uber.org/zap"
+)
+
+// maxRequestSize 签名请求体上限
+const maxRequestSize = 1 << 20
+
+// NewHandler 签名进程的HTTP接口：POST /v1/sign 签名，GET /healthz 健康检查
+// 签名请求需携带 Authorization: Bearer <token>
+func NewHandler(s Signer, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc(signPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeResult(w, http.StatusMethodNotAllowed, signResponse{Error: "method not allowed"})
+			return
+		}
+		if !validToken(r.Header.Get("Authorization"), token) {
+			writeResult(w, http.StatusUnauthorized, signResponse{Error: "unauthorized"})
+			return
+		}
+
+		var req signRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestSize)).Decode(&req); err != nil {
+			writeResult(w, http.StatusBadRequest, signResponse{Error: "bad request body"})
+			return
+		}
+		tx, err := dogechain.DecodeTx(req.Tx)
+		if err != nil {
+			writeResult(w, http.StatusBadRequest, signResponse{Error: "bad tx"})
+			return
+		}
+		prevouts := make([]dogechain.Prevout, 0, len(req.Prevouts))
+		for _, p := range req.Prevouts {
+			script, err := hex.DecodeString(p.PkScript)
+			if err != nil {
+				writeResult(w, http.StatusBadRequest, signResponse{Error: "bad prevout script"})
+				return
+			}
+			prevouts = append(prevouts, dogechain.Prevout{Value: p.Value, PkScript: script})
+		}
+
+		signed, err := s.SignTx(r.Context(), req.Address, tx, prevouts)
+		if err != nil {
+			status, code := failure(err)
+			zap.L().Warn("签名请求失败", zap.String("address", req.Address), zap.String("txid", tx.TxHash().String()),
+				zap.String("code", code), zap.Error(err))
+			writeResult(w, status, signResponse{Error: err.Error(), Code: code})
+			return
+		}
+
+		signedHex, err := dogechain.EncodeTx(signed)
+		if err != nil {
+			writeResult(w, http.StatusInternalServerError, signResponse{Error: err.Error()})
+			return
+		}
+		zap.L().Info("签名完成", zap.String("address", req.Address), zap.String("txid", signed.TxHash().String()))
+		writeResult(w, http.StatusOK, signResponse{Tx: signedHex})
+	})
+	return mux
+}
+
+// failure 签名失败对应的HTTP状态和分类
+func failure(err error) (int, string) {
+	switch {
+	case errors.Is(err, ErrUnknownKey):
+		return http.StatusNotFound, codeUnknownKey
+	case !errors.Is(err, ErrRejected):
+		return http.StatusInternalServerError, ""
+	case errors.Is(err, policy.ErrLimited):
+		return http.StatusForbidden, codeLimited
+	case errors.Is(err, policy.ErrPendingApproval):
+		return http.StatusForbidden, codePendingApproval
+	}
+	return http.StatusForbidden, codeDenied
+}
+
+// validToken 常量时间比较访问令牌，令牌为空时拒绝所有请求
+func validToken(header, token string) bool {
+	got, ok := strings.CutPrefix(header, "Bearer ")
+	return ok && token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+func writeResult(w http.ResponseWriter, status int, result signResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(result)
+}
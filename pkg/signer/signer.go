@@ -0,0 +1,78 @@
+// Package signer 交易签名
+// 业务代码只持有 Signer，不接触私钥：Local 在进程内通过 keystore 解密私钥签名，
+// Remote 将签名请求发给独立的签名进程（cmd/signerd），私钥和转出策略都只存在于签名进程中
+package signer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"claimask/pkg/dogechain"
+	"claimask/pkg/keystore"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+var (
+	// ErrRejected 签名请求被转出策略拒绝
+	ErrRejected = errors.New("signer: rejected by policy")
+	// ErrUnknownKey 签名方没有该地址的私钥
+	ErrUnknownKey = errors.New("signer: unknown key")
+)
+
+// Signer 交易签名
+type Signer interface {
+	// SignTx 用 address 的私钥签名 tx 的所有输入，prevouts 与输入一一对应，返回签名后的交易，不修改 tx
+	SignTx(ctx context.Context, address string, tx *wire.MsgTx, prevouts []dogechain.Prevout) (*wire.MsgTx, error)
+}
+
+// Policy 签名前的转出策略
+type Policy interface {
+	// Authorize 校验并预留本次转出的额度，返回错误时拒绝签名；签名失败的请求同样占用额度，由调用方归还
+	Authorize(address string, tx *wire.MsgTx, prevouts []dogechain.Prevout) error
+}
+
+// Local 进程内签名
+type Local struct {
+	keys   keystore.Keystore
+	policy Policy
+}
+
+// NewLocal 创建进程内签名，私钥按地址从 keys 获取，policy 为nil时不限制转出
+func NewLocal(keys keystore.Keystore, policy Policy) *Local {
+	return &Local{keys: keys, policy: policy}
+}
+
+// SignTx 校验转出策略后解密私钥签名，私钥在签名后清零
+func (l *Local) SignTx(ctx context.Context, address string, tx *wire.MsgTx, prevouts []dogechain.Prevout) (*wire.MsgTx, error) {
+	if len(prevouts) != len(tx.TxIn) {
+		return nil, fmt.Errorf("signer: got %d prevouts for %d inputs", len(prevouts), len(tx.TxIn))
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	signed := tx.Copy()
+	err := l.keys.Use(address, func(key []byte) error {
+		// 确认持有私钥后再校验策略，没有私钥的请求不占用额度
+		if l.policy != nil {
+			if err := l.policy.Authorize(address, tx, prevouts); err != nil {
+				return fmt.Errorf("%w: %w", ErrRejected, err)
+			}
+		}
+		wif, err := dogechain.DecodeWIF(key)
+		if err != nil {
+			return fmt.Errorf("signer: bad key for %s: %w", address, err)
+		}
+		defer wif.PrivKey.Zero()
+		return dogechain.SignP2PKH(signed, prevouts, wif)
+	})
+	if errors.Is(err, keystore.ErrKeyNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return signed, nil
+}
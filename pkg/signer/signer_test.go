@@ -0,0 +1,177 @@
+package signer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"claimask/pkg/dogechain"
+	"claimask/pkg/keystore"
+	"claimask/pkg/policy"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// newTestKeys 生成一个热钱包，返回地址和保存其私钥的 keystore
+func newTestKeys(t *testing.T) (string, keystore.Keystore) {
+	t.Helper()
+	key, err := btcec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wif, err := btcutil.NewWIF(key, &dogechain.DogeMainNetParams, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(wif.SerializePubKey()), &dogechain.DogeMainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, _ := keystore.GenerateMasterKey("k1")
+	keyring, err := keystore.ParseKeyring(entry, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := keystore.New(keyring, keystore.NewMemoryBackend(nil))
+	if err := store.Put(addr.EncodeAddress(), []byte(wif.String())); err != nil {
+		t.Fatal(err)
+	}
+	return addr.EncodeAddress(), store
+}
+
+// newPayout 构建一笔从 sender 转出 amount 的未签名交易
+func newPayout(t *testing.T, sender string, amount int64) *dogechain.PayoutTx {
+	t.Helper()
+	receiver, _ := newTestKeys(t)
+	payout, err := dogechain.BuildPayoutTx(sender,
+		[]dogechain.UTXO{{TxHash: "a1075db55d416d3ca199f55b6084e2115b9345e16c5cf302fc80e9d5fbf5d48d", Value: 1000000000}},
+		[]dogechain.PayOutput{{Address: receiver, Value: amount}}, 1000, 100000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payout
+}
+
+// verify 用脚本引擎校验第一个输入的签名
+func verify(t *testing.T, tx *wire.MsgTx, prevout dogechain.Prevout) {
+	t.Helper()
+	fetcher := txscript.NewCannedPrevOutputFetcher(prevout.PkScript, prevout.Value)
+	vm, err := txscript.NewEngine(prevout.PkScript, tx, 0, txscript.StandardVerifyFlags, nil,
+		txscript.NewTxSigHashes(tx, fetcher), prevout.Value, fetcher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.Execute(); err != nil {
+		t.Errorf("Signature verification failed: %v", err)
+	}
+}
+
+// 测试进程内签名：签名有效，不修改原交易，未知地址和超额转出被拒绝
+func TestLocalSigner(t *testing.T) {
+	sender, keys := newTestKeys(t)
+	local := NewLocal(keys, policy.NewEngine(policy.Rules{MaxTx: 300000000, DailyPerWallet: 500000000}, nil))
+	payout := newPayout(t, sender, 200000000)
+
+	signed, err := local.SignTx(context.Background(), sender, payout.Tx, payout.Prevouts)
+	if err != nil {
+		t.Fatalf("SignTx failed: %v", err)
+	}
+	if len(payout.Tx.TxIn[0].SignatureScript) != 0 {
+		t.Error("Expected the request tx to be left unsigned")
+	}
+	verify(t, signed, payout.Prevouts[0])
+
+	other, _ := newTestKeys(t)
+	if _, err := local.SignTx(context.Background(), other, payout.Tx, payout.Prevouts); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected ErrUnknownKey, got %v", err)
+	}
+	if _, err := local.SignTx(context.Background(), sender, payout.Tx, nil); err == nil {
+		t.Error("Expected prevout count mismatch error")
+	}
+
+	big := newPayout(t, sender, 400000000)
+	if _, err := local.SignTx(context.Background(), sender, big.Tx, big.Prevouts); !errors.Is(err, ErrRejected) {
+		t.Errorf("Expected per-tx limit to reject, got %v", err)
+	}
+	second := newPayout(t, sender, 200000000)
+	if _, err := local.SignTx(context.Background(), sender, second.Tx, second.Prevouts); err != nil {
+		t.Errorf("Expected rejected transfer not to count, got %v", err)
+	}
+	third := newPayout(t, sender, 200000000)
+	if _, err := local.SignTx(context.Background(), sender, third.Tx, third.Prevouts); !errors.Is(err, ErrRejected) {
+		t.Errorf("Expected daily limit to reject the third 2 DOGE transfer, got %v", err)
+	}
+}
+
+// 测试远程签名经由签名进程的HTTP接口完成，错误类型和访问令牌校验正确
+// 策略拒绝还原为与进程内签名相同的策略错误
+func TestRemoteSigner(t *testing.T) {
+	sender, keys := newTestKeys(t)
+	engine := policy.NewEngine(policy.Rules{MaxTx: 300000000, DailyPerWallet: 300000000}, policy.NewMemoryStore())
+	server := httptest.NewServer(NewHandler(NewLocal(keys, engine), "secret-token"))
+	defer server.Close()
+
+	remote := NewRemote(server.URL, "secret-token", 5*time.Second)
+	payout := newPayout(t, sender, 200000000)
+	signed, err := remote.SignTx(context.Background(), sender, payout.Tx, payout.Prevouts)
+	if err != nil {
+		t.Fatalf("SignTx failed: %v", err)
+	}
+	verify(t, signed, payout.Prevouts[0])
+
+	big := newPayout(t, sender, 400000000)
+	if _, err := remote.SignTx(context.Background(), sender, big.Tx, big.Prevouts); !errors.Is(err, ErrRejected) || !errors.Is(err, policy.ErrDenied) {
+		t.Errorf("Expected ErrRejected wrapping ErrDenied, got %v", err)
+	}
+	second := newPayout(t, sender, 200000000)
+	if _, err := remote.SignTx(context.Background(), sender, second.Tx, second.Prevouts); !errors.Is(err, ErrRejected) || !errors.Is(err, policy.ErrLimited) {
+		t.Errorf("Expected ErrRejected wrapping ErrLimited, got %v", err)
+	}
+	other, _ := newTestKeys(t)
+	if _, err := remote.SignTx(context.Background(), other, payout.Tx, payout.Prevouts); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected ErrUnknownKey, got %v", err)
+	}
+	if _, err := NewRemote(server.URL, "wrong", time.Second).SignTx(context.Background(), sender, payout.Tx, payout.Prevouts); err == nil {
+		t.Error("Expected wrong token to be rejected")
+	}
+
+	resp, err := http.Get(server.URL + "/healthz")
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("Unexpected healthz response %v %v", resp, err)
+	}
+	resp.Body.Close()
+}
+
+// 测试签名进程返回被改动的交易时远程签名拒绝使用
+func TestRemoteSignerRejectsTamperedTx(t *testing.T) {
+	sender, keys := newTestKeys(t)
+	local := NewLocal(keys, nil)
+	tamper := signerFunc(func(ctx context.Context, address string, tx *wire.MsgTx, prevouts []dogechain.Prevout) (*wire.MsgTx, error) {
+		signed, err := local.SignTx(ctx, address, tx, prevouts)
+		if err == nil {
+			signed.TxOut[0].Value++
+		}
+		return signed, err
+	})
+	server := httptest.NewServer(NewHandler(tamper, "t"))
+	defer server.Close()
+
+	payout := newPayout(t, sender, 200000000)
+	if _, err := NewRemote(server.URL, "t", time.Second).SignTx(context.Background(), sender, payout.Tx, payout.Prevouts); err == nil {
+		t.Error("Expected tampered tx to be rejected")
+	}
+}
+
+// signerFunc 函数形式的 Signer
+type signerFunc func(ctx context.Context, address string, tx *wire.MsgTx, prevouts []dogechain.Prevout) (*wire.MsgTx, error)
+
+func (f signerFunc) SignTx(ctx context.Context, address string, tx *wire.MsgTx, prevouts []dogechain.Prevout) (*wire.MsgTx, error) {
+	return f(ctx, address, tx, prevouts)
+}
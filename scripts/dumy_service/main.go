@@ -3,19 +3,89 @@ package main
 import (
 	"log"
 	"net/http"
+	"os"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"claimask/pkg/claimsig"
+	"claimask/pkg/money"
 )
 
+// 签名密钥与 richx.chainSign.keys 保持一致，格式 "id1:secret1,id2:secret2"
+const defaultSignKeys = "dev:claimask-dev-sign-key-please-change"
+
+// maxSkew 允许的请求时间戳偏差
+const maxSkew = 5 * time.Minute
+
+// claimRequest 领取请求
+type claimRequest struct {
+	Address   string       `json:"address"`
+	Amount    money.Amount `json:"amount"`
+	OrderID   string       `json:"orderId"`
+	Timestamp int64        `json:"timestamp"`
+	KeyID     string       `json:"keyId"`
+	Signature string       `json:"signature"`
+}
+
+// claimRecord 已受理的领取，按订单号保存，重复提交返回同一结果
+type claimRecord struct {
+	Status string `json:"status"`
+	TxID   string `json:"txid"`
+}
+
+// claimStore 内存中的领取记录
+type claimStore struct {
+	mu      sync.Mutex
+	records map[string]claimRecord
+}
+
+// getOrCreate 返回订单已有的记录，没有时发放并记录
+func (s *claimStore) getOrCreate(orderID string) (claimRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if record, ok := s.records[orderID]; ok {
+		return record, true
+	}
+	record := claimRecord{
+		Status: "confirmed",
+		TxID:   "0x" + time.Now().Format("20060102150405") + "000000000000000000000000",
+	}
+	s.records[orderID] = record
+	return record, false
+}
+
+// get 查询订单的领取记录
+func (s *claimStore) get(orderID string) (claimRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[orderID]
+	return record, ok
+}
+
 func main() {
 	// 初始化日志
 	logger := log.New(log.Writer(), "MockChainService: ", log.LstdFlags)
 
+	signKeys := os.Getenv("CLAIM_SIGN_KEYS")
+	if signKeys == "" {
+		signKeys = defaultSignKeys
+	}
+	keys, err := claimsig.ParseKeys(signKeys)
+	if err != nil {
+		logger.Fatalf("解析签名密钥失败: %v", err)
+	}
+	keyring, err := claimsig.NewKeyring("", keys)
+	if err != nil {
+		logger.Fatalf("初始化签名密钥失败: %v", err)
+	}
+
+	store := &claimStore{records: make(map[string]claimRecord)}
 	router := gin.Default()
 
 	router.POST("/claim", func(c *gin.Context) {
-		var req map[string]interface{}
+		var req claimRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
 			logger.Printf("解析请求参数失败: %v", err)
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -27,26 +97,64 @@ func main() {
 
 		// 打印接收到的参数
 		logger.Printf("接收到领取请求参数:")
-		logger.Printf("钱包地址: %v", req["address"])
-		logger.Printf("领取数额: %v", req["amount"])
-		logger.Printf("订单号: %v", req["orderId"])
-		logger.Printf("签名: %v", req["sha"])
-		logger.Printf("发送方: %v", req["from"])
-		logger.Printf("NFT UTXO: %v", req["nftUtxo"])
+		logger.Printf("钱包地址: %v", req.Address)
+		logger.Printf("领取数额: %v", req.Amount)
+		logger.Printf("订单号: %v", req.OrderID)
+		logger.Printf("签名: %v (key %v, timestamp %v)", req.Signature, req.KeyID, req.Timestamp)
 
-		// 打印收益发放信息
-		logger.Printf("已向地址 %v 发放收益 %v，订单号: %v", req["address"], req["amount"], req["orderId"])
+		claim := claimsig.Claim{Address: req.Address, Amount: req.Amount, OrderID: req.OrderID, Timestamp: req.Timestamp}
+		if err := keyring.Verify(claim, req.KeyID, req.Signature, time.Now(), maxSkew); err != nil {
+			logger.Printf("签名校验失败: %v", err)
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": "签名校验失败",
+			})
+			return
+		}
+
+		// 幂等键必须与订单号一致，同一订单重复提交只发放一次
+		if key := c.GetHeader("Idempotency-Key"); key != "" && key != req.OrderID {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "Idempotency-Key 与订单号不一致",
+			})
+			return
+		}
+		record, existed := store.getOrCreate(req.OrderID)
+		if existed {
+			logger.Printf("订单 %v 重复提交，返回已有结果", req.OrderID)
+		} else {
+			// 打印收益发放信息
+			logger.Printf("已向地址 %v 发放收益 %v，订单号: %v", req.Address, req.Amount, req.OrderID)
+		}
 
 		// 返回成功响应
 		c.JSON(http.StatusOK, gin.H{
 			"code":    200,
 			"message": "收益发放成功",
 			"data": gin.H{
-				"txid": "0x" + time.Now().Format("20060102150405") + "000000000000000000000000",
+				"txid": record.TxID,
 			},
 		})
 	})
 
+	// 查询订单发放状态，供对账任务使用
+	router.GET("/claim/:orderId", func(c *gin.Context) {
+		record, ok := store.get(c.Param("orderId"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{
+				"code":    404,
+				"message": "订单不存在",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"code":    200,
+			"message": "success",
+			"data":    record,
+		})
+	})
+
 	// 启动服务
 	logger.Printf("模拟链上服务启动在 http://localhost:7777")
 	if err := router.Run(":7777"); err != nil {
@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
@@ -16,9 +17,10 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis"
 	"github.com/google/uuid"
-	"github.com/jinzhu/gorm"
-	_ "github.com/jinzhu/gorm/dialects/mysql"
 	"github.com/spf13/viper"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
 )
 
 // 配置结构体
@@ -70,7 +72,7 @@ const (
 
 // RichRewardLog 对应Java中的RichRewardLogDo
 type RichRewardLog struct {
-	ID           uint       `gorm:"primary_key"`
+	ID           uint       `gorm:"primaryKey"`
 	Address      string     `gorm:"column:address;type:varchar(64)"`
 	TotalReward  *big.Float `gorm:"column:total_reward;type:decimal(65,18)"`
 	UpdateReward time.Time  `gorm:"column:update_reward"`
@@ -84,7 +86,7 @@ func (RichRewardLog) TableName() string {
 
 // ClaimOrder 对应Java中的OrderDo
 type ClaimOrder struct {
-	ID        uint       `gorm:"primary_key"`
+	ID        uint       `gorm:"primaryKey"`
 	OrderID   int64      `gorm:"column:order_id;unique"`
 	Address   string     `gorm:"column:address;type:varchar(64)"`
 	Amount    *big.Float `gorm:"column:amount;type:decimal(65,18)"`
@@ -191,14 +193,19 @@ func initDB(cfg *Config) (*DB, error) {
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/richx?charset=utf8mb4&parseTime=True&loc=Local",
 		cfg.MySQL.User, cfg.MySQL.Password, cfg.MySQL.Host, cfg.MySQL.Port)
 
-	db, err := gorm.Open("mysql", dsn)
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
+		Logger: gormlogger.Default.LogMode(gormlogger.Info),
+	})
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
 	if err != nil {
 		return nil, err
 	}
-	db.LogMode(true)
-	db.DB().SetMaxIdleConns(10)
-	db.DB().SetMaxOpenConns(100)
-	db.DB().SetConnMaxLifetime(time.Hour)
+	sqlDB.SetMaxIdleConns(10)
+	sqlDB.SetMaxOpenConns(100)
+	sqlDB.SetConnMaxLifetime(time.Hour)
 	return &DB{db}, nil
 }
 
@@ -316,7 +323,7 @@ func (r *RedisClient) Close() error {
 func (s *ClaimService) CanClaim(address string) (bool, *big.Float, error) {
 	var rewardLog RichRewardLog
 	if err := s.db.Where("address = ?", address).First(&rewardLog).Error; err != nil {
-		if gorm.IsRecordNotFoundError(err) {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return false, big.NewFloat(0), nil
 		}
 		return false, nil, err
@@ -607,7 +614,11 @@ func main() {
 	if err != nil {
 		logger.Fatalf("初始化数据库失败: %v", err)
 	}
-	defer db.Close()
+	defer func() {
+		if sqlDB, err := db.DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}()
 
 	// 初始化Redis客户端
 	redisClient, err := initRedis(cfg)
@@ -5,6 +5,9 @@ import (
 	"log"
 	"sync"
 	"time"
+
+	"claimask/pkg/policy"
+	"claimask/pkg/signer"
 )
 
 // 通用日志函数，替代JS中的logger
@@ -18,33 +21,35 @@ func sleep(duration time.Duration) {
 }
 
 // 处理函数的类型定义
-type ProcessFunc func(address string, privateKey string, messages []map[string]interface{})
+type ProcessFunc func(address string, txSigner signer.Signer, messages []map[string]interface{})
 type QueueProcessFunc func(args ...interface{}) error
 
 // SlowSpeedBox 带速率限制和地址过滤的延时队列处理器
 // 功能特点：
 // 1. 每分钟批量处理一次队列
 // 2. 24小时地址去重
-// 3. 单次交易金额限制
+// 3. 入队前按转出策略预检
 type SlowSpeedBox struct {
 	addressQueue []map[string]interface{} // 等待处理的地址队列
 	banAddress   []string                 // 24小时内禁止重复操作的地址列表
 	fun          ProcessFunc              // 依赖注入的实际业务处理函数
 	address      string                   // 发送方钱包地址
-	privateKey   string                   // 发送方私钥
+	signer       signer.Signer            // 发送方签名方，私钥不经过队列
+	policy       policy.Screener          // 入队前的转出策略预检，为nil时不预检
 	sender       *time.Ticker             // 定时处理器
 	bander       *time.Ticker             // 清空禁止列表的定时器
 	mutex        sync.Mutex               // 互斥锁保护队列操作
 }
 
 // NewSlowSpeedBox 创建一个新的SlowSpeedBox实例
-func NewSlowSpeedBox(fun ProcessFunc, address, privateKey string) *SlowSpeedBox {
+func NewSlowSpeedBox(fun ProcessFunc, address string, txSigner signer.Signer, screener policy.Screener) *SlowSpeedBox {
 	box := &SlowSpeedBox{
 		addressQueue: make([]map[string]interface{}, 0),
 		banAddress:   make([]string, 0),
 		fun:          fun,
 		address:      address,
-		privateKey:   privateKey,
+		signer:       txSigner,
+		policy:       screener,
 		mutex:        sync.Mutex{},
 	}
 
@@ -72,7 +77,7 @@ func NewSlowSpeedBox(fun ProcessFunc, address, privateKey string) *SlowSpeedBox
 					}
 				}()
 
-				box.fun(box.address, box.privateKey, queue)
+				box.fun(box.address, box.signer, queue)
 				for _, group := range queue {
 					logMessage("成功发送", group["amount"], "$doge给地址", group["address"])
 				}
@@ -99,17 +104,20 @@ func (b *SlowSpeedBox) Enqueue(message map[string]interface{}) error {
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
 
-	// 安全校验层
-	value, ok := message["value"].(float64)
-	if ok && value > 5000 {
-		return errors.New("失败，单次收益领取大于5000")
-	}
-
 	address, ok := message["address"].(string)
 	if !ok {
 		return errors.New("消息缺少有效的address字段")
 	}
 
+	// 安全校验层：按转出策略预检
+	if b.policy != nil {
+		value, _ := message["value"].(float64)
+		ref, _ := message["ref"].(string)
+		if err := b.policy.Screen(b.address, policy.Payout{Ref: ref, To: address, Value: int64(value)}); err != nil {
+			return err
+		}
+	}
+
 	// 检查是否在禁止列表中
 	for _, banned := range b.banAddress {
 		if banned == address {
@@ -137,23 +145,23 @@ func (b *SlowSpeedBox) Enqueue(message map[string]interface{}) error {
 // 2. 固定15秒间隔执行
 // 3. 自动队列延续
 type SlowSpeedQueue struct {
-	queue      [][]interface{}  // 任务存储队列
-	isPending  bool             // 处理状态锁
-	fun        QueueProcessFunc // 依赖注入的业务函数
-	address    string           // 相关地址
-	privateKey string           // 相关私钥
-	mutex      sync.Mutex       // 互斥锁保护队列操作
+	queue     [][]interface{}  // 任务存储队列
+	isPending bool             // 处理状态锁
+	fun       QueueProcessFunc // 依赖注入的业务函数
+	address   string           // 相关地址
+	signer    signer.Signer    // 相关签名方
+	mutex     sync.Mutex       // 互斥锁保护队列操作
 }
 
 // NewSlowSpeedQueue 创建一个新的SlowSpeedQueue实例
-func NewSlowSpeedQueue(fun QueueProcessFunc, address, privateKey string) *SlowSpeedQueue {
+func NewSlowSpeedQueue(fun QueueProcessFunc, address string, txSigner signer.Signer) *SlowSpeedQueue {
 	return &SlowSpeedQueue{
-		queue:      make([][]interface{}, 0),
-		isPending:  false,
-		fun:        fun,
-		address:    address,
-		privateKey: privateKey,
-		mutex:      sync.Mutex{},
+		queue:     make([][]interface{}, 0),
+		isPending: false,
+		fun:       fun,
+		address:   address,
+		signer:    txSigner,
+		mutex:     sync.Mutex{},
 	}
 }
 
@@ -0,0 +1,3 @@
+drop table if exists rich_reward_log;
+
+drop table if exists order_id;
@@ -1,5 +1,6 @@
--- auto-generated definition
-create table order_id
+-- 基线表结构，与迁移工具引入前的 sql/init.sql 一致
+-- 已按 init.sql 建表的库执行时跳过建表，只记录版本，后续结构变更见 0002 起的迁移
+create table if not exists order_id
 (
     id          int unsigned auto_increment
         primary key,
@@ -10,16 +11,7 @@ create table order_id
     update_time timestamp default CURRENT_TIMESTAMP null on update CURRENT_TIMESTAMP
 );
 
-
-
-
-
-DELETE FROM order_id where id > 0;
-ALTER TABLE order_id AUTO_INCREMENT = 1;
-
-
--- auto-generated definition
-create table rich_reward_log
+create table if not exists rich_reward_log
 (
     id            bigint auto_increment comment '主键 id'
         primary key,
@@ -36,4 +28,3 @@ create table rich_reward_log
         unique (address)
 )
     comment '会员收益表';
-
@@ -0,0 +1,18 @@
+drop index idx_campaign_status on order_id;
+
+drop index idx_address on order_id;
+
+drop index idx_status on order_id;
+
+alter table order_id
+    drop index uk_order_id,
+    drop column refunded_time,
+    drop column failed_time,
+    drop column confirmed_time,
+    drop column broadcast_time,
+    drop column queued_time,
+    drop column fail_reason,
+    drop column txid,
+    drop column status,
+    modify column json text not null,
+    drop column campaign;
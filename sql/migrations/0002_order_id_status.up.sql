@@ -0,0 +1,22 @@
+-- 领取订单状态机：记录活动、状态、打款交易和各状态的时间，订单号唯一
+alter table order_id
+    add column campaign       varchar(64) default ''        not null comment '活动标识' after address,
+    modify column json        text                          not null comment '订单数据：campaign/amount/eligibilityProof',
+    add column status         varchar(16) default 'created' not null comment '订单状态：created/queued/broadcast/confirmed/failed/refunded' after json,
+    add column txid           varchar(64)                   null comment '打款交易ID' after status,
+    add column fail_reason    varchar(255)                  null comment '失败原因' after txid,
+    add column queued_time    timestamp                     null after fail_reason,
+    add column broadcast_time timestamp                     null after queued_time,
+    add column confirmed_time timestamp                     null after broadcast_time,
+    add column failed_time    timestamp                     null after confirmed_time,
+    add column refunded_time  timestamp                     null after failed_time,
+    add constraint uk_order_id unique (order_id);
+
+create index idx_status
+    on order_id (status);
+
+create index idx_address
+    on order_id (address);
+
+create index idx_campaign_status
+    on order_id (campaign, status);
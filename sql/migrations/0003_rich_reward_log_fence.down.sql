@@ -0,0 +1,2 @@
+alter table rich_reward_log
+    drop column fence_token;
@@ -0,0 +1,3 @@
+-- 领取写入以领取锁的栅栏令牌为条件，防止锁过期后的旧持有者覆盖新数据
+alter table rich_reward_log
+    add column fence_token bigint default 0 not null comment '最近一次领取写入时持有的锁栅栏令牌' after latest_detail;
@@ -0,0 +1,5 @@
+drop table if exists claim_dead_letter;
+
+drop table if exists claim_outbox;
+
+drop table if exists `order`;
@@ -0,0 +1,63 @@
+create table `order`
+(
+    id          int unsigned auto_increment
+        primary key,
+    order_id    bigint                                not null comment '订单号',
+    address     varchar(64)                           not null comment '用户钱包地址',
+    amount      decimal(65, 18)                       not null comment '领取数额',
+    status      tinyint      default 1                not null comment '1:创建 2:处理中 3:已确认 4:失败',
+    txid        varchar(128)                          null comment '链上交易ID',
+    fail_reason varchar(255) default ''               not null comment '链上发放失败原因',
+    created_at  timestamp    default CURRENT_TIMESTAMP not null,
+    updated_at  timestamp    default CURRENT_TIMESTAMP not null on update CURRENT_TIMESTAMP,
+    constraint uk_order_id
+        unique (order_id)
+)
+    comment '领取订单表';
+
+create index idx_status_updated
+    on `order` (status, updated_at);
+
+-- 领取消息外发表，与订单在同一事务中写入，由 OutboxRelay 投递到 Kafka
+create table claim_outbox
+(
+    id            bigint unsigned auto_increment
+        primary key,
+    topic         varchar(128)                        not null comment '目标主题',
+    msg_key       varchar(128)                        not null comment '消息Key',
+    payload       text                                not null comment '消息内容',
+    status        tinyint   default 0                 not null comment '0:待发送 1:已发送',
+    attempts      int       default 0                 not null comment '发送失败次数',
+    last_error    varchar(512)                        null comment '最近一次发送错误',
+    next_retry_at timestamp default CURRENT_TIMESTAMP not null comment '下次可发送时间',
+    sent_at       timestamp                           null comment '发送成功时间',
+    created_at    timestamp default CURRENT_TIMESTAMP not null comment '创建时间'
+)
+    comment '领取消息外发表';
+
+create index idx_status_next_retry
+    on claim_outbox (status, next_retry_at);
+
+create table claim_dead_letter
+(
+    id             bigint unsigned auto_increment
+        primary key,
+    topic          varchar(128)                        not null comment '死信主题',
+    msg_id         varchar(64)                         not null comment '死信在消息队列中的ID',
+    msg_key        varchar(128)                        not null comment '消息Key',
+    payload        text                                not null comment '消息内容',
+    original_topic varchar(128)                        not null comment '最初投递的主题',
+    error_class    varchar(32)                         not null comment '错误分类：invalid/not_found/transient',
+    error_message  varchar(1024)                       null comment '错误信息',
+    retry_count    int       default 0                 not null comment '已重试次数',
+    failed_at      timestamp                           null comment '最后一次失败时间',
+    status         tinyint   default 0                 not null comment '0:待处理 1:已重新投递 2:已丢弃',
+    redriven_at    timestamp                           null comment '处理时间',
+    created_at     timestamp default CURRENT_TIMESTAMP not null comment '存档时间',
+    constraint uk_topic_msg_id
+        unique (topic, msg_id)
+)
+    comment '领取消息死信存档表';
+
+create index idx_status
+    on claim_dead_letter (status);
@@ -0,0 +1,5 @@
+drop table if exists rich_reward_accrual_run;
+
+drop table if exists rich_reward_accrual_log;
+
+drop table if exists rich_nft_snapshot;
@@ -0,0 +1,44 @@
+create table rich_nft_snapshot
+(
+    id            bigint unsigned auto_increment
+        primary key,
+    snapshot_date date        not null comment '快照所属领取日',
+    address       varchar(64) not null comment '持有人钱包地址',
+    nft_id        varchar(64) not null comment 'NFT标识',
+    rarity        varchar(32) not null comment '稀有度',
+    constraint uk_date_nft
+        unique (snapshot_date, nft_id)
+)
+    comment 'NFT每日持有快照';
+
+create index idx_date_address
+    on rich_nft_snapshot (snapshot_date, address);
+
+create table rich_reward_accrual_log
+(
+    id           bigint unsigned auto_increment
+        primary key,
+    accrual_date date                                not null comment '收益所属领取日',
+    address      varchar(64)                         not null comment '用户钱包地址',
+    nft_count    int                                 not null comment '当日持有NFT数量',
+    amount       decimal(9, 1)                       not null comment '当日收益',
+    created_at   timestamp default CURRENT_TIMESTAMP not null,
+    constraint uk_date_address
+        unique (accrual_date, address)
+)
+    comment '每日收益累加明细，保证每个地址每天只累加一次';
+
+create table rich_reward_accrual_run
+(
+    id           bigint unsigned auto_increment
+        primary key,
+    accrual_date date                                not null comment '领取日',
+    status       tinyint   default 0                 not null comment '0:进行中 1:已完成',
+    holders      int       default 0                 not null comment '累加的地址数',
+    total_amount decimal(20, 1) default 0.0          not null comment '累加的收益总额',
+    started_at   timestamp default CURRENT_TIMESTAMP not null,
+    finished_at  timestamp                           null,
+    constraint uk_accrual_date
+        unique (accrual_date)
+)
+    comment '每日收益累加任务';
@@ -0,0 +1,5 @@
+drop table if exists wallet_group;
+
+drop table if exists nft;
+
+drop table if exists utxo;
@@ -0,0 +1,47 @@
+-- 链上交易监控：地址UTXO、NFT持有状态和收款钱包组
+create table utxo
+(
+    id      bigint unsigned auto_increment
+        primary key,
+    address varchar(64)          not null comment '所属地址',
+    tx_hash varchar(64)          not null comment '交易哈希',
+    `index` int unsigned         not null comment '输出序号',
+    value   bigint               not null comment '金额（ELON）',
+    spent   tinyint(1) default 0 not null comment '是否已花费',
+    constraint uk_tx_hash
+        unique (tx_hash)
+)
+    comment '地址UTXO';
+
+create index idx_address
+    on utxo (address);
+
+create index idx_index
+    on utxo (`index`);
+
+create table nft
+(
+    nft_id        varchar(64)             not null comment 'NFT唯一标识'
+        primary key,
+    utxo_hash     varchar(64)             not null comment '承载NFT的UTXO',
+    owner_address varchar(34)             not null comment '当前所有者',
+    tax_status    int          default 0  not null comment '0:未缴税 1:已缴税',
+    tx_amt        bigint       default 0  not null comment '交易金额（ELON）',
+    gtid          varchar(128) default '' not null comment '全局交易标识',
+    constraint uk_utxo_hash
+        unique (utxo_hash)
+)
+    comment 'NFT持有状态';
+
+create table wallet_group
+(
+    group_id       int auto_increment
+        primary key,
+    receive_addr   varchar(34)            not null comment '收款地址',
+    private_key    text                   not null comment '加密存储的私钥',
+    current_utxo   varchar(64) default '' not null comment '当前使用的UTXO',
+    last_sync_time bigint      default 0  not null comment '最后同步时间戳',
+    constraint uk_receive_addr
+        unique (receive_addr)
+)
+    comment '收款钱包组';
@@ -0,0 +1,6 @@
+drop index idx_status on wallet_group;
+
+alter table wallet_group
+    drop column retired_at,
+    drop column created_at,
+    drop column status;
@@ -0,0 +1,8 @@
+-- 收款钱包组支持运行时新增和停用，停用的地址保留记录但不再监控
+alter table wallet_group
+    add column status     varchar(16) default 'active'          not null comment '状态：active/retired' after last_sync_time,
+    add column created_at timestamp   default CURRENT_TIMESTAMP not null comment '加入监控时间' after status,
+    add column retired_at timestamp                             null comment '停用时间' after created_at;
+
+create index idx_status
+    on wallet_group (status);
@@ -0,0 +1 @@
+drop table if exists deposit_address;
@@ -0,0 +1,25 @@
+-- 按订单分配的收款地址，由账户扩展公钥按 BIP44 路径 m/44'/3'/account'/0/addr_index 派生
+create table deposit_address
+(
+    id         bigint unsigned auto_increment
+        primary key,
+    address    varchar(34)                            not null comment '收款地址',
+    addr_index int unsigned                           not null comment '派生序号',
+    order_no   varchar(64)                            not null comment '订单号',
+    user_id    varchar(64)  default ''                not null comment '用户ID',
+    status     varchar(16)  default 'assigned'        not null comment '状态：assigned/paid',
+    tx_hash    varchar(64)  default ''                not null comment '首笔支付交易哈希',
+    received   bigint       default 0                 not null comment '首笔支付金额（ELON）',
+    created_at timestamp    default CURRENT_TIMESTAMP not null comment '分配时间',
+    paid_at    timestamp                              null comment '支付时间',
+    constraint uk_address
+        unique (address),
+    constraint uk_addr_index
+        unique (addr_index),
+    constraint uk_order_no
+        unique (order_no)
+)
+    comment '订单收款地址';
+
+create index idx_user_id
+    on deposit_address (user_id);
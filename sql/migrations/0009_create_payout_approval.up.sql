@@ -0,0 +1,20 @@
+-- 转出审批：超过审批阈值的转出需运营批准后才能签名
+create table payout_approval
+(
+    id         bigint unsigned auto_increment
+        primary key,
+    from_addr  varchar(34)                            not null comment '转出钱包',
+    ref        varchar(64)                            not null comment '业务单号',
+    to_addr    varchar(34)                            not null comment '收款地址',
+    value      bigint                                 not null comment '金额（ELON）',
+    status     varchar(16)  default 'pending'         not null comment '状态：pending/approved/rejected/used',
+    note       varchar(255) default ''                not null comment '审批备注',
+    created_at timestamp    default CURRENT_TIMESTAMP not null comment '创建时间',
+    decided_at timestamp                              null comment '审批时间',
+    constraint uk_from_ref
+        unique (from_addr, ref)
+)
+    comment '转出审批';
+
+create index idx_status
+    on payout_approval (status);
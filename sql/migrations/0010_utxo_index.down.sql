@@ -0,0 +1,16 @@
+drop table if exists utxo_block;
+
+drop index idx_spent_height on utxo;
+
+drop index idx_block_height on utxo;
+
+alter table utxo
+    drop index uk_outpoint,
+    drop column spent_height,
+    drop column spent_tx,
+    drop column block_hash,
+    drop column block_height,
+    add constraint uk_tx_hash unique (tx_hash);
+
+create index idx_index
+    on utxo (`index`);
@@ -0,0 +1,25 @@
+-- UTXO索引：同一交易有多个输出，按 (tx_hash, index) 唯一；记录输出和花费所在的区块，链重组时按高度撤销
+alter table utxo
+    drop index uk_tx_hash,
+    drop index idx_index,
+    add column block_height bigint      default 0  not null comment '所在区块高度' after value,
+    add column block_hash   varchar(64) default '' not null comment '所在区块哈希' after block_height,
+    add column spent_tx     varchar(64) default '' not null comment '花费交易哈希' after spent,
+    add column spent_height bigint      default 0  not null comment '花费交易所在区块高度' after spent_tx,
+    add constraint uk_outpoint unique (tx_hash, `index`);
+
+create index idx_block_height
+    on utxo (block_height);
+
+create index idx_spent_height
+    on utxo (spent_height);
+
+create table utxo_block
+(
+    height     bigint                              not null comment '区块高度'
+        primary key,
+    hash       varchar(64)                         not null comment '区块哈希',
+    prev_hash  varchar(64)                         not null comment '上一区块哈希',
+    indexed_at timestamp default CURRENT_TIMESTAMP not null comment '索引时间'
+)
+    comment 'UTXO索引已处理的区块，只保留最近的区块用于识别链重组';
@@ -0,0 +1,4 @@
+drop index idx_status_queued_time on order_id;
+
+alter table order_id
+    drop column raw_tx;
@@ -0,0 +1,6 @@
+-- 打款交易签名后先写入订单再广播，广播结果不明或进程崩溃时可按交易ID查询并重新广播同一笔交易
+alter table order_id
+    add column raw_tx mediumtext null comment '已签名的打款交易' after txid;
+
+create index idx_status_queued_time
+    on order_id (status, queued_time);
@@ -0,0 +1 @@
+drop table payout_spend;
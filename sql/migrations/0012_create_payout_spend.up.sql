@@ -0,0 +1,23 @@
+-- 转出记录：签名前授权时写入，广播后置为 committed，未广播时释放；每日额度和频率按此表统计，重启后不清零
+create table payout_spend
+(
+    id          bigint unsigned auto_increment
+        primary key,
+    spend_key   char(64)                              not null comment '交易去掉签名后的哈希',
+    n           int                                   not null comment '在转出输出中的序号',
+    from_addr   varchar(34)                           not null comment '转出钱包',
+    to_addr     varchar(34)                           not null comment '收款地址',
+    value       bigint                                not null comment '金额（ELON）',
+    approval_id bigint unsigned default 0             not null comment '使用的审批单',
+    status      varchar(16)     default 'reserved'    not null comment '状态：reserved/committed/released',
+    created_at  timestamp       default CURRENT_TIMESTAMP not null comment '授权时间',
+    constraint uk_spend_key_n
+        unique (spend_key, n)
+)
+    comment '转出记录';
+
+create index idx_from_created
+    on payout_spend (from_addr, created_at);
+
+create index idx_to_created
+    on payout_spend (to_addr, created_at);
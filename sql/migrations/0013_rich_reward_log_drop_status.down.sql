@@ -0,0 +1,2 @@
+alter table rich_reward_log
+    add column status bit default b'0' not null comment '今日是否已经领取，0未领取，1已领取' after latest_detail;
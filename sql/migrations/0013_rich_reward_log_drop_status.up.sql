@@ -0,0 +1,3 @@
+-- status 在领取时置1，领取日切换时没有重置，不能表示当天是否已领取；是否已领取以 latest 判断
+alter table rich_reward_log
+    drop column status;
@@ -0,0 +1,6 @@
+alter table rich_reward_accrual_run
+    drop column skipped;
+
+alter table rich_reward_accrual_log
+    drop column skip_reason,
+    drop column status;
@@ -0,0 +1,7 @@
+-- 地址的稀有度未配置收益或累加后超出 total_reward 范围时记为跳过，不再阻塞当天和之后的累加
+alter table rich_reward_accrual_log
+    add column status      tinyint      default 0  not null comment '0:已累加 1:已跳过' after amount,
+    add column skip_reason varchar(255) default '' not null comment '跳过原因' after status;
+
+alter table rich_reward_accrual_run
+    add column skipped int default 0 not null comment '跳过的地址数' after holders;
@@ -0,0 +1,10 @@
+// Package migrations 数据库表结构迁移文件，由 claimask migrate 子命令执行
+// 新增迁移时按下一个版本号成对添加 NNNN_name.up.sql 和 NNNN_name.down.sql，已发布的迁移文件不再修改
+package migrations
+
+import "embed"
+
+// FS 内嵌的迁移文件
+//
+//go:embed *.sql
+var FS embed.FS